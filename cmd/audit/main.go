@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/redbonzai/user-management-api/internal/config"
+	"github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// audit verify walks the audit log hash chain and exits non-zero if it has been tampered with
+func main() {
+	if len(os.Args) != 2 || os.Args[1] != "verify" {
+		fmt.Fprintln(os.Stderr, "usage: audit verify")
+		os.Exit(2)
+	}
+
+	// The .env file is optional here; the environment may already be populated
+	_ = godotenv.Load()
+	logger.InitLogger()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Fatal("could not load config:", zap.Error(err))
+	}
+	db.InitDB(cfg)
+
+	auditService := services.NewAuditService(repository.NewAuditRepository(db.DB))
+	result, err := auditService.Verify(context.Background())
+	if err != nil {
+		logger.Fatal("could not verify audit log:", zap.Error(err))
+	}
+
+	output, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(output))
+	if !result.Valid {
+		os.Exit(1)
+	}
+}
@@ -1,6 +1,7 @@
 package aws
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -14,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository/dynamo"
 )
 
 // CreateAWSSession initializes an AWS session with LocalStack.
@@ -173,29 +175,10 @@ func CreateSQSQueue(svc *sqs.SQS) {
 	}
 }
 
-// CreateDynamoDBTable creates a DynamoDB table.
-func CreateDynamoDBTable(svc *dynamodb.DynamoDB) {
-	_, err := svc.CreateTable(&dynamodb.CreateTableInput{
-		TableName: aws.String("MyTable"),
-		AttributeDefinitions: []*dynamodb.AttributeDefinition{
-			{
-				AttributeName: aws.String("ID"),
-				AttributeType: aws.String("S"),
-			},
-		},
-		KeySchema: []*dynamodb.KeySchemaElement{
-			{
-				AttributeName: aws.String("ID"),
-				KeyType:       aws.String("HASH"),
-			},
-		},
-		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
-			ReadCapacityUnits:  aws.Int64(5),
-			WriteCapacityUnits: aws.Int64(5),
-		},
-	})
-
-	if err != nil {
-		log.Fatalf("Failed to create DynamoDB table: %v", err)
+// CreateDynamoDBTables creates the tables of the DynamoDB user repository, if they do not exist yet.
+func CreateDynamoDBTables(svc *dynamodb.DynamoDB) {
+	if err := dynamo.CreateTables(context.Background(), svc, dynamo.DefaultTables); err != nil {
+		log.Fatalf("Failed to create DynamoDB tables: %v", err)
 	}
+	fmt.Printf("DynamoDB tables %s and %s are ready\n", dynamo.DefaultTables.Users, dynamo.DefaultTables.Tokens)
 }
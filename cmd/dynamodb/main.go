@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/joho/godotenv"
+	"github.com/redbonzai/user-management-api/cmd/aws"
+)
+
+// dynamodb create-tables provisions the tables of the DynamoDB user repository on LocalStack
+func main() {
+	if len(os.Args) != 2 || os.Args[1] != "create-tables" {
+		fmt.Fprintln(os.Stderr, "usage: dynamodb create-tables")
+		os.Exit(2)
+	}
+
+	// The .env file is optional here; the environment may already be populated
+	_ = godotenv.Load()
+	aws.CreateDynamoDBTables(dynamodb.New(aws.CreateAWSSession()))
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/redbonzai/user-management-api/internal/config"
+	"github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/importer"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository"
+	"github.com/redbonzai/user-management-api/internal/notifications"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// mappingFlag collects repeated -map source=field flags
+type mappingFlag []string
+
+func (mapping *mappingFlag) String() string { return strings.Join(*mapping, ",") }
+
+func (mapping *mappingFlag) Set(value string) error {
+	*mapping = append(*mapping, value)
+	return nil
+}
+
+// import bulk-creates users from a CSV or NDJSON file, printing the report as JSON. It exits
+// non-zero when any row failed.
+func main() {
+	var mappings mappingFlag
+	organizationID := flag.Int("org", 1, "organization ID to import into")
+	file := flag.String("file", "-", "CSV or NDJSON file, - for stdin")
+	format := flag.String("format", "", "csv or ndjson; defaults to the file extension")
+	mode := flag.String("mode", interfaces.ImportModeTransactional, "transactional or best_effort")
+	dryRun := flag.Bool("dry-run", false, "validate without creating anything")
+	invite := flag.Bool("invite", false, "invite rows that have no password")
+	invitedBy := flag.Int("invited-by", 0, "user ID the import runs as and records as the inviter; must be an owner to import owners")
+	reportPath := flag.String("report", "", "write the per-row error report as CSV to this path")
+	flag.Var(&mappings, "map", "column mapping as source=field, repeatable")
+	flag.Parse()
+
+	// The .env file is optional here; the environment may already be populated
+	_ = godotenv.Load()
+	logger.InitLogger()
+
+	mapping, err := importer.ParseMapping(mappings)
+	if err != nil {
+		logger.Fatal("invalid mapping:", zap.Error(err))
+	}
+	source, err := open(*file)
+	if err != nil {
+		logger.Fatal("could not open import file:", zap.Error(err))
+	}
+	defer source.Close()
+	if *format == "" {
+		*format = formatFromExtension(*file)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Fatal("could not load config:", zap.Error(err))
+	}
+	db.InitDB(cfg)
+
+	txManager := repository.NewTxManager(db.DB)
+	organizationService := services.NewOrganizationService(repository.NewOrganizationRepository(db.DB), txManager)
+	attributeService := services.NewAttributeService(repository.NewAttributeRepository(db.DB), organizationService)
+	userRepo := repository.NewUserRepository(db.DB, nil, cfg.QueryTimeout)
+	// The server's relay publishes the events of imported users
+	outboxRepo := repository.NewOutboxRepository(db.DB)
+	auditService := services.NewAuditService(repository.NewAuditRepository(db.DB))
+	userService := services.NewService(userRepo, attributeService, txManager, outboxRepo, auditService)
+	invitationService := services.NewInvitationService(
+		repository.NewInvitationRepository(db.DB),
+		txManager,
+		userService,
+		organizationService,
+		notifications.NewLogNotifier(cfg.InvitationURL),
+	)
+	importService := services.NewImportService(userRepo, txManager, outboxRepo, organizationService, invitationService)
+
+	// Interrupting the import rolls back a transactional one
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := importService.Import(ctx, *organizationID, source, interfaces.ImportOptions{
+		Format:                *format,
+		Mode:                  *mode,
+		DryRun:                *dryRun,
+		Mapping:               mapping,
+		InviteWithoutPassword: *invite,
+		InvitedBy:             *invitedBy,
+	})
+	if err != nil {
+		logger.Fatal("import failed:", zap.Error(err))
+	}
+
+	if *reportPath != "" {
+		if err := writeReport(*reportPath, report); err != nil {
+			logger.Fatal("could not write report:", zap.Error(err))
+		}
+	}
+	output, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(output))
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func open(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+func formatFromExtension(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return interfaces.ImportFormatNDJSON
+	}
+	return interfaces.ImportFormatCSV
+}
+
+func writeReport(path string, report interfaces.ImportReport) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := importer.WriteErrorReport(file, report); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/joho/godotenv"
 	"github.com/redbonzai/user-management-api/internal/config"
@@ -41,9 +42,15 @@ func main() {
 	}
 
 	logger.Info("-- Config loaded successfully --")
+
+	// `migrate ...` manages the schema instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(cfg, os.Args[2:])
+		return
+	}
 	db.InitDB(cfg)
 
-	router := infrastructure.NewRouter()
+	router := infrastructure.NewRouter(cfg)
 
 	// Serve static files for Swagger
 	router.Static("/swagger", "docs/swagger.yaml")
@@ -64,7 +71,7 @@ func main() {
 	//lambdaArn := fmt.Sprintf(os.Getenv("LAMBDA_ARN"))
 	//aws.CreateAPIGateway(apiGateWayClient, lambdaArn)
 	//aws.CreateSQSQueue(sqsClient)
-	//aws.CreateDynamoDBTable(dynamodbClient)
+	//aws.CreateDynamoDBTables(dynamodbClient)
 
 	if err := router.Start(cfg.ServerAddress); err != nil {
 		logger.Fatal("Server failed to start", zap.Error(err))
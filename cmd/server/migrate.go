@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/redbonzai/user-management-api/internal/config"
+	"github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const migrateUsage = "usage: user-management-api migrate up | down [N] | to VERSION | status"
+
+// migrate moves the database between the embedded migrations:
+//
+//	migrate up          applies every pending migration
+//	migrate down [N]    reverts the last N migrations, 1 by default
+//	migrate to VERSION  applies or reverts migrations until VERSION; 0 reverts them all
+//	migrate status      lists the applied and pending migrations
+func migrate(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		usage()
+	}
+
+	database, err := db.Connect(cfg)
+	if err != nil {
+		logger.Fatal("could not connect to the database:", zap.Error(err))
+	}
+	defer database.Close()
+
+	migrations, err := db.EmbeddedMigrations(cfg.DatabaseDriver)
+	if err != nil {
+		logger.Fatal("could not load the migrations:", zap.Error(err))
+	}
+	migrator := db.NewMigrator(database, migrations)
+
+	// Interrupting also stops waiting for another process's migrations
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		err = migrator.Up(ctx)
+	case args[0] == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				usage()
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case args[0] == "to" && len(args) == 2:
+		version, parseErr := strconv.ParseInt(args[1], 10, 64)
+		if parseErr != nil {
+			usage()
+		}
+		err = migrator.To(ctx, version)
+	case args[0] == "status" && len(args) == 1:
+		err = printStatus(ctx, migrator)
+	default:
+		usage()
+	}
+	if err != nil {
+		logger.Fatal("migration failed:", zap.Error(err))
+	}
+}
+
+func printStatus(ctx context.Context, migrator *db.Migrator) error {
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, migration := range status.Applied {
+		fmt.Printf("applied  %d_%s\n", migration.Version, migration.Name)
+	}
+	for _, migration := range status.Pending {
+		fmt.Printf("pending  %d_%s\n", migration.Version, migration.Name)
+	}
+
+	fmt.Printf("version %d of %d", status.Version, status.Latest)
+	switch {
+	case status.Dirty:
+		fmt.Print(", dirty")
+	case status.Ahead():
+		fmt.Print(", ahead of the migrations")
+	}
+	fmt.Println()
+	return nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, migrateUsage)
+	os.Exit(2)
+}
@@ -0,0 +1,30 @@
+package authz
+
+import (
+	"encoding/json"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// UserAttributes exposes the JSON fields of a user as policy attributes, never including the
+// password. The user's custom attributes are also exposed at the top level, e.g. department,
+// unless a field of the same name takes precedence.
+func UserAttributes(user interfaces.User) map[string]interface{} {
+	attributes := make(map[string]interface{})
+	encoded, err := json.Marshal(user)
+	if err != nil {
+		return attributes
+	}
+	if err := json.Unmarshal(encoded, &attributes); err != nil {
+		return attributes
+	}
+	delete(attributes, "password")
+	if custom, ok := attributes["attributes"].(map[string]interface{}); ok {
+		for name, value := range custom {
+			if _, taken := attributes[name]; !taken && name != "password" {
+				attributes[name] = value
+			}
+		}
+	}
+	return attributes
+}
@@ -0,0 +1,189 @@
+package authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// WatchInterval is how often Watch checks the policy file for changes
+const WatchInterval = 5 * time.Second
+
+type engine struct {
+	path     string
+	mutex    sync.RWMutex
+	policies []Policy
+	modTime  time.Time
+}
+
+// NewEngine loads policies from a YAML or JSON file. A missing file yields an engine that denies
+// everything until the file appears and is picked up by Watch or Reload.
+func NewEngine(path string) (interfaces.PolicyEngine, error) {
+	engine := &engine{path: path}
+	if err := engine.Reload(); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// NewEngineFromPolicies builds an engine from in-memory policies, without a backing file
+func NewEngineFromPolicies(policies []Policy) (interfaces.PolicyEngine, error) {
+	for i := range policies {
+		if err := policies[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &engine{policies: policies}, nil
+}
+
+// ParsePolicies decodes a policy document; JSON is accepted as a subset of YAML
+func ParsePolicies(data []byte) ([]Policy, error) {
+	document, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown keys are rejected so that a typo cannot silently drop a condition
+	var set PolicySet
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&set); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for i := range set.Policies {
+		if err := set.Policies[i].validate(); err != nil {
+			return nil, err
+		}
+		if seen[set.Policies[i].ID] {
+			return nil, fmt.Errorf("duplicate policy id %s", set.Policies[i].ID)
+		}
+		seen[set.Policies[i].ID] = true
+	}
+	return set.Policies, nil
+}
+
+// Reload re-reads the policy file. On error the previously loaded policies stay in force.
+func (engine *engine) Reload() error {
+	if engine.path == "" {
+		return nil
+	}
+	info, err := os.Stat(engine.path)
+	if err != nil {
+		logger.Error("Policy file unavailable", zap.String("path", engine.path), zap.Error(err))
+		return err
+	}
+	data, err := os.ReadFile(engine.path)
+	if err != nil {
+		logger.Error("Error reading policy file", zap.String("path", engine.path), zap.Error(err))
+		return err
+	}
+	policies, err := ParsePolicies(data)
+	if err != nil {
+		logger.Error("Invalid policy file, keeping previous policies", zap.String("path", engine.path), zap.Error(err))
+		return err
+	}
+
+	engine.mutex.Lock()
+	engine.policies = policies
+	engine.modTime = info.ModTime()
+	engine.mutex.Unlock()
+
+	logger.Info("Policies loaded", zap.String("path", engine.path), zap.Int("count", len(policies)))
+	return nil
+}
+
+// Watch polls the policy file and reloads it whenever its modification time changes,
+// until stop is closed.
+func Watch(policyEngine interfaces.PolicyEngine, interval time.Duration, stop <-chan struct{}) {
+	engine, ok := policyEngine.(*engine)
+	if !ok || engine.path == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(engine.path)
+			if err != nil {
+				continue
+			}
+			engine.mutex.RLock()
+			changed := !info.ModTime().Equal(engine.modTime)
+			engine.mutex.RUnlock()
+			if changed {
+				_ = engine.Reload()
+			}
+		}
+	}
+}
+
+// Evaluate applies deny-overrides: any matching deny wins, otherwise a matching allow grants
+// access, otherwise access is denied by default. Field-scoped policies only contribute to
+// DeniedFields.
+func (engine *engine) Evaluate(request interfaces.AuthzRequest, explain bool) interfaces.Decision {
+	engine.mutex.RLock()
+	policies := engine.policies
+	engine.mutex.RUnlock()
+
+	decision := interfaces.Decision{Reason: "no policy allows this action"}
+	var allowedBy, deniedBy string
+	deniedFields := make(map[string]bool)
+
+	for _, policy := range policies {
+		matched, reason := policy.evaluate(request)
+		if explain {
+			decision.Explanation = append(decision.Explanation, interfaces.PolicyEvaluation{
+				PolicyID: policy.ID,
+				Effect:   policy.Effect,
+				Matched:  matched,
+				Reason:   reason,
+			})
+		}
+		if !matched {
+			continue
+		}
+
+		switch {
+		case len(policy.Fields) > 0:
+			if policy.Effect == interfaces.EffectDeny {
+				for _, field := range policy.Fields {
+					deniedFields[field] = true
+				}
+			}
+		case policy.Effect == interfaces.EffectDeny && deniedBy == "":
+			deniedBy = policy.ID
+		case policy.Effect == interfaces.EffectAllow && allowedBy == "":
+			allowedBy = policy.ID
+		}
+	}
+
+	switch {
+	case deniedBy != "":
+		decision.PolicyID = deniedBy
+		decision.Reason = "denied by policy " + deniedBy
+	case allowedBy != "":
+		decision.Allowed = true
+		decision.PolicyID = allowedBy
+		decision.Reason = "allowed by policy " + allowedBy
+		for field := range deniedFields {
+			decision.DeniedFields = append(decision.DeniedFields, field)
+		}
+		sort.Strings(decision.DeniedFields)
+	}
+	return decision
+}
@@ -0,0 +1,224 @@
+package authz
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+const (
+	OpEquals    = "eq"
+	OpNotEquals = "ne"
+	OpIn        = "in"
+	OpNotIn     = "not_in"
+	OpContains  = "contains"
+	OpExists    = "exists"
+	OpNotExists = "not_exists"
+	OpMatches   = "matches"
+)
+
+// PolicySet is the document loaded from the policy file
+type PolicySet struct {
+	Policies []Policy `json:"policies"`
+}
+
+// Policy grants or denies actions when every condition holds.
+// A policy with fields only applies to those resource fields; it never decides the action as a whole.
+type Policy struct {
+	ID          string      `json:"id"`
+	Description string      `json:"description"`
+	Effect      string      `json:"effect"`
+	Actions     []string    `json:"actions"`
+	Fields      []string    `json:"fields"`
+	Conditions  []Condition `json:"conditions"`
+}
+
+// Condition compares the attribute at Attr with a literal Value or with the attribute at Ref.
+// Attribute paths start with "subject." or "resource.", e.g. subject.department.
+type Condition struct {
+	Attr  string      `json:"attr"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+	Ref   string      `json:"ref"`
+
+	pattern *regexp.Regexp
+}
+
+func (policy *Policy) validate() error {
+	if policy.ID == "" {
+		return fmt.Errorf("policy without id")
+	}
+	if policy.Effect != interfaces.EffectAllow && policy.Effect != interfaces.EffectDeny {
+		return fmt.Errorf("policy %s: effect must be allow or deny", policy.ID)
+	}
+	if len(policy.Actions) == 0 {
+		return fmt.Errorf("policy %s: at least one action is required", policy.ID)
+	}
+	for i := range policy.Conditions {
+		if err := policy.Conditions[i].validate(); err != nil {
+			return fmt.Errorf("policy %s: condition %d: %w", policy.ID, i+1, err)
+		}
+	}
+	return nil
+}
+
+func (condition *Condition) validate() error {
+	if !isAttributePath(condition.Attr) {
+		return fmt.Errorf("attr %q must start with subject. or resource.", condition.Attr)
+	}
+	if condition.Ref != "" && !isAttributePath(condition.Ref) {
+		return fmt.Errorf("ref %q must start with subject. or resource.", condition.Ref)
+	}
+	switch condition.Op {
+	case OpEquals, OpNotEquals, OpIn, OpNotIn, OpContains, OpExists, OpNotExists:
+	case OpMatches:
+		pattern, ok := condition.Value.(string)
+		if !ok {
+			return fmt.Errorf("matches requires a string pattern")
+		}
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		condition.pattern = compiled
+	default:
+		return fmt.Errorf("unknown operator %q", condition.Op)
+	}
+	return nil
+}
+
+// matchesAction supports exact actions, "*" and prefix wildcards such as "users:*"
+func (policy Policy) matchesAction(action string) bool {
+	for _, pattern := range policy.Actions {
+		if pattern == "*" || pattern == action {
+			return true
+		}
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(action, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate returns whether all conditions hold and, if not, a description of the first failure
+func (policy Policy) evaluate(request interfaces.AuthzRequest) (bool, string) {
+	if !policy.matchesAction(request.Action) {
+		return false, fmt.Sprintf("action %q not in %v", request.Action, policy.Actions)
+	}
+	for i, condition := range policy.Conditions {
+		if ok, detail := condition.holds(request); !ok {
+			return false, fmt.Sprintf("condition %d failed: %s", i+1, detail)
+		}
+	}
+	return true, "all conditions matched"
+}
+
+func (condition Condition) holds(request interfaces.AuthzRequest) (bool, string) {
+	actual, present := lookup(request, condition.Attr)
+	expected := condition.Value
+	if condition.Ref != "" {
+		expected, _ = lookup(request, condition.Ref)
+	}
+	detail := fmt.Sprintf("%s (%v) %s %v", condition.Attr, actual, condition.Op, expected)
+
+	switch condition.Op {
+	case OpExists:
+		return present && actual != nil, detail
+	case OpNotExists:
+		return !present || actual == nil, detail
+	}
+	if !present {
+		return false, fmt.Sprintf("%s is not set", condition.Attr)
+	}
+
+	switch condition.Op {
+	case OpEquals:
+		return equal(actual, expected), detail
+	case OpNotEquals:
+		return !equal(actual, expected), detail
+	case OpIn:
+		return contains(expected, actual), detail
+	case OpNotIn:
+		return !contains(expected, actual), detail
+	case OpContains:
+		return contains(actual, expected), detail
+	case OpMatches:
+		value, ok := actual.(string)
+		return ok && condition.pattern.MatchString(value), detail
+	}
+	return false, detail
+}
+
+func isAttributePath(path string) bool {
+	return strings.HasPrefix(path, "subject.") || strings.HasPrefix(path, "resource.")
+}
+
+// lookup resolves a dotted attribute path against the request, descending into nested maps
+func lookup(request interfaces.AuthzRequest, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	var current interface{}
+	switch parts[0] {
+	case "subject":
+		current = request.Subject
+	case "resource":
+		current = request.Resource
+	default:
+		return nil, false
+	}
+	for _, part := range parts[1:] {
+		attributes, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = attributes[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// equal compares loosely so that JSON numbers match Go ints and *string fields match strings
+func equal(left, right interface{}) bool {
+	left, right = normalize(left), normalize(right)
+	if leftNumber, ok := left.(float64); ok {
+		rightNumber, ok := right.(float64)
+		return ok && leftNumber == rightNumber
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func contains(collection, item interface{}) bool {
+	value := reflect.ValueOf(normalize(collection))
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if equal(value.Index(i).Interface(), item) {
+				return true
+			}
+		}
+	case reflect.String:
+		needle, ok := normalize(item).(string)
+		return ok && strings.Contains(value.String(), needle)
+	}
+	return false
+}
+
+func normalize(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case int:
+		return float64(typed)
+	case int64:
+		return float64(typed)
+	case float32:
+		return float64(typed)
+	case *string:
+		if typed == nil {
+			return nil
+		}
+		return *typed
+	}
+	return value
+}
@@ -3,15 +3,42 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	ServerAddress    string
-	PostgresUser     string
-	PostgresPassword string
-	PostgresDB       string
-	PostgresHost     string
-	DatabaseURL      string
+	ServerAddress        string
+	PostgresUser         string
+	PostgresPassword     string
+	PostgresDB           string
+	PostgresHost         string
+	DatabaseDriver       string
+	DatabaseURL          string
+	InvitationURL        string
+	PolicyFile           string
+	UserRetention        time.Duration
+	ExportBucket         string
+	ErasureGrace         time.Duration
+	AvatarBucket         string
+	AvatarDir            string
+	QueryTimeout         time.Duration
+	AutoMigrate          bool
+	MaxOpenConns         int
+	MaxIdleConns         int
+	ConnMaxLifetime      time.Duration
+	ConnMaxIdleTime      time.Duration
+	ReadReplicaURLs      []string
+	ReplicaCheckInterval time.Duration
+	UserCache            string
+	UserCacheTTL         time.Duration
+	UserCacheSize        int
+	UserCacheRedisURL    string
+	EventPublisher       string
+	EventWebhookURL      string
+	EventTopicARN        string
+	EventRelayInterval   time.Duration
 }
 
 func LoadConfig() (*Config, error) {
@@ -21,7 +48,18 @@ func LoadConfig() (*Config, error) {
 		PostgresPassword: os.Getenv("POSTGRES_PASSWORD"),
 		PostgresDB:       os.Getenv("POSTGRES_DB"),
 		PostgresHost:     os.Getenv("POSTGRES_HOST"),
+		DatabaseDriver:   os.Getenv("DB_DRIVER"),
 		DatabaseURL:      os.Getenv("DATABASE_URL"),
+		InvitationURL:    os.Getenv("INVITATION_URL"),
+		PolicyFile:       os.Getenv("POLICY_FILE"),
+		ExportBucket:     os.Getenv("EXPORT_BUCKET"),
+		AvatarBucket:     os.Getenv("AVATAR_BUCKET"),
+		AvatarDir:        os.Getenv("AVATAR_DIR"),
+	}
+
+	// Postgres is the default backend; DB_DRIVER=sqlite keeps everything in a single file
+	if cfg.DatabaseDriver == "" {
+		cfg.DatabaseDriver = "postgres"
 	}
 
 	// Check if any required environment variables are missing
@@ -29,17 +67,28 @@ func LoadConfig() (*Config, error) {
 	if cfg.ServerAddress == "" {
 		missingVars = append(missingVars, "SERVER_ADDRESS")
 	}
-	if cfg.PostgresUser == "" {
-		missingVars = append(missingVars, "POSTGRES_USER")
-	}
-	if cfg.PostgresPassword == "" {
-		missingVars = append(missingVars, "POSTGRES_PASSWORD")
-	}
-	if cfg.PostgresDB == "" {
-		missingVars = append(missingVars, "POSTGRES_DB")
-	}
-	if cfg.PostgresHost == "" {
-		missingVars = append(missingVars, "POSTGRES_HOST")
+
+	switch cfg.DatabaseDriver {
+	case "postgres":
+		if cfg.PostgresUser == "" {
+			missingVars = append(missingVars, "POSTGRES_USER")
+		}
+		if cfg.PostgresPassword == "" {
+			missingVars = append(missingVars, "POSTGRES_PASSWORD")
+		}
+		if cfg.PostgresDB == "" {
+			missingVars = append(missingVars, "POSTGRES_DB")
+		}
+		if cfg.PostgresHost == "" {
+			missingVars = append(missingVars, "POSTGRES_HOST")
+		}
+	case "sqlite":
+		// DATABASE_URL is the path of the database file
+		if cfg.DatabaseURL == "" {
+			cfg.DatabaseURL = "data/user-management.db"
+		}
+	default:
+		return nil, fmt.Errorf("invalid DB_DRIVER: %q", cfg.DatabaseDriver)
 	}
 
 	if len(missingVars) > 0 {
@@ -57,6 +106,166 @@ func LoadConfig() (*Config, error) {
 		)
 	}
 
+	// Link embedded in invitation notifications
+	if cfg.InvitationURL == "" {
+		cfg.InvitationURL = "http://localhost:4200/accept-invitation"
+	}
+
+	// Attribute-based access policies, reloaded when the file changes
+	if cfg.PolicyFile == "" {
+		cfg.PolicyFile = "config/policies.yaml"
+	}
+
+	// Soft-deleted users are purged once they have been deleted for this long
+	cfg.UserRetention = 30 * 24 * time.Hour
+	if retention := os.Getenv("USER_RETENTION"); retention != "" {
+		parsed, err := time.ParseDuration(retention)
+		if err != nil {
+			return nil, fmt.Errorf("invalid USER_RETENTION: %w", err)
+		}
+		cfg.UserRetention = parsed
+	}
+
+	// Admin-requested erasures can be cancelled until this much time has passed
+	cfg.ErasureGrace = 14 * 24 * time.Hour
+	if grace := os.Getenv("ERASURE_GRACE_PERIOD"); grace != "" {
+		parsed, err := time.ParseDuration(grace)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ERASURE_GRACE_PERIOD: %w", err)
+		}
+		cfg.ErasureGrace = parsed
+	}
+
+	// Avatars are kept on the local filesystem unless AVATAR_BUCKET selects S3
+	if cfg.AvatarDir == "" {
+		cfg.AvatarDir = "data/avatars"
+	}
+
+	// Each user repository call is cancelled after this long, on top of the request's own context
+	cfg.QueryTimeout = 5 * time.Second
+	if timeout := os.Getenv("DB_QUERY_TIMEOUT"); timeout != "" {
+		parsed, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_QUERY_TIMEOUT: %w", err)
+		}
+		cfg.QueryTimeout = parsed
+	}
+
+	// A database behind the embedded migrations is migrated at startup rather than refused. SQLite
+	// serves a single instance, so it is migrated by default; PostgreSQL deployments migrate with
+	// the migrate subcommand before rolling out.
+	cfg.AutoMigrate = cfg.DatabaseDriver == "sqlite"
+	if autoMigrate := os.Getenv("DB_AUTO_MIGRATE"); autoMigrate != "" {
+		parsed, err := strconv.ParseBool(autoMigrate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_AUTO_MIGRATE: %w", err)
+		}
+		cfg.AutoMigrate = parsed
+	}
+
+	// Pool limits of the primary database and of each read replica. DB_MAX_OPEN_CONNS=0 lifts the
+	// limit, and lifetimes of 0 let connections live on.
+	cfg.MaxOpenConns, cfg.MaxIdleConns = 25, 10
+	for name, target := range map[string]*int{"DB_MAX_OPEN_CONNS": &cfg.MaxOpenConns, "DB_MAX_IDLE_CONNS": &cfg.MaxIdleConns} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				return nil, fmt.Errorf("invalid %s: %q", name, value)
+			}
+			*target = parsed
+		}
+	}
+	cfg.ConnMaxLifetime, cfg.ConnMaxIdleTime = 30*time.Minute, 5*time.Minute
+	for name, target := range map[string]*time.Duration{"DB_CONN_MAX_LIFETIME": &cfg.ConnMaxLifetime, "DB_CONN_MAX_IDLE_TIME": &cfg.ConnMaxIdleTime} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	// Reads that need not see the latest writes go to these PostgreSQL replicas, which are
+	// checked this often and skipped while they cannot be reached
+	for _, url := range strings.Split(os.Getenv("DB_READ_REPLICAS"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			cfg.ReadReplicaURLs = append(cfg.ReadReplicaURLs, url)
+		}
+	}
+	if len(cfg.ReadReplicaURLs) > 0 && cfg.DatabaseDriver != "postgres" {
+		return nil, fmt.Errorf("DB_READ_REPLICAS requires DB_DRIVER=postgres")
+	}
+	cfg.ReplicaCheckInterval = 5 * time.Second
+	if interval := os.Getenv("DB_REPLICA_CHECK_INTERVAL"); interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid DB_REPLICA_CHECK_INTERVAL: %q", interval)
+		}
+		cfg.ReplicaCheckInterval = parsed
+	}
+
+	// Users looked up by ID or username are cached in process (memory) or on a Redis-protocol
+	// server shared by all instances (redis); none turns the cache off
+	cfg.UserCache = os.Getenv("USER_CACHE")
+	switch cfg.UserCache {
+	case "":
+		cfg.UserCache = "none"
+	case "none", "memory":
+	case "redis":
+		cfg.UserCacheRedisURL = os.Getenv("USER_CACHE_REDIS_URL")
+		if cfg.UserCacheRedisURL == "" {
+			return nil, fmt.Errorf("USER_CACHE=redis requires USER_CACHE_REDIS_URL")
+		}
+	default:
+		return nil, fmt.Errorf("invalid USER_CACHE: %q", cfg.UserCache)
+	}
+	cfg.UserCacheTTL = time.Minute
+	if ttl := os.Getenv("USER_CACHE_TTL"); ttl != "" {
+		parsed, err := time.ParseDuration(ttl)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid USER_CACHE_TTL: %q", ttl)
+		}
+		cfg.UserCacheTTL = parsed
+	}
+	cfg.UserCacheSize = 10000
+	if size := os.Getenv("USER_CACHE_SIZE"); size != "" {
+		parsed, err := strconv.Atoi(size)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid USER_CACHE_SIZE: %q", size)
+		}
+		cfg.UserCacheSize = parsed
+	}
+
+	// User events are recorded in the outbox and relayed to a webhook, an SNS topic or the log;
+	// none leaves them in the outbox until a publisher is configured
+	cfg.EventPublisher = os.Getenv("EVENT_PUBLISHER")
+	switch cfg.EventPublisher {
+	case "":
+		cfg.EventPublisher = "none"
+	case "none", "log":
+	case "webhook":
+		cfg.EventWebhookURL = os.Getenv("EVENT_WEBHOOK_URL")
+		if cfg.EventWebhookURL == "" {
+			return nil, fmt.Errorf("EVENT_PUBLISHER=webhook requires EVENT_WEBHOOK_URL")
+		}
+	case "sns":
+		cfg.EventTopicARN = os.Getenv("EVENT_TOPIC_ARN")
+		if cfg.EventTopicARN == "" {
+			return nil, fmt.Errorf("EVENT_PUBLISHER=sns requires EVENT_TOPIC_ARN")
+		}
+	default:
+		return nil, fmt.Errorf("invalid EVENT_PUBLISHER: %q", cfg.EventPublisher)
+	}
+	cfg.EventRelayInterval = time.Second
+	if interval := os.Getenv("EVENT_RELAY_INTERVAL"); interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid EVENT_RELAY_INTERVAL: %q", interval)
+		}
+		cfg.EventRelayInterval = parsed
+	}
+
 	// Print all configuration values for debugging
 	fmt.Printf("Config VARS: %+v\n", cfg) // %+v prints field names and values
 
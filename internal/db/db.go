@@ -1,7 +1,9 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 
 	_ "github.com/lib/pq"
@@ -12,15 +14,80 @@ var DB *sql.DB
 
 func InitDB(cfg *config.Config) {
 	var err error
-	DB, err = sql.Open("postgres", cfg.DatabaseURL)
+	DB, err = Connect(cfg)
 	if err != nil {
 		log.Fatalf("could not connect to the database: %v", err)
 	}
 
-	err = DB.Ping()
+	if err = CheckSchema(context.Background(), DB, cfg); err != nil {
+		log.Fatalf("could not start: %v", err)
+	}
+
+	Replicas, err = OpenReplicaSet(context.Background(), DB, cfg)
+	if err != nil {
+		log.Fatalf("could not open the read replicas: %v", err)
+	}
+	go Replicas.Watch(cfg.ReplicaCheckInterval, nil)
+
+	log.Printf("connected to the %s database successfully", cfg.DatabaseDriver)
+}
+
+// Connect opens the configured database with the configured pool limits and checks that it can
+// be reached
+func Connect(cfg *config.Config) (*sql.DB, error) {
+	database, err := Open(cfg.DatabaseDriver, cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	configurePool(database, cfg)
+	if err = database.Ping(); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("could not ping the database: %w", err)
+	}
+	return database, nil
+}
+
+// CheckSchema makes sure the database has the schema of the embedded migrations. A database
+// behind them is migrated if cfg.AutoMigrate is set and refused otherwise, since the
+// repositories would fail on it; one ahead of them, migrated by a newer release that may still
+// be rolling out, is accepted with a warning.
+func CheckSchema(ctx context.Context, database *sql.DB, cfg *config.Config) error {
+	migrations, err := EmbeddedMigrations(cfg.DatabaseDriver)
+	if err != nil {
+		return err
+	}
+	migrator := NewMigrator(database, migrations)
+	status, err := migrator.Status(ctx)
 	if err != nil {
-		log.Fatalf("could not ping the database: %v", err)
+		return fmt.Errorf("could not read the schema version: %w", err)
 	}
 
-	log.Println("connected to the database successfully")
+	switch {
+	case status.Dirty:
+		return fmt.Errorf("the schema is dirty at version %d: a migration failed part way", status.Version)
+	case status.Ahead():
+		log.Printf("warning: the schema is at version %d, newer than the latest migration %d", status.Version, status.Latest)
+	case len(status.Pending) > 0 && cfg.AutoMigrate:
+		if err = migrator.Up(ctx); err != nil {
+			return fmt.Errorf("could not migrate the database: %w", err)
+		}
+	case len(status.Pending) > 0:
+		return fmt.Errorf(
+			"the schema is at version %d, behind the latest migration %d (%d pending): run `migrate up` or set DB_AUTO_MIGRATE=true",
+			status.Version, status.Latest, len(status.Pending),
+		)
+	}
+	return nil
+}
+
+// Open returns a handle on the database without connecting to it. For SQLite the URL is the
+// path of the database file.
+func Open(driver, url string) (*sql.DB, error) {
+	switch driver {
+	case "postgres", "":
+		return sql.Open("postgres", url)
+	case "sqlite":
+		return OpenSQLite(url)
+	}
+	return nil, fmt.Errorf("unknown database driver %q", driver)
 }
@@ -0,0 +1,324 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"modernc.org/sqlite"
+)
+
+// migrationLock is the advisory lock key serializing migrations, so replicas starting together
+// apply each migration once
+const migrationLock = 7311
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationFile matches <version>_<name>.up.sql and <version>_<name>.down.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_-]+)\.(up|down)\.sql$`)
+
+// Migration is one version of the schema, with the statements leading to it from the version
+// before and back
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaStatus compares the version recorded in schema_migrations with the migrations
+type SchemaStatus struct {
+	Version int64
+	// Dirty is set when a migration run by another tool failed part way
+	Dirty   bool
+	Latest  int64
+	Applied []Migration
+	Pending []Migration
+}
+
+// Ahead reports whether the database was migrated past the migrations known here, e.g. by a
+// newer release
+func (status SchemaStatus) Ahead() bool {
+	return status.Version > status.Latest
+}
+
+// EmbeddedMigrations returns the migrations built into the binary for the database driver
+func EmbeddedMigrations(driver string) ([]Migration, error) {
+	dir := "migrations"
+	switch driver {
+	case "postgres", "":
+	case "sqlite":
+		dir = "migrations/sqlite"
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+	sub, err := fs.Sub(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+	return LoadMigrations(sub)
+}
+
+// LoadMigrations reads the migrations at the top of fsys, ordered by version. Every version needs
+// an up and a down file; any other file is an error, so a misnamed migration is not skipped.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name is not <version>_<name>.up.sql or .down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version", entry.Name())
+		}
+		contents, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %s: version %d is also named %s", entry.Name(), version, migration.Name)
+		}
+		if match[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		for direction, statements := range map[string]string{"up": migration.Up, "down": migration.Down} {
+			if strings.TrimSpace(statements) == "" {
+				return nil, fmt.Errorf("migration %d_%s: %s migration is missing or empty", migration.Version, migration.Name, direction)
+			}
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator moves a database between the versions of its migrations. Every migration runs in a
+// transaction of its own together with the update of schema_migrations, which keeps the layout
+// of the golang-migrate CLI. On PostgreSQL an advisory lock is held while migrating, so
+// concurrent runs wait for each other; SQLite serializes the transactions itself.
+type Migrator struct {
+	database   *sql.DB
+	migrations []Migration
+	sqlite     bool
+}
+
+func NewMigrator(database *sql.DB, migrations []Migration) *Migrator {
+	_, isSQLite := database.Driver().(*sqlite.Driver)
+	return &Migrator{database: database, migrations: migrations, sqlite: isSQLite}
+}
+
+// Status returns the version of the database without changing it
+func (migrator *Migrator) Status(ctx context.Context) (SchemaStatus, error) {
+	version, dirty, err := migrator.version(ctx, migrator.database)
+	if err != nil {
+		return SchemaStatus{}, err
+	}
+	status := SchemaStatus{Version: version, Dirty: dirty}
+	for _, migration := range migrator.migrations {
+		if migration.Version <= version {
+			status.Applied = append(status.Applied, migration)
+		} else {
+			status.Pending = append(status.Pending, migration)
+		}
+		status.Latest = migration.Version
+	}
+	return status, nil
+}
+
+// Up applies every pending migration
+func (migrator *Migrator) Up(ctx context.Context) error {
+	if len(migrator.migrations) == 0 {
+		return nil
+	}
+	return migrator.To(ctx, migrator.migrations[len(migrator.migrations)-1].Version)
+}
+
+// Down reverts the latest steps applied migrations
+func (migrator *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("invalid number of steps: %d", steps)
+	}
+	return migrator.locked(ctx, func(conn *sql.Conn) error {
+		version, _, err := migrator.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		index, err := migrator.index(version)
+		if err != nil {
+			return err
+		}
+		var target int64
+		if index-steps >= 0 {
+			target = migrator.migrations[index-steps].Version
+		}
+		return migrator.migrate(ctx, conn, target)
+	})
+}
+
+// To applies or reverts migrations until the database is at version, where 0 is the empty schema
+func (migrator *Migrator) To(ctx context.Context, version int64) error {
+	if _, err := migrator.index(version); err != nil {
+		return err
+	}
+	return migrator.locked(ctx, func(conn *sql.Conn) error {
+		return migrator.migrate(ctx, conn, version)
+	})
+}
+
+// locked runs migrate on a connection of its own holding the migration lock
+func (migrator *Migrator) locked(ctx context.Context, migrate func(*sql.Conn) error) error {
+	conn, err := migrator.database.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if !migrator.sqlite {
+		if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+			return fmt.Errorf("acquiring the migration lock: %w", err)
+		}
+		// Closing conn only returns it to the pool, so the session's lock is released explicitly,
+		// even if ctx was cancelled
+		defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLock)
+	}
+
+	_, err = conn.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	if err != nil {
+		return err
+	}
+	return migrate(conn)
+}
+
+// migrate takes one step at a time towards target. Each step reads the version again in its
+// transaction, so it starts from where the step before, or another process, left the database.
+func (migrator *Migrator) migrate(ctx context.Context, conn *sql.Conn, target int64) error {
+	for {
+		done, err := migrator.step(ctx, conn, target)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+func (migrator *Migrator) step(ctx context.Context, conn *sql.Conn, target int64) (bool, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	version, dirty, err := migrator.version(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	if dirty {
+		return false, fmt.Errorf("schema_migrations is dirty at version %d: a migration failed part way, so repair the schema and clear the dirty flag", version)
+	}
+	if version == target {
+		return true, nil
+	}
+	index, err := migrator.index(version)
+	if err != nil {
+		return false, err
+	}
+
+	// index is -1 at version 0, so index+1 is the first migration
+	var migration Migration
+	var statements, direction string
+	var next int64
+	if version < target {
+		migration = migrator.migrations[index+1]
+		statements, direction, next = migration.Up, "up", migration.Version
+	} else {
+		migration = migrator.migrations[index]
+		statements, direction = migration.Down, "down"
+		if index > 0 {
+			next = migrator.migrations[index-1].Version
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, statements); err != nil {
+		return false, fmt.Errorf("migration %d_%s %s: %w", migration.Version, migration.Name, direction, err)
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return false, err
+	}
+	if next != 0 {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", next); err != nil {
+			return false, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	log.Printf("migrated %s: %d_%s", direction, migration.Version, migration.Name)
+	return false, nil
+}
+
+// index returns the position of the migration of version, or -1 for version 0
+func (migrator *Migrator) index(version int64) (int, error) {
+	if version == 0 {
+		return -1, nil
+	}
+	for i, migration := range migrator.migrations {
+		if migration.Version == version {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("version %d is not among the migrations", version)
+}
+
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// version reads schema_migrations, where a database that has none is at version 0
+func (migrator *Migrator) version(ctx context.Context, database querier) (int64, bool, error) {
+	exists := "SELECT to_regclass('schema_migrations') IS NOT NULL"
+	if migrator.sqlite {
+		exists = "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')"
+	}
+	var found bool
+	if err := database.QueryRowContext(ctx, exists).Scan(&found); err != nil {
+		return 0, false, err
+	}
+	if !found {
+		return 0, false, nil
+	}
+
+	var version int64
+	var dirty bool
+	err := database.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
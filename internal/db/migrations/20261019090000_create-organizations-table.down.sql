@@ -0,0 +1,5 @@
+DROP TABLE organization_memberships;
+DROP INDEX idx_users_organization_id;
+DROP INDEX idx_users_organization_username;
+ALTER TABLE users DROP COLUMN organization_id;
+DROP TABLE organizations;
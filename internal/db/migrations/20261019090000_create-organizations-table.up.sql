@@ -0,0 +1,31 @@
+CREATE TABLE organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing users move into a default organization
+INSERT INTO organizations (id, name, slug) VALUES (1, 'Default', 'default');
+SELECT setval('organizations_id_seq', (SELECT MAX(id) FROM organizations));
+
+ALTER TABLE users ADD COLUMN organization_id INTEGER NOT NULL DEFAULT 1
+    REFERENCES organizations (id) ON DELETE CASCADE;
+ALTER TABLE users ALTER COLUMN organization_id DROP DEFAULT;
+
+-- Usernames are unique per organization rather than globally
+CREATE UNIQUE INDEX idx_users_organization_username ON users (organization_id, username);
+CREATE INDEX idx_users_organization_id ON users (organization_id);
+
+CREATE TABLE organization_memberships (
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role VARCHAR(30) NOT NULL DEFAULT 'member',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_memberships_user_id ON organization_memberships (user_id);
+
+INSERT INTO organization_memberships (organization_id, user_id, role)
+SELECT organization_id, id, 'member' FROM users;
@@ -0,0 +1,17 @@
+CREATE TABLE invitations (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    email VARCHAR(100) NOT NULL,
+    role VARCHAR(30) NOT NULL DEFAULT 'member',
+    status VARCHAR(30) NOT NULL DEFAULT 'pending',
+    invited_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    accepted_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_invitations_organization_status ON invitations (organization_id, status);
+
+-- At most one open invitation per email and organization
+CREATE UNIQUE INDEX idx_invitations_pending_email ON invitations (organization_id, lower(email))
+    WHERE status = 'pending';
@@ -0,0 +1,3 @@
+DROP TABLE user_group_roles;
+DROP TABLE user_group_members;
+DROP TABLE user_groups;
@@ -0,0 +1,7 @@
+DROP INDEX idx_users_deleted_at;
+DROP INDEX idx_users_organization_email;
+DROP INDEX idx_users_organization_username;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+CREATE UNIQUE INDEX idx_users_organization_username ON users (organization_id, username);
+ALTER TABLE users DROP COLUMN sessions_revoked_at;
+ALTER TABLE users DROP COLUMN deleted_at;
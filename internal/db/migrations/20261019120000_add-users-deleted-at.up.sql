@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN sessions_revoked_at TIMESTAMPTZ;
+
+-- Soft-deleted users no longer hold on to their username or email
+DROP INDEX idx_users_organization_username;
+CREATE UNIQUE INDEX idx_users_organization_username ON users (organization_id, username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_organization_email ON users (organization_id, email) WHERE deleted_at IS NULL;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
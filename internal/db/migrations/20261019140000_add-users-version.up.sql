@@ -0,0 +1,2 @@
+-- Incremented on every write; exposed to clients as the user's ETag
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,6 @@
+DROP TABLE erasure_requests;
+
+DROP INDEX idx_token_blacklist_user_id;
+ALTER TABLE token_blacklist DROP COLUMN user_id;
+
+ALTER TABLE users DROP COLUMN erased_at;
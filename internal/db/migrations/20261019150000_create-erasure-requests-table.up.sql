@@ -0,0 +1,22 @@
+-- Erased users keep an anonymized row so everything referencing them stays valid
+ALTER TABLE users ADD COLUMN erased_at TIMESTAMPTZ;
+
+-- Blacklisted tokens are attributed to their user so they can be exported and erased
+ALTER TABLE token_blacklist ADD COLUMN user_id INTEGER REFERENCES users (id) ON DELETE CASCADE;
+CREATE INDEX idx_token_blacklist_user_id ON token_blacklist (user_id);
+
+CREATE TABLE erasure_requests (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    requested_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(30) NOT NULL DEFAULT 'pending',
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMPTZ
+);
+
+-- At most one pending erasure per user
+CREATE UNIQUE INDEX idx_erasure_requests_pending_user ON erasure_requests (user_id) WHERE status = 'pending';
+CREATE INDEX idx_erasure_requests_due ON erasure_requests (scheduled_for) WHERE status = 'pending';
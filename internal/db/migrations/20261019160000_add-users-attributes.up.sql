@@ -0,0 +1,16 @@
+-- Organization-defined profile fields, validated against attribute_definitions on write
+ALTER TABLE users ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX idx_users_attributes ON users USING GIN (attributes jsonb_path_ops);
+
+CREATE TABLE attribute_definitions (
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    name VARCHAR(63) NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT false,
+    is_unique BOOLEAN NOT NULL DEFAULT false,
+    pattern TEXT NOT NULL DEFAULT '',
+    enum TEXT[] NOT NULL DEFAULT '{}',
+    visibility VARCHAR(30) NOT NULL DEFAULT 'public',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, name)
+);
@@ -0,0 +1,8 @@
+-- The images themselves live in blob storage under key_prefix; a new upload gets a new prefix
+CREATE TABLE user_avatars (
+    user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    key_prefix TEXT NOT NULL,
+    content_type VARCHAR(30) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
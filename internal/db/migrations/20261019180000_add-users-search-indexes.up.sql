@@ -0,0 +1,15 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Prefix full-text search over names, usernames and the parts of the email address. The
+-- 'simple' configuration does no stemming, which suits names better than a language does.
+ALTER TABLE users ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(username, '')), 'A') ||
+    setweight(to_tsvector('simple', translate(coalesce(email, ''), '@.', '  ')), 'B')
+) STORED;
+CREATE INDEX idx_users_search_vector ON users USING GIN (search_vector);
+
+-- Trigram indexes back the fuzzy matches for misspellings
+CREATE INDEX idx_users_name_trgm ON users USING GIN (name gin_trgm_ops);
+CREATE INDEX idx_users_username_trgm ON users USING GIN (username gin_trgm_ops);
+CREATE INDEX idx_users_email_trgm ON users USING GIN (email gin_trgm_ops);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_status_transitions;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+ALTER TABLE users ALTER COLUMN status DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN status SET DEFAULT NULL;
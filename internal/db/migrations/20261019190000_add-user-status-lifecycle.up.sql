@@ -0,0 +1,23 @@
+-- Statuses were free-form; users with none or an unknown one become active
+UPDATE users SET status = 'deleted' WHERE deleted_at IS NOT NULL AND erased_at IS NULL;
+UPDATE users SET status = 'active'
+    WHERE status IS NULL
+       OR status NOT IN ('pending', 'active', 'suspended', 'locked', 'deactivated', 'deleted', 'erased');
+
+ALTER TABLE users ALTER COLUMN status SET DEFAULT 'active';
+ALTER TABLE users ALTER COLUMN status SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_status_check
+    CHECK (status IN ('pending', 'active', 'suspended', 'locked', 'deactivated', 'deleted', 'erased'));
+
+CREATE TABLE user_status_transitions (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    from_status VARCHAR(30) NOT NULL,
+    to_status VARCHAR(30) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    changed_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_status_transitions_user_id ON user_status_transitions (user_id, id);
@@ -0,0 +1,7 @@
+DROP INDEX idx_users_organization_email;
+DROP INDEX idx_users_organization_username;
+CREATE UNIQUE INDEX idx_users_organization_username ON users (organization_id, username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_organization_email ON users (organization_id, email) WHERE deleted_at IS NULL;
+
+ALTER TABLE users DROP COLUMN email_normalized;
+ALTER TABLE users DROP COLUMN username_normalized;
@@ -0,0 +1,37 @@
+-- Usernames and emails are unique once lower-cased in Unicode NFKC form, so "Jane", "jane" and
+-- "ｊａｎｅ" are the same username. Letters are lower-cased one by one, not case folded: "Straße"
+-- and "STRASSE" stay distinct. NormalizeIdentity computes the same form in Go.
+ALTER TABLE users ADD COLUMN username_normalized TEXT
+    GENERATED ALWAYS AS (lower(normalize(username, NFKC))) STORED;
+ALTER TABLE users ADD COLUMN email_normalized TEXT
+    GENERATED ALWAYS AS (lower(normalize(email, NFKC))) STORED;
+
+-- Active users that only differ in case or form must be renamed or deleted before migrating
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(format('organization %s, %s %L: users %s', organization_id, field, value, ids), E'\n')
+    INTO duplicates
+    FROM (
+        SELECT organization_id, 'username' AS field, username_normalized AS value,
+               string_agg(id::text, ', ' ORDER BY id) AS ids
+        FROM users WHERE deleted_at IS NULL
+        GROUP BY organization_id, username_normalized HAVING count(*) > 1
+        UNION ALL
+        SELECT organization_id, 'email', email_normalized, string_agg(id::text, ', ' ORDER BY id)
+        FROM users WHERE deleted_at IS NULL AND email IS NOT NULL
+        GROUP BY organization_id, email_normalized HAVING count(*) > 1
+    ) AS conflicts;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION E'users with duplicate normalized usernames or emails:\n%', duplicates
+            USING HINT = 'Rename or delete the duplicates, then run the migration again.';
+    END IF;
+END $$;
+
+DROP INDEX idx_users_organization_username;
+DROP INDEX idx_users_organization_email;
+CREATE UNIQUE INDEX idx_users_organization_username ON users (organization_id, username_normalized)
+    WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_organization_email ON users (organization_id, email_normalized)
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,22 @@
+-- The values of unique attributes, claimed in the transaction that writes the user so that two
+-- active users of an organization can never hold the same one. Strings are stored as they are,
+-- other values as JSON.
+CREATE TABLE user_attribute_values (
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    organization_id INTEGER NOT NULL,
+    name VARCHAR(63) NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY (user_id, name),
+    FOREIGN KEY (organization_id, name) REFERENCES attribute_definitions (organization_id, name) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_user_attribute_values_value ON user_attribute_values (organization_id, name, value);
+
+-- Values already held by several users stay with the one that was created first
+INSERT INTO user_attribute_values (user_id, organization_id, name, value)
+SELECT u.id, u.organization_id, d.name, u.attributes ->> d.name
+FROM users u
+JOIN attribute_definitions d ON d.organization_id = u.organization_id AND d.is_unique
+WHERE u.deleted_at IS NULL AND jsonb_typeof(u.attributes -> d.name) <> 'null'
+ORDER BY u.id
+ON CONFLICT DO NOTHING;
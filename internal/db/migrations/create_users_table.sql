@@ -1,5 +0,0 @@
-CREATE IF NOT EXISTS TABLE users (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(100) NOT NULL,
-    email VARCHAR(100) UNIQUE NOT NULL
-);
@@ -0,0 +1,13 @@
+DROP TABLE user_status_transitions;
+DROP TABLE user_avatars;
+DROP TABLE attribute_definitions;
+DROP TABLE erasure_requests;
+DROP TABLE audit_events;
+DROP TABLE user_group_roles;
+DROP TABLE user_group_members;
+DROP TABLE user_groups;
+DROP TABLE invitations;
+DROP TABLE organization_memberships;
+DROP TABLE token_blacklist;
+DROP TABLE users;
+DROP TABLE organizations;
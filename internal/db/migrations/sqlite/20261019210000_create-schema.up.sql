@@ -0,0 +1,190 @@
+-- The SQLite schema matching the PostgreSQL migrations up to 20261019200000. Arrays and JSONB
+-- are stored as JSON text; normalize_identity is registered by the application, so users can
+-- only be written through it. There is no full-text search vector: SQLite searches are scored
+-- by the application.
+CREATE TABLE organizations (
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO organizations (id, name, slug) VALUES (1, 'Default', 'default');
+
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    status VARCHAR(30) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('pending', 'active', 'suspended', 'locked', 'deactivated', 'deleted', 'erased')),
+    username VARCHAR(100) NOT NULL,
+    password VARCHAR(100) NOT NULL,
+    deleted_at TIMESTAMP,
+    sessions_revoked_at TIMESTAMP,
+    -- Incremented on every write; exposed to clients as the user's ETag
+    version INTEGER NOT NULL DEFAULT 1,
+    erased_at TIMESTAMP,
+    attributes TEXT NOT NULL DEFAULT '{}',
+    username_normalized TEXT GENERATED ALWAYS AS (normalize_identity(username)) STORED,
+    email_normalized TEXT GENERATED ALWAYS AS (normalize_identity(email)) STORED
+);
+
+-- SQLite checks the most recent index first; like PostgreSQL, a user taking both an existing
+-- username and email is reported as a username conflict
+CREATE UNIQUE INDEX idx_users_organization_email ON users (organization_id, email_normalized)
+    WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX idx_users_organization_username ON users (organization_id, username_normalized)
+    WHERE deleted_at IS NULL;
+CREATE INDEX idx_users_organization_id ON users (organization_id);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE token_blacklist (
+    id INTEGER PRIMARY KEY,
+    token TEXT NOT NULL,
+    expiry TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    user_id INTEGER REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_token_blacklist_expiry ON token_blacklist (expiry);
+CREATE INDEX idx_token_blacklist_user_id ON token_blacklist (user_id);
+
+CREATE TABLE organization_memberships (
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role VARCHAR(30) NOT NULL DEFAULT 'member',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_memberships_user_id ON organization_memberships (user_id);
+
+CREATE TABLE invitations (
+    id INTEGER PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    email VARCHAR(100) NOT NULL,
+    role VARCHAR(30) NOT NULL DEFAULT 'member',
+    status VARCHAR(30) NOT NULL DEFAULT 'pending',
+    invited_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    accepted_at TIMESTAMP
+);
+
+CREATE INDEX idx_invitations_organization_status ON invitations (organization_id, status);
+
+-- At most one open invitation per email and organization
+CREATE UNIQUE INDEX idx_invitations_pending_email ON invitations (organization_id, lower(email))
+    WHERE status = 'pending';
+
+CREATE TABLE user_groups (
+    id INTEGER PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    parent_id INTEGER REFERENCES user_groups (id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (parent_id IS NULL OR parent_id <> id)
+);
+
+CREATE UNIQUE INDEX idx_user_groups_organization_name ON user_groups (organization_id, name);
+CREATE INDEX idx_user_groups_parent_id ON user_groups (parent_id);
+
+CREATE TABLE user_group_members (
+    group_id INTEGER NOT NULL REFERENCES user_groups (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX idx_user_group_members_user_id ON user_group_members (user_id);
+
+CREATE TABLE user_group_roles (
+    group_id INTEGER NOT NULL REFERENCES user_groups (id) ON DELETE CASCADE,
+    role VARCHAR(30) NOT NULL,
+    PRIMARY KEY (group_id, role)
+);
+
+CREATE TABLE audit_events (
+    id INTEGER PRIMARY KEY,
+    organization_id INTEGER NOT NULL,
+    actor_id INTEGER,
+    actor_username VARCHAR(100) NOT NULL DEFAULT '',
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(50) NOT NULL DEFAULT '',
+    target_id INTEGER,
+    changes TEXT,
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL UNIQUE
+);
+
+-- No foreign keys: events must outlive the users and organizations they mention
+CREATE INDEX idx_audit_events_organization_created ON audit_events (organization_id, created_at);
+CREATE INDEX idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX idx_audit_events_target_id ON audit_events (target_id);
+
+-- The log is append-only; the hash chain detects anything that bypasses this
+CREATE TRIGGER audit_events_append_only_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TRIGGER audit_events_append_only_delete BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TABLE erasure_requests (
+    id INTEGER PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    requested_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    status VARCHAR(30) NOT NULL DEFAULT 'pending',
+    scheduled_for TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+-- At most one pending erasure per user
+CREATE UNIQUE INDEX idx_erasure_requests_pending_user ON erasure_requests (user_id) WHERE status = 'pending';
+CREATE INDEX idx_erasure_requests_due ON erasure_requests (scheduled_for) WHERE status = 'pending';
+
+CREATE TABLE attribute_definitions (
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    name VARCHAR(63) NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT false,
+    is_unique BOOLEAN NOT NULL DEFAULT false,
+    pattern TEXT NOT NULL DEFAULT '',
+    enum TEXT NOT NULL DEFAULT '[]',
+    visibility VARCHAR(30) NOT NULL DEFAULT 'public',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, name)
+);
+
+-- The images themselves live in blob storage under key_prefix; a new upload gets a new prefix
+CREATE TABLE user_avatars (
+    user_id INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    key_prefix TEXT NOT NULL,
+    content_type VARCHAR(30) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_status_transitions (
+    id INTEGER PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    from_status VARCHAR(30) NOT NULL,
+    to_status VARCHAR(30) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    changed_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_status_transitions_user_id ON user_status_transitions (user_id, id);
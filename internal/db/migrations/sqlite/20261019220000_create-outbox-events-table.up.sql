@@ -0,0 +1,14 @@
+-- AUTOINCREMENT keeps the IDs of published and deleted events from being reused, since consumers
+-- tell deliveries apart by ID
+CREATE TABLE outbox_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_type VARCHAR(50) NOT NULL,
+    organization_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+-- No foreign keys: the events of a purged user must still be published
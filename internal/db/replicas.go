@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/config"
+)
+
+// replicaCheckTimeout bounds the ping of a health check
+const replicaCheckTimeout = 2 * time.Second
+
+// Replicas routes the reads of the process. Without DB_READ_REPLICAS it holds none, and every
+// read goes to DB.
+var Replicas *ReplicaSet
+
+// ReplicaSet hands out the read replicas of the primary database in turn, skipping those that
+// failed their last health check, and falls back to the primary while none is healthy
+type ReplicaSet struct {
+	primary  *pool
+	replicas []*pool
+	next     atomic.Uint64
+}
+
+// Replica names a read replica for NewReplicaSet
+type Replica struct {
+	Name string
+	DB   *sql.DB
+}
+
+type pool struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// PoolStats describes the connection pool of the primary or a replica
+type PoolStats struct {
+	Name    string `json:"name"`
+	Role    string `json:"role"`
+	Healthy bool   `json:"healthy"`
+	// MaxOpenConnections is 0 when the pool is unlimited
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMillis int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// NewReplicaSet routes reads among replicas, which count as healthy until checked
+func NewReplicaSet(primaryName string, primary *sql.DB, replicas ...Replica) *ReplicaSet {
+	set := &ReplicaSet{primary: newPool(primaryName, primary)}
+	for _, replica := range replicas {
+		set.replicas = append(set.replicas, newPool(replica.Name, replica.DB))
+	}
+	return set
+}
+
+func newPool(name string, database *sql.DB) *pool {
+	p := &pool{name: name, db: database}
+	p.healthy.Store(true)
+	return p
+}
+
+// OpenReplicaSet opens the replicas of cfg with the pool limits of the primary and checks them
+// once. A replica that cannot be reached does not stop the server; reads skip it until it can.
+func OpenReplicaSet(ctx context.Context, primary *sql.DB, cfg *config.Config) (*ReplicaSet, error) {
+	var replicas []Replica
+	for i, replicaURL := range cfg.ReadReplicaURLs {
+		database, err := sql.Open("postgres", replicaURL)
+		if err != nil {
+			for _, opened := range replicas {
+				opened.DB.Close()
+			}
+			return nil, fmt.Errorf("read replica %d: %w", i+1, err)
+		}
+		configurePool(database, cfg)
+		replicas = append(replicas, Replica{Name: poolName(replicaURL, fmt.Sprintf("replica-%d", i+1)), DB: database})
+	}
+	set := NewReplicaSet(poolName(cfg.DatabaseURL, "primary"), primary, replicas...)
+	set.Check(ctx)
+	return set, nil
+}
+
+// Reader returns the next healthy replica, or the primary if there is none
+func (set *ReplicaSet) Reader() *sql.DB {
+	count := uint64(len(set.replicas))
+	for range count {
+		replica := set.replicas[set.next.Add(1)%count]
+		if replica.healthy.Load() {
+			return replica.db
+		}
+	}
+	return set.primary.db
+}
+
+// Check pings the primary and every replica, logging those whose health changed. The primary's
+// health only shows in Stats, since writes have nowhere else to go.
+func (set *ReplicaSet) Check(ctx context.Context) {
+	for _, p := range append([]*pool{set.primary}, set.replicas...) {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+		err := p.db.PingContext(pingCtx)
+		cancel()
+
+		healthy := err == nil
+		if p.healthy.Swap(healthy) == healthy {
+			continue
+		}
+		if healthy {
+			log.Printf("database %s is reachable again", p.name)
+		} else {
+			log.Printf("database %s is unreachable: %v", p.name, err)
+		}
+	}
+}
+
+// Watch checks the databases every interval until stop is closed
+func (set *ReplicaSet) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			set.Check(context.Background())
+		}
+	}
+}
+
+// Stats reports the pools of the primary and of the replicas, as of the last health check
+func (set *ReplicaSet) Stats() []PoolStats {
+	stats := []PoolStats{set.primary.stats("primary")}
+	for _, replica := range set.replicas {
+		stats = append(stats, replica.stats("replica"))
+	}
+	return stats
+}
+
+// Close closes the replicas; the primary belongs to the caller
+func (set *ReplicaSet) Close() error {
+	var firstErr error
+	for _, replica := range set.replicas {
+		if err := replica.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (p *pool) stats(role string) PoolStats {
+	stats := p.db.Stats()
+	return PoolStats{
+		Name:               p.name,
+		Role:               role,
+		Healthy:            p.healthy.Load(),
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMillis: stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
+
+// configurePool applies the pool limits of cfg
+func configurePool(database *sql.DB, cfg *config.Config) {
+	database.SetMaxOpenConns(cfg.MaxOpenConns)
+	database.SetMaxIdleConns(cfg.MaxIdleConns)
+	database.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	database.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// poolName names a database by its host and database name, leaving out the credentials of its
+// URL, or by fallback when the URL has no host, e.g. a SQLite file or a key=value DSN
+func poolName(databaseURL, fallback string) string {
+	parsed, err := url.Parse(databaseURL)
+	if err != nil || parsed.Host == "" {
+		return fallback
+	}
+	return parsed.Host + parsed.Path
+}
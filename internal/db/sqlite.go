@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"modernc.org/sqlite"
+)
+
+// sqliteOptions turn on foreign keys, wait for locks instead of failing at once, let readers run
+// beside the writer, store times in a format the SQLite date functions understand, and take the
+// write lock when a read-write transaction begins, so it never fails upgrading a read lock
+const sqliteOptions = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)" +
+	"&_time_format=sqlite&_txlock=immediate"
+
+// The SQLite schema and repositories call these in place of PostgreSQL's normalize() and JSONB
+// containment. They must be registered before the first connection is opened.
+func init() {
+	must := func(err error) {
+		if err != nil {
+			panic(err)
+		}
+	}
+	must(sqlite.RegisterDeterministicScalarFunction("normalize_identity", 1, normalizeIdentity))
+	must(sqlite.RegisterDeterministicScalarFunction("attributes_contain", 2, attributesContain))
+}
+
+// OpenSQLite opens the SQLite database file, creating it and its directory if needed
+func OpenSQLite(file string) (*sql.DB, error) {
+	dsn := file
+	if !strings.HasPrefix(dsn, "file:") {
+		if dir := filepath.Dir(file); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return nil, err
+			}
+		}
+		dsn = "file:" + file
+	}
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + sqliteOptions
+	} else {
+		dsn += "?" + sqliteOptions
+	}
+
+	// sql.Open does not connect; it only hands over the registered driver
+	registered, err := sql.Open("sqlite", "")
+	if err != nil {
+		return nil, err
+	}
+	defer registered.Close()
+	return sql.OpenDB(sqliteConnector{dsn: dsn, driver: registered.Driver()}), nil
+}
+
+// sqliteConnector opens connections that store every time in UTC. SQLite compares times as text,
+// so times written in different zones would not sort.
+type sqliteConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (connector sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := connector.driver.Open(connector.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return utcConn{conn.(sqliteConn)}, nil
+}
+
+// Driver returns the SQLite driver itself, which tells repositories to use the SQLite dialect
+func (connector sqliteConnector) Driver() driver.Driver {
+	return connector.driver
+}
+
+// sqliteConn lists the interfaces of the driver's connections that utcConn passes on
+type sqliteConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+type utcConn struct {
+	sqliteConn
+}
+
+// CheckNamedValue converts time parameters to UTC and leaves every other one to the default
+// conversion
+func (conn utcConn) CheckNamedValue(value *driver.NamedValue) error {
+	if t, ok := value.Value.(time.Time); ok {
+		value.Value = t.UTC()
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func normalizeIdentity(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	switch value := args[0].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return interfaces.NormalizeIdentity(value), nil
+	case []byte:
+		return interfaces.NormalizeIdentity(string(value)), nil
+	}
+	return nil, fmt.Errorf("normalize_identity: unexpected %T", args[0])
+}
+
+// attributesContain is the JSONB @> operator for two JSON objects; NULL contains nothing
+func attributesContain(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+	var objects [2]map[string]interface{}
+	for i, arg := range args {
+		var encoded []byte
+		switch value := arg.(type) {
+		case nil:
+			return false, nil
+		case string:
+			encoded = []byte(value)
+		case []byte:
+			encoded = value
+		default:
+			return nil, fmt.Errorf("attributes_contain: unexpected %T", arg)
+		}
+		if err := json.Unmarshal(encoded, &objects[i]); err != nil {
+			return nil, fmt.Errorf("attributes_contain: %w", err)
+		}
+	}
+	return interfaces.AttributesContain(objects[0], objects[1]), nil
+}
@@ -0,0 +1,30 @@
+// Package events holds the publishers the outbox relay delivers user events through
+package events
+
+import (
+	"context"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type logPublisher struct{}
+
+// NewLogPublisher returns a publisher that logs the events instead of delivering them. It stands
+// in for a message broker in development.
+func NewLogPublisher() interfaces.EventPublisher {
+	return logPublisher{}
+}
+
+func (logPublisher) Publish(_ context.Context, message interfaces.OutboxMessage) error {
+	logger.Info(
+		"User event",
+		zap.Int64("eventID", message.ID),
+		zap.String("type", message.Type),
+		zap.Int("organizationID", message.OrganizationID),
+		zap.Int("userID", message.UserID),
+		zap.ByteString("payload", message.Payload),
+	)
+	return nil
+}
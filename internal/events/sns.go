@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+type snsPublisher struct {
+	client   *sns.SNS
+	topicARN string
+	fifo     bool
+}
+
+// NewSNSPublisher publishes the messages to the topic, with their type in the event_type message
+// attribute for subscription filters. On a FIFO topic the events of a user share a message group,
+// so subscribers receive them in order, and their IDs deduplicate redeliveries.
+func NewSNSPublisher(session *session.Session, topicARN string) interfaces.EventPublisher {
+	return &snsPublisher{client: sns.New(session), topicARN: topicARN, fifo: strings.HasSuffix(topicARN, ".fifo")}
+}
+
+func (publisher *snsPublisher) Publish(ctx context.Context, message interfaces.OutboxMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	input := &sns.PublishInput{
+		TopicArn: aws.String(publisher.topicARN),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"event_type": {DataType: aws.String("String"), StringValue: aws.String(message.Type)},
+		},
+	}
+	if publisher.fifo {
+		input.MessageGroupId = aws.String(message.OrderingKey())
+		input.MessageDeduplicationId = aws.String(strconv.FormatInt(message.ID, 10))
+	}
+	_, err = publisher.client.PublishWithContext(ctx, input)
+	return err
+}
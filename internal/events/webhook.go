@@ -0,0 +1,58 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+const (
+	// webhookTimeout bounds a delivery, so an endpoint that hangs cannot stall the relay
+	webhookTimeout  = 10 * time.Second
+	HeaderEventID   = "X-Event-ID"
+	HeaderEventType = "X-Event-Type"
+)
+
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher POSTs every message as JSON to url, with its ID and type in the
+// X-Event-ID and X-Event-Type headers. A response other than 2xx fails the delivery.
+func NewWebhookPublisher(url string) interfaces.EventPublisher {
+	return &webhookPublisher{url: url, client: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (publisher *webhookPublisher) Publish(ctx context.Context, message interfaces.OutboxMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, publisher.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEventID, strconv.FormatInt(message.ID, 10))
+	request.Header.Set(HeaderEventType, message.Type)
+
+	response, err := publisher.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// Draining the body lets the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", response.Status)
+	}
+	return nil
+}
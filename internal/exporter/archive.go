@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// ArchiveContentType is the media type of personal data archives
+const ArchiveContentType = "application/zip"
+
+// WritePersonalDataArchive writes the data as a zip of JSON documents, one per kind of record,
+// so a data subject can open each part on its own
+func WritePersonalDataArchive(writer io.Writer, data interfaces.PersonalData) error {
+	archive := zip.NewWriter(writer)
+	parts := []struct {
+		name    string
+		content interface{}
+	}{
+		{"profile.json", profileDocument(data.Profile)},
+		{"memberships.json", data.Memberships},
+		{"groups.json", data.Groups},
+		{"sessions.json", data.Sessions},
+		{"audit_events.json", data.AuditEvents},
+		{"tokens.json", data.Tokens},
+		{"invitations.json", data.Invitations},
+	}
+	for _, part := range parts {
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     part.name,
+			Method:   zip.Deflate,
+			Modified: data.GeneratedAt,
+		})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(part.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// profileDocument drops the password, which is never read for an export and would show as empty
+func profileDocument(user interfaces.User) map[string]interface{} {
+	profile := make(map[string]interface{})
+	if encoded, err := json.Marshal(user); err == nil {
+		_ = json.Unmarshal(encoded, &profile)
+	}
+	delete(profile, "password")
+	return profile
+}
+
+// ArchiveName names the archive after the user and the time it was generated
+func ArchiveName(data interfaces.PersonalData) string {
+	return fmt.Sprintf("personal-data-%d-%s.zip", data.Profile.ID, data.GeneratedAt.Format(time.DateOnly))
+}
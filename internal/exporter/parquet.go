@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// parquetRowGroupSize bounds the rows buffered in memory before a row group is written out
+const parquetRowGroupSize = 10 * interfaces.ExportBatchSize
+
+type parquetWriter struct {
+	output *parquet.Writer
+	fields []string
+	// columns maps each field to its leaf column index; the schema orders columns by name
+	columns map[string]int
+	row     parquet.Row
+}
+
+func newParquetWriter(output io.Writer, fields []string) *parquetWriter {
+	group := parquet.Group{}
+	for _, field := range fields {
+		group[field] = parquetNode(field)
+	}
+	schema := parquet.NewSchema("user", group)
+
+	columns := make(map[string]int, len(fields))
+	for i, path := range schema.Columns() {
+		columns[path[0]] = i
+	}
+	return &parquetWriter{
+		output: parquet.NewWriter(
+			output,
+			schema,
+			parquet.Compression(&parquet.Snappy),
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+		),
+		fields:  fields,
+		columns: columns,
+		row:     make(parquet.Row, len(fields)),
+	}
+}
+
+// parquetNode types the numeric fields as INT64 and the rest as optional UTF-8 strings
+func parquetNode(field string) parquet.Node {
+	switch field {
+	case "id", "organization_id", "version":
+		return parquet.Int(64)
+	}
+	return parquet.Optional(parquet.String())
+}
+
+func (writer *parquetWriter) Write(user interfaces.User) error {
+	for _, field := range writer.fields {
+		column := writer.columns[field]
+		switch value := fieldValue(user, field).(type) {
+		case int:
+			writer.row[column] = parquet.Int64Value(int64(value)).Level(0, 0, column)
+		case string:
+			writer.row[column] = parquet.ByteArrayValue([]byte(value)).Level(0, 1, column)
+		default:
+			writer.row[column] = parquet.NullValue().Level(0, 0, column)
+		}
+	}
+	_, err := writer.output.WriteRows([]parquet.Row{writer.row})
+	return err
+}
+
+// Close writes the last row group and the file footer
+func (writer *parquetWriter) Close() error {
+	return writer.output.Close()
+}
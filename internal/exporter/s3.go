@@ -0,0 +1,38 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type s3Store struct {
+	uploader *s3manager.Uploader
+	bucket   string
+}
+
+// NewS3Store uploads exports to the bucket as multipart uploads, so an export is never held in
+// memory as a whole
+func NewS3Store(session *session.Session, bucket string) interfaces.ExportStore {
+	return &s3Store{uploader: s3manager.NewUploader(session), bucket: bucket}
+}
+
+func (store *s3Store) Upload(key, contentType string, body io.Reader) (string, error) {
+	_, err := store.uploader.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(store.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	})
+	if err != nil {
+		logger.Error("Error uploading export:", zap.String("bucket", store.bucket), zap.String("key", key), zap.Error(err))
+		return "", err
+	}
+	return fmt.Sprintf("s3://%s/%s", store.bucket, key), nil
+}
@@ -0,0 +1,152 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// ContentTypes maps export formats to the media type they are served or stored as
+var ContentTypes = map[string]string{
+	interfaces.ExportFormatCSV:     "text/csv",
+	interfaces.ExportFormatNDJSON:  "application/x-ndjson",
+	interfaces.ExportFormatParquet: "application/vnd.apache.parquet",
+}
+
+// Writer encodes users one at a time. Close flushes whatever is still buffered and must be
+// called once all users are written.
+type Writer interface {
+	Write(user interfaces.User) error
+	Close() error
+}
+
+// ValidateOptions checks the format and fields, defaulting empty fields to every export field
+func ValidateOptions(options interfaces.ExportOptions) (interfaces.ExportOptions, error) {
+	if _, ok := ContentTypes[options.Format]; !ok {
+		return options, fmt.Errorf("%w: %q", interfaces.ErrExportFormat, options.Format)
+	}
+	if len(options.Fields) == 0 {
+		options.Fields = interfaces.ExportFields
+	}
+	seen := make(map[string]bool)
+	for _, field := range options.Fields {
+		if !isExportField(field) {
+			return options, fmt.Errorf("%w: %q", interfaces.ErrExportField, field)
+		}
+		if seen[field] {
+			return options, fmt.Errorf("%w: %q is selected twice", interfaces.ErrExportField, field)
+		}
+		seen[field] = true
+	}
+	return options, nil
+}
+
+// NewWriter returns a writer for the format that writes the given fields in order
+func NewWriter(output io.Writer, format string, fields []string) (Writer, error) {
+	switch format {
+	case interfaces.ExportFormatCSV:
+		return newCSVWriter(output, fields)
+	case interfaces.ExportFormatNDJSON:
+		return &ndjsonWriter{output: bufio.NewWriter(output), fields: fields}, nil
+	case interfaces.ExportFormatParquet:
+		return newParquetWriter(output, fields), nil
+	}
+	return nil, fmt.Errorf("%w: %q", interfaces.ErrExportFormat, format)
+}
+
+func isExportField(field string) bool {
+	for _, exportField := range interfaces.ExportFields {
+		if field == exportField {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValue returns the field as an int, a string or nil for a NULL status
+func fieldValue(user interfaces.User, field string) interface{} {
+	switch field {
+	case "id":
+		return user.ID
+	case "organization_id":
+		return user.OrganizationID
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "username":
+		return user.Username
+	case "status":
+		if user.Status == nil {
+			return nil
+		}
+		return *user.Status
+	case "version":
+		return user.Version
+	}
+	return nil
+}
+
+type csvWriter struct {
+	output *csv.Writer
+	fields []string
+	record []string
+}
+
+func newCSVWriter(output io.Writer, fields []string) (*csvWriter, error) {
+	writer := &csvWriter{output: csv.NewWriter(output), fields: fields, record: make([]string, len(fields))}
+	return writer, writer.output.Write(fields)
+}
+
+// Write leaves NULL values empty
+func (writer *csvWriter) Write(user interfaces.User) error {
+	for i, field := range writer.fields {
+		switch value := fieldValue(user, field).(type) {
+		case int:
+			writer.record[i] = strconv.Itoa(value)
+		case string:
+			writer.record[i] = value
+		default:
+			writer.record[i] = ""
+		}
+	}
+	return writer.output.Write(writer.record)
+}
+
+func (writer *csvWriter) Close() error {
+	writer.output.Flush()
+	return writer.output.Error()
+}
+
+type ndjsonWriter struct {
+	output *bufio.Writer
+	fields []string
+}
+
+// Write encodes the object by hand so keys keep the selected field order
+func (writer *ndjsonWriter) Write(user interfaces.User) error {
+	line := []byte{'{'}
+	for i, field := range writer.fields {
+		if i > 0 {
+			line = append(line, ',')
+		}
+		line = strconv.AppendQuote(line, field)
+		line = append(line, ':')
+		value, err := json.Marshal(fieldValue(user, field))
+		if err != nil {
+			return err
+		}
+		line = append(line, value...)
+	}
+	_, err := writer.output.Write(append(line, '}', '\n'))
+	return err
+}
+
+func (writer *ndjsonWriter) Close() error {
+	return writer.output.Flush()
+}
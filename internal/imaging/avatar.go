@@ -0,0 +1,87 @@
+// Package imaging decodes uploaded avatars and renders their thumbnails
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const jpegQuality = 85
+
+// Decode reads an avatar upload. The format is detected from the bytes rather than trusted from
+// the request, and the dimensions are checked before the pixels are decoded.
+func Decode(body io.Reader) (image.Image, string, error) {
+	data, err := io.ReadAll(io.LimitReader(body, interfaces.AvatarMaxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > interfaces.AvatarMaxBytes {
+		return nil, "", interfaces.ErrAvatarTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(interfaces.AvatarContentTypes, contentType) {
+		return nil, "", interfaces.ErrAvatarType
+	}
+	decodeConfig, decode := decoders(contentType)
+
+	config, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", interfaces.ErrAvatarType
+	}
+	if config.Width > interfaces.AvatarMaxDimension || config.Height > interfaces.AvatarMaxDimension {
+		return nil, "", interfaces.ErrAvatarTooLarge
+	}
+
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", interfaces.ErrAvatarType
+	}
+	return img, contentType, nil
+}
+
+// Thumbnail crops the center square of the image and scales it to size x size
+func Thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	edge := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, edge, edge).Add(image.Point{
+		X: bounds.Min.X + (bounds.Dx()-edge)/2,
+		Y: bounds.Min.Y + (bounds.Dy()-edge)/2,
+	})
+
+	thumbnail := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), img, crop, draw.Src, nil)
+	return thumbnail
+}
+
+// Encode writes the image as JPEG when it was uploaded as one and as PNG otherwise, which keeps
+// transparency. It returns the content type written. Re-encoding drops EXIF and other metadata.
+func Encode(writer io.Writer, img image.Image, uploadedType string) (string, error) {
+	if uploadedType == "image/jpeg" {
+		return "image/jpeg", jpeg.Encode(writer, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	return "image/png", png.Encode(writer, img)
+}
+
+func decoders(contentType string) (func(io.Reader) (image.Config, error), func(io.Reader) (image.Image, error)) {
+	switch contentType {
+	case "image/jpeg":
+		return jpeg.DecodeConfig, jpeg.Decode
+	case "image/gif":
+		// Animated GIFs keep their first frame
+		return gif.DecodeConfig, gif.Decode
+	case "image/webp":
+		return webp.DecodeConfig, webp.Decode
+	}
+	return png.DecodeConfig, png.Decode
+}
@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// maxLineSize bounds a single NDJSON line
+const maxLineSize = 1 << 20
+
+// ReadRecords parses a CSV file with a header row, or NDJSON with one object per line, into
+// records keyed by user field. Mapping renames source columns; columns that map to no user
+// field are ignored.
+func ReadRecords(source io.Reader, format string, mapping map[string]string) ([]interfaces.ImportRecord, error) {
+	if err := ValidateMapping(mapping); err != nil {
+		return nil, err
+	}
+	switch format {
+	case interfaces.ImportFormatCSV:
+		return readCSV(source, mapping)
+	case interfaces.ImportFormatNDJSON:
+		return readNDJSON(source, mapping)
+	}
+	return nil, fmt.Errorf("%w: %q", interfaces.ErrImportFormat, format)
+}
+
+// ValidateMapping checks that every mapping targets a known user field
+func ValidateMapping(mapping map[string]string) error {
+	for source, field := range mapping {
+		if !isImportField(field) {
+			return fmt.Errorf("%w: %s maps to unknown field %q", interfaces.ErrImportMapping, source, field)
+		}
+	}
+	return nil
+}
+
+// ParseMapping parses "source=field" pairs as given on the command line or in query strings
+func ParseMapping(pairs []string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range pairs {
+		source, field, ok := strings.Cut(pair, "=")
+		if !ok || source == "" {
+			return nil, fmt.Errorf("%w: %q is not source=field", interfaces.ErrImportMapping, pair)
+		}
+		mapping[strings.TrimSpace(source)] = strings.TrimSpace(field)
+	}
+	return mapping, ValidateMapping(mapping)
+}
+
+func readCSV(source io.Reader, mapping map[string]string) ([]interfaces.ImportRecord, error) {
+	reader := csv.NewReader(source)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	var records []interfaces.ImportRecord
+	for {
+		values, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		fields := make(map[string]string)
+		for i, value := range values {
+			if field, ok := fieldFor(strings.TrimPrefix(header[i], "\ufeff"), mapping); ok {
+				fields[field] = strings.TrimSpace(value)
+			}
+		}
+		records = append(records, interfaces.ImportRecord{Line: line, Fields: fields})
+	}
+}
+
+func readNDJSON(source io.Reader, mapping map[string]string) ([]interfaces.ImportRecord, error) {
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var records []interfaces.ImportRecord
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &object); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		fields := make(map[string]string)
+		for column, value := range object {
+			field, ok := fieldFor(column, mapping)
+			if !ok || value == nil {
+				continue
+			}
+			if text, isString := value.(string); isString {
+				fields[field] = strings.TrimSpace(text)
+			} else {
+				fields[field] = fmt.Sprint(value)
+			}
+		}
+		records = append(records, interfaces.ImportRecord{Line: line, Fields: fields})
+	}
+	return records, scanner.Err()
+}
+
+func fieldFor(column string, mapping map[string]string) (string, bool) {
+	if field, ok := mapping[column]; ok {
+		return field, true
+	}
+	return column, isImportField(column)
+}
+
+func isImportField(field string) bool {
+	for _, known := range interfaces.ImportFields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
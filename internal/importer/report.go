@@ -0,0 +1,29 @@
+package importer
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// WriteErrorReport writes the rows that were not imported as CSV, one line per row
+func WriteErrorReport(writer io.Writer, report interfaces.ImportReport) error {
+	output := csv.NewWriter(writer)
+	if err := output.Write([]string{"line", "username", "email", "status", "error"}); err != nil {
+		return err
+	}
+	for _, row := range report.Rows {
+		if row.Error == "" {
+			continue
+		}
+		if err := output.Write([]string{
+			strconv.Itoa(row.Line), row.Username, row.Email, row.Status, row.Error,
+		}); err != nil {
+			return err
+		}
+	}
+	output.Flush()
+	return output.Error()
+}
@@ -1,32 +1,142 @@
 package infrastructure
 
 import (
-	echoSwagger "github.com/swaggo/echo-swagger" //nolint:depguard
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/redbonzai/user-management-api/cmd/aws"
 	_ "github.com/redbonzai/user-management-api/docs"
+	"github.com/redbonzai/user-management-api/internal/authz"
+	"github.com/redbonzai/user-management-api/internal/config"
 	"github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/events"
+	"github.com/redbonzai/user-management-api/internal/exporter"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
 	"github.com/redbonzai/user-management-api/internal/interfaces/handler"
 	"github.com/redbonzai/user-management-api/internal/interfaces/repository"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository/cache"
+	"github.com/redbonzai/user-management-api/internal/jobs"
 	internalMiddleware "github.com/redbonzai/user-management-api/internal/middleware"
 	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/internal/notifications"
 	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/storage"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	echoSwagger "github.com/swaggo/echo-swagger" //nolint:depguard
+	"go.uber.org/zap"
+)
+
+const (
+	// importBodyLimit caps bulk import uploads
+	importBodyLimit = "20M"
+	// avatarBodyLimit leaves room for multipart framing around an interfaces.AvatarMaxBytes image
+	avatarBodyLimit = "6M"
 )
 
-func NewRouter() *echo.Echo {
+func NewRouter(cfg *config.Config) *echo.Echo {
 	router := echo.New()
 
 	router.Use(middleware.Logger())
 	router.Use(middleware.Recover())
+	router.Use(middleware.RequestID())
+	router.Use(internalMiddleware.PrimaryReads)
 
 	router.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"http://localhost:4200"},
-		AllowMethods: []string{echo.GET, echo.PUT, echo.POST, echo.DELETE},
+		AllowMethods: []string{echo.GET, echo.PUT, echo.PATCH, echo.POST, echo.DELETE},
+		// Browsers only let clients read the ETag they must echo back in If-Match when exposed,
+		// and the export file name when Content-Disposition is
+		ExposeHeaders: []string{"ETag", echo.HeaderContentDisposition},
 	}))
 
-	userRepo := repository.NewUserRepository(db.DB)
-	userService := services.NewService(userRepo)
-	userHandler := handler.NewUserHandler(userService)
+	auditRepo := repository.NewAuditRepository(db.DB)
+	auditService := services.NewAuditService(auditRepo)
+	auditHandler := handler.NewAuditHandler(auditService)
+
+	txManager := repository.NewTxManager(db.DB)
+	organizationRepo := repository.NewOrganizationRepository(db.DB)
+	organizationService := services.NewOrganizationService(organizationRepo, txManager)
+	organizationHandler := handler.NewOrganizationHandler(organizationService)
+	tenantMiddleware := tenant.Middleware(organizationService)
+	sessionRepo := repository.NewSessionRepository(db.DB)
+
+	attributeRepo := repository.NewAttributeRepository(db.DB)
+	attributeService := services.NewAttributeService(attributeRepo, organizationService)
+	attributeHandler := handler.NewAttributeHandler(attributeService)
+
+	userRepo := repository.NewUserRepository(db.DB, db.Replicas, cfg.QueryTimeout)
+	switch cfg.UserCache {
+	case "memory":
+		userRepo = cache.NewUserRepository(userRepo, cache.NewLRU(cfg.UserCacheSize), cfg.UserCacheTTL)
+	case "redis":
+		backend, err := cache.NewRedis(cfg.UserCacheRedisURL)
+		if err != nil {
+			logger.Fatal("could not configure the user cache:", zap.Error(err))
+		}
+		userRepo = cache.NewUserRepository(userRepo, backend, cfg.UserCacheTTL)
+	}
+	outboxRepo := repository.NewOutboxRepository(db.DB)
+	userService := services.NewService(userRepo, attributeService, txManager, outboxRepo, auditService)
+
+	avatarStore := storage.NewLocalStore(cfg.AvatarDir)
+	if cfg.AvatarBucket != "" {
+		avatarStore = storage.NewS3Store(aws.CreateAWSSession(), cfg.AvatarBucket)
+	}
+	avatarService := services.NewAvatarService(repository.NewAvatarRepository(db.DB), avatarStore, userService)
+	avatarHandler := handler.NewAvatarHandler(avatarService, auditService)
+	userHandler := handler.NewUserHandler(userService, auditService, attributeService, avatarService)
+
+	invitationRepo := repository.NewInvitationRepository(db.DB)
+	invitationService := services.NewInvitationService(
+		invitationRepo,
+		txManager,
+		userService,
+		organizationService,
+		notifications.NewLogNotifier(cfg.InvitationURL),
+	)
+	invitationHandler := handler.NewInvitationHandler(invitationService)
+
+	importService := services.NewImportService(userRepo, txManager, outboxRepo, organizationService, invitationService)
+	importHandler := handler.NewImportHandler(importService)
+
+	// Without a bucket, exports can only be streamed back to the client
+	var exportStore interfaces.ExportStore
+	if cfg.ExportBucket != "" {
+		exportStore = exporter.NewS3Store(aws.CreateAWSSession(), cfg.ExportBucket)
+	}
+	exportService := services.NewExportService(userRepo, exportStore)
+	exportHandler := handler.NewExportHandler(exportService, auditService)
+
+	privacyRepo := repository.NewPrivacyRepository(db.DB)
+	privacyService := services.NewPrivacyService(
+		privacyRepo,
+		userRepo,
+		txManager,
+		outboxRepo,
+		auditService,
+		avatarService,
+		cfg.ErasureGrace,
+	)
+	privacyHandler := handler.NewPrivacyHandler(privacyService, auditService)
+
+	groupRepo := repository.NewGroupRepository(db.DB)
+	groupService := services.NewGroupService(groupRepo, organizationService, txManager)
+	groupHandler := handler.NewGroupHandler(groupService)
+	requireAdmin := tenant.RequireAdmin(organizationService)
+	requireSelfOrAdmin := tenant.RequireSelfOrAdmin(organizationService)
+
+	policyEngine, err := authz.NewEngine(cfg.PolicyFile)
+	if err != nil {
+		logger.Fatal("could not load policies:", zap.Error(err))
+	}
+	go authz.Watch(policyEngine, authz.WatchInterval, nil)
+	go jobs.PurgeDeletedUsers(userService, cfg.UserRetention, jobs.PurgeInterval, nil)
+	go jobs.EraseDueUsers(privacyService, jobs.ErasureInterval, nil)
+	if publisher := eventPublisher(cfg); publisher != nil {
+		go jobs.RelayEvents(services.NewOutboxRelay(outboxRepo, publisher), cfg.EventRelayInterval, nil)
+	}
+	authzHandler := handler.NewAuthzHandler(policyEngine, userService, organizationService)
+	healthHandler := handler.NewHealthHandler(db.Replicas)
 
 	// Initialize repositories, services, and handlers
 	//roleRepo := repository.NewRoleRepository()
@@ -38,23 +148,109 @@ func NewRouter() *echo.Echo {
 	//permissionHandler := handler.NewPermissionHandler(permissionService)
 
 	// Public routes
-	router.POST("/users/login", userHandler.Login)
+	router.GET("/health/db", healthHandler.DatabaseHealth)
+	router.POST("/users/login", userHandler.Login, tenantMiddleware)
 	router.POST("/users/register", userHandler.Register)
+	router.POST("/users/invitations/accept", invitationHandler.AcceptInvitation)
 
 	// Apply the response interceptor
 	router.Use(internalMiddleware.ResponseInterceptor)
 
 	// Protected routes
 	protected := router.Group("/v1/users")
-	protected.Use(authentication.JWTMiddleware())
+	protected.Use(authentication.JWTMiddleware(sessionRepo), tenantMiddleware)
 
 	protected.GET("", userHandler.GetUsers)
+	protected.GET("/search", userHandler.SearchUsers)
 	protected.GET("/:id", userHandler.GetUser)
 	protected.POST("", userHandler.CreateUser)
+	protected.POST("/import", importHandler.ImportUsers, requireAdmin, middleware.BodyLimit(importBodyLimit))
+	protected.GET("/export", exportHandler.ExportUsers, requireAdmin)
 	protected.PATCH("/:id", userHandler.UpdateUser)
 	protected.DELETE("/:id", userHandler.DeleteUser)
+	protected.GET("/deleted", userHandler.GetDeletedUsers, requireAdmin)
+	protected.POST("/:id/restore", userHandler.RestoreUser, requireAdmin)
+	protected.POST("/:id/suspend", userHandler.SuspendUser, requireAdmin)
+	protected.POST("/:id/reactivate", userHandler.ReactivateUser, requireAdmin)
+	protected.POST("/:id/lock", userHandler.LockUser, requireAdmin)
+	protected.POST("/:id/deactivate", userHandler.DeactivateUser, requireAdmin)
+	protected.GET("/:id/status-history", userHandler.GetStatusHistory, requireAdmin)
 	protected.POST("/logout", userHandler.Logout)
 	protected.GET("/current-user", userHandler.GetAuthenticatedUser)
+	protected.GET("/current-user/data", privacyHandler.ExportOwnData)
+	protected.POST("/current-user/erasure", privacyHandler.EraseSelf)
+	protected.GET("/:id/data", privacyHandler.ExportUserData, requireAdmin)
+	protected.POST("/:id/erasure", privacyHandler.ScheduleErasure, requireAdmin)
+	protected.PUT("/:id/avatar", avatarHandler.PutAvatar, requireSelfOrAdmin, middleware.BodyLimit(avatarBodyLimit))
+	protected.GET("/:id/avatar", avatarHandler.GetAvatar)
+	protected.DELETE("/:id/avatar", avatarHandler.DeleteAvatar, requireSelfOrAdmin)
+	protected.GET("/:id/groups", groupHandler.GetUserGroups)
+	protected.GET("/:id/permissions", groupHandler.GetUserPermissions)
+
+	// Organization routes
+	organizations := router.Group("/v1/organizations")
+	organizations.Use(authentication.JWTMiddleware(sessionRepo))
+
+	organizations.GET("", organizationHandler.GetOrganizations)
+	organizations.POST("", organizationHandler.CreateOrganization)
+	organizations.GET("/:id", organizationHandler.GetOrganization)
+	organizations.GET("/:id/members", organizationHandler.GetMembers)
+	organizations.POST("/:id/members", organizationHandler.AddMember)
+	organizations.PATCH("/:id/members/:user_id", organizationHandler.UpdateMember)
+	organizations.DELETE("/:id/members/:user_id", organizationHandler.RemoveMember)
+
+	// Invitation routes
+	invitations := router.Group("/v1/invitations")
+	invitations.Use(authentication.JWTMiddleware(sessionRepo), tenantMiddleware, requireAdmin)
+
+	invitations.GET("", invitationHandler.GetInvitations)
+	invitations.POST("", invitationHandler.CreateInvitation)
+	invitations.POST("/:id/resend", invitationHandler.ResendInvitation)
+	invitations.DELETE("/:id", invitationHandler.RevokeInvitation)
+
+	// Group routes
+	groups := router.Group("/v1/groups")
+	groups.Use(authentication.JWTMiddleware(sessionRepo), tenantMiddleware)
+
+	groups.GET("", groupHandler.GetGroups)
+	groups.POST("", groupHandler.CreateGroup, requireAdmin)
+	groups.GET("/:id", groupHandler.GetGroup)
+	groups.PATCH("/:id", groupHandler.UpdateGroup, requireAdmin)
+	groups.DELETE("/:id", groupHandler.DeleteGroup, requireAdmin)
+	groups.GET("/:id/members", groupHandler.GetMembers)
+	groups.GET("/:id/members/transitive", groupHandler.GetTransitiveMembers)
+	groups.POST("/:id/members", groupHandler.AddMember, requireAdmin)
+	groups.DELETE("/:id/members/:user_id", groupHandler.RemoveMember, requireAdmin)
+	groups.POST("/:id/roles", groupHandler.AssignRole, requireAdmin)
+	groups.DELETE("/:id/roles/:role", groupHandler.UnassignRole, requireAdmin)
+
+	// Authorization routes
+	authorization := router.Group("/v1/authz")
+	authorization.Use(authentication.JWTMiddleware(sessionRepo), tenantMiddleware)
+
+	authorization.POST("/check", authzHandler.Check)
+
+	// Audit routes
+	audit := router.Group("/v1/audit")
+	audit.Use(authentication.JWTMiddleware(sessionRepo), tenantMiddleware, requireAdmin)
+
+	audit.GET("", auditHandler.GetEvents)
+
+	// Attribute definition routes
+	attributes := router.Group("/v1/attributes")
+	attributes.Use(authentication.JWTMiddleware(sessionRepo), tenantMiddleware)
+
+	attributes.GET("", attributeHandler.GetDefinitions)
+	attributes.POST("", attributeHandler.CreateDefinition, requireAdmin)
+	attributes.PUT("/:name", attributeHandler.UpdateDefinition, requireAdmin)
+	attributes.DELETE("/:name", attributeHandler.DeleteDefinition, requireAdmin)
+
+	// Erasure request routes
+	erasures := router.Group("/v1/erasures")
+	erasures.Use(authentication.JWTMiddleware(sessionRepo), tenantMiddleware, requireAdmin)
+
+	erasures.GET("", privacyHandler.GetErasureRequests)
+	erasures.DELETE("/:id", privacyHandler.CancelErasure)
 
 	// Role routes
 	//protected.GET("/roles", roleHandler.GetRoles)
@@ -79,3 +275,16 @@ func NewRouter() *echo.Echo {
 
 	return router
 }
+
+// eventPublisher returns the publisher EVENT_PUBLISHER selects, or nil when events stay in the outbox
+func eventPublisher(cfg *config.Config) interfaces.EventPublisher {
+	switch cfg.EventPublisher {
+	case "log":
+		return events.NewLogPublisher()
+	case "webhook":
+		return events.NewWebhookPublisher(cfg.EventWebhookURL)
+	case "sns":
+		return events.NewSNSPublisher(aws.CreateAWSSession(), cfg.EventTopicARN)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	// AttributeTypeDate holds calendar dates as YYYY-MM-DD strings
+	AttributeTypeDate = "date"
+
+	// AttributeVisibilityPublic attributes are shown to every member of the organization
+	AttributeVisibilityPublic = "public"
+	// AttributeVisibilityAdmin attributes are shown to organization owners and admins only
+	AttributeVisibilityAdmin = "admin"
+
+	// AttributeFilterPrefix marks GET /v1/users query parameters that filter by attribute, e.g. attr.department=sales
+	AttributeFilterPrefix = "attr."
+)
+
+var (
+	ErrInvalidAttribute           = errors.New("invalid attribute")
+	ErrAttributeConflict          = errors.New("attribute value is already taken")
+	ErrInvalidAttributeDefinition = errors.New("invalid attribute definition")
+	ErrAttributeExists            = errors.New("attribute is already defined")
+)
+
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// AttributeDefinition describes one custom profile field of an organization's users
+type AttributeDefinition struct {
+	OrganizationID int       `json:"organization_id"`
+	Name           string    `json:"name" validate:"required"`
+	Type           string    `json:"type" validate:"required"`
+	Required       bool      `json:"required"`
+	Unique         bool      `json:"unique"`
+	Pattern        string    `json:"pattern,omitempty"`
+	Enum           []string  `json:"enum,omitempty"`
+	Visibility     string    `json:"visibility"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Validate checks the definition itself; pattern and enum only apply to string attributes
+func (definition AttributeDefinition) Validate() error {
+	switch {
+	case !attributeNamePattern.MatchString(definition.Name):
+		return fmt.Errorf("%w: name must be lowercase letters, digits and underscores", ErrInvalidAttributeDefinition)
+	case definition.Type != AttributeTypeString && definition.Type != AttributeTypeNumber &&
+		definition.Type != AttributeTypeBoolean && definition.Type != AttributeTypeDate:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidAttributeDefinition, definition.Type)
+	case definition.Visibility != AttributeVisibilityPublic && definition.Visibility != AttributeVisibilityAdmin:
+		return fmt.Errorf("%w: unknown visibility %q", ErrInvalidAttributeDefinition, definition.Visibility)
+	case (definition.Pattern != "" || len(definition.Enum) > 0) && definition.Type != AttributeTypeString:
+		return fmt.Errorf("%w: pattern and enum apply to string attributes only", ErrInvalidAttributeDefinition)
+	}
+	if _, err := regexp.Compile(definition.Pattern); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttributeDefinition, err)
+	}
+	return nil
+}
+
+// Check validates a value decoded from JSON against the definition
+func (definition AttributeDefinition) Check(value interface{}) error {
+	switch definition.Type {
+	case AttributeTypeNumber:
+		if _, ok := value.(float64); !ok {
+			return definition.invalid("must be a number")
+		}
+		return nil
+	case AttributeTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return definition.invalid("must be a boolean")
+		}
+		return nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return definition.invalid("must be a string")
+	}
+	if definition.Type == AttributeTypeDate {
+		if _, err := time.Parse(time.DateOnly, text); err != nil {
+			return definition.invalid("must be a date formatted as YYYY-MM-DD")
+		}
+		return nil
+	}
+	if definition.Pattern != "" && !regexp.MustCompile(definition.Pattern).MatchString(text) {
+		return definition.invalid("must match " + definition.Pattern)
+	}
+	if len(definition.Enum) > 0 {
+		for _, allowed := range definition.Enum {
+			if text == allowed {
+				return nil
+			}
+		}
+		return definition.invalid(fmt.Sprintf("must be one of %v", definition.Enum))
+	}
+	return nil
+}
+
+// Parse converts a query string value to the attribute's JSON type
+func (definition AttributeDefinition) Parse(raw string) (interface{}, error) {
+	var value interface{} = raw
+	switch definition.Type {
+	case AttributeTypeNumber:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, definition.invalid("must be a number")
+		}
+		value = number
+	case AttributeTypeBoolean:
+		boolean, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, definition.invalid("must be a boolean")
+		}
+		value = boolean
+	}
+	return value, definition.Check(value)
+}
+
+func (definition AttributeDefinition) invalid(reason string) error {
+	return fmt.Errorf("%w: %s %s", ErrInvalidAttribute, definition.Name, reason)
+}
+
+type AttributeRepository interface {
+	GetDefinitions(ctx context.Context, organizationID int) ([]AttributeDefinition, error)
+	CreateDefinition(ctx context.Context, definition AttributeDefinition) (AttributeDefinition, error)
+	UpdateDefinition(ctx context.Context, definition AttributeDefinition) (AttributeDefinition, error)
+	// DeleteDefinition also removes the attribute from every user of the organization
+	DeleteDefinition(ctx context.Context, organizationID int, name string) error
+	// ClaimValues replaces the values of unique attributes held by the user, keyed by name, in the
+	// unit of work carried by ctx; ErrAttributeConflict when another user holds one
+	ClaimValues(ctx context.Context, user User, values map[string]interface{}) error
+}
+
+type AttributeService interface {
+	GetDefinitions(ctx context.Context, organizationID int) ([]AttributeDefinition, error)
+	CreateDefinition(ctx context.Context, definition AttributeDefinition) (AttributeDefinition, error)
+	UpdateDefinition(ctx context.Context, definition AttributeDefinition) (AttributeDefinition, error)
+	DeleteDefinition(ctx context.Context, organizationID int, name string) error
+	// ValidateAttributes checks a user's attributes before they are written
+	ValidateAttributes(ctx context.Context, user User) error
+	// ClaimUniqueValues claims the values of the user's unique attributes in the unit of work that
+	// writes the user, failing with ErrAttributeConflict when another user holds one. Deleted users
+	// release theirs.
+	ClaimUniqueValues(ctx context.Context, user User) error
+	// ParseFilter converts attr.<name> query values to typed values, limited to the attributes the caller may see
+	ParseFilter(
+		ctx context.Context,
+		organizationID, callerID int,
+		values map[string]string,
+	) (map[string]interface{}, error)
+	// View removes the attributes the caller may not see
+	View(ctx context.Context, organizationID, callerID int, users []User) ([]User, error)
+	// HiddenAttributes returns the names of the defined attributes the caller may not see
+	HiddenAttributes(ctx context.Context, organizationID, callerID int) (map[string]bool, error)
+}
+
+// AttributesContain implements the JSONB @> operator on attributes decoded from JSON, for
+// backends without it: wanted values must be equal to the stored ones, except that arrays only
+// need to contain the wanted elements and objects the wanted keys
+func AttributesContain(stored, wanted map[string]interface{}) bool {
+	return jsonContains(stored, wanted)
+}
+
+func jsonContains(stored, wanted interface{}) bool {
+	switch wanted := wanted.(type) {
+	case map[string]interface{}:
+		object, ok := stored.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range wanted {
+			if _, ok := object[key]; !ok || !jsonContains(object[key], value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		array, ok := stored.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, value := range wanted {
+			if !containsElement(array, value) {
+				return false
+			}
+		}
+		return true
+	}
+	return stored == wanted
+}
+
+func containsElement(array []interface{}, wanted interface{}) bool {
+	for _, element := range array {
+		if jsonContains(element, wanted) {
+			return true
+		}
+	}
+	return false
+}
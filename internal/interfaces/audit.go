@@ -0,0 +1,200 @@
+package interfaces
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AuditUserCreate       = "user.create"
+	AuditUserUpdate       = "user.update"
+	AuditUserDelete       = "user.delete"
+	AuditUserRestore      = "user.restore"
+	AuditUserStatusChange = "user.status_change"
+	AuditUserExport       = "user.export"
+	AuditUserDataExport   = "user.data_export"
+	AuditUserErase        = "user.erase"
+	AuditErasureSchedule  = "user.erasure_schedule"
+	AuditErasureCancel    = "user.erasure_cancel"
+	AuditUserAvatarUpdate = "user.avatar_update"
+	AuditUserAvatarDelete = "user.avatar_delete"
+	AuditAuthLogin        = "auth.login"
+	AuditAuthLoginFailed  = "auth.login_failed"
+	AuditAuthLogout       = "auth.logout"
+	AuditTargetUser       = "user"
+	AuditMaskedValue      = "********"
+	AuditDefaultPageLimit = 100
+	AuditMaxPageLimit     = 1000
+)
+
+// AuditGenesisHash is the previous hash of the first event in the chain
+var AuditGenesisHash = strings.Repeat("0", 64)
+
+// AuditEvent is one entry of the append-only audit log. Hash covers every other field and the
+// previous event's hash, so altering or removing a row breaks the chain from that point on.
+type AuditEvent struct {
+	ID             int64                  `json:"id"`
+	OrganizationID int                    `json:"organization_id"`
+	ActorID        *int                   `json:"actor_id"`
+	ActorUsername  string                 `json:"actor_username"`
+	Action         string                 `json:"action"`
+	TargetType     string                 `json:"target_type"`
+	TargetID       *int                   `json:"target_id"`
+	Changes        map[string]AuditChange `json:"changes,omitempty"`
+	IP             string                 `json:"ip"`
+	RequestID      string                 `json:"request_id"`
+	CreatedAt      time.Time              `json:"created_at"`
+	PrevHash       string                 `json:"prev_hash"`
+	Hash           string                 `json:"hash"`
+}
+
+// AuditSource describes who made a change and where the request came from
+type AuditSource struct {
+	ActorID       *int
+	ActorUsername string
+	IP            string
+	RequestID     string
+}
+
+type auditSourceKey struct{}
+
+// WithAuditSource returns a context whose changes are audited as made by source
+func WithAuditSource(ctx context.Context, source AuditSource) context.Context {
+	return context.WithValue(ctx, auditSourceKey{}, source)
+}
+
+// AuditSourceFrom returns the source ctx carries, or the zero source outside a request
+func AuditSourceFrom(ctx context.Context) AuditSource {
+	source, _ := ctx.Value(auditSourceKey{}).(AuditSource)
+	return source
+}
+
+// AuditChange holds the before and after value of a single field
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditFilter struct {
+	ActorID  *int
+	Action   string
+	TargetID *int
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+}
+
+// AuditVerification is the outcome of walking the whole hash chain
+type AuditVerification struct {
+	Checked  int    `json:"checked"`
+	Valid    bool   `json:"valid"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ComputeHash returns the SHA-256 of the previous hash and the event's content. CreatedAt is
+// hashed at microsecond precision in UTC, which is what PostgreSQL stores.
+func (event AuditEvent) ComputeHash() string {
+	changes, _ := json.Marshal(event.Changes)
+	fields := []string{
+		event.PrevHash,
+		strconv.FormatInt(event.ID, 10),
+		strconv.Itoa(event.OrganizationID),
+		optionalInt(event.ActorID),
+		event.ActorUsername,
+		event.Action,
+		event.TargetType,
+		optionalInt(event.TargetID),
+		string(changes),
+		event.IP,
+		event.RequestID,
+		event.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	}
+	digest := sha256.New()
+	for _, field := range fields {
+		// Length prefixes keep adjacent fields from running into each other
+		digest.Write([]byte(strconv.Itoa(len(field)) + ":" + field + ";"))
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+func optionalInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
+
+// maskedUserFields never appear in audit changes in clear text: the password hash, and the
+// personal data an erasure removes, since events cannot be altered once chained
+var maskedUserFields = map[string]bool{
+	"password":   true,
+	"name":       true,
+	"email":      true,
+	"username":   true,
+	"attributes": true,
+}
+
+// UserChanges diffs two versions of a user field by field, using their JSON names. Either side
+// may be nil for creations and deletions. Secret and personal fields are masked, so the change
+// only tells that they changed.
+func UserChanges(before, after *User) map[string]AuditChange {
+	beforeFields, afterFields := userFields(before), userFields(after)
+	changes := make(map[string]AuditChange)
+	for _, fields := range []map[string]interface{}{beforeFields, afterFields} {
+		for name := range fields {
+			if _, done := changes[name]; done {
+				continue
+			}
+			beforeValue, afterValue := beforeFields[name], afterFields[name]
+			if fmt.Sprint(beforeValue) == fmt.Sprint(afterValue) {
+				continue
+			}
+			if maskedUserFields[name] {
+				beforeValue, afterValue = mask(beforeValue), mask(afterValue)
+			}
+			changes[name] = AuditChange{Before: beforeValue, After: afterValue}
+		}
+	}
+	return changes
+}
+
+func userFields(user *User) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if user == nil {
+		return fields
+	}
+	encoded, err := json.Marshal(user)
+	if err == nil {
+		_ = json.Unmarshal(encoded, &fields)
+	}
+	return fields
+}
+
+func mask(value interface{}) interface{} {
+	if value == nil || value == "" {
+		return value
+	}
+	return AuditMaskedValue
+}
+
+type AuditRepository interface {
+	// Append assigns the ID, timestamp and chain hashes and stores the event
+	Append(ctx context.Context, event AuditEvent) (AuditEvent, error)
+	GetEvents(ctx context.Context, organizationID int, filter AuditFilter) ([]AuditEvent, error)
+	// GetChain returns up to limit events with an ID greater than afterID, across all organizations
+	GetChain(ctx context.Context, afterID int64, limit int) ([]AuditEvent, error)
+}
+
+type AuditService interface {
+	// Record stores the event in the unit of work carried by ctx, if any
+	Record(ctx context.Context, event AuditEvent) error
+	GetEvents(ctx context.Context, organizationID int, filter AuditFilter) ([]AuditEvent, error)
+	Verify(ctx context.Context) (AuditVerification, error)
+}
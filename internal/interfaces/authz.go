@@ -0,0 +1,45 @@
+package interfaces
+
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// AuthzRequest is the input to a policy decision
+type AuthzRequest struct {
+	Subject  map[string]interface{} `json:"subject"`
+	Action   string                 `json:"action"`
+	Resource map[string]interface{} `json:"resource"`
+}
+
+// AuthzCheckRequest is the body of POST /v1/authz/check. Subject and resource attributes may be
+// supplied directly or loaded from a user in the caller's organization by ID.
+type AuthzCheckRequest struct {
+	SubjectID  int                    `json:"subject_id"`
+	Subject    map[string]interface{} `json:"subject"`
+	Action     string                 `json:"action" validate:"required"`
+	ResourceID int                    `json:"resource_id"`
+	Resource   map[string]interface{} `json:"resource"`
+	Explain    bool                   `json:"explain"`
+}
+
+type Decision struct {
+	Allowed      bool               `json:"allowed"`
+	Reason       string             `json:"reason"`
+	PolicyID     string             `json:"policy_id,omitempty"`
+	DeniedFields []string           `json:"denied_fields,omitempty"`
+	Explanation  []PolicyEvaluation `json:"explanation,omitempty"`
+}
+
+// PolicyEvaluation records why a single policy did or did not apply
+type PolicyEvaluation struct {
+	PolicyID string `json:"policy_id"`
+	Effect   string `json:"effect"`
+	Matched  bool   `json:"matched"`
+	Reason   string `json:"reason"`
+}
+
+type PolicyEngine interface {
+	Evaluate(request AuthzRequest, explain bool) Decision
+	Reload() error
+}
@@ -0,0 +1,87 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	// AvatarMaxBytes is the largest image accepted for upload
+	AvatarMaxBytes = 5 << 20
+	// AvatarMaxDimension bounds the decoded width and height, so small files cannot expand to huge images
+	AvatarMaxDimension = 8000
+	// AvatarOriginal names the uploaded image, re-encoded without its metadata
+	AvatarOriginal = "original"
+	// AvatarURLExpiry is how long presigned avatar URLs stay valid
+	AvatarURLExpiry = 15 * time.Minute
+)
+
+// AvatarSizes are the square thumbnail edges generated for every upload, in pixels
+var AvatarSizes = []int{64, 256}
+
+// AvatarContentTypes are the accepted upload formats, detected from the image bytes
+var AvatarContentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+var (
+	ErrAvatarType     = errors.New("avatar must be a JPEG, PNG, GIF or WebP image")
+	ErrAvatarTooLarge = errors.New("avatar image is too large")
+	ErrBlobNotFound   = errors.New("blob not found")
+)
+
+type Avatar struct {
+	UserID         int `json:"-"`
+	OrganizationID int `json:"-"`
+	// KeyPrefix is the blob key the variants are stored under, as KeyPrefix/<variant>
+	KeyPrefix   string    `json:"-"`
+	ContentType string    `json:"content_type"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	URL         string    `json:"url"`
+	// Thumbnails holds a URL per entry of AvatarSizes, keyed by the edge length
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+// Key returns the blob key of a variant, AvatarOriginal or a size from AvatarSizes
+func (avatar Avatar) Key(variant string) string {
+	return avatar.KeyPrefix + "/" + variant
+}
+
+// AvatarVariants lists every stored variant of an avatar
+func AvatarVariants() []string {
+	variants := []string{AvatarOriginal}
+	for _, size := range AvatarSizes {
+		variants = append(variants, strconv.Itoa(size))
+	}
+	return variants
+}
+
+// BlobStore stores binary objects by key
+type BlobStore interface {
+	Put(key, contentType string, body io.Reader) error
+	// Get returns ErrBlobNotFound when no object has the key
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the objects; keys that do not exist are ignored
+	Delete(keys ...string) error
+	// URL returns a URL the object can be fetched from directly for the given time, or "" when the
+	// store cannot issue one and the object must be served through the API
+	URL(key string, expiry time.Duration) (string, error)
+}
+
+type AvatarRepository interface {
+	Get(ctx context.Context, organizationID, userID int) (Avatar, error)
+	GetMany(ctx context.Context, organizationID int, userIDs []int) (map[int]Avatar, error)
+	Save(ctx context.Context, avatar Avatar) (Avatar, error)
+	Delete(ctx context.Context, organizationID, userID int) error
+}
+
+type AvatarService interface {
+	// Upload validates and resizes the image and replaces the user's avatar
+	Upload(ctx context.Context, organizationID, userID int, image io.Reader) (Avatar, error)
+	// Open returns a variant of the user's avatar and its content type
+	Open(ctx context.Context, organizationID, userID int, variant string) (io.ReadCloser, string, error)
+	Delete(ctx context.Context, organizationID, userID int) error
+	// Attach sets Avatar on the users that have one
+	Attach(ctx context.Context, organizationID int, users []User) ([]User, error)
+}
@@ -0,0 +1,150 @@
+package interfaces
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	EventUserCreated  = "user.created"
+	EventUserUpdated  = "user.updated"
+	EventUserDeleted  = "user.deleted"
+	EventUserErased   = "user.erased"
+	EventUserLoggedIn = "user.logged_in"
+	// OutboxRelayBatch is how many messages the relay reads per query
+	OutboxRelayBatch = 100
+)
+
+// UserEvent is a domain event about one user. The events of a user are published in the order
+// they were recorded.
+type UserEvent interface {
+	EventType() string
+	EventUser() EventUser
+}
+
+// EventUser is the user as events describe it, without the password hash
+type EventUser struct {
+	ID             int                    `json:"id"`
+	OrganizationID int                    `json:"organization_id"`
+	Name           string                 `json:"name"`
+	Email          string                 `json:"email"`
+	Username       string                 `json:"username"`
+	Status         string                 `json:"status"`
+	Version        int                    `json:"version"`
+	DeletedAt      *time.Time             `json:"deleted_at,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+}
+
+func NewEventUser(user User) EventUser {
+	return EventUser{
+		ID:             user.ID,
+		OrganizationID: user.OrganizationID,
+		Name:           user.Name,
+		Email:          user.Email,
+		Username:       user.Username,
+		Status:         StatusOf(user),
+		Version:        user.Version,
+		DeletedAt:      user.DeletedAt,
+		Attributes:     user.Attributes,
+	}
+}
+
+// UserCreated is recorded when a user is created, by an administrator, a registration, an
+// accepted invitation or an import
+type UserCreated struct {
+	User EventUser `json:"user"`
+}
+
+// UserUpdated is recorded when a user's profile or status changes, or a deleted user is restored.
+// It carries the user as it is after the change.
+type UserUpdated struct {
+	User EventUser `json:"user"`
+}
+
+// UserDeleted is recorded when a user is soft-deleted
+type UserDeleted struct {
+	User EventUser `json:"user"`
+}
+
+// UserErased is recorded when a user's personal data is erased. It carries the anonymized user,
+// so consumers drop what they kept of the user's data.
+type UserErased struct {
+	User EventUser `json:"user"`
+}
+
+// UserLoggedIn is recorded when a user signs in with their password
+type UserLoggedIn struct {
+	User EventUser `json:"user"`
+}
+
+func (UserCreated) EventType() string           { return EventUserCreated }
+func (event UserCreated) EventUser() EventUser  { return event.User }
+func (UserUpdated) EventType() string           { return EventUserUpdated }
+func (event UserUpdated) EventUser() EventUser  { return event.User }
+func (UserDeleted) EventType() string           { return EventUserDeleted }
+func (event UserDeleted) EventUser() EventUser  { return event.User }
+func (UserErased) EventType() string            { return EventUserErased }
+func (event UserErased) EventUser() EventUser   { return event.User }
+func (UserLoggedIn) EventType() string          { return EventUserLoggedIn }
+func (event UserLoggedIn) EventUser() EventUser { return event.User }
+
+// OutboxMessage is a recorded event waiting to be published. ID identifies the event across
+// deliveries: a message may be delivered more than once, so consumers drop IDs they have seen.
+type OutboxMessage struct {
+	ID             int64           `json:"id"`
+	Type           string          `json:"type"`
+	OrganizationID int             `json:"organization_id"`
+	UserID         int             `json:"user_id"`
+	Payload        json.RawMessage `json:"payload"`
+	CreatedAt      time.Time       `json:"created_at"`
+	// Attempts counts the failed deliveries so far
+	Attempts int `json:"-"`
+}
+
+// NewOutboxMessage encodes the event; the ID and time are assigned when it is stored
+func NewOutboxMessage(event UserEvent) (OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+	user := event.EventUser()
+	return OutboxMessage{
+		Type:           event.EventType(),
+		OrganizationID: user.OrganizationID,
+		UserID:         user.ID,
+		Payload:        payload,
+	}, nil
+}
+
+// OrderingKey names the sequence the message is ordered in, the events of its user
+func (message OutboxMessage) OrderingKey() string {
+	return fmt.Sprintf("%d:%d", message.OrganizationID, message.UserID)
+}
+
+type OutboxRepository interface {
+	// Append stores the events in the unit of work carried by ctx, if any, so they commit or roll
+	// back with the change they describe
+	Append(ctx context.Context, events ...UserEvent) error
+	// Lock makes the caller the only relay until unlock is called. It reports false while another
+	// relay holds the lock.
+	Lock(ctx context.Context) (unlock func(), locked bool, err error)
+	// Pending returns up to limit messages with an ID greater than afterID, oldest first
+	Pending(ctx context.Context, afterID int64, limit int) ([]OutboxMessage, error)
+	// Delete removes published messages
+	Delete(ctx context.Context, ids ...int64) error
+	// Fail counts a failed delivery of the message and keeps it for the next attempt
+	Fail(ctx context.Context, id int64, cause error) error
+}
+
+// EventPublisher delivers messages to their consumers. Publish returns once the message is
+// accepted; a failed message is offered again later, after the messages published before it.
+type EventPublisher interface {
+	Publish(ctx context.Context, message OutboxMessage) error
+}
+
+type OutboxRelay interface {
+	// Relay publishes the pending messages and returns how many were published
+	Relay(ctx context.Context) (int, error)
+}
@@ -0,0 +1,58 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatNDJSON  = "ndjson"
+	ExportFormatParquet = "parquet"
+
+	// ExportBatchSize is the number of rows fetched from the export cursor at a time
+	ExportBatchSize = 500
+)
+
+// ExportFields are the user fields that can be exported; passwords never are
+var ExportFields = []string{"id", "organization_id", "name", "email", "username", "status", "version"}
+
+var (
+	ErrExportFormat      = errors.New("unsupported export format")
+	ErrExportField       = errors.New("unknown export field")
+	ErrExportDestination = errors.New("no export destination is configured")
+)
+
+// UserFilter narrows the active users of an organization; empty fields match everything
+type UserFilter struct {
+	Statuses []string
+	Role     string
+	// Search matches name, email or username case-insensitively
+	Search string
+	// Attributes matches users whose attributes contain these values
+	Attributes map[string]interface{}
+}
+
+type ExportOptions struct {
+	Format string
+	// Fields selects and orders the exported columns; empty exports all ExportFields
+	Fields []string
+	Filter UserFilter
+}
+
+type ExportResult struct {
+	Format   string `json:"format"`
+	Rows     int    `json:"rows"`
+	Location string `json:"location,omitempty"`
+}
+
+// ExportStore receives exports that are not streamed back to the client
+type ExportStore interface {
+	Upload(key, contentType string, body io.Reader) (location string, err error)
+}
+
+type ExportService interface {
+	Export(ctx context.Context, organizationID int, writer io.Writer, options ExportOptions) (ExportResult, error)
+	ExportToStore(ctx context.Context, organizationID int, options ExportOptions) (ExportResult, error)
+}
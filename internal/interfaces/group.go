@@ -0,0 +1,75 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrGroupCycle      = errors.New("group hierarchy would contain a cycle")
+	ErrNotOrganization = errors.New("user is not a member of the organization")
+)
+
+type Group struct {
+	ID             int       `json:"id"`
+	OrganizationID int       `json:"organization_id"`
+	Name           string    `json:"name" validate:"required"`
+	Description    string    `json:"description"`
+	ParentID       *int      `json:"parent_id"`
+	Roles          []string  `json:"roles"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type GroupRequest struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	ParentID    *int   `json:"parent_id"`
+}
+
+type GroupMember struct {
+	GroupID   int       `json:"group_id"`
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GroupMemberRequest struct {
+	UserID int `json:"user_id"`
+}
+
+type GroupRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type GroupRepository interface {
+	GetAll(ctx context.Context, organizationID int) ([]Group, error)
+	GetByID(ctx context.Context, organizationID, id int) (Group, error)
+	Create(ctx context.Context, group Group) (Group, error)
+	Update(ctx context.Context, group Group) (Group, error)
+	Delete(ctx context.Context, organizationID, id int) error
+	// GetParents returns the parent of each group of the organization, and locks the groups until
+	// the transaction ends, so concurrent changes to the hierarchy take turns
+	GetParents(ctx context.Context, organizationID int) (map[int]*int, error)
+	GetMembers(ctx context.Context, organizationID int, groupIDs ...int) ([]GroupMember, error)
+	GetGroupIDsForUser(ctx context.Context, organizationID, userID int) ([]int, error)
+	AddMember(ctx context.Context, organizationID, groupID, userID int) (GroupMember, error)
+	RemoveMember(ctx context.Context, organizationID, groupID, userID int) error
+	AssignRole(ctx context.Context, organizationID, groupID int, role string) error
+	UnassignRole(ctx context.Context, organizationID, groupID int, role string) error
+}
+
+type GroupService interface {
+	GetGroups(ctx context.Context, organizationID int) ([]Group, error)
+	GetGroup(ctx context.Context, organizationID, id int) (Group, error)
+	CreateGroup(ctx context.Context, group Group) (Group, error)
+	UpdateGroup(ctx context.Context, group Group) (Group, error)
+	DeleteGroup(ctx context.Context, organizationID, id int) error
+	GetMembers(ctx context.Context, organizationID, groupID int) ([]GroupMember, error)
+	AddMember(ctx context.Context, organizationID, groupID, userID int) (GroupMember, error)
+	RemoveMember(ctx context.Context, organizationID, groupID, userID int) error
+	AssignRole(ctx context.Context, organizationID, groupID int, role string) (Group, error)
+	UnassignRole(ctx context.Context, organizationID, groupID int, role string) (Group, error)
+	GetEffectiveGroups(ctx context.Context, organizationID, userID int) ([]Group, error)
+	GetTransitiveMembers(ctx context.Context, organizationID, groupID int) ([]GroupMember, error)
+	GetEffectivePermissions(ctx context.Context, organizationID, userID int) ([]string, error)
+}
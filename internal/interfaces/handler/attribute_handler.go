@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type AttributeHandler struct {
+	service interfaces.AttributeService
+}
+
+func NewAttributeHandler(service interfaces.AttributeService) *AttributeHandler {
+	return &AttributeHandler{service}
+}
+
+// GetDefinitions godoc
+// @Summary List attribute definitions
+// @Description List the custom user attributes defined for the current organization
+// @Tags attributes
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} interfaces.AttributeDefinition
+// @Router /v1/attributes [get]
+func (handler *AttributeHandler) GetDefinitions(context echo.Context) error {
+	definitions, err := handler.service.GetDefinitions(context.Request().Context(), tenant.FromContext(context))
+	if err != nil {
+		logger.Error("Error retrieving attribute definitions: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	return context.JSON(http.StatusOK, definitions)
+}
+
+// CreateDefinition godoc
+// @Summary Define an attribute
+// @Description Define a custom user attribute. Type is string, number, boolean or date; pattern and enum apply to strings; visibility is public (default) or admin.
+// @Tags attributes
+// @Accept  json
+// @Produce  json
+// @Param definition body interfaces.AttributeDefinition true "Attribute definition"
+// @Success 201 {object} interfaces.AttributeDefinition
+// @Failure 400 {string} string "Invalid definition"
+// @Failure 409 {string} string "Attribute is already defined"
+// @Router /v1/attributes [post]
+func (handler *AttributeHandler) CreateDefinition(context echo.Context) error {
+	var definition interfaces.AttributeDefinition
+	if err := context.Bind(&definition); err != nil {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+	definition.OrganizationID = tenant.FromContext(context)
+
+	created, err := handler.service.CreateDefinition(context.Request().Context(), definition)
+	if err != nil {
+		return attributeError(context, err)
+	}
+	logger.Info("Attribute defined", zap.String("name", created.Name))
+	return context.JSON(http.StatusCreated, created)
+}
+
+// UpdateDefinition godoc
+// @Summary Update an attribute definition
+// @Description Replace an attribute definition. Existing values are checked against it the next time the user is written,
+// @Description except that making it unique fails while two users share a value.
+// @Tags attributes
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Attribute name"
+// @Param definition body interfaces.AttributeDefinition true "Attribute definition"
+// @Success 200 {object} interfaces.AttributeDefinition
+// @Failure 400 {string} string "Invalid definition"
+// @Failure 409 {string} string "Attribute value is already taken"
+// @Router /v1/attributes/{name} [put]
+func (handler *AttributeHandler) UpdateDefinition(context echo.Context) error {
+	var definition interfaces.AttributeDefinition
+	if err := context.Bind(&definition); err != nil {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+	definition.OrganizationID = tenant.FromContext(context)
+	definition.Name = context.Param("name")
+
+	updated, err := handler.service.UpdateDefinition(context.Request().Context(), definition)
+	if err != nil {
+		return attributeError(context, err)
+	}
+	logger.Info("Attribute definition updated", zap.String("name", updated.Name))
+	return context.JSON(http.StatusOK, updated)
+}
+
+// DeleteDefinition godoc
+// @Summary Delete an attribute definition
+// @Description Delete an attribute definition and remove its values from every user
+// @Tags attributes
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Attribute name"
+// @Success 200 {object} map[string]string
+// @Router /v1/attributes/{name} [delete]
+func (handler *AttributeHandler) DeleteDefinition(context echo.Context) error {
+	name := context.Param("name")
+	if err := handler.service.DeleteDefinition(
+		context.Request().Context(),
+		tenant.FromContext(context),
+		name,
+	); err != nil {
+		return attributeError(context, err)
+	}
+	logger.Info("Attribute definition deleted", zap.String("name", name))
+	return context.JSON(http.StatusOK, map[string]string{
+		"message": "attribute deleted successfully",
+	})
+}
+
+func attributeError(context echo.Context, err error) error {
+	logger.Error("Attribute error: ", zap.Error(err))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return context.JSON(http.StatusNotFound, "Attribute not found")
+	case errors.Is(err, interfaces.ErrInvalidAttributeDefinition):
+		return context.JSON(http.StatusBadRequest, err.Error())
+	case errors.Is(err, interfaces.ErrAttributeExists), errors.Is(err, interfaces.ErrAttributeConflict):
+		return context.JSON(http.StatusConflict, err.Error())
+	}
+	return context.JSON(http.StatusInternalServerError, "Failed to process attribute request")
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/authz"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type AuthzHandler struct {
+	engine        interfaces.PolicyEngine
+	users         interfaces.Service
+	organizations interfaces.OrganizationService
+}
+
+func NewAuthzHandler(
+	engine interfaces.PolicyEngine,
+	users interfaces.Service,
+	organizations interfaces.OrganizationService,
+) *AuthzHandler {
+	return &AuthzHandler{engine, users, organizations}
+}
+
+// Check godoc
+// @Summary Evaluate an access decision
+// @Description Evaluate the policies for a subject, action and resource. The subject defaults to the caller; attributes in the body override those loaded by ID.
+// @Tags authz
+// @Accept  json
+// @Produce  json
+// @Param request body interfaces.AuthzCheckRequest true "Authorization check"
+// @Success 200 {object} interfaces.Decision
+// @Router /v1/authz/check [post]
+func (handler *AuthzHandler) Check(context echo.Context) error {
+	var request interfaces.AuthzCheckRequest
+	if err := context.Bind(&request); err != nil || request.Action == "" {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	organizationID := tenant.FromContext(context)
+	subjectID := request.SubjectID
+	if subjectID == 0 && len(request.Subject) == 0 {
+		claims, ok := authentication.ClaimsFromContext(context)
+		if !ok {
+			return context.JSON(http.StatusUnauthorized, "Unauthorized")
+		}
+		subjectID = claims.UserID
+	}
+
+	subject, err := handler.subjectAttributes(context, organizationID, subjectID)
+	if err != nil {
+		return authzError(context, err)
+	}
+	resource := make(map[string]interface{})
+	if request.ResourceID != 0 {
+		user, err := handler.users.GetUserByID(context.Request().Context(), organizationID, request.ResourceID)
+		if err != nil {
+			return authzError(context, err)
+		}
+		resource = authz.UserAttributes(user)
+	}
+
+	decision := handler.engine.Evaluate(interfaces.AuthzRequest{
+		Subject:  merge(subject, request.Subject),
+		Action:   request.Action,
+		Resource: merge(resource, request.Resource),
+	}, request.Explain)
+	return context.JSON(http.StatusOK, decision)
+}
+
+// subjectAttributes loads the user's attributes together with their role in the organization
+func (handler *AuthzHandler) subjectAttributes(context echo.Context, organizationID, userID int) (map[string]interface{}, error) {
+	if userID == 0 {
+		return make(map[string]interface{}), nil
+	}
+	user, err := handler.users.GetUserByID(context.Request().Context(), organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	attributes := authz.UserAttributes(user)
+	// A custom attribute named role must not pass for the organization role
+	delete(attributes, "role")
+	if membership, err := handler.organizations.GetMembership(
+		context.Request().Context(),
+		organizationID,
+		userID,
+	); err == nil {
+		attributes["role"] = membership.Role
+	}
+	return attributes, nil
+}
+
+func merge(attributes, overrides map[string]interface{}) map[string]interface{} {
+	for key, value := range overrides {
+		attributes[key] = value
+	}
+	return attributes
+}
+
+func authzError(context echo.Context, err error) error {
+	logger.Error("Authorization check error: ", zap.Error(err))
+	if errors.Is(err, sql.ErrNoRows) {
+		return context.JSON(http.StatusNotFound, "User not found")
+	}
+	return context.JSON(http.StatusInternalServerError, "Failed to evaluate authorization")
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// avatarFormField is the multipart field an avatar can be uploaded in
+const avatarFormField = "avatar"
+
+type AvatarHandler struct {
+	service interfaces.AvatarService
+	audit   interfaces.AuditService
+}
+
+func NewAvatarHandler(service interfaces.AvatarService, audit interfaces.AuditService) *AvatarHandler {
+	return &AvatarHandler{service, audit}
+}
+
+// PutAvatar godoc
+// @Summary Upload a user's avatar
+// @Description Replace a user's avatar with a JPEG, PNG, GIF or WebP image of at most 5 MiB, sent as the request body or as the "avatar" field of a multipart form. Square thumbnails are generated and metadata is stripped. Users may change their own avatar; admins may change anyone's.
+// @Tags users
+// @Accept  image/jpeg
+// @Accept  image/png
+// @Accept  mpfd
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} interfaces.Avatar
+// @Failure 413 {string} string "Image is too large"
+// @Failure 415 {string} string "Unsupported image type"
+// @Router /v1/users/{id}/avatar [put]
+func (handler *AvatarHandler) PutAvatar(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, "Invalid user ID")
+	}
+
+	body := context.Request().Body
+	if strings.HasPrefix(context.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		file, err := context.FormFile(avatarFormField)
+		if err != nil {
+			logger.Error("Invalid avatar upload: ", zap.Error(err))
+			return context.JSON(http.StatusBadRequest, "Missing avatar file")
+		}
+		upload, err := file.Open()
+		if err != nil {
+			logger.Error("Error opening avatar upload: ", zap.Error(err))
+			return context.JSON(http.StatusBadRequest, "Invalid avatar file")
+		}
+		defer upload.Close()
+		body = upload
+	}
+
+	avatar, err := handler.service.Upload(context.Request().Context(), tenant.FromContext(context), id, body)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return context.JSON(http.StatusNotFound, "User not found")
+		}
+		return avatarError(context, err)
+	}
+	logger.Info("Avatar updated", zap.Int("userID", id))
+	recordAudit(handler.audit, context, userEvent(interfaces.AuditUserAvatarUpdate, interfaces.User{ID: id}, nil))
+	return context.JSON(http.StatusOK, avatar)
+}
+
+// GetAvatar godoc
+// @Summary Download a user's avatar
+// @Description Download the uploaded avatar, or one of its thumbnails with size=64 or size=256
+// @Tags users
+// @Produce  image/jpeg
+// @Produce  image/png
+// @Param id path int true "User ID"
+// @Param size query string false "Thumbnail edge in pixels"
+// @Success 200 {file} file
+// @Failure 404 {string} string "User has no avatar"
+// @Router /v1/users/{id}/avatar [get]
+func (handler *AvatarHandler) GetAvatar(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, "Invalid user ID")
+	}
+	variant := context.QueryParam("size")
+	if variant == "" {
+		variant = interfaces.AvatarOriginal
+	}
+
+	body, contentType, err := handler.service.Open(
+		context.Request().Context(),
+		tenant.FromContext(context),
+		id,
+		variant,
+	)
+	if err != nil {
+		return avatarError(context, err)
+	}
+	defer body.Close()
+
+	// A new upload changes the URLs of the user response, so a short private cache is safe
+	context.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=300")
+	context.Response().Header().Set(echo.HeaderContentType, contentType)
+	context.Response().WriteHeader(http.StatusOK)
+	if _, err := io.Copy(context.Response(), body); err != nil {
+		logger.Error("Error streaming avatar: ", zap.Int("userID", id), zap.Error(err))
+	}
+	return nil
+}
+
+// DeleteAvatar godoc
+// @Summary Delete a user's avatar
+// @Description Remove a user's avatar and its thumbnails. Users may remove their own avatar; admins may remove anyone's.
+// @Tags users
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Router /v1/users/{id}/avatar [delete]
+func (handler *AvatarHandler) DeleteAvatar(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, "Invalid user ID")
+	}
+	if err := handler.service.Delete(context.Request().Context(), tenant.FromContext(context), id); err != nil {
+		return avatarError(context, err)
+	}
+	logger.Info("Avatar deleted", zap.Int("userID", id))
+	recordAudit(handler.audit, context, userEvent(interfaces.AuditUserAvatarDelete, interfaces.User{ID: id}, nil))
+	return context.JSON(http.StatusOK, map[string]string{
+		"message": "avatar deleted successfully",
+	})
+}
+
+func avatarError(context echo.Context, err error) error {
+	logger.Error("Avatar error: ", zap.Error(err))
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, interfaces.ErrBlobNotFound):
+		return context.JSON(http.StatusNotFound, "Avatar not found")
+	case errors.Is(err, interfaces.ErrAvatarType):
+		return context.JSON(http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, interfaces.ErrAvatarTooLarge):
+		return context.JSON(http.StatusRequestEntityTooLarge, err.Error())
+	}
+	return context.JSON(http.StatusInternalServerError, "Failed to process avatar")
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// versionETag renders a resource version as a strong entity tag
+func versionETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// etagMatches reports whether a comma-separated If-Match or If-None-Match header lists etag or "*".
+// With weak comparison, which RFC 9110 requires for If-None-Match, weak tags compare equal to their
+// strong counterpart; with strong comparison, required for If-Match, a weak tag never matches.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch enforces a required If-Match header against the current version. It writes the
+// 428 or 412 response itself and reports whether the request may proceed.
+func checkIfMatch(context echo.Context, version int) (bool, error) {
+	header := context.Request().Header.Get(headerIfMatch)
+	if header == "" {
+		return false, context.JSON(http.StatusPreconditionRequired, "If-Match header is required")
+	}
+	if !etagMatches(header, versionETag(version), false) {
+		context.Response().Header().Set(headerETag, versionETag(version))
+		return false, context.JSON(http.StatusPreconditionFailed, "User was modified, fetch it again and retry")
+	}
+	return true, nil
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/exporter"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// exportDestinationStore sends the export to the configured bucket instead of the response
+const exportDestinationStore = "s3"
+
+type ExportHandler struct {
+	service interfaces.ExportService
+	audit   interfaces.AuditService
+}
+
+func NewExportHandler(service interfaces.ExportService, audit interfaces.AuditService) *ExportHandler {
+	return &ExportHandler{service, audit}
+}
+
+// ExportUsers godoc
+// @Summary Export users
+// @Description Stream the organization's active users as CSV, NDJSON or Parquet, or upload the export to the configured S3 bucket with destination=s3. Passwords are never exported.
+// @Tags users
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Produce  application/vnd.apache.parquet
+// @Produce  json
+// @Param format query string false "csv (default), ndjson or parquet"
+// @Param fields query string false "Comma-separated fields in output order, e.g. id,email"
+// @Param status query []string false "Only users with these statuses" collectionFormat(multi)
+// @Param role query string false "Only users with this organization role"
+// @Param q query string false "Only users whose name, email or username contains this"
+// @Param destination query string false "s3 to upload the export instead of returning it"
+// @Success 200 {object} interfaces.ExportResult
+// @Router /v1/users/export [get]
+func (handler *ExportHandler) ExportUsers(context echo.Context) error {
+	options, err := exporter.ValidateOptions(exportOptions(context))
+	if err != nil {
+		logger.Error("Invalid export options: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, err.Error())
+	}
+	organizationID := tenant.FromContext(context)
+
+	if context.QueryParam("destination") == exportDestinationStore {
+		result, err := handler.service.ExportToStore(context.Request().Context(), organizationID, options)
+		if err != nil {
+			logger.Error("Error exporting users: ", zap.Error(err))
+			if errors.Is(err, interfaces.ErrExportDestination) {
+				return context.JSON(http.StatusBadRequest, err.Error())
+			}
+			return context.JSON(http.StatusInternalServerError, "Failed to export users")
+		}
+		handler.recordExport(context, options, result)
+		return context.JSON(http.StatusOK, result)
+	}
+
+	response := context.Response()
+	response.Header().Set(echo.HeaderContentType, exporter.ContentTypes[options.Format])
+	response.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="users.%s"`, options.Format))
+	response.WriteHeader(http.StatusOK)
+	result, err := handler.service.Export(context.Request().Context(), organizationID, response, options)
+	if err != nil {
+		// The status is already sent; a truncated body is all the client can be told
+		logger.Error("Error streaming user export: ", zap.Int("rows", result.Rows), zap.Error(err))
+		return nil
+	}
+	handler.recordExport(context, options, result)
+	return nil
+}
+
+func (handler *ExportHandler) recordExport(
+	context echo.Context,
+	options interfaces.ExportOptions,
+	result interfaces.ExportResult,
+) {
+	changes := map[string]interfaces.AuditChange{
+		"format": {After: result.Format},
+		"fields": {After: options.Fields},
+		"rows":   {After: result.Rows},
+	}
+	if result.Location != "" {
+		changes["location"] = interfaces.AuditChange{After: result.Location}
+	}
+	recordAudit(handler.audit, context, interfaces.AuditEvent{
+		Action:     interfaces.AuditUserExport,
+		TargetType: interfaces.AuditTargetUser,
+		Changes:    changes,
+	})
+}
+
+func exportOptions(context echo.Context) interfaces.ExportOptions {
+	options := interfaces.ExportOptions{
+		Format: context.QueryParam("format"),
+		Fields: splitList(context.QueryParam("fields")),
+		Filter: userFilter(context),
+	}
+	if options.Format == "" {
+		options.Format = interfaces.ExportFormatCSV
+	}
+	return options
+}
+
+// userFilter reads the status, role and q query parameters. Status may be repeated or comma-separated.
+func userFilter(context echo.Context) interfaces.UserFilter {
+	filter := interfaces.UserFilter{
+		Role:   context.QueryParam("role"),
+		Search: context.QueryParam("q"),
+	}
+	for _, status := range context.QueryParams()["status"] {
+		filter.Statuses = append(filter.Statuses, splitList(status)...)
+	}
+	return filter
+}
+
+// splitList splits a comma-separated query value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
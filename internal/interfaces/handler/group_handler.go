@@ -0,0 +1,393 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type GroupHandler struct {
+	service interfaces.GroupService
+}
+
+func NewGroupHandler(service interfaces.GroupService) *GroupHandler {
+	return &GroupHandler{service}
+}
+
+// GetGroups godoc
+// @Summary List groups
+// @Description List the groups of the current organization
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} interfaces.Group
+// @Router /v1/groups [get]
+func (handler *GroupHandler) GetGroups(context echo.Context) error {
+	groups, err := handler.service.GetGroups(context.Request().Context(), tenant.FromContext(context))
+	if err != nil {
+		logger.Error("Error retrieving groups: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	return context.JSON(http.StatusOK, groups)
+}
+
+// GetGroup godoc
+// @Summary Get a group by ID
+// @Description Get a group and its assigned roles
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Group ID"
+// @Success 200 {object} interfaces.Group
+// @Router /v1/groups/{id} [get]
+func (handler *GroupHandler) GetGroup(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid group ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	group, err := handler.service.GetGroup(context.Request().Context(), tenant.FromContext(context), id)
+	if err != nil {
+		return groupError(context, err)
+	}
+	return context.JSON(http.StatusOK, group)
+}
+
+// CreateGroup godoc
+// @Summary Create a group
+// @Description Create a group, optionally nested under a parent group
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param group body interfaces.GroupRequest true "Create Group"
+// @Success 201 {object} interfaces.Group
+// @Router /v1/groups [post]
+func (handler *GroupHandler) CreateGroup(context echo.Context) error {
+	var request interfaces.GroupRequest
+	if err := context.Bind(&request); err != nil || request.Name == "" {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	group, err := handler.service.CreateGroup(context.Request().Context(), interfaces.Group{
+		OrganizationID: tenant.FromContext(context),
+		Name:           request.Name,
+		Description:    request.Description,
+		ParentID:       request.ParentID,
+	})
+	if err != nil {
+		return groupError(context, err)
+	}
+	logger.Info("Group created", zap.Int("groupID", group.ID), zap.String("name", group.Name))
+	return context.JSON(http.StatusCreated, group)
+}
+
+// UpdateGroup godoc
+// @Summary Update a group
+// @Description Rename a group or move it under another parent
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Group ID"
+// @Param group body interfaces.GroupRequest true "Update Group"
+// @Success 200 {object} interfaces.Group
+// @Router /v1/groups/{id} [patch]
+func (handler *GroupHandler) UpdateGroup(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid group ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	var request interfaces.GroupRequest
+	if err := context.Bind(&request); err != nil {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	organizationID := tenant.FromContext(context)
+	existing, err := handler.service.GetGroup(context.Request().Context(), organizationID, id)
+	if err != nil {
+		return groupError(context, err)
+	}
+	if request.Name != "" {
+		existing.Name = request.Name
+	}
+	if request.Description != "" {
+		existing.Description = request.Description
+	}
+	if request.ParentID != nil {
+		existing.ParentID = request.ParentID
+	}
+
+	group, err := handler.service.UpdateGroup(context.Request().Context(), existing)
+	if err != nil {
+		return groupError(context, err)
+	}
+	logger.Info("Group updated", zap.Int("groupID", group.ID))
+	return context.JSON(http.StatusOK, group)
+}
+
+// DeleteGroup godoc
+// @Summary Delete a group
+// @Description Delete a group; child groups become top-level groups
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Group ID"
+// @Success 200 {object} map[string]string
+// @Router /v1/groups/{id} [delete]
+func (handler *GroupHandler) DeleteGroup(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid group ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	if err := handler.service.DeleteGroup(context.Request().Context(), tenant.FromContext(context), id); err != nil {
+		return groupError(context, err)
+	}
+	logger.Info("Group deleted", zap.Int("groupID", id))
+	return context.JSON(http.StatusOK, map[string]string{
+		"message": "group deleted successfully",
+	})
+}
+
+// GetMembers godoc
+// @Summary List direct group members
+// @Description List the users directly in a group
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Group ID"
+// @Success 200 {array} interfaces.GroupMember
+// @Router /v1/groups/{id}/members [get]
+func (handler *GroupHandler) GetMembers(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid group ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	members, err := handler.service.GetMembers(context.Request().Context(), tenant.FromContext(context), id)
+	if err != nil {
+		return groupError(context, err)
+	}
+	return context.JSON(http.StatusOK, members)
+}
+
+// GetTransitiveMembers godoc
+// @Summary List transitive group members
+// @Description List the users in a group or any of its descendant groups
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Group ID"
+// @Success 200 {array} interfaces.GroupMember
+// @Router /v1/groups/{id}/members/transitive [get]
+func (handler *GroupHandler) GetTransitiveMembers(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid group ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	members, err := handler.service.GetTransitiveMembers(context.Request().Context(), tenant.FromContext(context), id)
+	if err != nil {
+		return groupError(context, err)
+	}
+	return context.JSON(http.StatusOK, members)
+}
+
+// AddMember godoc
+// @Summary Add a group member
+// @Description Add an organization member to a group
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Group ID"
+// @Param member body interfaces.GroupMemberRequest true "Member"
+// @Success 201 {object} interfaces.GroupMember
+// @Router /v1/groups/{id}/members [post]
+func (handler *GroupHandler) AddMember(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid group ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	var request interfaces.GroupMemberRequest
+	if err := context.Bind(&request); err != nil || request.UserID == 0 {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	member, err := handler.service.AddMember(
+		context.Request().Context(),
+		tenant.FromContext(context),
+		id,
+		request.UserID,
+	)
+	if err != nil {
+		return groupError(context, err)
+	}
+	logger.Info("Group member added", zap.Int("groupID", id), zap.Int("userID", request.UserID))
+	return context.JSON(http.StatusCreated, member)
+}
+
+// RemoveMember godoc
+// @Summary Remove a group member
+// @Description Remove a user from a group
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Group ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Router /v1/groups/{id}/members/{user_id} [delete]
+func (handler *GroupHandler) RemoveMember(context echo.Context) error {
+	id, userID, err := membershipParams(context)
+	if err != nil {
+		logger.Error("Invalid group member ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	if err := handler.service.RemoveMember(
+		context.Request().Context(),
+		tenant.FromContext(context),
+		id,
+		userID,
+	); err != nil {
+		return groupError(context, err)
+	}
+	logger.Info("Group member removed", zap.Int("groupID", id), zap.Int("userID", userID))
+	return context.JSON(http.StatusOK, map[string]string{
+		"message": "member removed successfully",
+	})
+}
+
+// AssignRole godoc
+// @Summary Assign a role to a group
+// @Description Grant a role to every user who is effectively in the group
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Group ID"
+// @Param role body interfaces.GroupRoleRequest true "Role"
+// @Success 200 {object} interfaces.Group
+// @Router /v1/groups/{id}/roles [post]
+func (handler *GroupHandler) AssignRole(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid group ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+
+	var request interfaces.GroupRoleRequest
+	if err := context.Bind(&request); err != nil {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	group, err := handler.service.AssignRole(context.Request().Context(), tenant.FromContext(context), id, request.Role)
+	if err != nil {
+		return groupError(context, err)
+	}
+	logger.Info("Group role assigned", zap.Int("groupID", id), zap.String("role", request.Role))
+	return context.JSON(http.StatusOK, group)
+}
+
+// UnassignRole godoc
+// @Summary Remove a role from a group
+// @Description Revoke a role previously assigned to the group
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Group ID"
+// @Param role path string true "Role"
+// @Success 200 {object} interfaces.Group
+// @Router /v1/groups/{id}/roles/{role} [delete]
+func (handler *GroupHandler) UnassignRole(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid group ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	group, err := handler.service.UnassignRole(
+		context.Request().Context(),
+		tenant.FromContext(context),
+		id,
+		context.Param("role"),
+	)
+	if err != nil {
+		return groupError(context, err)
+	}
+	logger.Info("Group role unassigned", zap.Int("groupID", id), zap.String("role", context.Param("role")))
+	return context.JSON(http.StatusOK, group)
+}
+
+// GetUserGroups godoc
+// @Summary List a user's effective groups
+// @Description List the groups a user is in directly or through nested groups
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {array} interfaces.Group
+// @Router /v1/users/{id}/groups [get]
+func (handler *GroupHandler) GetUserGroups(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid User ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	groups, err := handler.service.GetEffectiveGroups(context.Request().Context(), tenant.FromContext(context), id)
+	if err != nil {
+		return groupError(context, err)
+	}
+	return context.JSON(http.StatusOK, groups)
+}
+
+// GetUserPermissions godoc
+// @Summary List a user's effective permissions
+// @Description Resolve permissions from the user's organization role and all effective group roles
+// @Tags groups
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {array} string
+// @Router /v1/users/{id}/permissions [get]
+func (handler *GroupHandler) GetUserPermissions(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid User ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	permissions, err := handler.service.GetEffectivePermissions(
+		context.Request().Context(),
+		tenant.FromContext(context),
+		id,
+	)
+	if err != nil {
+		return groupError(context, err)
+	}
+	return context.JSON(http.StatusOK, permissions)
+}
+
+func groupError(context echo.Context, err error) error {
+	logger.Error("Group error: ", zap.Error(err))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return context.JSON(http.StatusNotFound, "Group not found")
+	case errors.Is(err, interfaces.ErrNotOrganization):
+		return context.JSON(http.StatusNotFound, err.Error())
+	case errors.Is(err, interfaces.ErrInvalidRole):
+		return context.JSON(http.StatusBadRequest, err.Error())
+	case errors.Is(err, interfaces.ErrGroupCycle):
+		return context.JSON(http.StatusConflict, err.Error())
+	}
+	return context.JSON(http.StatusInternalServerError, "Failed to process group request")
+}
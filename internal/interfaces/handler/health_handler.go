@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/db"
+)
+
+type HealthHandler struct {
+	databases *db.ReplicaSet
+}
+
+func NewHealthHandler(databases *db.ReplicaSet) *HealthHandler {
+	return &HealthHandler{databases}
+}
+
+// DatabaseHealth godoc
+// @Summary Database health
+// @Description Report the health, as of the last check, and the connection pool statistics of the primary database and its read replicas. Answers 503 while the primary cannot be reached.
+// @Tags health
+// @Produce  json
+// @Success 200 {array} db.PoolStats
+// @Failure 503 {array} db.PoolStats
+// @Router /health/db [get]
+func (handler *HealthHandler) DatabaseHealth(context echo.Context) error {
+	stats := handler.databases.Stats()
+	status := http.StatusOK
+	if !stats[0].Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	return context.JSON(status, stats)
+}
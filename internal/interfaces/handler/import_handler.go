@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/importer"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// importFormats maps request content types to import formats
+var importFormats = map[string]string{
+	"text/csv":             interfaces.ImportFormatCSV,
+	"application/x-ndjson": interfaces.ImportFormatNDJSON,
+	"application/ndjson":   interfaces.ImportFormatNDJSON,
+}
+
+type ImportHandler struct {
+	service interfaces.ImportService
+}
+
+func NewImportHandler(service interfaces.ImportService) *ImportHandler {
+	return &ImportHandler{service}
+}
+
+// ImportUsers godoc
+// @Summary Bulk import users
+// @Description Import users from a CSV file with a header row or from NDJSON. Rows without a password can be invited instead. Only owners can import owners. With report=csv the response is a CSV of the rows that were not imported.
+// @Tags users
+// @Accept  text/csv
+// @Accept  application/x-ndjson
+// @Produce  json
+// @Produce  text/csv
+// @Param format query string false "csv or ndjson; defaults to the Content-Type"
+// @Param mode query string false "transactional (default) or best_effort"
+// @Param dry_run query bool false "Validate without creating anything"
+// @Param invite query bool false "Invite rows that have no password"
+// @Param map query []string false "Column mapping as source=field, e.g. mail=email" collectionFormat(multi)
+// @Param report query string false "csv to download the per-row error report"
+// @Success 200 {object} interfaces.ImportReport
+// @Failure 422 {object} interfaces.ImportReport
+// @Router /v1/users/import [post]
+func (handler *ImportHandler) ImportUsers(context echo.Context) error {
+	options, err := importOptions(context)
+	if err != nil {
+		logger.Error("Invalid import options: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	report, err := handler.service.Import(context.Request().Context(), tenant.FromContext(context), context.Request().Body, options)
+	if err != nil {
+		logger.Error("Error importing users: ", zap.Error(err))
+		if errors.Is(err, interfaces.ErrImportFormat) || errors.Is(err, interfaces.ErrImportMode) ||
+			errors.Is(err, interfaces.ErrImportMapping) {
+			return context.JSON(http.StatusBadRequest, err.Error())
+		}
+		return context.JSON(http.StatusUnprocessableEntity, err.Error())
+	}
+	logger.Info("Users imported",
+		zap.Int("total", report.Total),
+		zap.Int("created", report.Created),
+		zap.Int("invited", report.Invited),
+		zap.Int("failed", report.Failed),
+		zap.Bool("dryRun", report.DryRun),
+	)
+
+	// A transactional import that wrote nothing because of invalid rows is unprocessable
+	status := http.StatusOK
+	if report.Mode == interfaces.ImportModeTransactional && report.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	if context.QueryParam("report") == interfaces.ImportFormatCSV {
+		var output bytes.Buffer
+		if err := importer.WriteErrorReport(&output, report); err != nil {
+			logger.Error("Error writing import report: ", zap.Error(err))
+			return context.JSON(http.StatusInternalServerError, "Failed to write import report")
+		}
+		context.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="import-errors.csv"`)
+		return context.Blob(status, "text/csv", output.Bytes())
+	}
+	return context.JSON(status, report)
+}
+
+func importOptions(context echo.Context) (interfaces.ImportOptions, error) {
+	options := interfaces.ImportOptions{
+		Format: context.QueryParam("format"),
+		Mode:   context.QueryParam("mode"),
+	}
+	if options.Format == "" {
+		mediaType, _, _ := mime.ParseMediaType(context.Request().Header.Get(echo.HeaderContentType))
+		options.Format = importFormats[mediaType]
+	}
+
+	var err error
+	for name, target := range map[string]*bool{"dry_run": &options.DryRun, "invite": &options.InviteWithoutPassword} {
+		if value := context.QueryParam(name); value != "" {
+			if *target, err = strconv.ParseBool(value); err != nil {
+				return options, err
+			}
+		}
+	}
+	if options.Mapping, err = importer.ParseMapping(context.QueryParams()["map"]); err != nil {
+		return options, err
+	}
+	if claims, ok := authentication.ClaimsFromContext(context); ok {
+		options.InvitedBy = claims.UserID
+	}
+	return options, nil
+}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type InvitationHandler struct {
+	service interfaces.InvitationService
+}
+
+func NewInvitationHandler(service interfaces.InvitationService) *InvitationHandler {
+	return &InvitationHandler{service}
+}
+
+// GetInvitations godoc
+// @Summary List pending invitations
+// @Description List pending invitations of the current organization
+// @Tags invitations
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} interfaces.Invitation
+// @Router /v1/invitations [get]
+func (handler *InvitationHandler) GetInvitations(context echo.Context) error {
+	invitations, err := handler.service.GetPendingInvitations(context.Request().Context(), tenant.FromContext(context))
+	if err != nil {
+		logger.Error("Error retrieving invitations: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	return context.JSON(http.StatusOK, invitations)
+}
+
+// CreateInvitation godoc
+// @Summary Invite a user
+// @Description Invite an email address into the current organization with a role
+// @Tags invitations
+// @Accept  json
+// @Produce  json
+// @Param invitation body interfaces.InvitationRequest true "Invitation"
+// @Success 201 {object} interfaces.Invitation
+// @Router /v1/invitations [post]
+func (handler *InvitationHandler) CreateInvitation(context echo.Context) error {
+	claims, ok := authentication.ClaimsFromContext(context)
+	if !ok {
+		return context.JSON(http.StatusUnauthorized, "missing or malformed jwt")
+	}
+
+	var request interfaces.InvitationRequest
+	if err := context.Bind(&request); err != nil || request.Email == "" {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	invitation, err := handler.service.Invite(context.Request().Context(), interfaces.Invitation{
+		OrganizationID: tenant.FromContext(context),
+		Email:          request.Email,
+		Role:           request.Role,
+		InvitedBy:      &claims.UserID,
+	})
+	if err != nil {
+		return invitationError(context, err)
+	}
+	logger.Info("Invitation created", zap.Int("invitationID", invitation.ID), zap.String("email", invitation.Email))
+	return context.JSON(http.StatusCreated, invitation)
+}
+
+// ResendInvitation godoc
+// @Summary Resend an invitation
+// @Description Issue a fresh token for a pending invitation, invalidating earlier ones
+// @Tags invitations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} interfaces.Invitation
+// @Router /v1/invitations/{id}/resend [post]
+func (handler *InvitationHandler) ResendInvitation(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid invitation ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	invitation, err := handler.service.Resend(context.Request().Context(), tenant.FromContext(context), id)
+	if err != nil {
+		return invitationError(context, err)
+	}
+	logger.Info("Invitation resent", zap.Int("invitationID", invitation.ID))
+	return context.JSON(http.StatusOK, invitation)
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke an invitation
+// @Description Revoke a pending invitation
+// @Tags invitations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} interfaces.Invitation
+// @Router /v1/invitations/{id} [delete]
+func (handler *InvitationHandler) RevokeInvitation(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid invitation ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	invitation, err := handler.service.Revoke(context.Request().Context(), tenant.FromContext(context), id)
+	if err != nil {
+		return invitationError(context, err)
+	}
+	logger.Info("Invitation revoked", zap.Int("invitationID", invitation.ID))
+	return context.JSON(http.StatusOK, invitation)
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Accept an invitation token and create the account with the invitee's own password
+// @Tags invitations
+// @Accept  json
+// @Produce  json
+// @Param request body interfaces.AcceptInvitationRequest true "Acceptance"
+// @Success 201 {object} interfaces.User
+// @Router /users/invitations/accept [post]
+func (handler *InvitationHandler) AcceptInvitation(context echo.Context) error {
+	var request interfaces.AcceptInvitationRequest
+	if err := context.Bind(&request); err != nil ||
+		request.Token == "" || request.Username == "" || request.Password == "" {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	user, err := handler.service.Accept(context.Request().Context(), request)
+	if err != nil {
+		return invitationError(context, err)
+	}
+	logger.Info("Invitation accepted", zap.Int("userID", user.ID), zap.Int("organizationID", user.OrganizationID))
+	return context.JSON(http.StatusCreated, user)
+}
+
+func invitationError(context echo.Context, err error) error {
+	logger.Error("Invitation error: ", zap.Error(err))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return context.JSON(http.StatusNotFound, "Invitation not found")
+	case errors.Is(err, interfaces.ErrInvalidRole):
+		return context.JSON(http.StatusBadRequest, err.Error())
+	case errors.Is(err, interfaces.ErrOwnerRequired):
+		return context.JSON(http.StatusForbidden, err.Error())
+	case errors.Is(err, interfaces.ErrInvitationToken), errors.Is(err, interfaces.ErrInvitationExpired):
+		return context.JSON(http.StatusUnauthorized, err.Error())
+	case errors.Is(err, interfaces.ErrInvitationNotPending), errors.Is(err, interfaces.ErrAttributeConflict):
+		return context.JSON(http.StatusConflict, err.Error())
+	case isUserConflict(err):
+		return userConflictError(context, err)
+	case errors.Is(err, interfaces.ErrInvalidUser):
+		return context.JSON(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, interfaces.ErrInvalidAttribute):
+		return context.JSON(http.StatusUnprocessableEntity, err.Error())
+	}
+	return context.JSON(http.StatusInternalServerError, "Failed to process invitation")
+}
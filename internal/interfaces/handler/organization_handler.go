@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type OrganizationHandler struct {
+	service interfaces.OrganizationService
+}
+
+func NewOrganizationHandler(service interfaces.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{service}
+}
+
+// GetOrganizations godoc
+// @Summary List the caller's organizations
+// @Description List the organizations the authenticated user is a member of
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} interfaces.Organization
+// @Router /v1/organizations [get]
+func (handler *OrganizationHandler) GetOrganizations(context echo.Context) error {
+	claims, ok := authentication.ClaimsFromContext(context)
+	if !ok {
+		return context.JSON(http.StatusUnauthorized, "missing or malformed jwt")
+	}
+	organizations, err := handler.service.GetOrganizationsForUser(context.Request().Context(), claims.UserID)
+	if err != nil {
+		logger.Error("Error retrieving organizations: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	return context.JSON(http.StatusOK, organizations)
+}
+
+// GetOrganization godoc
+// @Summary Get an organization by ID
+// @Description Get an organization the authenticated user is a member of
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Organization ID"
+// @Success 200 {object} interfaces.Organization
+// @Router /v1/organizations/{id} [get]
+func (handler *OrganizationHandler) GetOrganization(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid organization ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	if _, ok, err := handler.authorize(context, id, false); !ok {
+		return err
+	}
+	organization, err := handler.service.GetOrganization(context.Request().Context(), id)
+	if err != nil {
+		logger.Error("Error retrieving organization: ", zap.Error(err))
+		return context.JSON(http.StatusNotFound, "Organization not found")
+	}
+	return context.JSON(http.StatusOK, organization)
+}
+
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Create an organization owned by the authenticated user
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Param organization body interfaces.OrganizationRequest true "Create Organization"
+// @Success 201 {object} interfaces.Organization
+// @Router /v1/organizations [post]
+func (handler *OrganizationHandler) CreateOrganization(context echo.Context) error {
+	claims, ok := authentication.ClaimsFromContext(context)
+	if !ok {
+		return context.JSON(http.StatusUnauthorized, "missing or malformed jwt")
+	}
+
+	var request interfaces.OrganizationRequest
+	if err := context.Bind(&request); err != nil || request.Name == "" || request.Slug == "" {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	organization, err := handler.service.CreateOrganization(
+		context.Request().Context(),
+		interfaces.Organization{Name: request.Name, Slug: request.Slug},
+		claims.UserID,
+	)
+	if err != nil {
+		logger.Error("Error creating organization: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, "Failed to create organization")
+	}
+	logger.Info("Organization created", zap.Int("organizationID", organization.ID), zap.String("slug", organization.Slug))
+	return context.JSON(http.StatusCreated, organization)
+}
+
+// GetMembers godoc
+// @Summary List organization members
+// @Description List the members of an organization and their roles
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Organization ID"
+// @Success 200 {array} interfaces.Membership
+// @Router /v1/organizations/{id}/members [get]
+func (handler *OrganizationHandler) GetMembers(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid organization ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	if _, ok, err := handler.authorize(context, id, false); !ok {
+		return err
+	}
+	members, err := handler.service.GetMembers(context.Request().Context(), id)
+	if err != nil {
+		logger.Error("Error retrieving members: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	return context.JSON(http.StatusOK, members)
+}
+
+// AddMember godoc
+// @Summary Add an organization member
+// @Description Add a user to an organization with a role (owner or admin only; only owners add owners)
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Organization ID"
+// @Param membership body interfaces.MembershipRequest true "Membership"
+// @Success 201 {object} interfaces.Membership
+// @Router /v1/organizations/{id}/members [post]
+func (handler *OrganizationHandler) AddMember(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid organization ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	actor, ok, err := handler.authorize(context, id, true)
+	if !ok {
+		return err
+	}
+
+	var request interfaces.MembershipRequest
+	if err := context.Bind(&request); err != nil || request.UserID == 0 {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	membership, err := handler.service.AddMember(context.Request().Context(), actor, interfaces.Membership{
+		OrganizationID: id,
+		UserID:         request.UserID,
+		Role:           request.Role,
+	})
+	if err != nil {
+		return handler.membershipError(context, err)
+	}
+	logger.Info("Member added", zap.Int("organizationID", id), zap.Int("userID", membership.UserID))
+	return context.JSON(http.StatusCreated, membership)
+}
+
+// UpdateMember godoc
+// @Summary Change a member's role
+// @Description Change the role of an organization member (owner or admin only; only owners grant or revoke the owner role, and the last owner keeps it)
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Organization ID"
+// @Param user_id path int true "User ID"
+// @Param membership body interfaces.MembershipRequest true "Membership"
+// @Success 200 {object} interfaces.Membership
+// @Router /v1/organizations/{id}/members/{user_id} [patch]
+func (handler *OrganizationHandler) UpdateMember(context echo.Context) error {
+	id, userID, err := membershipParams(context)
+	if err != nil {
+		logger.Error("Invalid membership ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	actor, ok, err := handler.authorize(context, id, true)
+	if !ok {
+		return err
+	}
+
+	var request interfaces.MembershipRequest
+	if err := context.Bind(&request); err != nil {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	membership, err := handler.service.UpdateMemberRole(context.Request().Context(), actor, interfaces.Membership{
+		OrganizationID: id,
+		UserID:         userID,
+		Role:           request.Role,
+	})
+	if err != nil {
+		return handler.membershipError(context, err)
+	}
+	logger.Info("Member role updated", zap.Int("organizationID", id), zap.Int("userID", userID))
+	return context.JSON(http.StatusOK, membership)
+}
+
+// RemoveMember godoc
+// @Summary Remove an organization member
+// @Description Remove a user from an organization (owner or admin only; only owners remove owners, and never the last one)
+// @Tags organizations
+// @Accept  json
+// @Produce  json
+// @Param id path int true "Organization ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Router /v1/organizations/{id}/members/{user_id} [delete]
+func (handler *OrganizationHandler) RemoveMember(context echo.Context) error {
+	id, userID, err := membershipParams(context)
+	if err != nil {
+		logger.Error("Invalid membership ID: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	actor, ok, err := handler.authorize(context, id, true)
+	if !ok {
+		return err
+	}
+
+	if err := handler.service.RemoveMember(context.Request().Context(), actor, id, userID); err != nil {
+		return handler.membershipError(context, err)
+	}
+	logger.Info("Member removed", zap.Int("organizationID", id), zap.Int("userID", userID))
+	return context.JSON(http.StatusOK, map[string]string{
+		"message": "member removed successfully",
+	})
+}
+
+// authorize returns the caller's membership of the organization. It writes a 401/403 response
+// and returns false when the caller is not a member, or is not an owner/admin when manage is set.
+func (handler *OrganizationHandler) authorize(
+	context echo.Context,
+	organizationID int,
+	manage bool,
+) (interfaces.Membership, bool, error) {
+	claims, ok := authentication.ClaimsFromContext(context)
+	if !ok {
+		return interfaces.Membership{}, false, context.JSON(http.StatusUnauthorized, "missing or malformed jwt")
+	}
+	membership, err := handler.service.GetMembership(context.Request().Context(), organizationID, claims.UserID)
+	if err != nil {
+		logger.Error("Membership lookup failed: ", zap.Int("organizationID", organizationID), zap.Error(err))
+		return membership, false, context.JSON(http.StatusForbidden, "Not a member of this organization")
+	}
+	if manage && !membership.CanManage() {
+		return membership, false, context.JSON(http.StatusForbidden, "Insufficient organization role")
+	}
+	return membership, true, nil
+}
+
+func (handler *OrganizationHandler) membershipError(context echo.Context, err error) error {
+	logger.Error("Error changing membership: ", zap.Error(err))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return context.JSON(http.StatusNotFound, "Membership not found")
+	case errors.Is(err, interfaces.ErrInvalidRole):
+		return context.JSON(http.StatusBadRequest, err.Error())
+	case errors.Is(err, interfaces.ErrOwnerRequired):
+		return context.JSON(http.StatusForbidden, err.Error())
+	case errors.Is(err, interfaces.ErrLastOwner):
+		return context.JSON(http.StatusConflict, err.Error())
+	}
+	return context.JSON(http.StatusInternalServerError, "Failed to change membership")
+}
+
+func membershipParams(context echo.Context) (int, int, error) {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		return 0, 0, err
+	}
+	userID, err := strconv.Atoi(context.Param("user_id"))
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, userID, nil
+}
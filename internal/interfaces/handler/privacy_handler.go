@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/exporter"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type PrivacyHandler struct {
+	service interfaces.PrivacyService
+	audit   interfaces.AuditService
+}
+
+func NewPrivacyHandler(service interfaces.PrivacyService, audit interfaces.AuditService) *PrivacyHandler {
+	return &PrivacyHandler{service, audit}
+}
+
+// ExportOwnData godoc
+// @Summary Download your personal data
+// @Description Download a zip archive of JSON documents with everything stored about the authenticated user: profile, memberships, groups, sessions, audit events, revoked tokens and invitations
+// @Tags privacy
+// @Produce  application/zip
+// @Success 200 {file} file
+// @Router /v1/users/current-user/data [get]
+func (handler *PrivacyHandler) ExportOwnData(context echo.Context) error {
+	claims, ok := authentication.ClaimsFromContext(context)
+	if !ok {
+		return context.JSON(http.StatusUnauthorized, "missing or malformed jwt")
+	}
+	return handler.exportData(context, claims.UserID)
+}
+
+// ExportUserData godoc
+// @Summary Download a user's personal data
+// @Description Download the personal data archive of a user of the organization, to answer a subject access request on their behalf
+// @Tags privacy
+// @Produce  application/zip
+// @Param id path int true "User ID"
+// @Success 200 {file} file
+// @Router /v1/users/{id}/data [get]
+func (handler *PrivacyHandler) ExportUserData(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, "Invalid user ID")
+	}
+	return handler.exportData(context, id)
+}
+
+func (handler *PrivacyHandler) exportData(context echo.Context, userID int) error {
+	data, err := handler.service.ExportPersonalData(context.Request().Context(), tenant.FromContext(context), userID)
+	if err != nil {
+		logger.Error("Error exporting personal data: ", zap.Int("userID", userID), zap.Error(err))
+		if errors.Is(err, sql.ErrNoRows) {
+			return context.JSON(http.StatusNotFound, "User not found")
+		}
+		return context.JSON(http.StatusInternalServerError, "Failed to export personal data")
+	}
+
+	var archive bytes.Buffer
+	if err := exporter.WritePersonalDataArchive(&archive, data); err != nil {
+		logger.Error("Error writing personal data archive: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, "Failed to export personal data")
+	}
+	recordAudit(handler.audit, context, interfaces.AuditEvent{
+		Action:     interfaces.AuditUserDataExport,
+		TargetType: interfaces.AuditTargetUser,
+		TargetID:   &userID,
+	})
+
+	context.Response().Header().Set(
+		echo.HeaderContentDisposition,
+		fmt.Sprintf(`attachment; filename="%s"`, exporter.ArchiveName(data)),
+	)
+	return context.Blob(http.StatusOK, exporter.ArchiveContentType, archive.Bytes())
+}
+
+// EraseSelf godoc
+// @Summary Erase your account
+// @Description Immediately anonymize the authenticated user and their records and revoke their sessions. This cannot be undone.
+// @Tags privacy
+// @Accept  json
+// @Produce  json
+// @Param request body interfaces.ErasureRequestInput false "Optional reason"
+// @Success 200 {object} interfaces.ErasureRequest
+// @Router /v1/users/current-user/erasure [post]
+func (handler *PrivacyHandler) EraseSelf(context echo.Context) error {
+	claims, ok := authentication.ClaimsFromContext(context)
+	if !ok {
+		return context.JSON(http.StatusUnauthorized, "missing or malformed jwt")
+	}
+	var input interfaces.ErasureRequestInput
+	if err := context.Bind(&input); err != nil {
+		return context.JSON(http.StatusBadRequest, "Invalid request")
+	}
+
+	request, err := handler.service.EraseNow(
+		context.Request().Context(),
+		tenant.FromContext(context),
+		claims.UserID,
+		input.Reason,
+	)
+	if err != nil {
+		logger.Error("Error erasing user: ", zap.Int("userID", claims.UserID), zap.Error(err))
+		return erasureError(context, err)
+	}
+	return context.JSON(http.StatusOK, request)
+}
+
+// ScheduleErasure godoc
+// @Summary Schedule the erasure of a user
+// @Description Schedule a user of the organization to be anonymized once the grace period is over. The request can be cancelled until then.
+// @Tags privacy
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param request body interfaces.ErasureRequestInput false "Reason"
+// @Success 202 {object} interfaces.ErasureRequest
+// @Router /v1/users/{id}/erasure [post]
+func (handler *PrivacyHandler) ScheduleErasure(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, "Invalid user ID")
+	}
+	claims, ok := authentication.ClaimsFromContext(context)
+	if !ok {
+		return context.JSON(http.StatusUnauthorized, "missing or malformed jwt")
+	}
+	var input interfaces.ErasureRequestInput
+	if err := context.Bind(&input); err != nil {
+		return context.JSON(http.StatusBadRequest, "Invalid request")
+	}
+
+	request, err := handler.service.ScheduleErasure(
+		context.Request().Context(),
+		tenant.FromContext(context),
+		id,
+		claims.UserID,
+		input.Reason,
+	)
+	if err != nil {
+		logger.Error("Error scheduling erasure: ", zap.Int("userID", id), zap.Error(err))
+		return erasureError(context, err)
+	}
+	recordAudit(handler.audit, context, erasureEvent(interfaces.AuditErasureSchedule, request))
+	return context.JSON(http.StatusAccepted, request)
+}
+
+// GetErasureRequests godoc
+// @Summary List erasure requests
+// @Description List the organization's erasure requests, newest first
+// @Tags privacy
+// @Produce  json
+// @Success 200 {array} interfaces.ErasureRequest
+// @Router /v1/erasures [get]
+func (handler *PrivacyHandler) GetErasureRequests(context echo.Context) error {
+	requests, err := handler.service.GetErasureRequests(context.Request().Context(), tenant.FromContext(context))
+	if err != nil {
+		logger.Error("Error retrieving erasure requests: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, "Failed to retrieve erasure requests")
+	}
+	return context.JSON(http.StatusOK, requests)
+}
+
+// CancelErasure godoc
+// @Summary Cancel an erasure request
+// @Description Cancel a pending erasure request before its grace period is over
+// @Tags privacy
+// @Produce  json
+// @Param id path int true "Erasure request ID"
+// @Success 200 {object} interfaces.ErasureRequest
+// @Router /v1/erasures/{id} [delete]
+func (handler *PrivacyHandler) CancelErasure(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, "Invalid erasure request ID")
+	}
+
+	request, err := handler.service.CancelErasure(context.Request().Context(), tenant.FromContext(context), id)
+	if err != nil {
+		logger.Error("Error cancelling erasure: ", zap.Int("requestID", id), zap.Error(err))
+		if errors.Is(err, sql.ErrNoRows) {
+			return context.JSON(http.StatusNotFound, "Erasure request not found")
+		}
+		return erasureError(context, err)
+	}
+	recordAudit(handler.audit, context, erasureEvent(interfaces.AuditErasureCancel, request))
+	return context.JSON(http.StatusOK, request)
+}
+
+// erasureEvent records the request rather than the user's data, which is about to be erased
+func erasureEvent(action string, request interfaces.ErasureRequest) interfaces.AuditEvent {
+	return interfaces.AuditEvent{
+		Action:     action,
+		TargetType: interfaces.AuditTargetUser,
+		TargetID:   &request.UserID,
+		Changes: map[string]interfaces.AuditChange{
+			"erasure_request": {After: request.ID},
+			"scheduled_for":   {After: request.ScheduledFor},
+		},
+	}
+}
+
+func erasureError(context echo.Context, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return context.JSON(http.StatusNotFound, "User not found")
+	case errors.Is(err, interfaces.ErrErasureNotPending):
+		return context.JSON(http.StatusConflict, err.Error())
+	}
+	return context.JSON(http.StatusInternalServerError, "Failed to process erasure")
+}
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strconv"
@@ -12,30 +15,62 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/redbonzai/user-management-api/internal/interfaces"
 	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
 	"github.com/redbonzai/user-management-api/pkg/logger"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserHandler struct {
-	service interfaces.Service
+	service    interfaces.Service
+	audit      interfaces.AuditService
+	attributes interfaces.AttributeService
+	avatars    interfaces.AvatarService
 }
 
-func NewUserHandler(service interfaces.Service) *UserHandler {
-	return &UserHandler{service}
+func NewUserHandler(
+	service interfaces.Service,
+	audit interfaces.AuditService,
+	attributes interfaces.AttributeService,
+	avatars interfaces.AvatarService,
+) *UserHandler {
+	return &UserHandler{service, audit, attributes, avatars}
+}
+
+// userEvent builds an audit event targeting the given user
+func userEvent(action string, user interfaces.User, changes map[string]interfaces.AuditChange) interfaces.AuditEvent {
+	return interfaces.AuditEvent{
+		Action:     action,
+		TargetType: interfaces.AuditTargetUser,
+		TargetID:   &user.ID,
+		Changes:    changes,
+	}
 }
 
 // GetUsers godoc
 // @Summary Get all users
-// @Description Get all users
+// @Description Get all users, optionally filtered. Attributes the caller may not see are left out and cannot be filtered on.
 // @Tags users
 // @Accept  json
 // @Produce  json
+// @Param status query []string false "Statuses, repeated or comma-separated"
+// @Param role query string false "Organization role"
+// @Param q query string false "Search in name, email and username"
+// @Param attr.name query string false "Attribute value, e.g. attr.department=sales"
 // @Success 200 {array} user.User
+// @Failure 400 {string} string "Unknown attribute or invalid value"
 // @Router /v1/users [get]
 func (handler *UserHandler) GetUsers(context echo.Context) error {
-	users, err := handler.service.GetUsers()
-	fmt.Printf("GET USERS : %v+\n", users)
+	organizationID := tenant.FromContext(context)
+	filter, err := handler.listFilter(context)
+	if err != nil {
+		return filterError(context, err)
+	}
+
+	users, err := handler.service.GetUsers(context.Request().Context(), organizationID, filter)
+	if err == nil {
+		users, err = handler.view(context, users...)
+	}
 	if err != nil {
 		logger.Error("Error retrieving users: ", zap.Error(err))
 		return context.JSON(http.StatusInternalServerError, err)
@@ -44,6 +79,63 @@ func (handler *UserHandler) GetUsers(context echo.Context) error {
 	return context.JSON(http.StatusOK, users)
 }
 
+// SearchUsers godoc
+// @Summary Search users
+// @Description Find users by partial or misspelled name, username or email. Every word matches as a prefix, and close misspellings match by trigram similarity; results are ordered by score and carry the matched words highlighted with <mark>. The GET /v1/users filters apply as well.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param q query string true "Search text"
+// @Param limit query int false "Maximum number of results, at most 100" default(20)
+// @Param status query []string false "Statuses, repeated or comma-separated"
+// @Param role query string false "Organization role"
+// @Param attr.name query string false "Attribute value, e.g. attr.department=sales"
+// @Success 200 {array} interfaces.UserSearchResult
+// @Failure 400 {string} string "Missing search text or invalid filter"
+// @Router /v1/users/search [get]
+func (handler *UserHandler) SearchUsers(context echo.Context) error {
+	text := strings.TrimSpace(context.QueryParam("q"))
+	if text == "" {
+		return context.JSON(http.StatusBadRequest, "Missing search text")
+	}
+	limit := 0
+	if raw := context.QueryParam("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			return context.JSON(http.StatusBadRequest, "Invalid limit")
+		}
+		limit = parsed
+	}
+	filter, err := handler.listFilter(context)
+	if err != nil {
+		return filterError(context, err)
+	}
+	// q is the search text here, not the substring filter of GET /v1/users
+	filter.Search = ""
+
+	results, err := handler.service.SearchUsers(context.Request().Context(), tenant.FromContext(context), text, filter, limit)
+	if err != nil {
+		logger.Error("Error searching users: ", zap.Error(err))
+		if errors.Is(err, interfaces.ErrInvalidSearch) {
+			return context.JSON(http.StatusBadRequest, err.Error())
+		}
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+
+	users := make([]interfaces.User, len(results))
+	for i, result := range results {
+		users[i] = result.User
+	}
+	if users, err = handler.view(context, users...); err != nil {
+		logger.Error("Error preparing user response: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	for i := range results {
+		results[i].User = users[i]
+	}
+	return context.JSON(http.StatusOK, results)
+}
+
 // GetUser godoc
 // @Summary Get a user by ID
 // @Description Get a user by ID
@@ -51,7 +143,9 @@ func (handler *UserHandler) GetUsers(context echo.Context) error {
 // @Accept  json
 // @Produce  json
 // @Param id path int true "User ID"
+// @Param If-None-Match header string false "ETag from a previous response"
 // @Success 200 {object} user.User
+// @Success 304
 // @Router /v1/users/{id} [get]
 func (handler *UserHandler) GetUser(context echo.Context) error {
 	id, err := strconv.Atoi(context.Param("id"))
@@ -59,12 +153,18 @@ func (handler *UserHandler) GetUser(context echo.Context) error {
 		logger.Error("Invalid User ID: ", zap.Error(err))
 		return context.JSON(http.StatusBadRequest, "Invalid ID")
 	}
-	retrievedUser, err := handler.service.GetUserByID(id)
+	retrievedUser, err := handler.service.GetUserByID(context.Request().Context(), tenant.FromContext(context), id)
 	if err != nil {
 		logger.Error("Error retrieving user: ", zap.Error(err))
 		return context.JSON(http.StatusNotFound, "User not found")
 	}
-	return context.JSON(http.StatusOK, retrievedUser)
+
+	etag := versionETag(retrievedUser.Version)
+	context.Response().Header().Set(headerETag, etag)
+	if etagMatches(context.Request().Header.Get(headerIfNoneMatch), etag, true) {
+		return context.NoContent(http.StatusNotModified)
+	}
+	return handler.viewJSON(context, http.StatusOK, retrievedUser)
 }
 
 // CreateUser godoc
@@ -82,25 +182,34 @@ func (handler *UserHandler) CreateUser(context echo.Context) error {
 		logger.Error("Invalid input: ", zap.Error(err))
 		return context.JSON(http.StatusBadRequest, "Invalid input")
 	}
-	newUser, err := handler.service.CreateUser(createdUser)
+	createdUser.OrganizationID = tenant.FromContext(context)
+	newUser, err := handler.service.CreateUser(audited(context), createdUser)
 	if err != nil {
 		logger.Error("Error creating user: ", zap.Error(err))
-		return context.JSON(http.StatusInternalServerError, err)
+		return userWriteError(context, err)
 	}
 	logger.Info("User created", zap.Int("userID", newUser.ID), zap.String("name", newUser.Name))
-	return context.JSON(http.StatusCreated, newUser)
+	return handler.viewJSON(context, http.StatusCreated, newUser)
 }
 
 // UpdateUser godoc
 // @Summary Update a user
-// @Description Update a user
+// @Description Partially update a user with a JSON Merge Patch (RFC 7396, also used for plain application/json) or a JSON Patch (RFC 6902). Fields set to null or removed are cleared; id, organization_id, version and deleted_at are immutable. Attributes the caller may not see cannot be patched and keep their values.
 // @Tags users
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
 // @Accept  json
 // @Produce  json
 // @Param id path int true "User ID"
-// @Param user body user.User true "Update User"
-// @Success 200
-// @Router /v1/users/{id} [put]
+// @Param If-Match header string true "ETag of the version being updated"
+// @Param patch body object true "Merge patch or JSON Patch operations"
+// @Success 200 {object} interfaces.User
+// @Failure 409 {string} string "A JSON Patch test operation failed"
+// @Failure 412 {string} string "User was modified"
+// @Failure 415 {string} string "Unsupported patch format"
+// @Failure 422 {string} string "The patched user is invalid or changes an attribute the caller may not see"
+// @Failure 428 {string} string "If-Match header is required"
+// @Router /v1/users/{id} [patch]
 func (handler *UserHandler) UpdateUser(context echo.Context) error {
 	id, err := strconv.Atoi(context.Param("id"))
 	if err != nil {
@@ -108,31 +217,32 @@ func (handler *UserHandler) UpdateUser(context echo.Context) error {
 		return context.JSON(http.StatusBadRequest, "Invalid ID")
 	}
 
-	var updatedUser interfaces.User
-	if err := context.Bind(&updatedUser); err != nil {
+	body, err := io.ReadAll(context.Request().Body)
+	if err != nil {
 		logger.Error("Invalid input: ", zap.Error(err))
 		return context.JSON(http.StatusBadRequest, "Invalid input")
 	}
 
-	// Fetch the existing user to compare changes
-	existingUser, err := handler.service.GetUserByID(id)
+	// Fetch the existing user to apply the patch to
+	organizationID := tenant.FromContext(context)
+	existingUser, err := handler.service.GetUserByID(context.Request().Context(), organizationID, id)
 	if err != nil {
 		logger.Error("User not found: ", zap.Error(err))
 		return context.JSON(http.StatusNotFound, "User not found")
 	}
-
-	// Update only the fields provided in the request
-	if updatedUser.Name == "" {
-		updatedUser.Name = existingUser.Name
+	if ok, err := checkIfMatch(context, existingUser.Version); !ok {
+		return err
 	}
-	if updatedUser.Email == "" {
-		updatedUser.Email = existingUser.Email
-	}
-	if updatedUser.Status == nil {
-		updatedUser.Status = existingUser.Status
+
+	hidden, err := handler.attributes.HiddenAttributes(context.Request().Context(), organizationID, callerID(context))
+	if err != nil {
+		logger.Error("Error reading attribute definitions: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, "Failed to patch user")
 	}
-	if updatedUser.Username == "" {
-		updatedUser.Username = existingUser.Username
+	updatedUser, err := patchUser(existingUser, hidden, context.Request().Header.Get(echo.HeaderContentType), body)
+	if err != nil {
+		logger.Error("Error patching user: ", zap.Error(err))
+		return userPatchError(context, err)
 	}
 	if updatedUser.Password != "" {
 		// Hash the new password
@@ -146,24 +256,27 @@ func (handler *UserHandler) UpdateUser(context echo.Context) error {
 		updatedUser.Password = existingUser.Password
 	}
 
-	updatedUser.ID = id
-	updated, err := handler.service.UpdateUser(updatedUser)
+	updated, err := handler.service.UpdateUser(audited(context), updatedUser)
 	if err != nil {
 		logger.Error("Error updating user: ", zap.Error(err))
-		return context.JSON(http.StatusInternalServerError, err)
+		return userWriteError(context, err)
 	}
-	logger.Info("User updated", zap.Int("userID", updatedUser.ID), zap.String("name", updatedUser.Name))
-	return context.JSON(http.StatusOK, updated)
+	context.Response().Header().Set(headerETag, versionETag(updated.Version))
+	logger.Info("User updated", zap.Int("userID", updated.ID), zap.String("name", updated.Name))
+	return handler.viewJSON(context, http.StatusOK, updated)
 }
 
 // DeleteUser godoc
 // @Summary Delete a user
-// @Description Delete a user
+// @Description Soft-delete a user and revoke their sessions. The user can be restored until the retention period ends.
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Param id path int true "User ID"
-// @Success 204
+// @Param If-Match header string true "ETag of the version being deleted"
+// @Success 200 {object} interfaces.User
+// @Failure 412 {string} string "User was modified"
+// @Failure 428 {string} string "If-Match header is required"
 // @Router /v1/users/{id} [delete]
 func (handler *UserHandler) DeleteUser(context echo.Context) error {
 	id, err := strconv.Atoi(context.Param("id"))
@@ -171,15 +284,74 @@ func (handler *UserHandler) DeleteUser(context echo.Context) error {
 		logger.Error("Invalid user ID", zap.Error(err))
 		return context.JSON(http.StatusBadRequest, "Invalid ID")
 	}
-	deletedUser, err := handler.service.DeleteUser(id)
+	organizationID := tenant.FromContext(context)
+	existingUser, err := handler.service.GetUserByID(context.Request().Context(), organizationID, id)
 	if err != nil {
 		logger.Error("Error deleting user", zap.Int("userID", id), zap.Error(err))
-		return context.JSON(http.StatusInternalServerError, err)
+		return userWriteError(context, err)
+	}
+	if ok, err := checkIfMatch(context, existingUser.Version); !ok {
+		return err
+	}
+
+	deletedUser, err := handler.service.DeleteUser(audited(context), organizationID, id, existingUser.Version)
+	if err != nil {
+		logger.Error("Error deleting user", zap.Int("userID", id), zap.Error(err))
+		return userWriteError(context, err)
 	}
 	logger.Info("User deleted", zap.Int("userID", deletedUser.ID), zap.String("name", deletedUser.Name))
 	return context.JSON(http.StatusOK, deletedUser)
 }
 
+// GetDeletedUsers godoc
+// @Summary List deleted users
+// @Description List soft-deleted users of the current organization that have not been purged yet
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} interfaces.User
+// @Router /v1/users/deleted [get]
+func (handler *UserHandler) GetDeletedUsers(context echo.Context) error {
+	users, err := handler.service.GetDeletedUsers(context.Request().Context(), tenant.FromContext(context))
+	if err != nil {
+		logger.Error("Error retrieving deleted users: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	return context.JSON(http.StatusOK, users)
+}
+
+// RestoreUser godoc
+// @Summary Restore a deleted user
+// @Description Restore a soft-deleted user. Sessions revoked by the deletion stay revoked.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} interfaces.User
+// @Router /v1/users/{id}/restore [post]
+func (handler *UserHandler) RestoreUser(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid user ID", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	restoredUser, err := handler.service.RestoreUser(audited(context), tenant.FromContext(context), id)
+	if err != nil {
+		logger.Error("Error restoring user", zap.Int("userID", id), zap.Error(err))
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return context.JSON(http.StatusNotFound, "Deleted user not found")
+		case errors.Is(err, interfaces.ErrAttributeConflict):
+			return context.JSON(http.StatusConflict, err.Error())
+		case isUserConflict(err):
+			return userConflictError(context, err)
+		}
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	logger.Info("User restored", zap.Int("userID", restoredUser.ID))
+	return context.JSON(http.StatusOK, restoredUser)
+}
+
 // Login godoc
 // @Summary Login a user
 // @Description Login a user and return a JWT token
@@ -197,7 +369,7 @@ func (handler *UserHandler) Login(context echo.Context) error {
 		return context.JSON(http.StatusBadRequest, "Invalid input")
 	}
 
-	user, err := handler.service.GetUserByUsername(loginRequest.Username)
+	user, err := handler.service.GetUserByUsername(context.Request().Context(), tenant.FromContext(context), loginRequest.Username)
 	logger.Info("User retrieved", zap.Any("user", user))
 
 	if err != nil || user.Password == "" || user.Username == "" {
@@ -207,19 +379,39 @@ func (handler *UserHandler) Login(context echo.Context) error {
 			zap.String("username", loginRequest.Username),
 			zap.String("password", loginRequest.Password),
 		)
+		recordAudit(handler.audit, context, interfaces.AuditEvent{
+			ActorUsername: loginRequest.Username,
+			Action:        interfaces.AuditAuthLoginFailed,
+		})
 		return context.JSON(http.StatusUnauthorized, "Invalid username or password")
 	}
 
 	// Compare the hashed password with the login password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(loginRequest.Password)); err != nil {
+		failed := userEvent(interfaces.AuditAuthLoginFailed, user, nil)
+		failed.ActorUsername = loginRequest.Username
+		recordAudit(handler.audit, context, failed)
 		return context.JSON(http.StatusUnauthorized, "Invalid password")
 	}
+	if !interfaces.CanAuthenticate(user) {
+		failed := userEvent(interfaces.AuditAuthLoginFailed, user, nil)
+		failed.ActorID, failed.ActorUsername = &user.ID, user.Username
+		recordAudit(handler.audit, context, failed)
+		return context.JSON(http.StatusForbidden, interfaces.ErrUserInactive.Error())
+	}
 
-	token, err := authentication.GenerateToken(user.Username)
+	token, err := authentication.GenerateToken(user)
 	if err != nil {
 		return context.JSON(http.StatusInternalServerError, "Failed to generate token")
 	}
 
+	if err := handler.service.RecordLogin(context.Request().Context(), user); err != nil {
+		logger.Error("Error recording login event: ", zap.Int("userID", user.ID), zap.Error(err))
+	}
+	login := userEvent(interfaces.AuditAuthLogin, user, nil)
+	login.ActorID, login.ActorUsername = &user.ID, user.Username
+	recordAudit(handler.audit, context, login)
+
 	return context.JSON(http.StatusOK, map[string]string{
 		"token": token,
 	})
@@ -259,11 +451,17 @@ func (handler *UserHandler) Logout(context echo.Context) error {
 
 	// Blacklist the token
 	expiry := time.Unix(claims.ExpiresAt, 0)
-	err = handler.service.Logout(tokenStr, expiry)
+	err = handler.service.Logout(context.Request().Context(), claims.UserID, tokenStr, expiry)
 	if err != nil {
 		return context.JSON(http.StatusInternalServerError, "Failed to logout")
 	}
 
+	recordAudit(handler.audit, context, interfaces.AuditEvent{
+		ActorID:       &claims.UserID,
+		ActorUsername: claims.Username,
+		Action:        interfaces.AuditAuthLogout,
+	})
+
 	return context.JSON(http.StatusOK, map[string]string{
 		"message": "logged out successfully",
 	})
@@ -271,24 +469,27 @@ func (handler *UserHandler) Logout(context echo.Context) error {
 
 // Register godoc
 // @Summary Register a new user
-// @Description Register a new user
+// @Description Register a new user in the default organization. Joining any other organization takes an invitation.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param user body user.User true "User"
 // @Success 201 {object} user.User
+// @Failure 403 {string} string "Joining this organization requires an invitation"
 // @Router /register [post]
 func (handler *UserHandler) Register(context echo.Context) error {
+	// The request is unauthenticated, so it cannot choose its organization
+	if header := context.Request().Header.Get(tenant.HeaderOrganizationID); header != "" &&
+		header != strconv.Itoa(tenant.DefaultOrganizationID) {
+		return context.JSON(http.StatusForbidden, "Joining this organization requires an invitation")
+	}
+
 	var registerRequest interfaces.RegisterRequest
 	if err := context.Bind(&registerRequest); err != nil {
 		return context.JSON(http.StatusBadRequest, "Invalid input")
 	}
 
-	// Check for unique username
-	isUnique, err := handler.service.IsUsernameUnique(registerRequest.Username)
-	if err != nil || !isUnique {
-		return context.JSON(http.StatusConflict, "Username already exists")
-	}
+	organizationID := tenant.DefaultOrganizationID
 
 	// Hash the password using the userRepository method
 	hashedPassword, err := handler.service.HashPassword(registerRequest.Password)
@@ -301,16 +502,20 @@ func (handler *UserHandler) Register(context echo.Context) error {
 
 	// Create the user
 	newUser := interfaces.User{
-		Name:     registerRequest.Name,
-		Email:    registerRequest.Email,
-		Username: registerRequest.Username,
-		Password: hashedPassword,
-		Status:   registerRequest.Status,
+		OrganizationID: organizationID,
+		Name:           registerRequest.Name,
+		Email:          registerRequest.Email,
+		Username:       registerRequest.Username,
+		Password:       hashedPassword,
+		Attributes:     registerRequest.Attributes,
 	}
-	//*newUser.Status = "active"
 
-	createdUser, err := handler.service.CreateUser(newUser)
+	createdUser, err := handler.service.CreateUser(audited(context), newUser)
 	if err != nil {
+		if errors.Is(err, interfaces.ErrInvalidAttribute) || errors.Is(err, interfaces.ErrAttributeConflict) ||
+			errors.Is(err, interfaces.ErrInvalidUser) || isUserConflict(err) {
+			return userWriteError(context, err)
+		}
 		return context.JSON(http.StatusInternalServerError, "Failed to create user")
 	}
 
@@ -326,20 +531,106 @@ func (handler *UserHandler) Register(context echo.Context) error {
 // @Success 200 {object} user.User
 // @Router /v1/current-user [get]
 func (handler *UserHandler) GetAuthenticatedUser(context echo.Context) error {
-	authHeader := context.Request().Header.Get("Authorization")
-	fmt.Printf("AUTH HEADER: %v\n", authHeader)
-
-	if authHeader == "" {
+	// JWTMiddleware already checked the token and its session
+	claims, ok := authentication.ClaimsFromContext(context)
+	if !ok {
 		return context.JSON(http.StatusUnauthorized, "missing or malformed jwt")
 	}
-	tokenStr := strings.Split(authHeader, " ")[1]
-
-	username, err := authentication.ParseToken(tokenStr)
 
-	user, err := handler.service.GetUserByUsername(username)
+	user, err := handler.service.GetUserByUsername(context.Request().Context(), claims.OrganizationID, claims.Username)
 	if err != nil {
 		return context.JSON(http.StatusNotFound, map[string]string{"message": "User not found"})
 	}
 
-	return context.JSON(http.StatusOK, user)
+	return handler.viewJSON(context, http.StatusOK, user)
+}
+
+// listFilter reads the GET /v1/users filters, with attribute filters limited to those the caller may see
+func (handler *UserHandler) listFilter(context echo.Context) (interfaces.UserFilter, error) {
+	filter := userFilter(context)
+	attributes, err := handler.attributes.ParseFilter(
+		context.Request().Context(),
+		tenant.FromContext(context),
+		callerID(context),
+		attributeQuery(context),
+	)
+	filter.Attributes = attributes
+	return filter, err
+}
+
+func filterError(context echo.Context, err error) error {
+	logger.Error("Invalid attribute filter: ", zap.Error(err))
+	if errors.Is(err, interfaces.ErrInvalidAttribute) {
+		return context.JSON(http.StatusBadRequest, err.Error())
+	}
+	return context.JSON(http.StatusInternalServerError, err)
+}
+
+// view removes the attributes the authenticated user may not see and attaches avatar URLs
+func (handler *UserHandler) view(context echo.Context, users ...interfaces.User) ([]interfaces.User, error) {
+	organizationID := tenant.FromContext(context)
+	viewed, err := handler.attributes.View(context.Request().Context(), organizationID, callerID(context), users)
+	if err != nil {
+		return nil, err
+	}
+	return handler.avatars.Attach(context.Request().Context(), organizationID, viewed)
+}
+
+// viewJSON responds with a single user as the authenticated user may see it
+func (handler *UserHandler) viewJSON(context echo.Context, code int, user interfaces.User) error {
+	viewed, err := handler.view(context, user)
+	if err != nil {
+		logger.Error("Error preparing user response: ", zap.Error(err))
+		return context.JSON(http.StatusInternalServerError, err)
+	}
+	return context.JSON(code, viewed[0])
+}
+
+// callerID returns the authenticated user's ID, or 0 when the request carries no claims
+func callerID(context echo.Context) int {
+	if claims, ok := authentication.ClaimsFromContext(context); ok {
+		return claims.UserID
+	}
+	return 0
+}
+
+// attributeQuery collects the attr.<name> query parameters by attribute name
+func attributeQuery(context echo.Context) map[string]string {
+	values := map[string]string{}
+	for key, value := range context.QueryParams() {
+		if name, ok := strings.CutPrefix(key, interfaces.AttributeFilterPrefix); ok && len(value) > 0 {
+			values[name] = value[0]
+		}
+	}
+	return values
+}
+
+// userWriteError maps errors from versioned user writes to responses
+func userWriteError(context echo.Context, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return context.JSON(http.StatusNotFound, "User not found")
+	case errors.Is(err, interfaces.ErrVersionConflict):
+		return context.JSON(http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, interfaces.ErrInvalidUser), errors.Is(err, interfaces.ErrInvalidAttribute):
+		return context.JSON(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, interfaces.ErrAttributeConflict):
+		return context.JSON(http.StatusConflict, err.Error())
+	case isUserConflict(err):
+		return userConflictError(context, err)
+	}
+	return context.JSON(http.StatusInternalServerError, err)
+}
+
+func isUserConflict(err error) bool {
+	return errors.Is(err, interfaces.ErrUserConflict) || interfaces.ConflictField(err) != ""
+}
+
+// userConflictError answers 409 naming the field, username or email, that is already taken
+func userConflictError(context echo.Context, err error) error {
+	body := map[string]string{"error": err.Error()}
+	if field := interfaces.ConflictField(err); field != "" {
+		body["field"] = field
+	}
+	return context.JSON(http.StatusConflict, body)
 }
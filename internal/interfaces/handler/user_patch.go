@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/patch"
+)
+
+var errUnsupportedPatch = errors.New("unsupported patch media type")
+
+// patchUser applies a merge patch or JSON Patch to the user and validates the result. The
+// stored password hash is never exposed to the patch: the document carries an empty password,
+// and a non-empty password in the result is a new plain-text password to hash. Attributes the
+// caller may not see are left out of the document, cannot be patched and keep their values.
+func patchUser(
+	existing interfaces.User,
+	hidden map[string]bool,
+	contentType string,
+	body []byte,
+) (interfaces.User, error) {
+	document := existing
+	document.Password = ""
+	document.Attributes = make(map[string]interface{}, len(existing.Attributes))
+	for name, value := range existing.Attributes {
+		if !hidden[name] {
+			document.Attributes[name] = value
+		}
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return existing, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case patch.MediaTypeMergePatch, echo.MIMEApplicationJSON:
+		if err = checkMergePatch(hidden, body); err == nil {
+			encoded, err = patch.MergePatch(encoded, body)
+		}
+	case patch.MediaTypeJSONPatch:
+		if err = checkJSONPatch(hidden, body); err == nil {
+			encoded, err = patch.ApplyJSONPatch(encoded, body)
+		}
+	default:
+		return existing, fmt.Errorf("%w: %q", errUnsupportedPatch, mediaType)
+	}
+	if err != nil {
+		return existing, err
+	}
+
+	var patched interfaces.User
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return existing, fmt.Errorf("%w: %v", interfaces.ErrInvalidUser, err)
+	}
+
+	immutable := []struct {
+		field   string
+		changed bool
+	}{
+		{"id", patched.ID != existing.ID},
+		{"organization_id", patched.OrganizationID != existing.OrganizationID},
+		{"version", patched.Version != existing.Version},
+		{"deleted_at", (patched.DeletedAt == nil) != (existing.DeletedAt == nil)},
+		// Status changes go through the lifecycle endpoints, which check the transition
+		{"status", interfaces.StatusOf(patched) != interfaces.StatusOf(existing)},
+	}
+	for _, check := range immutable {
+		if check.changed {
+			return existing, fmt.Errorf("%w: %s is immutable", interfaces.ErrInvalidUser, check.field)
+		}
+	}
+
+	for name := range patched.Attributes {
+		if hidden[name] {
+			return existing, hiddenAttributeError(name)
+		}
+	}
+	for name := range hidden {
+		if value, ok := existing.Attributes[name]; ok {
+			if patched.Attributes == nil {
+				patched.Attributes = make(map[string]interface{})
+			}
+			patched.Attributes[name] = value
+		}
+	}
+	return patched, patched.Validate()
+}
+
+// checkMergePatch rejects a merge patch that sets or removes a hidden attribute
+func checkMergePatch(hidden map[string]bool, body []byte) error {
+	var changes struct {
+		Attributes json.RawMessage `json:"attributes"`
+	}
+	var attributes map[string]json.RawMessage
+	// A patch or attributes member that is not an object replaces the document or the attributes
+	// as a whole, which is checked once the patch is applied
+	if json.Unmarshal(body, &changes) != nil || json.Unmarshal(changes.Attributes, &attributes) != nil {
+		return nil
+	}
+	for name := range attributes {
+		if hidden[name] {
+			return hiddenAttributeError(name)
+		}
+	}
+	return nil
+}
+
+// checkJSONPatch rejects a JSON Patch with an operation on a hidden attribute, including tests,
+// which could otherwise probe its value
+func checkJSONPatch(hidden map[string]bool, body []byte) error {
+	paths, err := patch.Paths(body)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if len(path) > 1 && path[0] == "attributes" && hidden[path[1]] {
+			return hiddenAttributeError(path[1])
+		}
+	}
+	return nil
+}
+
+// hiddenAttributeError reports a hidden attribute as the attribute service reports undefined ones,
+// so callers cannot tell them apart
+func hiddenAttributeError(name string) error {
+	return fmt.Errorf("%w: %s is not defined", interfaces.ErrInvalidAttribute, name)
+}
+
+func userPatchError(context echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errUnsupportedPatch):
+		return context.JSON(http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, patch.ErrTestFailed):
+		return context.JSON(http.StatusConflict, err.Error())
+	case errors.Is(err, patch.ErrInvalidPatch):
+		return context.JSON(http.StatusBadRequest, err.Error())
+	case errors.Is(err, interfaces.ErrInvalidUser), errors.Is(err, interfaces.ErrInvalidAttribute):
+		return context.JSON(http.StatusUnprocessableEntity, err.Error())
+	}
+	return context.JSON(http.StatusInternalServerError, "Failed to patch user")
+}
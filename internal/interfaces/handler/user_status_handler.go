@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// SuspendUser godoc
+// @Summary Suspend a user
+// @Description Suspend an active or locked user. Their sessions are revoked and they cannot sign in until reactivated.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param request body interfaces.StatusChangeRequest false "Reason for the change"
+// @Success 200 {object} interfaces.User
+// @Failure 409 {string} string "User status changed concurrently"
+// @Failure 422 {string} string "The user cannot be suspended from its current status"
+// @Router /v1/users/{id}/suspend [post]
+func (handler *UserHandler) SuspendUser(context echo.Context) error {
+	return handler.changeStatus(context, interfaces.UserStatusSuspended)
+}
+
+// ReactivateUser godoc
+// @Summary Reactivate a user
+// @Description Return a pending, suspended, locked or deactivated user to the active status
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param request body interfaces.StatusChangeRequest false "Reason for the change"
+// @Success 200 {object} interfaces.User
+// @Failure 409 {string} string "User status changed concurrently"
+// @Failure 422 {string} string "The user is already active"
+// @Router /v1/users/{id}/reactivate [post]
+func (handler *UserHandler) ReactivateUser(context echo.Context) error {
+	return handler.changeStatus(context, interfaces.UserStatusActive)
+}
+
+// LockUser godoc
+// @Summary Lock a user
+// @Description Lock an active user, e.g. after suspicious activity. Their sessions are revoked.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param request body interfaces.StatusChangeRequest false "Reason for the change"
+// @Success 200 {object} interfaces.User
+// @Failure 409 {string} string "User status changed concurrently"
+// @Failure 422 {string} string "Only active users can be locked"
+// @Router /v1/users/{id}/lock [post]
+func (handler *UserHandler) LockUser(context echo.Context) error {
+	return handler.changeStatus(context, interfaces.UserStatusLocked)
+}
+
+// DeactivateUser godoc
+// @Summary Deactivate a user
+// @Description Deactivate a user who should no longer sign in without deleting them
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param request body interfaces.StatusChangeRequest false "Reason for the change"
+// @Success 200 {object} interfaces.User
+// @Failure 409 {string} string "User status changed concurrently"
+// @Failure 422 {string} string "The user is already deactivated"
+// @Router /v1/users/{id}/deactivate [post]
+func (handler *UserHandler) DeactivateUser(context echo.Context) error {
+	return handler.changeStatus(context, interfaces.UserStatusDeactivated)
+}
+
+// GetStatusHistory godoc
+// @Summary List a user's status changes
+// @Description List the status transitions of a user, oldest first
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {array} interfaces.StatusTransition
+// @Router /v1/users/{id}/status-history [get]
+func (handler *UserHandler) GetStatusHistory(context echo.Context) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid user ID", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	history, err := handler.service.GetStatusHistory(context.Request().Context(), tenant.FromContext(context), id)
+	if err != nil {
+		logger.Error("Error retrieving status history", zap.Int("userID", id), zap.Error(err))
+		return statusError(context, err)
+	}
+	return context.JSON(http.StatusOK, history)
+}
+
+func (handler *UserHandler) changeStatus(context echo.Context, status string) error {
+	id, err := strconv.Atoi(context.Param("id"))
+	if err != nil {
+		logger.Error("Invalid user ID", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid ID")
+	}
+	var request interfaces.StatusChangeRequest
+	if err := context.Bind(&request); err != nil {
+		logger.Error("Invalid input: ", zap.Error(err))
+		return context.JSON(http.StatusBadRequest, "Invalid input")
+	}
+
+	organizationID := tenant.FromContext(context)
+	changedUser, err := handler.service.ChangeUserStatus(audited(context), organizationID, id, status, request.Reason, callerID(context))
+	if err != nil {
+		logger.Error("Error changing user status", zap.Int("userID", id), zap.String("status", status), zap.Error(err))
+		return statusError(context, err)
+	}
+
+	logger.Info("User status changed", zap.Int("userID", id), zap.String("status", status))
+	context.Response().Header().Set(headerETag, versionETag(changedUser.Version))
+	return handler.viewJSON(context, http.StatusOK, changedUser)
+}
+
+func statusError(context echo.Context, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return context.JSON(http.StatusNotFound, "User not found")
+	case errors.Is(err, interfaces.ErrIllegalTransition):
+		return context.JSON(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, interfaces.ErrVersionConflict):
+		return context.JSON(http.StatusConflict, err.Error())
+	}
+	return context.JSON(http.StatusInternalServerError, err)
+}
@@ -0,0 +1,94 @@
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// ErrUserConflict is returned when an active user already holds a username or email and the
+	// violated index cannot be told apart
+	ErrUserConflict  = errors.New("an active user already has this username or email")
+	ErrUsernameTaken = errors.New("username already exists")
+	ErrEmailTaken    = errors.New("email already exists")
+	// ErrConfusableUsername is returned for usernames that could pass for a different one
+	ErrConfusableUsername = errors.New("username contains confusable characters")
+)
+
+// usernameScriptSets are the script combinations a username may mix, as in the highly
+// restrictive level of Unicode TS #39. Common and Inherited characters (digits, punctuation,
+// combining marks) go with any script.
+var usernameScriptSets = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// NormalizeIdentity returns the form usernames and emails are compared in. It matches the
+// username_normalized and email_normalized columns, lower(normalize(value, NFKC)), so "Ｊane"
+// and "jane" are the same username. This lower-cases each letter rather than case folding, so
+// "Straße" and "STRASSE" stay distinct.
+func NormalizeIdentity(value string) string {
+	return strings.ToLower(norm.NFKC.String(value))
+}
+
+// CheckUsername rejects usernames with invisible or control characters, and usernames mixing
+// letters of different scripts, such as a Cyrillic "а" in an otherwise Latin name
+func CheckUsername(username string) error {
+	scripts := map[string]bool{}
+	for _, r := range username {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return fmt.Errorf("%w: invisible character %U", ErrConfusableUsername, r)
+		}
+		if script := scriptOf(r); script != "" {
+			scripts[script] = true
+		}
+	}
+	if len(scripts) <= 1 {
+		return nil
+	}
+	for _, allowed := range usernameScriptSets {
+		if containsScripts(allowed, scripts) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: letters from %s", ErrConfusableUsername, strings.Join(slices.Sorted(maps.Keys(scripts)), ", "))
+}
+
+// ConflictField names the field a uniqueness error is about, or returns "" if it is not one
+func ConflictField(err error) string {
+	switch {
+	case errors.Is(err, ErrUsernameTaken):
+		return "username"
+	case errors.Is(err, ErrEmailTaken):
+		return "email"
+	}
+	return ""
+}
+
+func scriptOf(r rune) string {
+	if unicode.In(r, unicode.Common, unicode.Inherited) {
+		return ""
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return ""
+}
+
+func containsScripts(allowed []string, scripts map[string]bool) bool {
+	for script := range scripts {
+		if !slices.Contains(allowed, script) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+
+	// ImportModeTransactional creates every user or none of them
+	ImportModeTransactional = "transactional"
+	// ImportModeBestEffort creates the valid rows and reports the rest
+	ImportModeBestEffort = "best_effort"
+
+	ImportRowValid   = "valid"
+	ImportRowCreated = "created"
+	ImportRowInvited = "invited"
+	ImportRowFailed  = "failed"
+	ImportRowSkipped = "skipped"
+)
+
+// ImportFields are the user fields a source column can be mapped to
+var ImportFields = []string{"name", "email", "username", "password", "status", "role"}
+
+var (
+	ErrImportFormat  = errors.New("unsupported import format")
+	ErrImportMode    = errors.New("unsupported import mode")
+	ErrImportMapping = errors.New("invalid column mapping")
+)
+
+type ImportOptions struct {
+	Format string
+	Mode   string
+	DryRun bool
+	// Mapping maps source column names to ImportFields; unmapped columns keep their own name
+	Mapping map[string]string
+	// InviteWithoutPassword sends an invitation for rows without a password instead of failing them
+	InviteWithoutPassword bool
+	// InvitedBy is the user running the import. It is recorded on the invitations the import
+	// issues, and only owners can import owners.
+	InvitedBy int
+}
+
+// ImportRecord is one parsed source row, keyed by user field
+type ImportRecord struct {
+	Line   int
+	Fields map[string]string
+}
+
+type ImportRowResult struct {
+	Line     int    `json:"line"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Status   string `json:"status"`
+	UserID   int    `json:"user_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Format  string            `json:"format"`
+	Mode    string            `json:"mode"`
+	DryRun  bool              `json:"dry_run"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Invited int               `json:"invited"`
+	Failed  int               `json:"failed"`
+	Skipped int               `json:"skipped"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+type ImportService interface {
+	Import(ctx context.Context, organizationID int, source io.Reader, options ImportOptions) (ImportReport, error)
+}
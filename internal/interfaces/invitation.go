@@ -0,0 +1,74 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+var (
+	ErrInvitationExpired    = errors.New("invitation has expired")
+	ErrInvitationNotPending = errors.New("invitation is no longer pending")
+	ErrInvitationToken      = errors.New("invalid invitation token")
+)
+
+// Invitation offers an email address a membership with Role. InvitedBy is nil once the inviting
+// user was purged.
+type Invitation struct {
+	ID             int        `json:"id"`
+	OrganizationID int        `json:"organization_id"`
+	Email          string     `json:"email" validate:"required,email"`
+	Role           string     `json:"role"`
+	Status         string     `json:"status"`
+	InvitedBy      *int       `json:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+}
+
+type InvitationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role"`
+}
+
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	// Attributes are validated against the organization's attribute definitions
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+type InvitationRepository interface {
+	GetPending(ctx context.Context, organizationID int) ([]Invitation, error)
+	GetByID(ctx context.Context, organizationID, id int) (Invitation, error)
+	// Create stores the invitation in the unit of work carried by ctx, if any
+	Create(ctx context.Context, invitation Invitation) (Invitation, error)
+	Update(ctx context.Context, invitation Invitation) (Invitation, error)
+	// Claim marks the invitation accepted at invitation.AcceptedAt in the unit of work carried by
+	// ctx, provided it is still pending. Otherwise it returns ErrInvitationNotPending, so of
+	// concurrent accepts only one claims the invitation.
+	Claim(ctx context.Context, invitation Invitation) error
+}
+
+type InvitationService interface {
+	GetPendingInvitations(ctx context.Context, organizationID int) ([]Invitation, error)
+	// Invite issues the invitation; only owners, as InvitedBy, invite owners. Inside a unit of
+	// work the invitation is delivered once the unit of work commits.
+	Invite(ctx context.Context, invitation Invitation) (Invitation, error)
+	Resend(ctx context.Context, organizationID, id int) (Invitation, error)
+	Revoke(ctx context.Context, organizationID, id int) (Invitation, error)
+	Accept(ctx context.Context, request AcceptInvitationRequest) (User, error)
+}
+
+// Notifier delivers invitation tokens to invitees
+type Notifier interface {
+	NotifyInvitation(invitation Invitation, token string) error
+}
@@ -0,0 +1,89 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+var (
+	ErrInvalidRole = errors.New("invalid organization role")
+	// ErrOwnerRequired is returned when a caller who is not an owner grants or takes the owner role
+	ErrOwnerRequired = errors.New("only owners can grant or revoke the owner role")
+	// ErrLastOwner is returned for changes that would leave an organization without an owner
+	ErrLastOwner = errors.New("an organization must keep at least one owner")
+)
+
+type Organization struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name" validate:"required"`
+	Slug      string    `json:"slug" gorm:"unique;not null" validate:"required"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Membership struct {
+	OrganizationID int       `json:"organization_id"`
+	UserID         int       `json:"user_id"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// CanManage reports whether the membership role may administer the organization
+func (membership Membership) CanManage() bool {
+	return membership.Role == RoleOwner || membership.Role == RoleAdmin
+}
+
+// IsValidRole reports whether role is one of the known per-organization roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleOwner, RoleAdmin, RoleMember:
+		return true
+	}
+	return false
+}
+
+type OrganizationRequest struct {
+	Name string `json:"name" validate:"required"`
+	Slug string `json:"slug" validate:"required"`
+}
+
+type MembershipRequest struct {
+	UserID int    `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+type OrganizationRepository interface {
+	GetByID(ctx context.Context, id int) (Organization, error)
+	GetBySlug(ctx context.Context, slug string) (Organization, error)
+	GetForUser(ctx context.Context, userID int) ([]Organization, error)
+	Create(ctx context.Context, organization Organization, ownerID int) (Organization, error)
+	GetMembers(ctx context.Context, organizationID int) ([]Membership, error)
+	GetMembership(ctx context.Context, organizationID, userID int) (Membership, error)
+	// GetOwners returns the IDs of the organization's owners, and locks their memberships until
+	// the unit of work carried by ctx ends
+	GetOwners(ctx context.Context, organizationID int) ([]int, error)
+	AddMember(ctx context.Context, membership Membership) (Membership, error)
+	UpdateMemberRole(ctx context.Context, membership Membership) (Membership, error)
+	RemoveMember(ctx context.Context, organizationID, userID int) error
+}
+
+// OrganizationService changes memberships on behalf of actor, the membership of the caller in
+// the organization. Only owners grant, revoke or remove the owner role, and every organization
+// keeps at least one owner.
+type OrganizationService interface {
+	GetOrganization(ctx context.Context, id int) (Organization, error)
+	GetOrganizationBySlug(ctx context.Context, slug string) (Organization, error)
+	GetOrganizationsForUser(ctx context.Context, userID int) ([]Organization, error)
+	CreateOrganization(ctx context.Context, organization Organization, ownerID int) (Organization, error)
+	GetMembers(ctx context.Context, organizationID int) ([]Membership, error)
+	GetMembership(ctx context.Context, organizationID, userID int) (Membership, error)
+	AddMember(ctx context.Context, actor, membership Membership) (Membership, error)
+	UpdateMemberRole(ctx context.Context, actor, membership Membership) (Membership, error)
+	RemoveMember(ctx context.Context, actor Membership, organizationID, userID int) error
+}
@@ -0,0 +1,29 @@
+package interfaces
+
+const (
+	PermissionUsersRead          = "users:read"
+	PermissionUsersWrite         = "users:write"
+	PermissionInvitationsManage  = "invitations:manage"
+	PermissionGroupsManage       = "groups:manage"
+	PermissionOrganizationManage = "organization:manage"
+)
+
+// RolePermissions maps each organization role to the permissions it grants
+var RolePermissions = map[string][]string{
+	RoleMember: {
+		PermissionUsersRead,
+	},
+	RoleAdmin: {
+		PermissionUsersRead,
+		PermissionUsersWrite,
+		PermissionInvitationsManage,
+		PermissionGroupsManage,
+	},
+	RoleOwner: {
+		PermissionUsersRead,
+		PermissionUsersWrite,
+		PermissionInvitationsManage,
+		PermissionGroupsManage,
+		PermissionOrganizationManage,
+	},
+}
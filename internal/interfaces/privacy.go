@@ -0,0 +1,85 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	ErasurePending   = "pending"
+	ErasureCompleted = "completed"
+	ErasureCancelled = "cancelled"
+
+	// UserStatusErased marks the anonymized row an erasure leaves behind
+	UserStatusErased = "erased"
+)
+
+// ErrErasureNotPending is returned when cancelling an erasure that already ran or was cancelled
+var ErrErasureNotPending = errors.New("erasure request is no longer pending")
+
+// ErasureRequest schedules the anonymization of a user. Self-service requests are scheduled
+// immediately; requests made by an admin wait for the grace period so they can be cancelled.
+type ErasureRequest struct {
+	ID             int        `json:"id"`
+	OrganizationID int        `json:"organization_id"`
+	UserID         int        `json:"user_id"`
+	RequestedBy    *int       `json:"requested_by"`
+	Reason         string     `json:"reason"`
+	Status         string     `json:"status"`
+	ScheduledFor   time.Time  `json:"scheduled_for"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at"`
+}
+
+type ErasureRequestInput struct {
+	Reason string `json:"reason"`
+}
+
+// PersonalData is everything stored about a user, as handed out for a subject access request
+type PersonalData struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	Profile     User             `json:"profile"`
+	Memberships []Membership     `json:"memberships"`
+	Groups      []Group          `json:"groups"`
+	Sessions    PersonalSessions `json:"sessions"`
+	AuditEvents []AuditEvent     `json:"audit_events"`
+	Tokens      []RevokedToken   `json:"tokens"`
+	Invitations []Invitation     `json:"invitations"`
+}
+
+// PersonalSessions describes the user's sign-ins. Tokens are stateless, so sessions are
+// reconstructed from the authentication events of the audit log.
+type PersonalSessions struct {
+	RevokedAt *time.Time   `json:"revoked_at"`
+	Events    []AuditEvent `json:"events"`
+}
+
+// RevokedToken is a blacklisted token of the user; the token itself is not disclosed
+type RevokedToken struct {
+	ID        int       `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type PrivacyRepository interface {
+	GetPersonalData(ctx context.Context, organizationID, userID int) (PersonalData, error)
+	// ScheduleErasure keeps the earlier date when the user already has a pending request
+	ScheduleErasure(ctx context.Context, request ErasureRequest) (ErasureRequest, error)
+	GetErasureRequests(ctx context.Context, organizationID int) ([]ErasureRequest, error)
+	GetDueErasureRequests(ctx context.Context, now time.Time) ([]ErasureRequest, error)
+	CancelErasureRequest(ctx context.Context, organizationID, id int) (ErasureRequest, error)
+	// Erase anonymizes the user in the unit of work carried by ctx and completes the request. It
+	// returns the anonymized user with the completed request.
+	Erase(ctx context.Context, request ErasureRequest) (ErasureRequest, User, error)
+}
+
+type PrivacyService interface {
+	ExportPersonalData(ctx context.Context, organizationID, userID int) (PersonalData, error)
+	// EraseNow erases the user immediately, running any pending request early
+	EraseNow(ctx context.Context, organizationID, userID int, reason string) (ErasureRequest, error)
+	ScheduleErasure(ctx context.Context, organizationID, userID, requestedBy int, reason string) (ErasureRequest, error)
+	GetErasureRequests(ctx context.Context, organizationID int) ([]ErasureRequest, error)
+	CancelErasure(ctx context.Context, organizationID, id int) (ErasureRequest, error)
+	EraseDueUsers(ctx context.Context) (int, error)
+}
@@ -1,14 +1,33 @@
 package interfaces
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Repository interface {
-	GetAll() ([]User, error)
-	GetByUsername(username string) (User, error)
-	GetByID(id int) (User, error)
-	Create(user User) (User, error)
-	Update(user User) (User, error)
-	Delete(id int) (User, error)
+	GetAll(ctx context.Context, organizationID int, filter UserFilter) ([]User, error)
+	// Search matches users by prefix full-text search or trigram similarity; filter.Search is ignored
+	Search(ctx context.Context, organizationID int, text string, filter UserFilter, limit int) ([]UserSearchResult, error)
+	GetByUsername(ctx context.Context, organizationID int, username string) (User, error)
+	GetByID(ctx context.Context, organizationID, id int) (User, error)
+	Create(ctx context.Context, user User) (User, error)
+	CreateMany(ctx context.Context, users []User) ([]User, error)
+	FindExisting(ctx context.Context, organizationID int, usernames, emails []string) ([]User, error)
+	Update(ctx context.Context, user User) (User, error)
+	Delete(ctx context.Context, organizationID, id, version int) (User, error)
+	GetDeleted(ctx context.Context, organizationID int) ([]User, error)
+	StreamUsers(ctx context.Context, organizationID int, filter UserFilter, fn func(User) error) error
+	Restore(ctx context.Context, organizationID, id int) (User, error)
+	// ChangeStatus moves the user from transition.FromStatus to transition.ToStatus and records the transition
+	ChangeStatus(ctx context.Context, transition StatusTransition) (User, error)
+	GetStatusHistory(ctx context.Context, organizationID, userID int) ([]StatusTransition, error)
+	// Purge permanently removes the users soft-deleted before deletedBefore, across all
+	// organizations, and returns their IDs, organizations and usernames
+	Purge(ctx context.Context, deletedBefore time.Time) ([]User, error)
 	GenerateHashFromPassword(password string) (string, error)
-	BlacklistToken(token string, expiry time.Time) error
+	BlacklistToken(ctx context.Context, userID int, token string, expiry time.Time) error
+	// Evict drops the copies a cache holds of users changed without the repository, such as erased
+	// ones, once the unit of work carried by ctx committed. Repositories without a cache do nothing.
+	Evict(ctx context.Context, users ...User)
 }
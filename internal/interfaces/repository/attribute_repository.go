@@ -0,0 +1,313 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var attributeDefinitionColumns = []string{
+	"organization_id", "name", "type", "required", "is_unique", "pattern", "enum", "visibility", "created_at",
+}
+
+type attributeRepository struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewAttributeRepository(db *sql.DB) interfaces.AttributeRepository {
+	return &attributeRepository{db, dialectOf(db)}
+}
+
+func (repository *attributeRepository) GetDefinitions(
+	ctx context.Context,
+	organizationID int,
+) ([]interfaces.AttributeDefinition, error) {
+	rows, err := squirrel.
+		Select(attributeDefinitionColumns...).
+		From("attribute_definitions").
+		Where(squirrel.Eq{"organization_id": organizationID}).
+		OrderBy("name").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building attribute definition query:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	definitions := []interfaces.AttributeDefinition{}
+	for rows.Next() {
+		definition, err := scanAttributeDefinition(rows)
+		if err != nil {
+			logger.Error("Error scanning attribute definition row:", zap.Error(err))
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, rows.Err()
+}
+
+// CreateDefinition returns ErrAttributeExists when the name is already defined
+func (repository *attributeRepository) CreateDefinition(
+	ctx context.Context,
+	definition interfaces.AttributeDefinition,
+) (interfaces.AttributeDefinition, error) {
+	enum, err := repository.dialect.stringsValue(definition.Enum)
+	if err != nil {
+		return definition, err
+	}
+	query, args, err := squirrel.Insert("attribute_definitions").
+		Columns("organization_id", "name", "type", "required", "is_unique", "pattern", "enum", "visibility").
+		Values(
+			definition.OrganizationID,
+			definition.Name,
+			definition.Type,
+			definition.Required,
+			definition.Unique,
+			definition.Pattern,
+			enum,
+			definition.Visibility,
+		).
+		Suffix("RETURNING created_at").
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return definition, err
+	}
+
+	if err = conn(ctx, repository.db).QueryRowContext(ctx, query, args...).Scan(&definition.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return definition, interfaces.ErrAttributeExists
+		}
+		logger.Error("Error creating attribute definition:", zap.String("name", definition.Name), zap.Error(err))
+		return definition, err
+	}
+	return definition, nil
+}
+
+// UpdateDefinition replaces everything but the name; existing values are not revalidated until
+// the user is next written. Making the attribute unique claims the values the active users hold,
+// failing with ErrAttributeConflict when two of them share one.
+func (repository *attributeRepository) UpdateDefinition(
+	ctx context.Context,
+	definition interfaces.AttributeDefinition,
+) (interfaces.AttributeDefinition, error) {
+	enum, err := repository.dialect.stringsValue(definition.Enum)
+	if err != nil {
+		return definition, err
+	}
+	query, args, err := squirrel.Update("attribute_definitions").
+		Set("type", definition.Type).
+		Set("required", definition.Required).
+		Set("is_unique", definition.Unique).
+		Set("pattern", definition.Pattern).
+		Set("enum", enum).
+		Set("visibility", definition.Visibility).
+		Where(squirrel.Eq{"organization_id": definition.OrganizationID, "name": definition.Name}).
+		Suffix("RETURNING created_at").
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return definition, err
+	}
+
+	err = inTx(ctx, repository.db, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&definition.CreatedAt); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				logger.Error(
+					"Error updating attribute definition:",
+					zap.String("name", definition.Name),
+					zap.Error(err),
+				)
+			}
+			return err
+		}
+		return repository.claimDefinitionValues(ctx, tx, definition)
+	})
+	return definition, err
+}
+
+// claimDefinitionValues releases the values claimed for the attribute and, when it is unique,
+// claims the ones the active users of the organization hold
+func (repository *attributeRepository) claimDefinitionValues(
+	ctx context.Context,
+	tx *sql.Tx,
+	definition interfaces.AttributeDefinition,
+) error {
+	_, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM user_attribute_values WHERE organization_id = $1 AND name = $2",
+		definition.OrganizationID,
+		definition.Name,
+	)
+	if err != nil || !definition.Unique {
+		if err != nil {
+			logger.Error("Error releasing attribute values:", zap.String("name", definition.Name), zap.Error(err))
+		}
+		return err
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT id, attributes FROM users WHERE organization_id = $1 AND deleted_at IS NULL AND "+
+			repository.dialect.hasAttribute("$2"),
+		definition.OrganizationID,
+		definition.Name,
+	)
+	if err != nil {
+		logger.Error("Error querying attribute values:", zap.String("name", definition.Name), zap.Error(err))
+		return err
+	}
+	holders := map[int]interface{}{}
+	for rows.Next() {
+		var (
+			userID     int
+			attributes map[string]interface{}
+		)
+		if err = rows.Scan(&userID, attributesColumn(&attributes)); err != nil {
+			closeRows(rows)
+			logger.Error("Error scanning attribute values:", zap.String("name", definition.Name), zap.Error(err))
+			return err
+		}
+		holders[userID] = attributes[definition.Name]
+	}
+	closeRows(rows)
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for userID, value := range holders {
+		user := interfaces.User{ID: userID, OrganizationID: definition.OrganizationID}
+		if err = repository.claimValue(ctx, tx, user, definition.Name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repository *attributeRepository) DeleteDefinition(ctx context.Context, organizationID int, name string) error {
+	return inTx(ctx, repository.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			"DELETE FROM attribute_definitions WHERE organization_id = $1 AND name = $2",
+			organizationID,
+			name,
+		)
+		if err != nil {
+			logger.Error("Error deleting attribute definition:", zap.String("name", name), zap.Error(err))
+			return err
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return sql.ErrNoRows
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"UPDATE users SET attributes = "+repository.dialect.removeAttribute("$2")+
+				" WHERE organization_id = $1 AND "+repository.dialect.hasAttribute("$2"),
+			organizationID,
+			name,
+		)
+		if err != nil {
+			logger.Error("Error removing attribute from users:", zap.String("name", name), zap.Error(err))
+		}
+		return err
+	})
+}
+
+// ClaimValues replaces the values of unique attributes claimed for the user with values, in the
+// unit of work carried by ctx
+func (repository *attributeRepository) ClaimValues(
+	ctx context.Context,
+	user interfaces.User,
+	values map[string]interface{},
+) error {
+	db := conn(ctx, repository.db)
+	if _, err := db.ExecContext(ctx, "DELETE FROM user_attribute_values WHERE user_id = $1", user.ID); err != nil {
+		logger.Error("Error releasing attribute values:", zap.Int("user_id", user.ID), zap.Error(err))
+		return err
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := repository.claimValue(ctx, db, user, name, values[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimValue fails with ErrAttributeConflict when another user holds the value; nil values are
+// not claimed
+func (repository *attributeRepository) claimValue(
+	ctx context.Context,
+	db querier,
+	user interfaces.User,
+	name string,
+	value interface{},
+) error {
+	if value == nil {
+		return nil
+	}
+	key, err := attributeValueKey(value)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(
+		ctx,
+		"INSERT INTO user_attribute_values (user_id, organization_id, name, value) VALUES ($1, $2, $3, $4)",
+		user.ID,
+		user.OrganizationID,
+		name,
+		key,
+	)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %s", interfaces.ErrAttributeConflict, name)
+	}
+	if err != nil {
+		logger.Error("Error claiming attribute value:", zap.String("name", name), zap.Error(err))
+	}
+	return err
+}
+
+// attributeValueKey stores strings as they are and other values as JSON, as the migration that
+// created user_attribute_values does
+func attributeValueKey(value interface{}) (string, error) {
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
+
+func scanAttributeDefinition(row squirrel.RowScanner) (interfaces.AttributeDefinition, error) {
+	var definition interfaces.AttributeDefinition
+	err := row.Scan(
+		&definition.OrganizationID,
+		&definition.Name,
+		&definition.Type,
+		&definition.Required,
+		&definition.Unique,
+		&definition.Pattern,
+		stringsColumn(&definition.Enum),
+		&definition.Visibility,
+		&definition.CreatedAt,
+	)
+	return definition, err
+}
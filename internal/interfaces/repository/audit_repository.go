@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// auditChainLock is the advisory lock key serializing appends, so every event sees the hash of
+// the one before it
+const auditChainLock = 7310
+
+var auditColumns = []string{
+	"id", "organization_id", "actor_id", "actor_username", "action", "target_type", "target_id",
+	"changes", "ip", "request_id", "created_at", "prev_hash", "hash",
+}
+
+type auditRepository struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewAuditRepository(db *sql.DB) interfaces.AuditRepository {
+	return &auditRepository{db, dialectOf(db)}
+}
+
+// Append joins the unit of work carried by ctx, if any, so the event commits or rolls back with
+// the change it records. The chain lock is then held until that transaction ends.
+func (repository *auditRepository) Append(ctx context.Context, event interfaces.AuditEvent) (interfaces.AuditEvent, error) {
+	err := inTx(ctx, repository.db, func(tx *sql.Tx) error {
+		// SQLite write transactions already exclude each other
+		if !repository.dialect.sqlite {
+			if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", auditChainLock); err != nil {
+				logger.Error("Error locking audit chain:", zap.Error(err))
+				return err
+			}
+		}
+
+		event.PrevHash = interfaces.AuditGenesisHash
+		err := tx.QueryRowContext(ctx, "SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1").Scan(&event.PrevHash)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Error("Error reading audit chain head:", zap.Error(err))
+			return err
+		}
+		if err = tx.QueryRowContext(ctx, repository.dialect.nextID("audit_events")).Scan(&event.ID); err != nil {
+			logger.Error("Error allocating audit event ID:", zap.Error(err))
+			return err
+		}
+		event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		event.Hash = event.ComputeHash()
+
+		// JSONB parameters go over the wire as text; a []byte would be sent as bytea
+		var changes interface{}
+		if event.Changes != nil {
+			encoded, err := json.Marshal(event.Changes)
+			if err != nil {
+				return err
+			}
+			changes = string(encoded)
+		}
+
+		query, args, err := squirrel.Insert("audit_events").
+			Columns(auditColumns...).
+			Values(
+				event.ID,
+				event.OrganizationID,
+				event.ActorID,
+				event.ActorUsername,
+				event.Action,
+				event.TargetType,
+				event.TargetID,
+				changes,
+				event.IP,
+				event.RequestID,
+				event.CreatedAt,
+				event.PrevHash,
+				event.Hash,
+			).
+			PlaceholderFormat(repository.dialect.placeholders).
+			ToSql()
+		if err != nil {
+			logger.Error("Error building SQL query:", zap.Error(err))
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			logger.Error("Error appending audit event:", zap.Error(err))
+			return err
+		}
+		return nil
+	})
+	return event, err
+}
+
+// GetEvents returns the organization's events matching the filter, newest first
+func (repository *auditRepository) GetEvents(
+	ctx context.Context,
+	organizationID int,
+	filter interfaces.AuditFilter,
+) ([]interfaces.AuditEvent, error) {
+	builder := squirrel.
+		Select(auditColumns...).
+		From("audit_events").
+		Where(squirrel.Eq{"organization_id": organizationID})
+	if filter.ActorID != nil {
+		builder = builder.Where(squirrel.Eq{"actor_id": *filter.ActorID})
+	}
+	if filter.Action != "" {
+		builder = builder.Where(squirrel.Eq{"action": filter.Action})
+	}
+	if filter.TargetID != nil {
+		builder = builder.Where(squirrel.Eq{"target_id": *filter.TargetID})
+	}
+	if filter.From != nil {
+		builder = builder.Where(squirrel.GtOrEq{"created_at": *filter.From})
+	}
+	if filter.To != nil {
+		builder = builder.Where(squirrel.Lt{"created_at": *filter.To})
+	}
+
+	rows, err := builder.
+		OrderBy("id DESC").
+		Limit(uint64(filter.Limit)).
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building audit query:", zap.Error(err))
+		return nil, err
+	}
+	return scanAuditEvents(rows)
+}
+
+func (repository *auditRepository) GetChain(
+	ctx context.Context,
+	afterID int64,
+	limit int,
+) ([]interfaces.AuditEvent, error) {
+	rows, err := squirrel.
+		Select(auditColumns...).
+		From("audit_events").
+		Where(squirrel.Gt{"id": afterID}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building audit chain query:", zap.Error(err))
+		return nil, err
+	}
+	return scanAuditEvents(rows)
+}
+
+func scanAuditEvents(rows *sql.Rows) ([]interfaces.AuditEvent, error) {
+	defer closeRows(rows)
+
+	events := []interfaces.AuditEvent{}
+	for rows.Next() {
+		var event interfaces.AuditEvent
+		var changes []byte
+		if err := rows.Scan(
+			&event.ID,
+			&event.OrganizationID,
+			&event.ActorID,
+			&event.ActorUsername,
+			&event.Action,
+			&event.TargetType,
+			&event.TargetID,
+			&changes,
+			&event.IP,
+			&event.RequestID,
+			&event.CreatedAt,
+			&event.PrevHash,
+			&event.Hash,
+		); err != nil {
+			logger.Error("Error scanning audit event row:", zap.Error(err))
+			return nil, err
+		}
+		if changes != nil {
+			if err := json.Unmarshal(changes, &event.Changes); err != nil {
+				logger.Error("Error decoding audit changes:", zap.Int64("eventID", event.ID), zap.Error(err))
+				return nil, err
+			}
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
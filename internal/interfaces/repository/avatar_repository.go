@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var avatarColumns = []string{"user_id", "organization_id", "key_prefix", "content_type", "updated_at"}
+
+type avatarRepository struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewAvatarRepository(db *sql.DB) interfaces.AvatarRepository {
+	return &avatarRepository{db, dialectOf(db)}
+}
+
+func (repository *avatarRepository) Get(ctx context.Context, organizationID, userID int) (interfaces.Avatar, error) {
+	query, args, err := squirrel.
+		Select(avatarColumns...).
+		From("user_avatars").
+		Where(squirrel.Eq{"organization_id": organizationID, "user_id": userID}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return interfaces.Avatar{}, err
+	}
+	return scanAvatar(conn(ctx, repository.db).QueryRowContext(ctx, query, args...))
+}
+
+func (repository *avatarRepository) GetMany(
+	ctx context.Context,
+	organizationID int,
+	userIDs []int,
+) (map[int]interfaces.Avatar, error) {
+	avatars := make(map[int]interfaces.Avatar)
+	if len(userIDs) == 0 {
+		return avatars, nil
+	}
+	rows, err := squirrel.
+		Select(avatarColumns...).
+		From("user_avatars").
+		Where(squirrel.Eq{"organization_id": organizationID, "user_id": userIDs}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building avatar query:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		avatar, err := scanAvatar(rows)
+		if err != nil {
+			logger.Error("Error scanning avatar row:", zap.Error(err))
+			return nil, err
+		}
+		avatars[avatar.UserID] = avatar
+	}
+	return avatars, rows.Err()
+}
+
+// Save inserts or replaces the user's avatar
+func (repository *avatarRepository) Save(ctx context.Context, avatar interfaces.Avatar) (interfaces.Avatar, error) {
+	query, args, err := squirrel.Insert("user_avatars").
+		Columns("user_id", "organization_id", "key_prefix", "content_type").
+		Values(avatar.UserID, avatar.OrganizationID, avatar.KeyPrefix, avatar.ContentType).
+		Suffix(`ON CONFLICT (user_id) DO UPDATE
+			SET key_prefix = EXCLUDED.key_prefix, content_type = EXCLUDED.content_type, updated_at = CURRENT_TIMESTAMP
+			RETURNING updated_at`).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return avatar, err
+	}
+
+	if err = conn(ctx, repository.db).QueryRowContext(ctx, query, args...).Scan(&avatar.UpdatedAt); err != nil {
+		logger.Error("Error saving avatar:", zap.Int("userID", avatar.UserID), zap.Error(err))
+	}
+	return avatar, err
+}
+
+func (repository *avatarRepository) Delete(ctx context.Context, organizationID, userID int) error {
+	query, args, err := squirrel.Delete("user_avatars").
+		Where(squirrel.Eq{"organization_id": organizationID, "user_id": userID}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return err
+	}
+	return execAffectingRowContext(ctx, conn(ctx, repository.db), query, args...)
+}
+
+func scanAvatar(row squirrel.RowScanner) (interfaces.Avatar, error) {
+	var avatar interfaces.Avatar
+	err := row.Scan(&avatar.UserID, &avatar.OrganizationID, &avatar.KeyPrefix, &avatar.ContentType, &avatar.UpdatedAt)
+	return avatar, err
+}
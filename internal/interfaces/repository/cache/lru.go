@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU keeps up to size entries in process, evicting the least recently used one to make room
+type LRU struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// order holds the entries, most recently used first
+	order *list.List
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewLRU(size int) *LRU {
+	return &LRU{size: size, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+func (cache *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*lruEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		cache.remove(element)
+		return nil, false, nil
+	}
+	cache.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (cache *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	if cache.size <= 0 {
+		return nil
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry := &lruEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+	if element, ok := cache.entries[key]; ok {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return nil
+	}
+	cache.entries[key] = cache.order.PushFront(entry)
+	for cache.order.Len() > cache.size {
+		cache.remove(cache.order.Back())
+	}
+	return nil
+}
+
+func (cache *LRU) Delete(_ context.Context, keys ...string) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for _, key := range keys {
+		if element, ok := cache.entries[key]; ok {
+			cache.remove(element)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not evicted yet
+func (cache *LRU) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.order.Len()
+}
+
+func (cache *LRU) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*lruEntry).key)
+}
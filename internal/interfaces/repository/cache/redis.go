@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// redisTimeout bounds a command whose context has no deadline
+	redisTimeout = time.Second
+	// redisIdleConns is how many connections are kept open between commands
+	redisIdleConns = 16
+)
+
+// Redis stores entries on a server speaking the Redis protocol, such as Redis or Valkey. Only
+// GET, SET with an expiry and DEL are used, so any stand-in implementing them will do.
+type Redis struct {
+	address  string
+	username string
+	password string
+	database int
+	idle     chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis connects lazily to the server of a URL like redis://:password@localhost:6379/0, with
+// an optional ACL user before the colon
+func NewRedis(rawURL string) (*Redis, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Scheme != "redis" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Redis URL %q: expected redis://[:password@]host:port[/database]", parsed.Redacted())
+	}
+	redis := &Redis{address: parsed.Host, idle: make(chan *redisConn, redisIdleConns)}
+	if password, ok := parsed.User.Password(); ok {
+		redis.username, redis.password = parsed.User.Username(), password
+	}
+	if database := strings.TrimPrefix(parsed.Path, "/"); database != "" {
+		if redis.database, err = strconv.Atoi(database); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", database)
+		}
+	}
+	return redis, nil
+}
+
+func (redis *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := redis.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	return reply.bulk, reply.bulk != nil, nil
+}
+
+func (redis *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	// The expiry is in milliseconds and must be positive
+	_, err := redis.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	return err
+}
+
+func (redis *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := redis.do(ctx, "DEL", keys...)
+	return err
+}
+
+// Close closes the idle connections
+func (redis *Redis) Close() error {
+	for {
+		select {
+		case conn := <-redis.idle:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// redisReply is a reply of the server; bulk is nil for a missing key
+type redisReply struct {
+	bulk    []byte
+	integer int64
+}
+
+// do sends a command on an idle connection or a new one, and keeps the connection for the next
+// command unless it failed
+func (redis *Redis) do(ctx context.Context, command string, args ...string) (redisReply, error) {
+	conn, err := redis.conn(ctx)
+	if err != nil {
+		return redisReply{}, err
+	}
+	reply, err := conn.do(ctx, command, args...)
+	var serverErr redisError
+	if err != nil && !errors.As(err, &serverErr) {
+		conn.conn.Close()
+		return reply, err
+	}
+	select {
+	case redis.idle <- conn:
+	default:
+		conn.conn.Close()
+	}
+	return reply, err
+}
+
+func (redis *Redis) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-redis.idle:
+		return conn, nil
+	default:
+	}
+
+	var dialer net.Dialer
+	dialCtx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	netConn, err := dialer.DialContext(dialCtx, "tcp", redis.address)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	if redis.password != "" {
+		auth := []string{redis.password}
+		if redis.username != "" {
+			auth = []string{redis.username, redis.password}
+		}
+		if _, err = conn.do(ctx, "AUTH", auth...); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if redis.database != 0 {
+		if _, err = conn.do(ctx, "SELECT", strconv.Itoa(redis.database)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// redisError is an error reply of the server, after which the connection can still be used
+type redisError string
+
+func (err redisError) Error() string {
+	return "redis: " + string(err)
+}
+
+func (conn *redisConn) do(ctx context.Context, command string, args ...string) (redisReply, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisTimeout)
+	}
+	if err := conn.conn.SetDeadline(deadline); err != nil {
+		return redisReply{}, err
+	}
+
+	// Commands are arrays of bulk strings
+	var request strings.Builder
+	fmt.Fprintf(&request, "*%d\r\n$%d\r\n%s\r\n", len(args)+1, len(command), command)
+	for _, arg := range args {
+		fmt.Fprintf(&request, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(conn.conn, request.String()); err != nil {
+		return redisReply{}, err
+	}
+	return conn.read()
+}
+
+// read parses a simple string, error, integer or bulk string reply, the only kinds GET, SET, DEL,
+// AUTH and SELECT answer with
+func (conn *redisConn) read() (redisReply, error) {
+	line, err := conn.reader.ReadString('\n')
+	if err != nil {
+		return redisReply{}, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return redisReply{}, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return redisReply{}, nil
+	case '-':
+		return redisReply{}, redisError(line[1:])
+	case ':':
+		integer, err := strconv.ParseInt(line[1:], 10, 64)
+		return redisReply{integer: integer}, err
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return redisReply{}, err
+		}
+		if length < 0 {
+			return redisReply{}, nil
+		}
+		bulk := make([]byte, length+2)
+		if _, err = io.ReadFull(conn.reader, bulk); err != nil {
+			return redisReply{}, err
+		}
+		return redisReply{bulk: bulk[:length]}, nil
+	}
+	return redisReply{}, fmt.Errorf("redis: unexpected reply %q", line)
+}
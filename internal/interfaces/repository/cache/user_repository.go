@@ -0,0 +1,264 @@
+// Package cache holds a read-through cache for the user lookups made on every sign-in and
+// authenticated request. It wraps any interfaces.Repository and stores users in a Backend: the
+// in-process LRU, or a server speaking the Redis protocol that several instances share.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// Backend stores encoded entries until their time to live has passed. It must be safe for
+// concurrent use.
+type Backend interface {
+	// Get reports false for a missing or expired key
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+type userRepository struct {
+	interfaces.Repository
+	backend Backend
+	ttl     time.Duration
+	loads   singleflight.Group
+	// generation counts invalidations. A lookup that overlapped one may have read what was just
+	// overwritten, so it does not store its result.
+	generation atomic.Uint64
+}
+
+// NewUserRepository caches the users next returns by ID and by username for ttl. Concurrent
+// misses on a key share one lookup. Writes through the repository drop the entries of the users
+// they change, and Evict those of users changed otherwise, e.g. erased, once the change committed;
+// users changed through another instance with an in-process backend may be served from the cache
+// until their entries expire. Lookups in a unit of work bypass the cache, since they must see the
+// work's own writes, and so do those under interfaces.WithPrimary, which must see the latest
+// state. Entries leave out the password hash, which only those latest-state lookups, such as a
+// sign-in's, are given. A failing backend is logged and skipped.
+func NewUserRepository(next interfaces.Repository, backend Backend, ttl time.Duration) interfaces.Repository {
+	return &userRepository{Repository: next, backend: backend, ttl: ttl}
+}
+
+func (repository *userRepository) GetByID(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	if bypass(ctx) {
+		return repository.Repository.GetByID(ctx, organizationID, id)
+	}
+	key := idKey(organizationID, id)
+	if encoded, ok := repository.get(ctx, key); ok {
+		return decodeUser(encoded)
+	}
+	encoded, err := repository.load(ctx, key, func(ctx context.Context) ([]byte, error) {
+		generation := repository.generation.Load()
+		user, err := repository.Repository.GetByID(ctx, organizationID, id)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := encodeUser(user)
+		if err != nil {
+			return nil, err
+		}
+		repository.set(ctx, generation, key, encoded)
+		return encoded, nil
+	})
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	return decodeUser(encoded)
+}
+
+// GetByUsername keeps the ID of the user holding the username and reads the user by ID, so a
+// rename only has to drop the user's entry. An entry whose user no longer holds the username is
+// ignored.
+func (repository *userRepository) GetByUsername(ctx context.Context, organizationID int, username string) (interfaces.User, error) {
+	if bypass(ctx) {
+		return repository.Repository.GetByUsername(ctx, organizationID, username)
+	}
+	key := usernameKey(organizationID, username)
+	if encoded, ok := repository.get(ctx, key); ok {
+		if id, err := strconv.Atoi(string(encoded)); err == nil {
+			user, err := repository.GetByID(ctx, organizationID, id)
+			if err == nil && interfaces.NormalizeIdentity(user.Username) == interfaces.NormalizeIdentity(username) {
+				return user, nil
+			}
+		}
+	}
+
+	encoded, err := repository.load(ctx, key, func(ctx context.Context) ([]byte, error) {
+		generation := repository.generation.Load()
+		user, err := repository.Repository.GetByUsername(ctx, organizationID, username)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := encodeUser(user)
+		if err != nil {
+			return nil, err
+		}
+		repository.set(ctx, generation, idKey(organizationID, user.ID), encoded)
+		repository.set(ctx, generation, key, []byte(strconv.Itoa(user.ID)))
+		return encoded, nil
+	})
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	return decodeUser(encoded)
+}
+
+func (repository *userRepository) Create(ctx context.Context, user interfaces.User) (interfaces.User, error) {
+	created, err := repository.Repository.Create(ctx, user)
+	if err == nil {
+		repository.invalidate(ctx, created)
+	}
+	return created, err
+}
+
+func (repository *userRepository) CreateMany(ctx context.Context, users []interfaces.User) ([]interfaces.User, error) {
+	created, err := repository.Repository.CreateMany(ctx, users)
+	if err == nil {
+		repository.invalidate(ctx, created...)
+	}
+	return created, err
+}
+
+func (repository *userRepository) Update(ctx context.Context, user interfaces.User) (interfaces.User, error) {
+	updated, err := repository.Repository.Update(ctx, user)
+	if err == nil {
+		repository.invalidate(ctx, updated)
+	}
+	return updated, err
+}
+
+func (repository *userRepository) Delete(ctx context.Context, organizationID, id, version int) (interfaces.User, error) {
+	deleted, err := repository.Repository.Delete(ctx, organizationID, id, version)
+	if err == nil {
+		repository.invalidate(ctx, deleted)
+	}
+	return deleted, err
+}
+
+func (repository *userRepository) Restore(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	restored, err := repository.Repository.Restore(ctx, organizationID, id)
+	if err == nil {
+		repository.invalidate(ctx, restored)
+	}
+	return restored, err
+}
+
+func (repository *userRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]interfaces.User, error) {
+	purged, err := repository.Repository.Purge(ctx, deletedBefore)
+	if len(purged) > 0 {
+		repository.invalidate(ctx, purged...)
+	}
+	return purged, err
+}
+
+func (repository *userRepository) ChangeStatus(ctx context.Context, transition interfaces.StatusTransition) (interfaces.User, error) {
+	changed, err := repository.Repository.ChangeStatus(ctx, transition)
+	if err == nil {
+		repository.invalidate(ctx, changed)
+	}
+	return changed, err
+}
+
+// load runs fetch for key, unless a lookup of key is already under way, whose result it then
+// shares. Callers stop waiting when their context ends, while the lookup itself runs on.
+func (repository *userRepository) load(
+	ctx context.Context,
+	key string,
+	fetch func(ctx context.Context) ([]byte, error),
+) ([]byte, error) {
+	results := repository.loads.DoChan(key, func() (interface{}, error) {
+		return fetch(context.WithoutCancel(ctx))
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.([]byte), nil
+	}
+}
+
+func (repository *userRepository) get(ctx context.Context, key string) ([]byte, bool) {
+	encoded, ok, err := repository.backend.Get(ctx, key)
+	if err != nil {
+		logger.Error("Error reading user cache:", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+	return encoded, ok
+}
+
+// set stores the result of a lookup that started at generation, unless an invalidation overlapped it
+func (repository *userRepository) set(ctx context.Context, generation uint64, key string, encoded []byte) {
+	if repository.generation.Load() != generation {
+		return
+	}
+	if err := repository.backend.Set(context.WithoutCancel(ctx), key, encoded, repository.ttl); err != nil {
+		logger.Error("Error writing user cache:", zap.String("key", key), zap.Error(err))
+	}
+}
+
+func (repository *userRepository) Evict(ctx context.Context, users ...interfaces.User) {
+	repository.invalidate(ctx, users...)
+}
+
+// invalidate drops the entries of the users once the unit of work carried by ctx, if any,
+// committed, and lets lookups already under way finish without new callers joining them. Until
+// then, lookups outside the work read the committed users and may cache them, and would go on
+// serving them after the commit if the entries were dropped any earlier.
+func (repository *userRepository) invalidate(ctx context.Context, users ...interfaces.User) {
+	interfaces.AfterCommit(ctx, func() {
+		repository.evict(ctx, users)
+	})
+}
+
+func (repository *userRepository) evict(ctx context.Context, users []interfaces.User) {
+	keys := make([]string, 0, 2*len(users))
+	for _, user := range users {
+		keys = append(keys, idKey(user.OrganizationID, user.ID), usernameKey(user.OrganizationID, user.Username))
+	}
+	repository.generation.Add(1)
+	for _, key := range keys {
+		repository.loads.Forget(key)
+	}
+	if err := repository.backend.Delete(context.WithoutCancel(ctx), keys...); err != nil {
+		logger.Error("Error invalidating user cache:", zap.Strings("keys", keys), zap.Error(err))
+	}
+}
+
+// bypass reports whether lookups with ctx must read the repository rather than the cache
+func bypass(ctx context.Context) bool {
+	return interfaces.InUnitOfWork(ctx) || interfaces.UsesPrimary(ctx)
+}
+
+func idKey(organizationID, id int) string {
+	return fmt.Sprintf("user:%d:id:%d", organizationID, id)
+}
+
+func usernameKey(organizationID int, username string) string {
+	return fmt.Sprintf("user:%d:username:%s", organizationID, interfaces.NormalizeIdentity(username))
+}
+
+// encodeUser leaves the password hash out, so the cache, which a Redis backend shares, never
+// holds it
+func encodeUser(user interfaces.User) ([]byte, error) {
+	user.Password = ""
+	return json.Marshal(user)
+}
+
+// decodeUser gives every caller a copy of its own, since users hold maps and pointers
+func decodeUser(encoded []byte) (interfaces.User, error) {
+	var user interfaces.User
+	err := json.Unmarshal(encoded, &user)
+	return user, err
+}
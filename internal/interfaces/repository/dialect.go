@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+)
+
+// dialect holds the SQL that differs between PostgreSQL and SQLite. Statements written by hand
+// keep PostgreSQL's $N placeholders, which SQLite binds by position as well; the normalize_identity
+// and attributes_contain functions used on SQLite are registered by the db package.
+type dialect struct {
+	sqlite       bool
+	placeholders squirrel.PlaceholderFormat
+}
+
+// dialectOf picks the dialect of the database's driver
+func dialectOf(database *sql.DB) dialect {
+	if database != nil {
+		if _, ok := database.Driver().(*sqlite.Driver); ok {
+			return dialect{sqlite: true, placeholders: squirrel.Question}
+		}
+	}
+	return dialect{placeholders: squirrel.Dollar}
+}
+
+// normalized returns the expression the username_normalized and email_normalized columns are
+// generated with, applied to value
+func (dialect dialect) normalized(value string) string {
+	if dialect.sqlite {
+		return fmt.Sprintf("normalize_identity(%s)", value)
+	}
+	return fmt.Sprintf("lower(normalize(%s, NFKC))", value)
+}
+
+// normalizedIn matches a normalized column against the normalized elements of the array
+// parameter built by stringsValue
+func (dialect dialect) normalizedIn(column string) string {
+	if dialect.sqlite {
+		return column + " IN (SELECT " + dialect.normalized("value") + " FROM json_each(?))"
+	}
+	return column + " IN (SELECT " + dialect.normalized("value") + " FROM unnest(?::text[]) AS value)"
+}
+
+// containsAttributes tests a JSON object column against the encoded attributes in param, like
+// the JSONB @> operator
+func (dialect dialect) containsAttributes(column, param string) string {
+	if dialect.sqlite {
+		return fmt.Sprintf("attributes_contain(%s, %s)", column, param)
+	}
+	return fmt.Sprintf("%s @> %s::jsonb", column, param)
+}
+
+// iLike matches a column case-insensitively against a pattern escaped with likeEscaper. SQLite
+// only folds the case of ASCII letters.
+func (dialect dialect) iLike(column string) string {
+	if dialect.sqlite {
+		return column + ` LIKE ? ESCAPE '\'`
+	}
+	return column + " ILIKE ?"
+}
+
+// removeAttribute returns the attributes column without the key in param
+func (dialect dialect) removeAttribute(param string) string {
+	if dialect.sqlite {
+		return fmt.Sprintf(`json_remove(attributes, '$."' || %s || '"')`, param)
+	}
+	return "attributes - " + param
+}
+
+// hasAttribute tests whether the attributes column holds the key in param
+func (dialect dialect) hasAttribute(param string) string {
+	if dialect.sqlite {
+		return fmt.Sprintf(`json_type(attributes, '$."' || %s || '"') IS NOT NULL`, param)
+	}
+	// The JSONB ? operator: hand-written statements are not rebound, so it is no placeholder
+	return "attributes ? " + param
+}
+
+// roleList aggregates the roles joined as r into an array read by stringsColumn
+func (dialect dialect) roleList() string {
+	if dialect.sqlite {
+		return "COALESCE(json_group_array(r.role ORDER BY r.role) FILTER (WHERE r.role IS NOT NULL), '[]')"
+	}
+	return "COALESCE(array_agg(r.role ORDER BY r.role) FILTER (WHERE r.role IS NOT NULL), '{}')"
+}
+
+// forUpdate locks the selected rows until the transaction ends. SQLite transactions hold the
+// database's write lock from their first statement instead.
+func (dialect dialect) forUpdate() string {
+	if dialect.sqlite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// nextID allocates the next ID of a table before its row is inserted. On SQLite the caller must
+// be in a write transaction, which keeps other writers out until it ends.
+func (dialect dialect) nextID(table string) string {
+	if dialect.sqlite {
+		return "SELECT COALESCE(MAX(id), 0) + 1 FROM " + table
+	}
+	return fmt.Sprintf("SELECT nextval('%s_id_seq')", table)
+}
+
+// stringsValue encodes a string array parameter: a text[] on PostgreSQL and a JSON array, which
+// stringsColumn reads back, on SQLite
+func (dialect dialect) stringsValue(values []string) (interface{}, error) {
+	if !dialect.sqlite {
+		return pq.StringArray(values), nil
+	}
+	if values == nil {
+		values = []string{}
+	}
+	encoded, err := json.Marshal(values)
+	return string(encoded), err
+}
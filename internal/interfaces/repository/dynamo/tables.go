@@ -0,0 +1,161 @@
+// Package dynamo holds a DynamoDB user repository. It follows the semantics of the PostgreSQL
+// repository, which the repository conformance specs check against LocalStack.
+//
+// Users live in one table keyed by pk and sk, partitioned by organization:
+//
+//	pk          sk                       item
+//	ORG#<org>   USER#<id>                the user, with username_key and email_key while active
+//	ORG#<org>   USERNAME#<normalized>    the claim of an active user on a username
+//	ORG#<org>   EMAIL#<normalized>       the claim of an active user on an email
+//	ORG#<org>   HISTORY#<user>#<id>      a status transition of the user
+//	COUNTER     users, transitions       the last ID handed out
+//
+// IDs are zero-padded, so items sort by ID. Secondary indexes cannot be unique: every write that
+// takes or frees a username or email puts or deletes its claim in the same transaction, on
+// condition that no other user holds it. The sparse username-index and email-index GSIs, keyed by
+// <org>#<normalized>, find the active users holding usernames and emails. Revoked tokens are kept
+// in a table of their own until DynamoDB's TTL removes them.
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+const (
+	usernameIndex = "username-index"
+	emailIndex    = "email-index"
+	// tokenExpiry is the TTL attribute of the token table, in Unix seconds
+	tokenExpiry = "expires_at"
+)
+
+// Tables names the tables the repository uses
+type Tables struct {
+	Users  string
+	Tokens string
+}
+
+// DefaultTables are the tables of a deployment; tests use their own
+var DefaultTables = Tables{Users: "users", Tokens: "revoked_tokens"}
+
+// CreateTables creates the tables that do not exist yet, waits until they are active and turns on
+// the expiry of revoked tokens, so it can run on every deployment
+func CreateTables(ctx context.Context, client dynamodbiface.DynamoDBAPI, tables Tables) error {
+	// Imports only need to know who holds a username or email
+	lookup := &dynamodb.Projection{
+		ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
+		NonKeyAttributes: aws.StringSlice([]string{"id", "username", "email"}),
+	}
+	users := &dynamodb.CreateTableInput{
+		TableName:            aws.String(tables.Users),
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: attributeDefinitions("pk", "sk", "username_key", "email_key"),
+		KeySchema:            keySchema("pk", "sk"),
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{IndexName: aws.String(usernameIndex), KeySchema: keySchema("username_key", ""), Projection: lookup},
+			{IndexName: aws.String(emailIndex), KeySchema: keySchema("email_key", ""), Projection: lookup},
+		},
+	}
+	tokens := &dynamodb.CreateTableInput{
+		TableName:            aws.String(tables.Tokens),
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: attributeDefinitions("token"),
+		KeySchema:            keySchema("token", ""),
+	}
+	for _, input := range []*dynamodb.CreateTableInput{users, tokens} {
+		if err := createTable(ctx, client, input); err != nil {
+			return err
+		}
+	}
+
+	described, err := client.DescribeTimeToLiveWithContext(ctx, &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(tables.Tokens),
+	})
+	if err != nil {
+		return fmt.Errorf("describing the TTL of %s: %w", tables.Tokens, err)
+	}
+	if description := described.TimeToLiveDescription; description != nil {
+		switch aws.StringValue(description.TimeToLiveStatus) {
+		case dynamodb.TimeToLiveStatusEnabled, dynamodb.TimeToLiveStatusEnabling:
+			return nil
+		}
+	}
+	_, err = client.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(tables.Tokens),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(tokenExpiry),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("enabling the TTL of %s: %w", tables.Tokens, err)
+	}
+	return nil
+}
+
+// DeleteTables deletes the tables, ignoring those that do not exist
+func DeleteTables(ctx context.Context, client dynamodbiface.DynamoDBAPI, tables Tables) error {
+	for _, table := range []string{tables.Users, tables.Tokens} {
+		_, err := client.DeleteTableWithContext(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(table)})
+		if err != nil && !hasCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+			return fmt.Errorf("deleting table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func createTable(ctx context.Context, client dynamodbiface.DynamoDBAPI, input *dynamodb.CreateTableInput) error {
+	_, err := client.CreateTableWithContext(ctx, input)
+	if err != nil && !hasCode(err, dynamodb.ErrCodeResourceInUseException) {
+		return fmt.Errorf("creating table %s: %w", aws.StringValue(input.TableName), err)
+	}
+	err = client.WaitUntilTableExistsWithContext(
+		ctx,
+		&dynamodb.DescribeTableInput{TableName: input.TableName},
+		request.WithWaiterDelay(request.ConstantWaiterDelay(time.Second)),
+	)
+	if err != nil {
+		return fmt.Errorf("waiting for table %s: %w", aws.StringValue(input.TableName), err)
+	}
+	return nil
+}
+
+// attributeDefinitions declares the string key attributes of a table and its indexes
+func attributeDefinitions(names ...string) []*dynamodb.AttributeDefinition {
+	definitions := make([]*dynamodb.AttributeDefinition, len(names))
+	for i, name := range names {
+		definitions[i] = &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(name),
+			AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+		}
+	}
+	return definitions
+}
+
+// keySchema returns a key of the partition key and, unless it is empty, the sort key
+func keySchema(partitionKey, sortKey string) []*dynamodb.KeySchemaElement {
+	schema := []*dynamodb.KeySchemaElement{
+		{AttributeName: aws.String(partitionKey), KeyType: aws.String(dynamodb.KeyTypeHash)},
+	}
+	if sortKey != "" {
+		schema = append(schema, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(sortKey),
+			KeyType:       aws.String(dynamodb.KeyTypeRange),
+		})
+	}
+	return schema
+}
+
+// hasCode reports whether err is an AWS error with the given code
+func hasCode(err error, code string) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == code
+}
@@ -0,0 +1,43 @@
+package dynamo
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// TokenBlacklist looks up the tokens blacklisted through the repository's BlacklistToken
+type TokenBlacklist struct {
+	client dynamodbiface.DynamoDBAPI
+	table  string
+}
+
+func NewTokenBlacklist(client dynamodbiface.DynamoDBAPI, tables Tables) *TokenBlacklist {
+	return &TokenBlacklist{client, tables.Tokens}
+}
+
+// IsBlacklisted reports whether the token is blacklisted and has not expired. DynamoDB deletes
+// expired tokens only some time after their expiry, so the expiry is checked here as well.
+func (blacklist *TokenBlacklist) IsBlacklisted(ctx context.Context, token string) (bool, error) {
+	output, err := blacklist.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(blacklist.table),
+		Key:            map[string]*dynamodb.AttributeValue{"token": {S: aws.String(token)}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return false, contextError(ctx, err)
+	}
+	expiry, ok := output.Item[tokenExpiry]
+	if !ok || expiry.N == nil {
+		return false, nil
+	}
+	expiresAt, err := strconv.ParseInt(*expiry.N, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return time.Now().Unix() < expiresAt, nil
+}
@@ -0,0 +1,1245 @@
+package dynamo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// maxTransactItems is the most items DynamoDB writes in one transaction
+	maxTransactItems = 100
+	// transactRetries bounds the retries of a transaction cancelled by a conflicting one
+	transactRetries = 8
+	// transactBackoff is the base delay before retrying a transaction; it doubles with every attempt
+	transactBackoff = 20 * time.Millisecond
+	// timeLayout has a fixed width, so stored times compare like the times they encode
+	timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+	usersCounter       = "users"
+	transitionsCounter = "transitions"
+	usernameClaim      = "USERNAME"
+	emailClaim         = "EMAIL"
+	userPrefix         = "USER#"
+
+	// Codes of the reasons a transaction was cancelled, one per item
+	reasonConditionFailed = "ConditionalCheckFailed"
+	reasonConflict        = "TransactionConflict"
+)
+
+// errUserChanged stands for a failed condition on the user item: it was changed, deleted or
+// restored since it was read
+var errUserChanged = errors.New("user changed concurrently")
+
+type userRepository struct {
+	client       dynamodbiface.DynamoDBAPI
+	tables       Tables
+	queryTimeout time.Duration
+}
+
+// NewUserRepository returns a repository on tables provisioned by CreateTables whose calls each
+// time out after queryTimeout, unless it is zero. StreamUsers and CreateMany are bounded by their
+// context only. Each call is atomic, but calls do not join the units of work of an
+// interfaces.TxManager.
+func NewUserRepository(client dynamodbiface.DynamoDBAPI, tables Tables, queryTimeout time.Duration) interfaces.Repository {
+	return &userRepository{client, tables, queryTimeout}
+}
+
+// userItem is a user as stored in the users table
+type userItem struct {
+	PK             string `dynamodbav:"pk"`
+	SK             string `dynamodbav:"sk"`
+	ID             int    `dynamodbav:"id"`
+	OrganizationID int    `dynamodbav:"organization_id"`
+	Name           string `dynamodbav:"name"`
+	Email          string `dynamodbav:"email"`
+	Username       string `dynamodbav:"username"`
+	Password       string `dynamodbav:"password"`
+	Status         string `dynamodbav:"status"`
+	// Role is the user's role in the organization
+	Role string `dynamodbav:"role"`
+	// Attributes holds the JSON encoded attributes, which decode like a JSONB column's
+	Attributes        string `dynamodbav:"attributes"`
+	Version           int    `dynamodbav:"version"`
+	DeletedAt         string `dynamodbav:"deleted_at,omitempty"`
+	SessionsRevokedAt string `dynamodbav:"sessions_revoked_at,omitempty"`
+	CreatedAt         string `dynamodbav:"created_at"`
+	// UsernameKey and EmailKey are set while the user is active, so only active users are indexed
+	UsernameKey string `dynamodbav:"username_key,omitempty"`
+	EmailKey    string `dynamodbav:"email_key,omitempty"`
+}
+
+// claimItem reserves a username or email of the organization for an active user
+type claimItem struct {
+	PK     string `dynamodbav:"pk"`
+	SK     string `dynamodbav:"sk"`
+	UserID int    `dynamodbav:"user_id"`
+}
+
+type transitionItem struct {
+	PK             string `dynamodbav:"pk"`
+	SK             string `dynamodbav:"sk"`
+	ID             int    `dynamodbav:"id"`
+	OrganizationID int    `dynamodbav:"organization_id"`
+	UserID         int    `dynamodbav:"user_id"`
+	FromStatus     string `dynamodbav:"from_status"`
+	ToStatus       string `dynamodbav:"to_status"`
+	Reason         string `dynamodbav:"reason"`
+	ChangedBy      *int   `dynamodbav:"changed_by,omitempty"`
+	CreatedAt      string `dynamodbav:"created_at"`
+}
+
+// write is an item of a transaction and the error a failure of its condition stands for
+type write struct {
+	item   *dynamodb.TransactWriteItem
+	failed error
+}
+
+// withTimeout bounds a call by the repository's query timeout on top of the caller's context
+func (repository *userRepository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if repository.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, repository.queryTimeout)
+}
+
+func (repository *userRepository) GetAll(ctx context.Context, organizationID int, filter interfaces.UserFilter) ([]interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	var users []interfaces.User
+	err := repository.users(ctx, organizationID, false, func(item userItem, user interfaces.User) error {
+		if matches(item, user, filter) {
+			users = append(users, user)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Search scores the filtered users with interfaces.MatchSearch, like the SQLite repository
+func (repository *userRepository) Search(
+	ctx context.Context,
+	organizationID int,
+	text string,
+	filter interfaces.UserFilter,
+	limit int,
+) ([]interfaces.UserSearchResult, error) {
+	terms, err := interfaces.SearchTerms(text)
+	if err != nil {
+		return nil, err
+	}
+	filter.Search = ""
+	users, err := repository.GetAll(ctx, organizationID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []interfaces.UserSearchResult{}
+	for _, user := range users {
+		if result, ok := interfaces.MatchSearch(user, terms); ok {
+			results = append(results, result)
+		}
+	}
+	return interfaces.RankSearchResults(results, limit), nil
+}
+
+// GetByUsername follows the username's claim, which is read consistently unlike the index
+func (repository *userRepository) GetByUsername(ctx context.Context, organizationID int, username string) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	var claim claimItem
+	found, err := repository.getItem(ctx, organizationKey(organizationID), claimKey(usernameClaim, username), &claim)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	if !found {
+		return interfaces.User{}, sql.ErrNoRows
+	}
+	item, err := repository.get(ctx, organizationID, claim.UserID)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	return item.user()
+}
+
+func (repository *userRepository) GetByID(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	item, err := repository.get(ctx, organizationID, id)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	return item.user()
+}
+
+// Create inserts the user as a member of the user's organization
+func (repository *userRepository) Create(ctx context.Context, createdUser interfaces.User) (interfaces.User, error) {
+	created, err := repository.CreateMany(ctx, []interfaces.User{createdUser})
+	if err != nil {
+		return createdUser, err
+	}
+	return created[0], nil
+}
+
+// CreateMany inserts the users with their claims. A transaction holds at most 33 users, so larger
+// batches are written in several; when one of them fails, the users written before are deleted
+// again. Readers may see those users until then.
+func (repository *userRepository) CreateMany(ctx context.Context, users []interfaces.User) ([]interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return []interfaces.User{}, nil
+	}
+
+	// A transaction cannot write an item twice, so users taken by an earlier one of the batch are
+	// rejected before anything is written
+	usernames := make(map[string]bool)
+	emails := make(map[string]bool)
+	for _, user := range users {
+		username := interfaces.NormalizeIdentity(user.Username)
+		email := interfaces.NormalizeIdentity(user.Email)
+		if usernames[username] {
+			return nil, interfaces.ErrUsernameTaken
+		}
+		if emails[email] {
+			return nil, interfaces.ErrEmailTaken
+		}
+		usernames[username] = true
+		emails[email] = true
+	}
+
+	firstID, err := repository.nextIDs(ctx, usersCounter, len(users))
+	if err != nil {
+		return nil, err
+	}
+	now := formatTime(time.Now())
+	items := make([]userItem, len(users))
+	var writes []write
+	for i, user := range users {
+		status := interfaces.StatusOf(user)
+		user.ID = firstID + i
+		user.Status = &status
+		user.Version = 1
+		item, err := newUserItem(user)
+		if err != nil {
+			return nil, err
+		}
+		item.Role = interfaces.RoleMember
+		item.CreatedAt = now
+		items[i] = item
+
+		for _, next := range []struct {
+			item   interface{}
+			failed error
+		}{
+			{item, nil},
+			{claimItem{item.PK, claimKey(usernameClaim, user.Username), user.ID}, interfaces.ErrUsernameTaken},
+			{claimItem{item.PK, claimKey(emailClaim, user.Email), user.ID}, interfaces.ErrEmailTaken},
+		} {
+			put, err := repository.putIfAbsent(next.item, next.failed)
+			if err != nil {
+				return nil, err
+			}
+			writes = append(writes, put)
+		}
+	}
+
+	// Every user takes three items, which must be written in the same transaction
+	perTransaction := maxTransactItems / 3 * 3
+	for start := 0; start < len(writes); start += perTransaction {
+		end := min(start+perTransaction, len(writes))
+		if err := repository.transact(ctx, writes[start:end]); err != nil {
+			if start > 0 {
+				repository.undoCreate(ctx, items[:start/3])
+			}
+			return nil, err
+		}
+	}
+
+	created := make([]interfaces.User, len(items))
+	for i, item := range items {
+		if created[i], err = item.user(); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+// FindExisting returns the organization's active users holding any of the usernames or emails.
+// It reads the eventually consistent indexes, which may miss users created a moment ago; their
+// claims still keep them from being taken twice.
+func (repository *userRepository) FindExisting(ctx context.Context, organizationID int, usernames, emails []string) ([]interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	found := make(map[int]interfaces.User)
+	for _, lookup := range []struct {
+		index  string
+		key    string
+		values []string
+	}{
+		{usernameIndex, "username_key", usernames},
+		{emailIndex, "email_key", emails},
+	} {
+		for _, value := range lookup.values {
+			condition := expression.Key(lookup.key).Equal(expression.Value(lookupKey(organizationID, value)))
+			built, err := expression.NewBuilder().WithKeyCondition(condition).Build()
+			if err != nil {
+				logger.Error("Error building DynamoDB expression: ", zap.Error(err))
+				return nil, err
+			}
+			input := &dynamodb.QueryInput{
+				TableName:                 aws.String(repository.tables.Users),
+				IndexName:                 aws.String(lookup.index),
+				KeyConditionExpression:    built.KeyCondition(),
+				ExpressionAttributeNames:  built.Names(),
+				ExpressionAttributeValues: built.Values(),
+			}
+			err = repository.query(ctx, input, func(item map[string]*dynamodb.AttributeValue) error {
+				var holder struct {
+					ID       int    `dynamodbav:"id"`
+					Username string `dynamodbav:"username"`
+					Email    string `dynamodbav:"email"`
+				}
+				if err := dynamodbattribute.UnmarshalMap(item, &holder); err != nil {
+					return err
+				}
+				found[holder.ID] = interfaces.User{ID: holder.ID, Username: holder.Username, Email: holder.Email}
+				return nil
+			})
+			if err != nil {
+				logger.Error("Error finding existing users:", zap.Error(err))
+				return nil, err
+			}
+		}
+	}
+
+	var users []interfaces.User
+	for _, user := range found {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+// Update writes every mutable field and moves the claims of a changed username or email. A
+// non-zero version must match the stored one; without one, changes made since the user was read
+// are retried.
+func (repository *userRepository) Update(ctx context.Context, updatedUser interfaces.User) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	attributes, err := encodeAttributes(updatedUser.Attributes)
+	if err != nil {
+		return updatedUser, err
+	}
+	for attempt := 0; ; attempt++ {
+		stored, err := repository.get(ctx, updatedUser.OrganizationID, updatedUser.ID)
+		if err != nil {
+			return updatedUser, err
+		}
+		if updatedUser.Version != 0 && updatedUser.Version != stored.Version {
+			return updatedUser, interfaces.ErrVersionConflict
+		}
+
+		updated := stored
+		updated.Name = updatedUser.Name
+		updated.Email = updatedUser.Email
+		updated.Username = updatedUser.Username
+		updated.Password = updatedUser.Password
+		updated.Attributes = attributes
+		updated.UsernameKey = lookupKey(stored.OrganizationID, updated.Username)
+		updated.EmailKey = lookupKey(stored.OrganizationID, updated.Email)
+		updated.Version++
+
+		update := expression.
+			Set(expression.Name("name"), expression.Value(updated.Name)).
+			Set(expression.Name("email"), expression.Value(updated.Email)).
+			Set(expression.Name("username"), expression.Value(updated.Username)).
+			Set(expression.Name("password"), expression.Value(updated.Password)).
+			Set(expression.Name("attributes"), expression.Value(updated.Attributes)).
+			Set(expression.Name("username_key"), expression.Value(updated.UsernameKey)).
+			Set(expression.Name("email_key"), expression.Value(updated.EmailKey)).
+			Set(expression.Name("version"), expression.Value(updated.Version))
+		writes, err := repository.updateUser(stored, update, isActive(stored))
+		if err != nil {
+			return updatedUser, err
+		}
+		if updated.UsernameKey != stored.UsernameKey {
+			moved, err := repository.moveClaim(stored, usernameClaim, stored.Username, updated.Username, interfaces.ErrUsernameTaken)
+			if err != nil {
+				return updatedUser, err
+			}
+			writes = append(writes, moved...)
+		}
+		if updated.EmailKey != stored.EmailKey {
+			moved, err := repository.moveClaim(stored, emailClaim, stored.Email, updated.Email, interfaces.ErrEmailTaken)
+			if err != nil {
+				return updatedUser, err
+			}
+			writes = append(writes, moved...)
+		}
+
+		err = repository.transact(ctx, writes)
+		if errors.Is(err, errUserChanged) {
+			if updatedUser.Version == 0 && attempt < transactRetries {
+				continue
+			}
+			return updatedUser, repository.missingOrConflict(ctx, updatedUser.OrganizationID, updatedUser.ID)
+		}
+		if err != nil {
+			return updatedUser, err
+		}
+		return updated.user()
+	}
+}
+
+// missingOrConflict tells a user that no longer exists from one changed since it was read
+func (repository *userRepository) missingOrConflict(ctx context.Context, organizationID, id int) error {
+	if _, err := repository.get(ctx, organizationID, id); err != nil {
+		return err
+	}
+	return interfaces.ErrVersionConflict
+}
+
+// Delete soft-deletes the user, frees their username and email, moves them to the deleted status
+// and revokes every session issued to them so far. A non-zero version must match the stored one.
+func (repository *userRepository) Delete(ctx context.Context, organizationID, id, version int) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	stored, err := repository.get(ctx, organizationID, id)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	if version != 0 && stored.Version != version {
+		deletedUser, err := stored.user()
+		if err != nil {
+			return deletedUser, err
+		}
+		return deletedUser, interfaces.ErrVersionConflict
+	}
+
+	now := time.Now()
+	deleted := stored
+	deleted.Status = interfaces.UserStatusDeleted
+	deleted.DeletedAt = formatTime(now)
+	deleted.SessionsRevokedAt = deleted.DeletedAt
+	deleted.UsernameKey = ""
+	deleted.EmailKey = ""
+	deleted.Version++
+
+	update := expression.
+		Set(expression.Name("status"), expression.Value(deleted.Status)).
+		Set(expression.Name("deleted_at"), expression.Value(deleted.DeletedAt)).
+		Set(expression.Name("sessions_revoked_at"), expression.Value(deleted.SessionsRevokedAt)).
+		Set(expression.Name("version"), expression.Value(deleted.Version)).
+		Remove(expression.Name("username_key")).
+		Remove(expression.Name("email_key"))
+	writes, err := repository.updateUser(stored, update, true)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	writes = append(writes,
+		repository.deleteItem(stored.PK, claimKey(usernameClaim, stored.Username)),
+		repository.deleteItem(stored.PK, claimKey(emailClaim, stored.Email)),
+	)
+	recorded, err := repository.recordTransition(ctx, interfaces.StatusTransition{
+		OrganizationID: organizationID,
+		UserID:         id,
+		FromStatus:     stored.Status,
+		ToStatus:       interfaces.UserStatusDeleted,
+	})
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	writes = append(writes, recorded)
+
+	if err = repository.transact(ctx, writes); err != nil {
+		if errors.Is(err, errUserChanged) {
+			err = repository.missingOrConflict(ctx, organizationID, id)
+		}
+		return interfaces.User{}, err
+	}
+	deletedUser, err := deleted.user()
+	if err != nil {
+		return deletedUser, err
+	}
+	deletedUser.DeletedAt = &now
+	return deletedUser, nil
+}
+
+// GetDeleted lists the soft-deleted users of the organization, most recently deleted first
+func (repository *userRepository) GetDeleted(ctx context.Context, organizationID int) ([]interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	users := []interfaces.User{}
+	err := repository.users(ctx, organizationID, true, func(_ userItem, user interfaces.User) error {
+		users = append(users, user)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].DeletedAt.After(*users[j].DeletedAt) })
+	return users, nil
+}
+
+// StreamUsers passes the organization's active users matching the filter to fn in ID order,
+// without passwords or attributes. Users are read a page at a time, so fn sees each page as it
+// was when it was read rather than a snapshot of the whole organization.
+func (repository *userRepository) StreamUsers(
+	ctx context.Context,
+	organizationID int,
+	filter interfaces.UserFilter,
+	fn func(interfaces.User) error,
+) error {
+	return repository.users(ctx, organizationID, false, func(item userItem, user interfaces.User) error {
+		if !matches(item, user, filter) {
+			return nil
+		}
+		user.Password = ""
+		user.Attributes = nil
+		return fn(user)
+	})
+}
+
+// Restore returns a soft-deleted user to the status it had before the deletion, unless another
+// user took their username or email since. Sessions revoked by the deletion stay revoked.
+func (repository *userRepository) Restore(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	var stored userItem
+	found, err := repository.getItem(ctx, organizationKey(organizationID), userKey(id), &stored)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	if !found || isActive(stored) {
+		return interfaces.User{}, sql.ErrNoRows
+	}
+	history, err := repository.GetStatusHistory(ctx, organizationID, id)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+
+	restored := stored
+	restored.Status = interfaces.UserStatusActive
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].ToStatus == interfaces.UserStatusDeleted {
+			restored.Status = history[i].FromStatus
+			break
+		}
+	}
+	restored.DeletedAt = ""
+	restored.UsernameKey = lookupKey(organizationID, stored.Username)
+	restored.EmailKey = lookupKey(organizationID, stored.Email)
+	restored.Version++
+
+	update := expression.
+		Set(expression.Name("status"), expression.Value(restored.Status)).
+		Set(expression.Name("username_key"), expression.Value(restored.UsernameKey)).
+		Set(expression.Name("email_key"), expression.Value(restored.EmailKey)).
+		Set(expression.Name("version"), expression.Value(restored.Version)).
+		Remove(expression.Name("deleted_at"))
+	writes, err := repository.updateUser(stored, update, false)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	for _, next := range []struct {
+		claim  claimItem
+		failed error
+	}{
+		{claimItem{stored.PK, claimKey(usernameClaim, stored.Username), id}, interfaces.ErrUsernameTaken},
+		{claimItem{stored.PK, claimKey(emailClaim, stored.Email), id}, interfaces.ErrEmailTaken},
+	} {
+		put, err := repository.putIfAbsent(next.claim, next.failed)
+		if err != nil {
+			return interfaces.User{}, err
+		}
+		writes = append(writes, put)
+	}
+	recorded, err := repository.recordTransition(ctx, interfaces.StatusTransition{
+		OrganizationID: organizationID,
+		UserID:         id,
+		FromStatus:     interfaces.UserStatusDeleted,
+		ToStatus:       restored.Status,
+	})
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	writes = append(writes, recorded)
+
+	if err = repository.transact(ctx, writes); err != nil {
+		// The user was restored or purged in the meantime
+		if errors.Is(err, errUserChanged) {
+			return interfaces.User{}, sql.ErrNoRows
+		}
+		return interfaces.User{}, err
+	}
+	return restored.user()
+}
+
+// ChangeStatus updates the status only while it still equals transition.FromStatus, so two
+// concurrent changes cannot both apply. Leaving the active status revokes the user's sessions.
+func (repository *userRepository) ChangeStatus(ctx context.Context, transition interfaces.StatusTransition) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	stored, err := repository.get(ctx, transition.OrganizationID, transition.UserID)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	if stored.Status != transition.FromStatus {
+		return interfaces.User{}, interfaces.ErrVersionConflict
+	}
+
+	changed := stored
+	changed.Status = transition.ToStatus
+	changed.Version++
+	update := expression.
+		Set(expression.Name("status"), expression.Value(changed.Status)).
+		Set(expression.Name("version"), expression.Value(changed.Version))
+	if transition.FromStatus == interfaces.UserStatusActive {
+		changed.SessionsRevokedAt = formatTime(time.Now())
+		update = update.Set(expression.Name("sessions_revoked_at"), expression.Value(changed.SessionsRevokedAt))
+	}
+	writes, err := repository.updateUser(stored, update, true)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	recorded, err := repository.recordTransition(ctx, transition)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	writes = append(writes, recorded)
+
+	if err = repository.transact(ctx, writes); err != nil {
+		if errors.Is(err, errUserChanged) {
+			err = repository.missingOrConflict(ctx, transition.OrganizationID, transition.UserID)
+		}
+		return interfaces.User{}, err
+	}
+	return changed.user()
+}
+
+// GetStatusHistory lists the user's status transitions, oldest first
+func (repository *userRepository) GetStatusHistory(ctx context.Context, organizationID, userID int) ([]interfaces.StatusTransition, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	input, err := repository.prefixQuery(organizationKey(organizationID), historyPrefix(userID))
+	if err != nil {
+		return nil, err
+	}
+	history := []interfaces.StatusTransition{}
+	err = repository.query(ctx, input, func(item map[string]*dynamodb.AttributeValue) error {
+		var stored transitionItem
+		if err := dynamodbattribute.UnmarshalMap(item, &stored); err != nil {
+			return err
+		}
+		createdAt, err := time.Parse(timeLayout, stored.CreatedAt)
+		if err != nil {
+			return err
+		}
+		history = append(history, interfaces.StatusTransition{
+			ID:             stored.ID,
+			OrganizationID: stored.OrganizationID,
+			UserID:         stored.UserID,
+			FromStatus:     stored.FromStatus,
+			ToStatus:       stored.ToStatus,
+			Reason:         stored.Reason,
+			ChangedBy:      stored.ChangedBy,
+			CreatedAt:      createdAt,
+		})
+		return nil
+	})
+	if err != nil {
+		logger.Error("Error retrieving status history:", zap.Int("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	return history, nil
+}
+
+// Purge permanently removes users soft-deleted before the given time, across all organizations,
+// with their status history. It scans the whole table, which suits a periodic job. Transitions
+// made by a purged user keep their changed_by.
+func (repository *userRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	cutoff := expression.Value(formatTime(deletedBefore))
+	filter := expression.Name("sk").BeginsWith(userPrefix).And(expression.Name("deleted_at").LessThan(cutoff))
+	built, err := expression.NewBuilder().WithFilter(filter).Build()
+	if err != nil {
+		logger.Error("Error building DynamoDB expression: ", zap.Error(err))
+		return nil, err
+	}
+	input := &dynamodb.ScanInput{
+		TableName:                 aws.String(repository.tables.Users),
+		ConsistentRead:            aws.Bool(true),
+		FilterExpression:          built.Filter(),
+		ExpressionAttributeNames:  built.Names(),
+		ExpressionAttributeValues: built.Values(),
+	}
+
+	var purged []interfaces.User
+	for {
+		output, err := repository.client.ScanWithContext(ctx, input)
+		if err != nil {
+			logger.Error("Error scanning for deleted users:", zap.Error(err))
+			return purged, contextError(ctx, err)
+		}
+		for _, item := range output.Items {
+			var stored userItem
+			if err := dynamodbattribute.UnmarshalMap(item, &stored); err != nil {
+				return purged, err
+			}
+			removed, err := repository.purgeUser(ctx, stored, built)
+			if err != nil {
+				logger.Error("Error purging deleted user:", zap.Int("userID", stored.ID), zap.Error(err))
+				return purged, err
+			}
+			if removed {
+				purged = append(purged, interfaces.User{ID: stored.ID, OrganizationID: stored.OrganizationID, Username: stored.Username})
+			}
+		}
+		if len(output.LastEvaluatedKey) == 0 {
+			return purged, nil
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// purgeUser deletes the user, unless it was restored since the scan, and then its history
+func (repository *userRepository) purgeUser(ctx context.Context, stored userItem, deletedBefore expression.Expression) (bool, error) {
+	_, err := repository.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(repository.tables.Users),
+		Key:                       itemKey(stored.PK, stored.SK),
+		ConditionExpression:       deletedBefore.Filter(),
+		ExpressionAttributeNames:  deletedBefore.Names(),
+		ExpressionAttributeValues: deletedBefore.Values(),
+	})
+	if hasCode(err, dynamodb.ErrCodeConditionalCheckFailedException) {
+		return false, nil
+	}
+	if err != nil {
+		return false, contextError(ctx, err)
+	}
+
+	input, err := repository.prefixQuery(stored.PK, historyPrefix(stored.ID))
+	if err != nil {
+		return true, err
+	}
+	err = repository.query(ctx, input, func(item map[string]*dynamodb.AttributeValue) error {
+		_, err := repository.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(repository.tables.Users),
+			Key:       map[string]*dynamodb.AttributeValue{"pk": item["pk"], "sk": item["sk"]},
+		})
+		return contextError(ctx, err)
+	})
+	return true, err
+}
+
+// GenerateHashFromPassword hashes like the PostgreSQL repository
+func (repository *userRepository) GenerateHashFromPassword(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	return string(bytes), err
+}
+
+// BlacklistToken revokes a given token of the user until its expiration, after which DynamoDB
+// removes it
+func (repository *userRepository) BlacklistToken(ctx context.Context, userID int, token string, expiry time.Time) error {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	_, err := repository.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(repository.tables.Tokens),
+		Item: map[string]*dynamodb.AttributeValue{
+			"token":     {S: aws.String(token)},
+			"user_id":   {N: aws.String(strconv.Itoa(userID))},
+			tokenExpiry: {N: aws.String(strconv.FormatInt(expiry.Unix(), 10))},
+		},
+	})
+	if err != nil {
+		logger.Error("Error blacklisting token:", zap.Int("userID", userID), zap.Error(err))
+		return contextError(ctx, err)
+	}
+	return nil
+}
+
+// Evict does nothing, since the repository keeps no copies of the users it returns
+func (repository *userRepository) Evict(context.Context, ...interfaces.User) {}
+
+// get returns the organization's active user, or sql.ErrNoRows
+func (repository *userRepository) get(ctx context.Context, organizationID, id int) (userItem, error) {
+	var item userItem
+	found, err := repository.getItem(ctx, organizationKey(organizationID), userKey(id), &item)
+	if err != nil {
+		return item, err
+	}
+	if !found || !isActive(item) {
+		return userItem{}, sql.ErrNoRows
+	}
+	return item, nil
+}
+
+// getItem reads an item of the users table consistently into out and reports whether it exists
+func (repository *userRepository) getItem(ctx context.Context, pk, sk string, out interface{}) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	output, err := repository.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(repository.tables.Users),
+		Key:            itemKey(pk, sk),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		logger.Error("Error reading DynamoDB item:", zap.String("sk", sk), zap.Error(err))
+		return false, contextError(ctx, err)
+	}
+	if len(output.Item) == 0 {
+		return false, nil
+	}
+	return true, dynamodbattribute.UnmarshalMap(output.Item, out)
+}
+
+// users passes the organization's active users, or its soft-deleted ones, to fn in ID order
+func (repository *userRepository) users(
+	ctx context.Context,
+	organizationID int,
+	deleted bool,
+	fn func(item userItem, user interfaces.User) error,
+) error {
+	filter := expression.AttributeNotExists(expression.Name("deleted_at"))
+	if deleted {
+		filter = expression.AttributeExists(expression.Name("deleted_at"))
+	}
+	condition := expression.Key("pk").Equal(expression.Value(organizationKey(organizationID))).
+		And(expression.KeyBeginsWith(expression.Key("sk"), userPrefix))
+	built, err := expression.NewBuilder().WithKeyCondition(condition).WithFilter(filter).Build()
+	if err != nil {
+		logger.Error("Error building DynamoDB expression: ", zap.Error(err))
+		return err
+	}
+	input := &dynamodb.QueryInput{
+		TableName:                 aws.String(repository.tables.Users),
+		ConsistentRead:            aws.Bool(true),
+		KeyConditionExpression:    built.KeyCondition(),
+		FilterExpression:          built.Filter(),
+		ExpressionAttributeNames:  built.Names(),
+		ExpressionAttributeValues: built.Values(),
+	}
+
+	err = repository.query(ctx, input, func(stored map[string]*dynamodb.AttributeValue) error {
+		var item userItem
+		if err := dynamodbattribute.UnmarshalMap(stored, &item); err != nil {
+			return err
+		}
+		user, err := item.user()
+		if err != nil {
+			return err
+		}
+		return fn(item, user)
+	})
+	if err != nil && ctx.Err() == nil {
+		logger.Error("Error retrieving users:", zap.Int("organizationID", organizationID), zap.Error(err))
+	}
+	return err
+}
+
+// prefixQuery reads the items of a partition whose sort key starts with prefix, in order
+func (repository *userRepository) prefixQuery(pk, prefix string) (*dynamodb.QueryInput, error) {
+	condition := expression.Key("pk").Equal(expression.Value(pk)).
+		And(expression.KeyBeginsWith(expression.Key("sk"), prefix))
+	built, err := expression.NewBuilder().WithKeyCondition(condition).Build()
+	if err != nil {
+		logger.Error("Error building DynamoDB expression: ", zap.Error(err))
+		return nil, err
+	}
+	return &dynamodb.QueryInput{
+		TableName:                 aws.String(repository.tables.Users),
+		ConsistentRead:            aws.Bool(true),
+		KeyConditionExpression:    built.KeyCondition(),
+		ExpressionAttributeNames:  built.Names(),
+		ExpressionAttributeValues: built.Values(),
+	}, nil
+}
+
+// query passes every item the query finds to fn, page by page
+func (repository *userRepository) query(
+	ctx context.Context,
+	input *dynamodb.QueryInput,
+	fn func(item map[string]*dynamodb.AttributeValue) error,
+) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		output, err := repository.client.QueryWithContext(ctx, input)
+		if err != nil {
+			return contextError(ctx, err)
+		}
+		for _, item := range output.Items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(output.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// nextIDs reserves count consecutive IDs of the counter and returns the first. Like the IDs of a
+// PostgreSQL sequence, those of failed writes are not handed out again.
+func (repository *userRepository) nextIDs(ctx context.Context, counter string, count int) (int, error) {
+	built, err := expression.NewBuilder().
+		WithUpdate(expression.Add(expression.Name("value"), expression.Value(count))).
+		Build()
+	if err != nil {
+		logger.Error("Error building DynamoDB expression: ", zap.Error(err))
+		return 0, err
+	}
+	output, err := repository.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(repository.tables.Users),
+		Key:                       itemKey("COUNTER", counter),
+		UpdateExpression:          built.Update(),
+		ExpressionAttributeNames:  built.Names(),
+		ExpressionAttributeValues: built.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueUpdatedNew),
+	})
+	if err != nil {
+		logger.Error("Error allocating IDs:", zap.String("counter", counter), zap.Error(err))
+		return 0, contextError(ctx, err)
+	}
+	var allocated struct {
+		Value int `dynamodbav:"value"`
+	}
+	if err := dynamodbattribute.UnmarshalMap(output.Attributes, &allocated); err != nil {
+		return 0, err
+	}
+	return allocated.Value - count + 1, nil
+}
+
+// recordTransition returns the write recording a status change in the transaction of the change
+func (repository *userRepository) recordTransition(ctx context.Context, transition interfaces.StatusTransition) (write, error) {
+	id, err := repository.nextIDs(ctx, transitionsCounter, 1)
+	if err != nil {
+		return write{}, err
+	}
+	return repository.putIfAbsent(transitionItem{
+		PK:             organizationKey(transition.OrganizationID),
+		SK:             fmt.Sprintf("%s%010d", historyPrefix(transition.UserID), id),
+		ID:             id,
+		OrganizationID: transition.OrganizationID,
+		UserID:         transition.UserID,
+		FromStatus:     transition.FromStatus,
+		ToStatus:       transition.ToStatus,
+		Reason:         transition.Reason,
+		ChangedBy:      transition.ChangedBy,
+		CreatedAt:      formatTime(time.Now()),
+	}, nil)
+}
+
+// updateUser returns the write applying update to the user while it is at the stored version, and
+// still active or still deleted
+func (repository *userRepository) updateUser(stored userItem, update expression.UpdateBuilder, active bool) ([]write, error) {
+	condition := expression.Name("version").Equal(expression.Value(stored.Version))
+	if active {
+		condition = condition.And(expression.AttributeNotExists(expression.Name("deleted_at")))
+	} else {
+		condition = condition.And(expression.AttributeExists(expression.Name("deleted_at")))
+	}
+	built, err := expression.NewBuilder().WithUpdate(update).WithCondition(condition).Build()
+	if err != nil {
+		logger.Error("Error building DynamoDB expression: ", zap.Error(err))
+		return nil, err
+	}
+	return []write{{
+		item: &dynamodb.TransactWriteItem{Update: &dynamodb.Update{
+			TableName:                 aws.String(repository.tables.Users),
+			Key:                       itemKey(stored.PK, stored.SK),
+			UpdateExpression:          built.Update(),
+			ConditionExpression:       built.Condition(),
+			ExpressionAttributeNames:  built.Names(),
+			ExpressionAttributeValues: built.Values(),
+		}},
+		failed: errUserChanged,
+	}}, nil
+}
+
+// moveClaim returns the writes handing the user's claim on a username or email over to a new one
+func (repository *userRepository) moveClaim(stored userItem, kind, from, to string, taken error) ([]write, error) {
+	put, err := repository.putIfAbsent(claimItem{stored.PK, claimKey(kind, to), stored.ID}, taken)
+	if err != nil {
+		return nil, err
+	}
+	return []write{put, repository.deleteItem(stored.PK, claimKey(kind, from))}, nil
+}
+
+// putIfAbsent returns the write of an item whose key must not exist yet; failed is the error of
+// an existing one
+func (repository *userRepository) putIfAbsent(item interface{}, failed error) (write, error) {
+	encoded, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return write{}, err
+	}
+	built, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name("pk"))).
+		Build()
+	if err != nil {
+		logger.Error("Error building DynamoDB expression: ", zap.Error(err))
+		return write{}, err
+	}
+	return write{
+		item: &dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+			TableName:                aws.String(repository.tables.Users),
+			Item:                     encoded,
+			ConditionExpression:      built.Condition(),
+			ExpressionAttributeNames: built.Names(),
+		}},
+		failed: failed,
+	}, nil
+}
+
+func (repository *userRepository) deleteItem(pk, sk string) write {
+	return write{item: &dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+		TableName: aws.String(repository.tables.Users),
+		Key:       itemKey(pk, sk),
+	}}}
+}
+
+// transact runs the writes as one transaction. When it is cancelled by a failed condition, the
+// error of the first such write is returned; transactions cancelled by a conflicting one are
+// retried with backoff.
+func (repository *userRepository) transact(ctx context.Context, writes []write) error {
+	items := make([]*dynamodb.TransactWriteItem, len(writes))
+	for i, next := range writes {
+		items[i] = next.item
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_, err := repository.client.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: items,
+		})
+		var cancelled *dynamodb.TransactionCanceledException
+		if !errors.As(err, &cancelled) {
+			if err != nil {
+				logger.Error("Error writing DynamoDB transaction:", zap.Error(err))
+			}
+			return contextError(ctx, err)
+		}
+
+		conflicted := false
+		for i, reason := range cancelled.CancellationReasons {
+			switch aws.StringValue(reason.Code) {
+			case reasonConditionFailed:
+				if i < len(writes) && writes[i].failed != nil {
+					return writes[i].failed
+				}
+			case reasonConflict:
+				conflicted = true
+			}
+		}
+		if !conflicted || attempt >= transactRetries {
+			logger.Error("Error writing DynamoDB transaction:", zap.Error(err))
+			return err
+		}
+
+		// Jitter keeps the conflicting transactions from colliding again
+		backoff := transactBackoff << attempt
+		backoff += rand.N(backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// undoCreate deletes users whose creation was committed before a later part of their batch failed
+func (repository *userRepository) undoCreate(ctx context.Context, items []userItem) {
+	// The deletions must run even when the batch failed because its context ended
+	ctx = context.WithoutCancel(ctx)
+	var writes []write
+	for _, item := range items {
+		writes = append(writes,
+			repository.deleteItem(item.PK, item.SK),
+			repository.deleteItem(item.PK, claimKey(usernameClaim, item.Username)),
+			repository.deleteItem(item.PK, claimKey(emailClaim, item.Email)),
+		)
+	}
+	for start := 0; start < len(writes); start += maxTransactItems {
+		end := min(start+maxTransactItems, len(writes))
+		if err := repository.transact(ctx, writes[start:end]); err != nil {
+			logger.Error("Error deleting users of a failed batch:", zap.Error(err))
+		}
+	}
+}
+
+// newUserItem returns the item of an active user
+func newUserItem(user interfaces.User) (userItem, error) {
+	attributes, err := encodeAttributes(user.Attributes)
+	if err != nil {
+		return userItem{}, err
+	}
+	return userItem{
+		PK:             organizationKey(user.OrganizationID),
+		SK:             userKey(user.ID),
+		ID:             user.ID,
+		OrganizationID: user.OrganizationID,
+		Name:           user.Name,
+		Email:          user.Email,
+		Username:       user.Username,
+		Password:       user.Password,
+		Status:         interfaces.StatusOf(user),
+		Attributes:     attributes,
+		Version:        user.Version,
+		UsernameKey:    lookupKey(user.OrganizationID, user.Username),
+		EmailKey:       lookupKey(user.OrganizationID, user.Email),
+	}, nil
+}
+
+func (item userItem) user() (interfaces.User, error) {
+	status := item.Status
+	user := interfaces.User{
+		ID:             item.ID,
+		OrganizationID: item.OrganizationID,
+		Name:           item.Name,
+		Email:          item.Email,
+		Status:         &status,
+		Username:       item.Username,
+		Password:       item.Password,
+		Version:        item.Version,
+		Attributes:     map[string]interface{}{},
+	}
+	if item.Attributes != "" {
+		if err := json.Unmarshal([]byte(item.Attributes), &user.Attributes); err != nil {
+			return user, err
+		}
+	}
+	if item.DeletedAt != "" {
+		deletedAt, err := time.Parse(timeLayout, item.DeletedAt)
+		if err != nil {
+			return user, err
+		}
+		user.DeletedAt = &deletedAt
+	}
+	return user, nil
+}
+
+func isActive(item userItem) bool {
+	return item.DeletedAt == ""
+}
+
+// matches applies the filter like filterUsers does in SQL
+func matches(item userItem, user interfaces.User, filter interfaces.UserFilter) bool {
+	if len(filter.Statuses) > 0 && !contains(filter.Statuses, item.Status) {
+		return false
+	}
+	if filter.Role != "" && item.Role != filter.Role {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(user.Name), search) &&
+			!strings.Contains(strings.ToLower(user.Email), search) &&
+			!strings.Contains(strings.ToLower(user.Username), search) {
+			return false
+		}
+	}
+	if len(filter.Attributes) > 0 {
+		var wanted map[string]interface{}
+		encoded, err := encodeAttributes(filter.Attributes)
+		if err != nil || json.Unmarshal([]byte(encoded), &wanted) != nil {
+			return false
+		}
+		return interfaces.AttributesContain(user.Attributes, wanted)
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeAttributes stores nil attributes as an empty object, like the attributes column's default
+func encodeAttributes(attributes map[string]interface{}) (string, error) {
+	if attributes == nil {
+		return "{}", nil
+	}
+	encoded, err := json.Marshal(attributes)
+	return string(encoded), err
+}
+
+// contextError reports a call the SDK gave up on because its context ended as the context's error
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func itemKey(pk, sk string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{"pk": {S: aws.String(pk)}, "sk": {S: aws.String(sk)}}
+}
+
+func organizationKey(organizationID int) string {
+	return fmt.Sprintf("ORG#%d", organizationID)
+}
+
+func userKey(id int) string {
+	return fmt.Sprintf("%s%010d", userPrefix, id)
+}
+
+func historyPrefix(userID int) string {
+	return fmt.Sprintf("HISTORY#%010d#", userID)
+}
+
+func claimKey(kind, value string) string {
+	return kind + "#" + interfaces.NormalizeIdentity(value)
+}
+
+// lookupKey is the key of the username and email indexes
+func lookupKey(organizationID int, value string) string {
+	return fmt.Sprintf("%d#%s", organizationID, interfaces.NormalizeIdentity(value))
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type groupRepository struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewGroupRepository(db *sql.DB) interfaces.GroupRepository {
+	return &groupRepository{db, dialectOf(db)}
+}
+
+func (repository *groupRepository) selectGroups() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"g.id",
+			"g.organization_id",
+			"g.name",
+			"g.description",
+			"g.parent_id",
+			"g.created_at",
+			repository.dialect.roleList(),
+		).
+		From("user_groups g").
+		LeftJoin("user_group_roles r ON r.group_id = g.id").
+		GroupBy("g.id").
+		PlaceholderFormat(repository.dialect.placeholders)
+}
+
+func (repository *groupRepository) GetAll(ctx context.Context, organizationID int) ([]interfaces.Group, error) {
+	var groups []interfaces.Group
+	rows, err := repository.selectGroups().
+		Where(squirrel.Eq{"g.organization_id": organizationID}).
+		OrderBy("g.id").
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building group query:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			logger.Error("Error scanning group row:", zap.Error(err))
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+func (repository *groupRepository) GetByID(ctx context.Context, organizationID, id int) (interfaces.Group, error) {
+	query, args, err := repository.selectGroups().
+		Where(squirrel.Eq{"g.organization_id": organizationID, "g.id": id}).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return interfaces.Group{}, err
+	}
+
+	group, err := scanGroup(conn(ctx, repository.db).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("Group not found", zap.Int("groupID", id))
+			return group, err
+		}
+		logger.Error("Error retrieving group:", zap.Int("groupID", id), zap.Error(err))
+		return group, err
+	}
+	return group, nil
+}
+
+func (repository *groupRepository) Create(ctx context.Context, group interfaces.Group) (interfaces.Group, error) {
+	query, args, err := squirrel.Insert("user_groups").
+		Columns("organization_id", "name", "description", "parent_id").
+		Values(group.OrganizationID, group.Name, group.Description, group.ParentID).
+		Suffix("RETURNING id").
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return group, err
+	}
+
+	if err = conn(ctx, repository.db).QueryRowContext(ctx, query, args...).Scan(&group.ID); err != nil {
+		logger.Error("Error creating group:", zap.Error(err))
+		return group, err
+	}
+	return repository.GetByID(ctx, group.OrganizationID, group.ID)
+}
+
+func (repository *groupRepository) Update(ctx context.Context, group interfaces.Group) (interfaces.Group, error) {
+	query, args, err := squirrel.Update("user_groups").
+		Set("name", group.Name).
+		Set("description", group.Description).
+		Set("parent_id", group.ParentID).
+		Where(squirrel.Eq{"organization_id": group.OrganizationID, "id": group.ID}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return group, err
+	}
+
+	if _, err = conn(ctx, repository.db).ExecContext(ctx, query, args...); err != nil {
+		logger.Error("Error updating group:", zap.Error(err))
+		return group, err
+	}
+	return repository.GetByID(ctx, group.OrganizationID, group.ID)
+}
+
+func (repository *groupRepository) Delete(ctx context.Context, organizationID, id int) error {
+	query, args, err := squirrel.Delete("user_groups").
+		Where(squirrel.Eq{"organization_id": organizationID, "id": id}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return err
+	}
+	return execAffectingRowContext(ctx, conn(ctx, repository.db), query, args...)
+}
+
+// GetParents locks the organization's groups, so concurrent changes to the hierarchy take turns
+func (repository *groupRepository) GetParents(ctx context.Context, organizationID int) (map[int]*int, error) {
+	query, args, err := squirrel.
+		Select("id", "parent_id").
+		From("user_groups").
+		Where(squirrel.Eq{"organization_id": organizationID}).
+		OrderBy("id").
+		Suffix(strings.TrimSpace(repository.dialect.forUpdate())).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := conn(ctx, repository.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("Error retrieving group hierarchy:", zap.Int("organizationID", organizationID), zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	parents := make(map[int]*int)
+	for rows.Next() {
+		var (
+			id       int
+			parentID sql.NullInt64
+		)
+		if err := rows.Scan(&id, &parentID); err != nil {
+			logger.Error("Error scanning group row:", zap.Error(err))
+			return nil, err
+		}
+		var parent *int
+		if parentID.Valid {
+			parentOf := int(parentID.Int64)
+			parent = &parentOf
+		}
+		parents[id] = parent
+	}
+	return parents, rows.Err()
+}
+
+func (repository *groupRepository) GetMembers(
+	ctx context.Context,
+	organizationID int,
+	groupIDs ...int,
+) ([]interfaces.GroupMember, error) {
+	var members []interfaces.GroupMember
+	rows, err := squirrel.
+		Select("m.group_id", "m.user_id", "m.created_at").
+		From("user_group_members m").
+		Join("user_groups g ON g.id = m.group_id").
+		Where(squirrel.Eq{"g.organization_id": organizationID, "m.group_id": groupIDs}).
+		OrderBy("m.group_id", "m.user_id").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building group member query:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		var member interfaces.GroupMember
+		if err := rows.Scan(&member.GroupID, &member.UserID, &member.CreatedAt); err != nil {
+			logger.Error("Error scanning group member row:", zap.Error(err))
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (repository *groupRepository) GetGroupIDsForUser(ctx context.Context, organizationID, userID int) ([]int, error) {
+	var groupIDs []int
+	rows, err := squirrel.
+		Select("m.group_id").
+		From("user_group_members m").
+		Join("user_groups g ON g.id = m.group_id").
+		Where(squirrel.Eq{"g.organization_id": organizationID, "m.user_id": userID}).
+		OrderBy("m.group_id").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building group membership query:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		var groupID int
+		if err := rows.Scan(&groupID); err != nil {
+			logger.Error("Error scanning group membership row:", zap.Error(err))
+			return nil, err
+		}
+		groupIDs = append(groupIDs, groupID)
+	}
+	return groupIDs, rows.Err()
+}
+
+// AddMember only inserts when the group belongs to the organization
+func (repository *groupRepository) AddMember(
+	ctx context.Context,
+	organizationID, groupID, userID int,
+) (interfaces.GroupMember, error) {
+	member := interfaces.GroupMember{GroupID: groupID, UserID: userID}
+	err := conn(ctx, repository.db).QueryRowContext(
+		ctx,
+		`INSERT INTO user_group_members (group_id, user_id)
+		SELECT id, $2 FROM user_groups WHERE id = $1 AND organization_id = $3
+		RETURNING created_at`,
+		groupID, userID, organizationID,
+	).Scan(&member.CreatedAt)
+	if err != nil {
+		logger.Error("Error adding group member:", zap.Int("groupID", groupID), zap.Error(err))
+		return member, err
+	}
+	return member, nil
+}
+
+func (repository *groupRepository) RemoveMember(ctx context.Context, organizationID, groupID, userID int) error {
+	return execAffectingRowContext(
+		ctx,
+		conn(ctx, repository.db),
+		`DELETE FROM user_group_members
+		WHERE group_id = $2 AND user_id = $3
+			AND group_id IN (SELECT id FROM user_groups WHERE organization_id = $1)`,
+		organizationID, groupID, userID,
+	)
+}
+
+func (repository *groupRepository) AssignRole(ctx context.Context, organizationID, groupID int, role string) error {
+	_, err := conn(ctx, repository.db).ExecContext(
+		ctx,
+		`INSERT INTO user_group_roles (group_id, role)
+		SELECT id, $2 FROM user_groups WHERE id = $1 AND organization_id = $3
+		ON CONFLICT DO NOTHING`,
+		groupID, role, organizationID,
+	)
+	if err != nil {
+		logger.Error("Error assigning group role:", zap.Int("groupID", groupID), zap.Error(err))
+	}
+	return err
+}
+
+func (repository *groupRepository) UnassignRole(ctx context.Context, organizationID, groupID int, role string) error {
+	return execAffectingRowContext(
+		ctx,
+		conn(ctx, repository.db),
+		`DELETE FROM user_group_roles
+		WHERE group_id = $2 AND role = $3
+			AND group_id IN (SELECT id FROM user_groups WHERE organization_id = $1)`,
+		organizationID, groupID, role,
+	)
+}
+
+func scanGroup(row squirrel.RowScanner) (interfaces.Group, error) {
+	var group interfaces.Group
+	var parentID sql.NullInt64
+	err := row.Scan(
+		&group.ID,
+		&group.OrganizationID,
+		&group.Name,
+		&group.Description,
+		&parentID,
+		&group.CreatedAt,
+		stringsColumn(&group.Roles),
+	)
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		group.ParentID = &id
+	}
+	return group, err
+}
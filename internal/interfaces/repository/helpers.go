@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func closeRows(rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		logger.Error("Error closing rows:", zap.Error(err))
+	}
+}
+
+func rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		logger.Error("Error rolling back transaction:", zap.Error(err))
+	}
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// execAffectingRowContext runs a write bound to ctx and reports sql.ErrNoRows when nothing matched
+func execAffectingRowContext(ctx context.Context, db execer, query string, args ...interface{}) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("Error executing statement:", zap.Error(err))
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a unique or primary key constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY)
+}
+
+// userConflict maps a unique violation on the users table to the error naming the field that is
+// taken, and returns nil for any other error
+func userConflict(err error) error {
+	if !isUniqueViolation(err) {
+		return nil
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Constraint {
+		case "idx_users_organization_username":
+			return interfaces.ErrUsernameTaken
+		case "idx_users_organization_email":
+			return interfaces.ErrEmailTaken
+		}
+		return interfaces.ErrUserConflict
+	}
+	// SQLite names the indexed columns rather than the index, e.g.
+	// "UNIQUE constraint failed: users.organization_id, users.username_normalized"
+	switch message := err.Error(); {
+	case strings.Contains(message, "users.username_normalized"):
+		return interfaces.ErrUsernameTaken
+	case strings.Contains(message, "users.email_normalized"):
+		return interfaces.ErrEmailTaken
+	}
+	return interfaces.ErrUserConflict
+}
+
+// attributesColumn scans a JSONB object column into attributes, leaving an empty map for NULL
+func attributesColumn(attributes *map[string]interface{}) sql.Scanner {
+	return attributesScanner{attributes}
+}
+
+type attributesScanner struct {
+	attributes *map[string]interface{}
+}
+
+func (scanner attributesScanner) Scan(src interface{}) error {
+	*scanner.attributes = map[string]interface{}{}
+	switch data := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(data, scanner.attributes)
+	case string:
+		return json.Unmarshal([]byte(data), scanner.attributes)
+	}
+	return fmt.Errorf("cannot scan %T into attributes", src)
+}
+
+// attributesValue encodes attributes for a JSONB parameter. It is a string because lib/pq
+// sends []byte as bytea.
+func attributesValue(attributes map[string]interface{}) (string, error) {
+	if attributes == nil {
+		return "{}", nil
+	}
+	encoded, err := json.Marshal(attributes)
+	return string(encoded), err
+}
+
+// stringsColumn scans a text[] column, or the JSON array SQLite stores in its place, leaving an
+// empty slice for NULL
+func stringsColumn(values *[]string) sql.Scanner {
+	return stringsScanner{values}
+}
+
+type stringsScanner struct {
+	values *[]string
+}
+
+func (scanner stringsScanner) Scan(src interface{}) error {
+	*scanner.values = []string{}
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("cannot scan %T into strings", src)
+	}
+	if len(data) > 0 && data[0] == '[' {
+		return json.Unmarshal(data, scanner.values)
+	}
+	var array pq.StringArray
+	if err := array.Scan(data); err != nil {
+		return err
+	}
+	*scanner.values = array
+	return nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var invitationColumns = []string{
+	"id", "organization_id", "email", "role", "status", "invited_by", "expires_at", "created_at", "accepted_at",
+}
+
+type invitationRepository struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewInvitationRepository(db *sql.DB) interfaces.InvitationRepository {
+	return &invitationRepository{db, dialectOf(db)}
+}
+
+func (repository *invitationRepository) GetPending(
+	ctx context.Context,
+	organizationID int,
+) ([]interfaces.Invitation, error) {
+	var invitations []interfaces.Invitation
+	rows, err := squirrel.
+		Select(invitationColumns...).
+		From("invitations").
+		Where(squirrel.Eq{"organization_id": organizationID, "status": interfaces.InvitationPending}).
+		OrderBy("id").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building invitation query:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			logger.Error("Error scanning invitation row:", zap.Error(err))
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (repository *invitationRepository) GetByID(
+	ctx context.Context,
+	organizationID, id int,
+) (interfaces.Invitation, error) {
+	query, args, err := squirrel.
+		Select(invitationColumns...).
+		From("invitations").
+		Where(squirrel.Eq{"organization_id": organizationID, "id": id}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return interfaces.Invitation{}, err
+	}
+
+	invitation, err := scanInvitation(conn(ctx, repository.db).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("Invitation not found", zap.Int("invitationID", id))
+			return invitation, err
+		}
+		logger.Error("Error retrieving invitation:", zap.Int("invitationID", id), zap.Error(err))
+		return invitation, err
+	}
+	return invitation, nil
+}
+
+func (repository *invitationRepository) Create(ctx context.Context, invitation interfaces.Invitation) (interfaces.Invitation, error) {
+	query, args, err := squirrel.Insert("invitations").
+		Columns("organization_id", "email", "role", "status", "invited_by", "expires_at").
+		Values(
+			invitation.OrganizationID,
+			invitation.Email,
+			invitation.Role,
+			invitation.Status,
+			invitation.InvitedBy,
+			invitation.ExpiresAt,
+		).
+		Suffix("RETURNING id, created_at").
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return invitation, err
+	}
+
+	err = conn(ctx, repository.db).QueryRowContext(ctx, query, args...).Scan(&invitation.ID, &invitation.CreatedAt)
+	if err != nil {
+		logger.Error("Error creating invitation:", zap.Error(err))
+		return invitation, err
+	}
+	return invitation, nil
+}
+
+func (repository *invitationRepository) Update(
+	ctx context.Context,
+	invitation interfaces.Invitation,
+) (interfaces.Invitation, error) {
+	query, args, err := squirrel.Update("invitations").
+		Set("status", invitation.Status).
+		Set("expires_at", invitation.ExpiresAt).
+		Set("accepted_at", invitation.AcceptedAt).
+		Where(squirrel.Eq{"organization_id": invitation.OrganizationID, "id": invitation.ID}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return invitation, err
+	}
+
+	if _, err = conn(ctx, repository.db).ExecContext(ctx, query, args...); err != nil {
+		logger.Error("Error updating invitation:", zap.Error(err))
+		return invitation, err
+	}
+	return repository.GetByID(ctx, invitation.OrganizationID, invitation.ID)
+}
+
+// Claim updates the invitation only while it is pending, so the first of concurrent claims
+// holds its row until its unit of work ends and the others then match nothing
+func (repository *invitationRepository) Claim(ctx context.Context, invitation interfaces.Invitation) error {
+	query, args, err := squirrel.Update("invitations").
+		Set("status", interfaces.InvitationAccepted).
+		Set("accepted_at", invitation.AcceptedAt).
+		Where(squirrel.Eq{
+			"organization_id": invitation.OrganizationID,
+			"id":              invitation.ID,
+			"status":          interfaces.InvitationPending,
+		}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return err
+	}
+
+	err = execAffectingRowContext(ctx, conn(ctx, repository.db), query, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return interfaces.ErrInvitationNotPending
+	}
+	return err
+}
+
+func scanInvitation(row squirrel.RowScanner) (interfaces.Invitation, error) {
+	var invitation interfaces.Invitation
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.OrganizationID,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.Status,
+		&invitation.InvitedBy,
+		&invitation.ExpiresAt,
+		&invitation.CreatedAt,
+		&invitation.AcceptedAt,
+	)
+	return invitation, err
+}
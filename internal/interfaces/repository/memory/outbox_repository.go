@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+type outboxRepository struct {
+	mutex sync.Mutex
+	relay sync.Mutex
+
+	messages []interfaces.OutboxMessage
+	lastID   int64
+}
+
+// NewOutboxRepository returns an empty outbox. Like the other in-memory repositories it does not
+// join units of work, so events are kept even when the work that recorded them fails.
+func NewOutboxRepository() interfaces.OutboxRepository {
+	return &outboxRepository{}
+}
+
+func (repository *outboxRepository) Append(ctx context.Context, events ...interfaces.UserEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	messages := make([]interfaces.OutboxMessage, len(events))
+	for i, event := range events {
+		message, err := interfaces.NewOutboxMessage(event)
+		if err != nil {
+			return err
+		}
+		messages[i] = message
+	}
+
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	createdAt := time.Now().UTC()
+	for _, message := range messages {
+		repository.lastID++
+		message.ID, message.CreatedAt = repository.lastID, createdAt
+		repository.messages = append(repository.messages, message)
+	}
+	return nil
+}
+
+func (repository *outboxRepository) Lock(context.Context) (func(), bool, error) {
+	if !repository.relay.TryLock() {
+		return nil, false, nil
+	}
+	return repository.relay.Unlock, true, nil
+}
+
+func (repository *outboxRepository) Pending(ctx context.Context, afterID int64, limit int) ([]interfaces.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	messages := []interfaces.OutboxMessage{}
+	for _, message := range repository.messages {
+		if message.ID > afterID && len(messages) < limit {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (repository *outboxRepository) Delete(ctx context.Context, ids ...int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deleted := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	kept := repository.messages[:0]
+	for _, message := range repository.messages {
+		if !deleted[message.ID] {
+			kept = append(kept, message)
+		}
+	}
+	repository.messages = kept
+	return nil
+}
+
+func (repository *outboxRepository) Fail(ctx context.Context, id int64, _ error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for i := range repository.messages {
+		if repository.messages[i].ID == id {
+			repository.messages[i].Attempts++
+		}
+	}
+	return nil
+}
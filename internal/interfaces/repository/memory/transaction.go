@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+type txManager struct{}
+
+// NewTxManager runs units of work directly, for services wired to in-memory repositories. The
+// work is not isolated and a failed unit of work keeps the changes made before the failure, so
+// the functions it registered with interfaces.AfterCommit run either way.
+func NewTxManager() interfaces.TxManager {
+	return txManager{}
+}
+
+func (txManager) WithinTx(ctx context.Context, _ interfaces.TxOptions, fn func(ctx context.Context) error) error {
+	if interfaces.InUnitOfWork(ctx) {
+		return fn(ctx)
+	}
+	work := interfaces.WithUnitOfWork(ctx)
+	defer interfaces.Committed(work)
+	return fn(work)
+}
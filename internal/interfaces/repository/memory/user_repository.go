@@ -0,0 +1,567 @@
+// Package memory holds thread-safe in-memory repositories for tests and demos. They follow the
+// semantics of the PostgreSQL repositories, including sql.ErrNoRows for missing rows and the
+// uniqueness rules, which the repository conformance specs check against both.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type membershipKey struct {
+	organizationID int
+	userID         int
+}
+
+type userRepository struct {
+	mutex sync.RWMutex
+
+	users            map[int]interfaces.User
+	lastUserID       int
+	transitions      []interfaces.StatusTransition
+	lastTransitionID int
+	memberships      map[membershipKey]string
+	blacklist        map[string]time.Time
+}
+
+// NewUserRepository returns an empty repository. Each call is atomic, but calls do not join the
+// units of work of an interfaces.TxManager: there is nothing to roll back.
+func NewUserRepository() interfaces.Repository {
+	return &userRepository{
+		users:       make(map[int]interfaces.User),
+		memberships: make(map[membershipKey]string),
+		blacklist:   make(map[string]time.Time),
+	}
+}
+
+func (repository *userRepository) GetAll(ctx context.Context, organizationID int, filter interfaces.UserFilter) ([]interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	var users []interfaces.User
+	for _, user := range repository.active(organizationID) {
+		if repository.matches(user, filter) {
+			users = append(users, copyUser(user))
+		}
+	}
+	return users, nil
+}
+
+// Search scores the filtered users with interfaces.MatchSearch
+func (repository *userRepository) Search(
+	ctx context.Context,
+	organizationID int,
+	text string,
+	filter interfaces.UserFilter,
+	limit int,
+) ([]interfaces.UserSearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	terms, err := interfaces.SearchTerms(text)
+	if err != nil {
+		return nil, err
+	}
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	filter.Search = ""
+	results := []interfaces.UserSearchResult{}
+	for _, user := range repository.active(organizationID) {
+		if !repository.matches(user, filter) {
+			continue
+		}
+		if result, ok := interfaces.MatchSearch(copyUser(user), terms); ok {
+			results = append(results, result)
+		}
+	}
+	return interfaces.RankSearchResults(results, limit), nil
+}
+
+func (repository *userRepository) GetByUsername(ctx context.Context, organizationID int, username string) (interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return interfaces.User{}, err
+	}
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	normalized := interfaces.NormalizeIdentity(username)
+	for _, user := range repository.active(organizationID) {
+		if interfaces.NormalizeIdentity(user.Username) == normalized {
+			return copyUser(user), nil
+		}
+	}
+	return interfaces.User{}, sql.ErrNoRows
+}
+
+func (repository *userRepository) GetByID(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return interfaces.User{}, err
+	}
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	user, err := repository.get(organizationID, id)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	return copyUser(user), nil
+}
+
+// Create inserts the user and its member role in the user's organization
+func (repository *userRepository) Create(ctx context.Context, createdUser interfaces.User) (interfaces.User, error) {
+	created, err := repository.CreateMany(ctx, []interfaces.User{createdUser})
+	if err != nil {
+		return createdUser, err
+	}
+	return created[0], nil
+}
+
+// CreateMany inserts either every user or, when one of them is taken, none
+func (repository *userRepository) CreateMany(ctx context.Context, users []interfaces.User) ([]interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	// Users already taken by an earlier one of the batch conflict too
+	pending := make([]interfaces.User, 0, len(users))
+	for _, user := range users {
+		if err := repository.checkUnique(user, pending); err != nil {
+			return nil, err
+		}
+		attributes, err := normalizeAttributes(user.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		status := interfaces.StatusOf(user)
+		user.Status = &status
+		user.Attributes = attributes
+		user.Version = 1
+		user.DeletedAt = nil
+		user.Avatar = nil
+		pending = append(pending, user)
+	}
+
+	created := make([]interfaces.User, 0, len(pending))
+	for _, user := range pending {
+		repository.lastUserID++
+		user.ID = repository.lastUserID
+		repository.users[user.ID] = user
+		repository.memberships[membershipKey{user.OrganizationID, user.ID}] = interfaces.RoleMember
+		created = append(created, copyUser(user))
+	}
+	return created, nil
+}
+
+// FindExisting returns the organization's active users holding any of the usernames or emails
+func (repository *userRepository) FindExisting(ctx context.Context, organizationID int, usernames, emails []string) ([]interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	taken := make(map[string]bool)
+	for _, username := range usernames {
+		taken["username:"+interfaces.NormalizeIdentity(username)] = true
+	}
+	for _, email := range emails {
+		taken["email:"+interfaces.NormalizeIdentity(email)] = true
+	}
+
+	var users []interfaces.User
+	for _, user := range repository.active(organizationID) {
+		if taken["username:"+interfaces.NormalizeIdentity(user.Username)] || taken["email:"+interfaces.NormalizeIdentity(user.Email)] {
+			users = append(users, interfaces.User{ID: user.ID, Username: user.Username, Email: user.Email})
+		}
+	}
+	return users, nil
+}
+
+// Update writes every mutable field; a non-zero version must match the stored one
+func (repository *userRepository) Update(ctx context.Context, updatedUser interfaces.User) (interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return updatedUser, err
+	}
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	stored, err := repository.get(updatedUser.OrganizationID, updatedUser.ID)
+	if err != nil {
+		return updatedUser, err
+	}
+	if updatedUser.Version != 0 && updatedUser.Version != stored.Version {
+		return updatedUser, interfaces.ErrVersionConflict
+	}
+	if err := repository.checkUnique(updatedUser, nil); err != nil {
+		return updatedUser, err
+	}
+	attributes, err := normalizeAttributes(updatedUser.Attributes)
+	if err != nil {
+		return updatedUser, err
+	}
+
+	stored.Name = updatedUser.Name
+	stored.Email = updatedUser.Email
+	stored.Username = updatedUser.Username
+	stored.Password = updatedUser.Password
+	stored.Attributes = attributes
+	stored.Version++
+	repository.users[stored.ID] = stored
+	return copyUser(stored), nil
+}
+
+// Delete soft-deletes the user and moves it to the deleted status. A non-zero version must match
+// the stored one.
+func (repository *userRepository) Delete(ctx context.Context, organizationID, id, version int) (interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return interfaces.User{}, err
+	}
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	stored, err := repository.get(organizationID, id)
+	if err != nil {
+		return stored, err
+	}
+	if version != 0 && stored.Version != version {
+		return copyUser(stored), interfaces.ErrVersionConflict
+	}
+
+	now := time.Now()
+	repository.recordTransition(interfaces.StatusTransition{
+		OrganizationID: organizationID,
+		UserID:         id,
+		FromStatus:     interfaces.StatusOf(stored),
+		ToStatus:       interfaces.UserStatusDeleted,
+	})
+	status := interfaces.UserStatusDeleted
+	stored.Status = &status
+	stored.DeletedAt = &now
+	stored.Version++
+	repository.users[id] = stored
+	return copyUser(stored), nil
+}
+
+// GetDeleted lists the soft-deleted users of the organization, most recently deleted first
+func (repository *userRepository) GetDeleted(ctx context.Context, organizationID int) ([]interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	users := []interfaces.User{}
+	for _, user := range repository.users {
+		if user.OrganizationID == organizationID && user.DeletedAt != nil {
+			users = append(users, copyUser(user))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].DeletedAt.After(*users[j].DeletedAt) })
+	return users, nil
+}
+
+// StreamUsers passes the organization's active users matching the filter to fn in ID order,
+// without passwords. fn sees the users as they were when the call started.
+func (repository *userRepository) StreamUsers(
+	ctx context.Context,
+	organizationID int,
+	filter interfaces.UserFilter,
+	fn func(interfaces.User) error,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// The snapshot is taken under the lock; fn runs without it, so it may call the repository
+	repository.mutex.RLock()
+	var snapshot []interfaces.User
+	for _, user := range repository.active(organizationID) {
+		if repository.matches(user, filter) {
+			user.Password = ""
+			user.Attributes = nil
+			snapshot = append(snapshot, copyUser(user))
+		}
+	}
+	repository.mutex.RUnlock()
+
+	for _, user := range snapshot {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore returns a soft-deleted user to the status it had before the deletion
+func (repository *userRepository) Restore(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return interfaces.User{}, err
+	}
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	stored, ok := repository.users[id]
+	if !ok || stored.OrganizationID != organizationID || stored.DeletedAt == nil {
+		return interfaces.User{}, sql.ErrNoRows
+	}
+	if err := repository.checkUnique(stored, nil); err != nil {
+		return interfaces.User{}, err
+	}
+
+	restored := interfaces.UserStatusActive
+	for i := len(repository.transitions) - 1; i >= 0; i-- {
+		transition := repository.transitions[i]
+		if transition.UserID == id && transition.ToStatus == interfaces.UserStatusDeleted {
+			restored = transition.FromStatus
+			break
+		}
+	}
+	repository.recordTransition(interfaces.StatusTransition{
+		OrganizationID: organizationID,
+		UserID:         id,
+		FromStatus:     interfaces.UserStatusDeleted,
+		ToStatus:       restored,
+	})
+	stored.Status = &restored
+	stored.DeletedAt = nil
+	stored.Version++
+	repository.users[id] = stored
+	return copyUser(stored), nil
+}
+
+// ChangeStatus moves the user from transition.FromStatus to transition.ToStatus and records the
+// transition. A user no longer in FromStatus is a version conflict.
+func (repository *userRepository) ChangeStatus(ctx context.Context, transition interfaces.StatusTransition) (interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return interfaces.User{}, err
+	}
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	stored, err := repository.get(transition.OrganizationID, transition.UserID)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	if interfaces.StatusOf(stored) != transition.FromStatus {
+		return interfaces.User{}, interfaces.ErrVersionConflict
+	}
+
+	repository.recordTransition(transition)
+	status := transition.ToStatus
+	stored.Status = &status
+	stored.Version++
+	repository.users[stored.ID] = stored
+	return copyUser(stored), nil
+}
+
+// GetStatusHistory lists the user's status transitions, oldest first
+func (repository *userRepository) GetStatusHistory(ctx context.Context, organizationID, userID int) ([]interfaces.StatusTransition, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	history := []interfaces.StatusTransition{}
+	for _, transition := range repository.transitions {
+		if transition.OrganizationID == organizationID && transition.UserID == userID {
+			if transition.ChangedBy != nil {
+				changedBy := *transition.ChangedBy
+				transition.ChangedBy = &changedBy
+			}
+			history = append(history, transition)
+		}
+	}
+	return history, nil
+}
+
+// Purge permanently removes users soft-deleted before the given time, with their memberships and
+// status history
+func (repository *userRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]interfaces.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	var users []interfaces.User
+	purged := make(map[int]bool)
+	for id, user := range repository.users {
+		if user.DeletedAt != nil && user.DeletedAt.Before(deletedBefore) {
+			purged[id] = true
+			users = append(users, interfaces.User{ID: id, OrganizationID: user.OrganizationID, Username: user.Username})
+			delete(repository.users, id)
+			delete(repository.memberships, membershipKey{user.OrganizationID, id})
+		}
+	}
+
+	transitions := repository.transitions[:0]
+	for _, transition := range repository.transitions {
+		if purged[transition.UserID] {
+			continue
+		}
+		if transition.ChangedBy != nil && purged[*transition.ChangedBy] {
+			transition.ChangedBy = nil
+		}
+		transitions = append(transitions, transition)
+	}
+	repository.transitions = transitions
+	return users, nil
+}
+
+// GenerateHashFromPassword uses bcrypt's minimum cost: the hashes verify like the PostgreSQL
+// repository's, but tests do not wait for them
+func (repository *userRepository) GenerateHashFromPassword(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	return string(bytes), err
+}
+
+// BlacklistToken blacklists a given token of the user until its expiration
+func (repository *userRepository) BlacklistToken(ctx context.Context, userID int, token string, expiry time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.blacklist[token] = expiry
+	return nil
+}
+
+// Evict does nothing, since the repository keeps no copies of the users it returns
+func (repository *userRepository) Evict(context.Context, ...interfaces.User) {}
+
+// get returns the organization's active user, or sql.ErrNoRows
+func (repository *userRepository) get(organizationID, id int) (interfaces.User, error) {
+	user, ok := repository.users[id]
+	if !ok || user.OrganizationID != organizationID || user.DeletedAt != nil {
+		return interfaces.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+// active returns the organization's users that are not soft-deleted, in ID order
+func (repository *userRepository) active(organizationID int) []interfaces.User {
+	var users []interfaces.User
+	for _, user := range repository.users {
+		if user.OrganizationID == organizationID && user.DeletedAt == nil {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+// checkUnique enforces the unique indexes on the normalized username and email of active users,
+// against the stored users other than user itself and the pending ones
+func (repository *userRepository) checkUnique(user interfaces.User, pending []interfaces.User) error {
+	username := interfaces.NormalizeIdentity(user.Username)
+	email := interfaces.NormalizeIdentity(user.Email)
+	others := append(repository.active(user.OrganizationID), pending...)
+	for _, other := range others {
+		if other.OrganizationID != user.OrganizationID || (user.ID != 0 && other.ID == user.ID) {
+			continue
+		}
+		if interfaces.NormalizeIdentity(other.Username) == username {
+			return interfaces.ErrUsernameTaken
+		}
+		if interfaces.NormalizeIdentity(other.Email) == email {
+			return interfaces.ErrEmailTaken
+		}
+	}
+	return nil
+}
+
+// matches applies the filter like filterUsers does in SQL
+func (repository *userRepository) matches(user interfaces.User, filter interfaces.UserFilter) bool {
+	if len(filter.Statuses) > 0 && !contains(filter.Statuses, interfaces.StatusOf(user)) {
+		return false
+	}
+	if filter.Role != "" && repository.memberships[membershipKey{user.OrganizationID, user.ID}] != filter.Role {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(user.Name), search) &&
+			!strings.Contains(strings.ToLower(user.Email), search) &&
+			!strings.Contains(strings.ToLower(user.Username), search) {
+			return false
+		}
+	}
+	if len(filter.Attributes) > 0 {
+		wanted, err := normalizeAttributes(filter.Attributes)
+		if err != nil || !interfaces.AttributesContain(user.Attributes, wanted) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordTransition appends a status change to the history; the caller holds the write lock
+func (repository *userRepository) recordTransition(transition interfaces.StatusTransition) {
+	repository.lastTransitionID++
+	transition.ID = repository.lastTransitionID
+	transition.CreatedAt = time.Now().UTC()
+	if transition.ChangedBy != nil {
+		changedBy := *transition.ChangedBy
+		transition.ChangedBy = &changedBy
+	}
+	repository.transitions = append(repository.transitions, transition)
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAttributes round-trips attributes through JSON, as storing them in a JSONB column
+// does: numbers come back as float64 and nil as an empty object
+func normalizeAttributes(attributes map[string]interface{}) (map[string]interface{}, error) {
+	normalized := map[string]interface{}{}
+	if attributes == nil {
+		return normalized, nil
+	}
+	encoded, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(encoded, &normalized)
+	return normalized, err
+}
+
+// copyUser keeps callers from changing stored users through the status, deletion time or attributes
+func copyUser(user interfaces.User) interfaces.User {
+	if user.Status != nil {
+		status := *user.Status
+		user.Status = &status
+	}
+	if user.DeletedAt != nil {
+		deletedAt := *user.DeletedAt
+		user.DeletedAt = &deletedAt
+	}
+	if user.Attributes != nil {
+		attributes, _ := normalizeAttributes(user.Attributes)
+		user.Attributes = attributes
+	}
+	return user
+}
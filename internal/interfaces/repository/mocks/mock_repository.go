@@ -5,7 +5,9 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	interfaces "github.com/redbonzai/user-management-api/internal/interfaces"
@@ -34,92 +36,272 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// BlacklistToken mocks base method.
+func (m *MockRepository) BlacklistToken(ctx context.Context, userID int, token string, expiry time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlacklistToken", ctx, userID, token, expiry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BlacklistToken indicates an expected call of BlacklistToken.
+func (mr *MockRepositoryMockRecorder) BlacklistToken(ctx, userID, token, expiry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlacklistToken", reflect.TypeOf((*MockRepository)(nil).BlacklistToken), ctx, userID, token, expiry)
+}
+
+// ChangeStatus mocks base method.
+func (m *MockRepository) ChangeStatus(ctx context.Context, transition interfaces.StatusTransition) (interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeStatus", ctx, transition)
+	ret0, _ := ret[0].(interfaces.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeStatus indicates an expected call of ChangeStatus.
+func (mr *MockRepositoryMockRecorder) ChangeStatus(ctx, transition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeStatus", reflect.TypeOf((*MockRepository)(nil).ChangeStatus), ctx, transition)
+}
+
 // Create mocks base method.
-func (m *MockRepository) Create(user interfaces.User) (interfaces.User, error) {
+func (m *MockRepository) Create(ctx context.Context, user interfaces.User) (interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", user)
+	ret := m.ctrl.Call(m, "Create", ctx, user)
 	ret0, _ := ret[0].(interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockRepositoryMockRecorder) Create(user interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Create(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, user)
+}
+
+// CreateMany mocks base method.
+func (m *MockRepository) CreateMany(ctx context.Context, users []interfaces.User) ([]interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateMany", ctx, users)
+	ret0, _ := ret[0].([]interfaces.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateMany indicates an expected call of CreateMany.
+func (mr *MockRepositoryMockRecorder) CreateMany(ctx, users interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateMany", reflect.TypeOf((*MockRepository)(nil).CreateMany), ctx, users)
 }
 
 // Delete mocks base method.
-func (m *MockRepository) Delete(id int) (interfaces.User, error) {
+func (m *MockRepository) Delete(ctx context.Context, organizationID, id, version int) (interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", ctx, organizationID, id, version)
 	ret0, _ := ret[0].(interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockRepositoryMockRecorder) Delete(id interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Delete(ctx, organizationID, id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), ctx, organizationID, id, version)
+}
+
+// Evict mocks base method.
+func (m *MockRepository) Evict(ctx context.Context, users ...interfaces.User) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range users {
+		varargs = append(varargs, a)
+	}
+	m.ctrl.Call(m, "Evict", varargs...)
+}
+
+// Evict indicates an expected call of Evict.
+func (mr *MockRepositoryMockRecorder) Evict(ctx interface{}, users ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, users...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Evict", reflect.TypeOf((*MockRepository)(nil).Evict), varargs...)
+}
+
+// FindExisting mocks base method.
+func (m *MockRepository) FindExisting(ctx context.Context, organizationID int, usernames, emails []string) ([]interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindExisting", ctx, organizationID, usernames, emails)
+	ret0, _ := ret[0].([]interfaces.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindExisting indicates an expected call of FindExisting.
+func (mr *MockRepositoryMockRecorder) FindExisting(ctx, organizationID, usernames, emails interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindExisting", reflect.TypeOf((*MockRepository)(nil).FindExisting), ctx, organizationID, usernames, emails)
+}
+
+// GenerateHashFromPassword mocks base method.
+func (m *MockRepository) GenerateHashFromPassword(password string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateHashFromPassword", password)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateHashFromPassword indicates an expected call of GenerateHashFromPassword.
+func (mr *MockRepositoryMockRecorder) GenerateHashFromPassword(password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateHashFromPassword", reflect.TypeOf((*MockRepository)(nil).GenerateHashFromPassword), password)
 }
 
 // GetAll mocks base method.
-func (m *MockRepository) GetAll() ([]interfaces.User, error) {
+func (m *MockRepository) GetAll(ctx context.Context, organizationID int, filter interfaces.UserFilter) ([]interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
+	ret := m.ctrl.Call(m, "GetAll", ctx, organizationID, filter)
 	ret0, _ := ret[0].([]interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockRepositoryMockRecorder) GetAll() *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetAll(ctx, organizationID, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockRepository)(nil).GetAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockRepository)(nil).GetAll), ctx, organizationID, filter)
 }
 
 // GetByID mocks base method.
-func (m *MockRepository) GetByID(id int) (interfaces.User, error) {
+func (m *MockRepository) GetByID(ctx context.Context, organizationID, id int) (interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByID", id)
+	ret := m.ctrl.Call(m, "GetByID", ctx, organizationID, id)
 	ret0, _ := ret[0].(interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByID indicates an expected call of GetByID.
-func (mr *MockRepositoryMockRecorder) GetByID(id interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetByID(ctx, organizationID, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockRepository)(nil).GetByID), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockRepository)(nil).GetByID), ctx, organizationID, id)
 }
 
 // GetByUsername mocks base method.
-func (m *MockRepository) GetByUsername(username string) (interfaces.User, error) {
+func (m *MockRepository) GetByUsername(ctx context.Context, organizationID int, username string) (interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByUsername", username)
+	ret := m.ctrl.Call(m, "GetByUsername", ctx, organizationID, username)
 	ret0, _ := ret[0].(interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByUsername indicates an expected call of GetByUsername.
-func (mr *MockRepositoryMockRecorder) GetByUsername(username interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetByUsername(ctx, organizationID, username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUsername", reflect.TypeOf((*MockRepository)(nil).GetByUsername), ctx, organizationID, username)
+}
+
+// GetDeleted mocks base method.
+func (m *MockRepository) GetDeleted(ctx context.Context, organizationID int) ([]interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeleted", ctx, organizationID)
+	ret0, _ := ret[0].([]interfaces.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeleted indicates an expected call of GetDeleted.
+func (mr *MockRepositoryMockRecorder) GetDeleted(ctx, organizationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeleted", reflect.TypeOf((*MockRepository)(nil).GetDeleted), ctx, organizationID)
+}
+
+// GetStatusHistory mocks base method.
+func (m *MockRepository) GetStatusHistory(ctx context.Context, organizationID, userID int) ([]interfaces.StatusTransition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatusHistory", ctx, organizationID, userID)
+	ret0, _ := ret[0].([]interfaces.StatusTransition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatusHistory indicates an expected call of GetStatusHistory.
+func (mr *MockRepositoryMockRecorder) GetStatusHistory(ctx, organizationID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatusHistory", reflect.TypeOf((*MockRepository)(nil).GetStatusHistory), ctx, organizationID, userID)
+}
+
+// Purge mocks base method.
+func (m *MockRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, deletedBefore)
+	ret0, _ := ret[0].([]interfaces.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockRepositoryMockRecorder) Purge(ctx, deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockRepository)(nil).Purge), ctx, deletedBefore)
+}
+
+// Restore mocks base method.
+func (m *MockRepository) Restore(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, organizationID, id)
+	ret0, _ := ret[0].(interfaces.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockRepositoryMockRecorder) Restore(ctx, organizationID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockRepository)(nil).Restore), ctx, organizationID, id)
+}
+
+// Search mocks base method.
+func (m *MockRepository) Search(ctx context.Context, organizationID int, text string, filter interfaces.UserFilter, limit int) ([]interfaces.UserSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, organizationID, text, filter, limit)
+	ret0, _ := ret[0].([]interfaces.UserSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockRepositoryMockRecorder) Search(ctx, organizationID, text, filter, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockRepository)(nil).Search), ctx, organizationID, text, filter, limit)
+}
+
+// StreamUsers mocks base method.
+func (m *MockRepository) StreamUsers(ctx context.Context, organizationID int, filter interfaces.UserFilter, fn func(interfaces.User) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamUsers", ctx, organizationID, filter, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamUsers indicates an expected call of StreamUsers.
+func (mr *MockRepositoryMockRecorder) StreamUsers(ctx, organizationID, filter, fn interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUsername", reflect.TypeOf((*MockRepository)(nil).GetByUsername), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamUsers", reflect.TypeOf((*MockRepository)(nil).StreamUsers), ctx, organizationID, filter, fn)
 }
 
 // Update mocks base method.
-func (m *MockRepository) Update(user interfaces.User) (interfaces.User, error) {
+func (m *MockRepository) Update(ctx context.Context, user interfaces.User) (interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", user)
+	ret := m.ctrl.Call(m, "Update", ctx, user)
 	ret0, _ := ret[0].(interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockRepositoryMockRecorder) Update(user interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Update(ctx, user interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), ctx, user)
 }
@@ -0,0 +1,297 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type organizationRepository struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewOrganizationRepository(db *sql.DB) interfaces.OrganizationRepository {
+	return &organizationRepository{db, dialectOf(db)}
+}
+
+func (repository *organizationRepository) GetByID(ctx context.Context, id int) (interfaces.Organization, error) {
+	return repository.getOne(ctx, squirrel.Eq{"id": id})
+}
+
+func (repository *organizationRepository) GetBySlug(ctx context.Context, slug string) (interfaces.Organization, error) {
+	return repository.getOne(ctx, squirrel.Eq{"slug": slug})
+}
+
+func (repository *organizationRepository) getOne(
+	ctx context.Context,
+	predicate squirrel.Eq,
+) (interfaces.Organization, error) {
+	var organization interfaces.Organization
+	query, args, err := squirrel.
+		Select("id", "name", "slug", "created_at").
+		From("organizations").
+		Where(predicate).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return organization, err
+	}
+
+	err = conn(ctx, repository.db).QueryRowContext(ctx, query, args...).Scan(
+		&organization.ID,
+		&organization.Name,
+		&organization.Slug,
+		&organization.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.Error("Organization not found", zap.Any("predicate", predicate))
+			return organization, err
+		}
+		logger.Error("Error retrieving organization:", zap.Any("predicate", predicate), zap.Error(err))
+		return organization, err
+	}
+	return organization, nil
+}
+
+func (repository *organizationRepository) GetForUser(
+	ctx context.Context,
+	userID int,
+) ([]interfaces.Organization, error) {
+	var organizations []interfaces.Organization
+	rows, err := squirrel.
+		Select("o.id", "o.name", "o.slug", "o.created_at").
+		From("organizations o").
+		Join("organization_memberships m ON m.organization_id = o.id").
+		Where(squirrel.Eq{"m.user_id": userID}).
+		OrderBy("o.id").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building organization query:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		var organization interfaces.Organization
+		if err := rows.Scan(
+			&organization.ID,
+			&organization.Name,
+			&organization.Slug,
+			&organization.CreatedAt,
+		); err != nil {
+			logger.Error("Error scanning organization row:", zap.Error(err))
+			return nil, err
+		}
+		organizations = append(organizations, organization)
+	}
+	return organizations, rows.Err()
+}
+
+// Create inserts the organization and makes ownerID its owner in one transaction
+func (repository *organizationRepository) Create(
+	ctx context.Context,
+	organization interfaces.Organization,
+	ownerID int,
+) (interfaces.Organization, error) {
+	query, args, err := squirrel.Insert("organizations").
+		Columns("name", "slug").
+		Values(organization.Name, organization.Slug).
+		Suffix("RETURNING id, created_at").
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return organization, err
+	}
+
+	err = inTx(ctx, repository.db, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&organization.ID, &organization.CreatedAt); err != nil {
+			logger.Error("Error creating organization:", zap.Error(err))
+			return err
+		}
+
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO organization_memberships (organization_id, user_id, role) VALUES ($1, $2, $3)",
+			organization.ID,
+			ownerID,
+			interfaces.RoleOwner,
+		)
+		if err != nil {
+			logger.Error("Error creating organization owner:", zap.Error(err))
+		}
+		return err
+	})
+	return organization, err
+}
+
+func (repository *organizationRepository) GetMembers(
+	ctx context.Context,
+	organizationID int,
+) ([]interfaces.Membership, error) {
+	var memberships []interfaces.Membership
+	rows, err := squirrel.
+		Select("organization_id", "user_id", "role", "created_at").
+		From("organization_memberships").
+		Where(squirrel.Eq{"organization_id": organizationID}).
+		OrderBy("user_id").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building membership query:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	for rows.Next() {
+		var membership interfaces.Membership
+		if err := rows.Scan(
+			&membership.OrganizationID,
+			&membership.UserID,
+			&membership.Role,
+			&membership.CreatedAt,
+		); err != nil {
+			logger.Error("Error scanning membership row:", zap.Error(err))
+			return nil, err
+		}
+		memberships = append(memberships, membership)
+	}
+	return memberships, rows.Err()
+}
+
+func (repository *organizationRepository) GetMembership(
+	ctx context.Context,
+	organizationID, userID int,
+) (interfaces.Membership, error) {
+	var membership interfaces.Membership
+	query, args, err := squirrel.
+		Select("organization_id", "user_id", "role", "created_at").
+		From("organization_memberships").
+		Where(squirrel.Eq{"organization_id": organizationID, "user_id": userID}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return membership, err
+	}
+
+	err = conn(ctx, repository.db).QueryRowContext(ctx, query, args...).Scan(
+		&membership.OrganizationID,
+		&membership.UserID,
+		&membership.Role,
+		&membership.CreatedAt,
+	)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Error(
+			"Error retrieving membership:",
+			zap.Int("organizationID", organizationID),
+			zap.Int("userID", userID),
+			zap.Error(err),
+		)
+	}
+	return membership, err
+}
+
+// GetOwners locks the owners' memberships, so concurrent changes to them take turns
+func (repository *organizationRepository) GetOwners(ctx context.Context, organizationID int) ([]int, error) {
+	query, args, err := squirrel.
+		Select("user_id").
+		From("organization_memberships").
+		Where(squirrel.Eq{"organization_id": organizationID, "role": interfaces.RoleOwner}).
+		OrderBy("user_id").
+		Suffix(strings.TrimSpace(repository.dialect.forUpdate())).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := conn(ctx, repository.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("Error retrieving organization owners:", zap.Int("organizationID", organizationID), zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	var owners []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			logger.Error("Error scanning owner row:", zap.Error(err))
+			return nil, err
+		}
+		owners = append(owners, userID)
+	}
+	return owners, rows.Err()
+}
+
+func (repository *organizationRepository) AddMember(
+	ctx context.Context,
+	membership interfaces.Membership,
+) (interfaces.Membership, error) {
+	query, args, err := squirrel.Insert("organization_memberships").
+		Columns("organization_id", "user_id", "role").
+		Values(membership.OrganizationID, membership.UserID, membership.Role).
+		Suffix("RETURNING created_at").
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return membership, err
+	}
+
+	if err = conn(ctx, repository.db).QueryRowContext(ctx, query, args...).Scan(&membership.CreatedAt); err != nil {
+		logger.Error("Error adding organization member:", zap.Error(err))
+		return membership, err
+	}
+	return membership, nil
+}
+
+// UpdateMemberRole returns sql.ErrNoRows when the user is not a member
+func (repository *organizationRepository) UpdateMemberRole(
+	ctx context.Context,
+	membership interfaces.Membership,
+) (interfaces.Membership, error) {
+	query, args, err := squirrel.Update("organization_memberships").
+		Set("role", membership.Role).
+		Where(squirrel.Eq{"organization_id": membership.OrganizationID, "user_id": membership.UserID}).
+		Suffix("RETURNING created_at").
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return membership, err
+	}
+
+	err = conn(ctx, repository.db).QueryRowContext(ctx, query, args...).Scan(&membership.CreatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("Error updating member role:", zap.Error(err))
+	}
+	return membership, err
+}
+
+func (repository *organizationRepository) RemoveMember(ctx context.Context, organizationID, userID int) error {
+	query, args, err := squirrel.Delete("organization_memberships").
+		Where(squirrel.Eq{"organization_id": organizationID, "user_id": userID}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return err
+	}
+
+	return execAffectingRowContext(ctx, conn(ctx, repository.db), query, args...)
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// outboxRelayLock is the advisory lock key held by the relay, so instances publishing at the
+// same time cannot reorder a user's events
+const outboxRelayLock = 7312
+
+var outboxColumns = []string{"id", "event_type", "organization_id", "user_id", "payload", "created_at", "attempts"}
+
+type outboxRepository struct {
+	db      *sql.DB
+	dialect dialect
+	// relay stands in for the advisory lock on SQLite, whose database only this process opens
+	relay sync.Mutex
+}
+
+func NewOutboxRepository(db *sql.DB) interfaces.OutboxRepository {
+	return &outboxRepository{db: db, dialect: dialectOf(db)}
+}
+
+// Append inserts the events after the writes already made in the unit of work, so the events of
+// a user, whose row those writes lock, are numbered in commit order
+func (repository *outboxRepository) Append(ctx context.Context, events ...interfaces.UserEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+	builder := squirrel.Insert("outbox_events").
+		Columns("event_type", "organization_id", "user_id", "payload", "created_at").
+		PlaceholderFormat(repository.dialect.placeholders)
+	for _, event := range events {
+		message, err := interfaces.NewOutboxMessage(event)
+		if err != nil {
+			logger.Error("Error encoding outbox event:", zap.String("type", event.EventType()), zap.Error(err))
+			return err
+		}
+		// JSONB parameters go over the wire as text; a []byte would be sent as bytea
+		builder = builder.Values(message.Type, message.OrganizationID, message.UserID, string(message.Payload), createdAt)
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query:", zap.Error(err))
+		return err
+	}
+	if _, err = conn(ctx, repository.db).ExecContext(ctx, query, args...); err != nil {
+		logger.Error("Error appending outbox events:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Lock holds a session advisory lock on PostgreSQL on a connection of its own, which unlock
+// releases and returns to the pool
+func (repository *outboxRepository) Lock(ctx context.Context) (func(), bool, error) {
+	if repository.dialect.sqlite {
+		if !repository.relay.TryLock() {
+			return nil, false, nil
+		}
+		return repository.relay.Unlock, true, nil
+	}
+
+	session, err := repository.db.Conn(ctx)
+	if err != nil {
+		logger.Error("Error opening outbox relay connection:", zap.Error(err))
+		return nil, false, err
+	}
+	var locked bool
+	if err = session.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", outboxRelayLock).Scan(&locked); err != nil || !locked {
+		session.Close()
+		if err != nil {
+			logger.Error("Error locking outbox relay:", zap.Error(err))
+		}
+		return nil, false, err
+	}
+	unlock := func() {
+		// Closing the connection only returns it to the pool, so the lock is released explicitly
+		if _, err := session.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", outboxRelayLock); err != nil {
+			logger.Error("Error unlocking outbox relay:", zap.Error(err))
+		}
+		session.Close()
+	}
+	return unlock, true, nil
+}
+
+func (repository *outboxRepository) Pending(ctx context.Context, afterID int64, limit int) ([]interfaces.OutboxMessage, error) {
+	rows, err := squirrel.
+		Select(outboxColumns...).
+		From("outbox_events").
+		Where(squirrel.Gt{"id": afterID}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(repository.db).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error querying outbox events:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	messages := []interfaces.OutboxMessage{}
+	for rows.Next() {
+		var message interfaces.OutboxMessage
+		var payload []byte
+		if err := rows.Scan(
+			&message.ID,
+			&message.Type,
+			&message.OrganizationID,
+			&message.UserID,
+			&payload,
+			&message.CreatedAt,
+			&message.Attempts,
+		); err != nil {
+			logger.Error("Error scanning outbox event row:", zap.Error(err))
+			return nil, err
+		}
+		message.Payload = payload
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (repository *outboxRepository) Delete(ctx context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := squirrel.
+		Delete("outbox_events").
+		Where(squirrel.Eq{"id": ids}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(repository.db).
+		ExecContext(ctx)
+	if err != nil {
+		logger.Error("Error deleting outbox events:", zap.Error(err))
+	}
+	return err
+}
+
+func (repository *outboxRepository) Fail(ctx context.Context, id int64, cause error) error {
+	_, err := squirrel.
+		Update("outbox_events").
+		Set("attempts", squirrel.Expr("attempts + 1")).
+		Set("last_error", cause.Error()).
+		Where(squirrel.Eq{"id": id}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(repository.db).
+		ExecContext(ctx)
+	if err != nil {
+		logger.Error("Error recording outbox delivery failure:", zap.Int64("eventID", id), zap.Error(err))
+	}
+	return err
+}
@@ -0,0 +1,446 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+var erasureColumns = []string{
+	"id", "organization_id", "user_id", "requested_by", "reason", "status", "scheduled_for", "created_at", "completed_at",
+}
+
+type privacyRepository struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewPrivacyRepository(db *sql.DB) interfaces.PrivacyRepository {
+	return &privacyRepository{db, dialectOf(db)}
+}
+
+// GetPersonalData reads everything stored about the user from one snapshot. Erased users have
+// nothing left to export and are reported as sql.ErrNoRows.
+func (repository *privacyRepository) GetPersonalData(
+	ctx context.Context,
+	organizationID, userID int,
+) (interfaces.PersonalData, error) {
+	data := interfaces.PersonalData{
+		Memberships: []interfaces.Membership{},
+		Groups:      []interfaces.Group{},
+		Tokens:      []interfaces.RevokedToken{},
+		Invitations: []interfaces.Invitation{},
+	}
+	tx, err := repository.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		logger.Error("Error starting personal data transaction:", zap.Error(err))
+		return data, err
+	}
+	defer rollback(tx)
+
+	profile := &data.Profile
+	err = tx.QueryRowContext(
+		ctx,
+		`SELECT id, organization_id, name, email, status, username, version, deleted_at, sessions_revoked_at, attributes
+		FROM users WHERE organization_id = $1 AND id = $2 AND erased_at IS NULL`,
+		organizationID, userID,
+	).Scan(
+		&profile.ID,
+		&profile.OrganizationID,
+		&profile.Name,
+		&profile.Email,
+		&profile.Status,
+		&profile.Username,
+		&profile.Version,
+		&profile.DeletedAt,
+		&data.Sessions.RevokedAt,
+		attributesColumn(&profile.Attributes),
+	)
+	if err != nil {
+		return data, err
+	}
+
+	readers := []func(ctx context.Context, tx *sql.Tx, data *interfaces.PersonalData) error{
+		repository.readMemberships,
+		repository.readGroups,
+		repository.readAuditEvents,
+		repository.readRevokedTokens,
+		repository.readInvitations,
+	}
+	for _, read := range readers {
+		if err := read(ctx, tx, &data); err != nil {
+			logger.Error("Error reading personal data:", zap.Int("userID", userID), zap.Error(err))
+			return data, err
+		}
+	}
+	data.GeneratedAt = time.Now().UTC()
+	return data, tx.Commit()
+}
+
+func (repository *privacyRepository) readMemberships(
+	ctx context.Context,
+	tx *sql.Tx,
+	data *interfaces.PersonalData,
+) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT organization_id, user_id, role, created_at FROM organization_memberships WHERE user_id = $1",
+		data.Profile.ID,
+	)
+	if err != nil {
+		return err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var membership interfaces.Membership
+		if err := rows.Scan(&membership.OrganizationID, &membership.UserID, &membership.Role, &membership.CreatedAt); err != nil {
+			return err
+		}
+		data.Memberships = append(data.Memberships, membership)
+	}
+	return rows.Err()
+}
+
+func (repository *privacyRepository) readGroups(ctx context.Context, tx *sql.Tx, data *interfaces.PersonalData) error {
+	rows, err := (&groupRepository{dialect: repository.dialect}).selectGroups().
+		Where("g.id IN (SELECT group_id FROM user_group_members WHERE user_id = ?)", data.Profile.ID).
+		OrderBy("g.id").
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		group, err := scanGroup(rows)
+		if err != nil {
+			return err
+		}
+		data.Groups = append(data.Groups, group)
+	}
+	return rows.Err()
+}
+
+// readAuditEvents collects the events the user performed or was the target of, and derives the
+// session history from the authentication events among them
+func (repository *privacyRepository) readAuditEvents(
+	ctx context.Context,
+	tx *sql.Tx,
+	data *interfaces.PersonalData,
+) error {
+	rows, err := squirrel.
+		Select(auditColumns...).
+		From("audit_events").
+		Where(squirrel.Eq{"organization_id": data.Profile.OrganizationID}).
+		Where(squirrel.Or{
+			squirrel.Eq{"actor_id": data.Profile.ID},
+			squirrel.Eq{"target_type": interfaces.AuditTargetUser, "target_id": data.Profile.ID},
+		}).
+		OrderBy("id").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	if data.AuditEvents, err = scanAuditEvents(rows); err != nil {
+		return err
+	}
+
+	data.Sessions.Events = []interfaces.AuditEvent{}
+	for _, event := range data.AuditEvents {
+		switch event.Action {
+		case interfaces.AuditAuthLogin, interfaces.AuditAuthLoginFailed, interfaces.AuditAuthLogout:
+			data.Sessions.Events = append(data.Sessions.Events, event)
+		}
+	}
+	return nil
+}
+
+func (repository *privacyRepository) readRevokedTokens(
+	ctx context.Context,
+	tx *sql.Tx,
+	data *interfaces.PersonalData,
+) error {
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT id, expiry, created_at FROM token_blacklist WHERE user_id = $1 ORDER BY id",
+		data.Profile.ID,
+	)
+	if err != nil {
+		return err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		var token interfaces.RevokedToken
+		if err := rows.Scan(&token.ID, &token.ExpiresAt, &token.RevokedAt); err != nil {
+			return err
+		}
+		data.Tokens = append(data.Tokens, token)
+	}
+	return rows.Err()
+}
+
+// readInvitations collects the invitations sent to the user's email and those the user sent
+func (repository *privacyRepository) readInvitations(
+	ctx context.Context,
+	tx *sql.Tx,
+	data *interfaces.PersonalData,
+) error {
+	rows, err := squirrel.
+		Select(
+			"id", "organization_id", "email", "role", "status", "COALESCE(invited_by, 0)",
+			"expires_at", "created_at", "accepted_at",
+		).
+		From("invitations").
+		Where(squirrel.Eq{"organization_id": data.Profile.OrganizationID}).
+		Where(squirrel.Or{
+			squirrel.Expr("lower(email) = lower(?)", data.Profile.Email),
+			squirrel.Eq{"invited_by": data.Profile.ID},
+		}).
+		OrderBy("id").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(tx).
+		QueryContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeRows(rows)
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return err
+		}
+		data.Invitations = append(data.Invitations, invitation)
+	}
+	return rows.Err()
+}
+
+// ScheduleErasure creates a pending request for an active or soft-deleted user of the
+// organization, returning sql.ErrNoRows when there is no such user
+func (repository *privacyRepository) ScheduleErasure(
+	ctx context.Context,
+	request interfaces.ErasureRequest,
+) (interfaces.ErasureRequest, error) {
+	row := conn(ctx, repository.db).QueryRowContext(
+		ctx,
+		`INSERT INTO erasure_requests (organization_id, user_id, requested_by, reason, scheduled_for)
+		SELECT organization_id, id, $3, $4, $5 FROM users
+		WHERE organization_id = $1 AND id = $2 AND erased_at IS NULL
+		ON CONFLICT (user_id) WHERE status = 'pending'
+		DO UPDATE SET scheduled_for = CASE
+			WHEN EXCLUDED.scheduled_for < erasure_requests.scheduled_for THEN EXCLUDED.scheduled_for
+			ELSE erasure_requests.scheduled_for
+		END
+		RETURNING `+erasureColumnList(),
+		request.OrganizationID,
+		request.UserID,
+		request.RequestedBy,
+		request.Reason,
+		request.ScheduledFor,
+	)
+	scheduled, err := scanErasureRequest(row)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("Error scheduling erasure:", zap.Int("userID", request.UserID), zap.Error(err))
+	}
+	return scheduled, err
+}
+
+func (repository *privacyRepository) GetErasureRequests(
+	ctx context.Context,
+	organizationID int,
+) ([]interfaces.ErasureRequest, error) {
+	rows, err := squirrel.
+		Select(erasureColumns...).
+		From("erasure_requests").
+		Where(squirrel.Eq{"organization_id": organizationID}).
+		OrderBy("id DESC").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building erasure request query:", zap.Error(err))
+		return nil, err
+	}
+	return scanErasureRequests(rows)
+}
+
+// GetDueErasureRequests returns the pending requests of every organization scheduled at or before now
+func (repository *privacyRepository) GetDueErasureRequests(
+	ctx context.Context,
+	now time.Time,
+) ([]interfaces.ErasureRequest, error) {
+	rows, err := squirrel.
+		Select(erasureColumns...).
+		From("erasure_requests").
+		Where(squirrel.Eq{"status": interfaces.ErasurePending}).
+		Where(squirrel.LtOrEq{"scheduled_for": now}).
+		OrderBy("scheduled_for").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building due erasure query:", zap.Error(err))
+		return nil, err
+	}
+	return scanErasureRequests(rows)
+}
+
+// CancelErasureRequest returns sql.ErrNoRows for an unknown request and ErrErasureNotPending
+// for one that already ran or was cancelled
+func (repository *privacyRepository) CancelErasureRequest(
+	ctx context.Context,
+	organizationID, id int,
+) (interfaces.ErasureRequest, error) {
+	row := conn(ctx, repository.db).QueryRowContext(
+		ctx,
+		`UPDATE erasure_requests SET status = $3
+		WHERE organization_id = $1 AND id = $2 AND status = $4
+		RETURNING `+erasureColumnList(),
+		organizationID, id, interfaces.ErasureCancelled, interfaces.ErasurePending,
+	)
+	cancelled, err := scanErasureRequest(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := conn(ctx, repository.db).QueryRowContext(
+			ctx,
+			"SELECT EXISTS (SELECT 1 FROM erasure_requests WHERE organization_id = $1 AND id = $2)",
+			organizationID, id,
+		).Scan(&exists); err != nil {
+			return cancelled, err
+		}
+		if exists {
+			return cancelled, interfaces.ErrErasureNotPending
+		}
+	}
+	return cancelled, err
+}
+
+// Erase anonymizes the user and their records in the unit of work carried by ctx, or a
+// transaction of its own, and completes the request. The users row stays as a tombstone so
+// foreign keys and audit references remain valid; the audit log itself is append-only and is
+// retained unaltered so its hash chain still verifies.
+func (repository *privacyRepository) Erase(
+	ctx context.Context,
+	request interfaces.ErasureRequest,
+) (interfaces.ErasureRequest, interfaces.User, error) {
+	completed := request
+	erased := interfaces.User{ID: request.UserID, OrganizationID: request.OrganizationID}
+	err := inTx(ctx, repository.db, func(tx *sql.Tx) error {
+		var email string
+		err := tx.QueryRowContext(
+			ctx,
+			"SELECT email FROM users WHERE organization_id = $1 AND id = $2 AND erased_at IS NULL"+
+				repository.dialect.forUpdate(),
+			request.OrganizationID, request.UserID,
+		).Scan(&email)
+		if err != nil {
+			logger.Error("Error locking user for erasure:", zap.Int("userID", request.UserID), zap.Error(err))
+			return err
+		}
+
+		now := time.Now()
+		placeholder := fmt.Sprintf("erased-%d", request.UserID)
+		placeholderEmail := placeholder + "@erased.invalid"
+		// An empty password hash matches no password
+		err = tx.QueryRowContext(
+			ctx,
+			`UPDATE users SET name = '', email = $2, username = $3, password = '', status = $4, attributes = '{}',
+			deleted_at = COALESCE(deleted_at, $5), sessions_revoked_at = $5, erased_at = $5,
+			version = version + 1
+			WHERE id = $1
+			RETURNING username, email, status, version, deleted_at`,
+			request.UserID, placeholderEmail, placeholder, interfaces.UserStatusErased, now,
+		).Scan(&erased.Username, &erased.Email, &erased.Status, &erased.Version, &erased.DeletedAt)
+		if err != nil {
+			logger.Error("Error erasing user:", zap.Int("userID", request.UserID), zap.Error(err))
+			return err
+		}
+
+		statements := []struct {
+			query string
+			args  []interface{}
+		}{
+			{`UPDATE invitations SET email = $3,
+				status = CASE WHEN status = $4 THEN $5 ELSE status END
+				WHERE organization_id = $1 AND lower(email) = lower($2)`,
+				[]interface{}{
+					request.OrganizationID, email, placeholderEmail,
+					interfaces.InvitationPending, interfaces.InvitationRevoked,
+				}},
+			{"DELETE FROM user_attribute_values WHERE user_id = $1", []interface{}{request.UserID}},
+			// Revoked tokens carry the username; sessions_revoked_at keeps them unusable
+			{"DELETE FROM token_blacklist WHERE user_id = $1", []interface{}{request.UserID}},
+			{"DELETE FROM user_group_members WHERE user_id = $1", []interface{}{request.UserID}},
+			{"DELETE FROM organization_memberships WHERE user_id = $1", []interface{}{request.UserID}},
+		}
+		for _, statement := range statements {
+			if _, err = tx.ExecContext(ctx, statement.query, statement.args...); err != nil {
+				logger.Error("Error erasing user data:", zap.Int("userID", request.UserID), zap.Error(err))
+				return err
+			}
+		}
+
+		completed, err = scanErasureRequest(tx.QueryRowContext(
+			ctx,
+			`UPDATE erasure_requests SET status = $2, completed_at = $4
+			WHERE id = $1 AND status = $3
+			RETURNING `+erasureColumnList(),
+			request.ID, interfaces.ErasureCompleted, interfaces.ErasurePending, now,
+		))
+		if errors.Is(err, sql.ErrNoRows) {
+			return interfaces.ErrErasureNotPending
+		}
+		return err
+	})
+	if err != nil {
+		return request, interfaces.User{}, err
+	}
+	return completed, erased, nil
+}
+
+func erasureColumnList() string {
+	return strings.Join(erasureColumns, ", ")
+}
+
+func scanErasureRequests(rows *sql.Rows) ([]interfaces.ErasureRequest, error) {
+	defer closeRows(rows)
+
+	requests := []interfaces.ErasureRequest{}
+	for rows.Next() {
+		request, err := scanErasureRequest(rows)
+		if err != nil {
+			logger.Error("Error scanning erasure request row:", zap.Error(err))
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, rows.Err()
+}
+
+func scanErasureRequest(row squirrel.RowScanner) (interfaces.ErasureRequest, error) {
+	var request interfaces.ErasureRequest
+	err := row.Scan(
+		&request.ID,
+		&request.OrganizationID,
+		&request.UserID,
+		&request.RequestedBy,
+		&request.Reason,
+		&request.Status,
+		&request.ScheduledFor,
+		&request.CreatedAt,
+		&request.CompletedAt,
+	)
+	return request, err
+}
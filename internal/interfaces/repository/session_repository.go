@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+// NewSessionRepository reads db, the primary, never a read replica
+func NewSessionRepository(db *sql.DB) interfaces.SessionRepository {
+	return &sessionRepository{db}
+}
+
+func (repository *sessionRepository) IsBlacklisted(ctx context.Context, token string) (bool, error) {
+	var blacklisted bool
+	err := conn(ctx, repository.db).QueryRowContext(
+		ctx,
+		"SELECT EXISTS (SELECT 1 FROM token_blacklist WHERE token = $1)",
+		token,
+	).Scan(&blacklisted)
+	if err != nil {
+		logger.Error("Error checking token blacklist:", zap.Error(err))
+	}
+	return blacklisted, err
+}
+
+func (repository *sessionRepository) IsRevoked(ctx context.Context, userID int, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	err := conn(ctx, repository.db).QueryRowContext(
+		ctx,
+		`SELECT deleted_at IS NOT NULL OR status <> $3 OR COALESCE(sessions_revoked_at >= $2, false)
+		FROM users WHERE id = $1`,
+		userID,
+		issuedAt,
+		interfaces.UserStatusActive,
+	).Scan(&revoked)
+	// A purged user has no row left to authenticate against
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, nil
+	}
+	if err != nil {
+		logger.Error("Error checking token revocation:", zap.Int("userID", userID), zap.Error(err))
+	}
+	return revoked, err
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// txRetryBackoff is the base delay before retrying a transaction; it doubles with every attempt
+const txRetryBackoff = 20 * time.Millisecond
+
+type txKey struct{}
+
+// querier is satisfied by *sql.DB and *sql.Tx, and can be passed to squirrel's RunWith
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type txManager struct {
+	db *sql.DB
+}
+
+func NewTxManager(db *sql.DB) interfaces.TxManager {
+	return &txManager{db}
+}
+
+// WithinTx commits when fn returns nil and rolls back otherwise. Serialization failures and
+// deadlocks roll back and run fn again, up to options.MaxRetries times. Only the functions the
+// committed attempt registered with interfaces.AfterCommit run.
+func (manager *txManager) WithinTx(
+	ctx context.Context,
+	options interfaces.TxOptions,
+	fn func(ctx context.Context) error,
+) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+	retries := options.MaxRetries
+	if retries == 0 {
+		retries = interfaces.TxDefaultRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := manager.run(ctx, options, fn)
+		if err == nil || !isRetryable(err) || attempt >= retries {
+			return err
+		}
+		logger.Info("Retrying transaction", zap.Int("attempt", attempt+1), zap.Error(err))
+
+		// Jitter keeps the conflicting transactions from colliding again
+		backoff := txRetryBackoff << attempt
+		backoff += rand.N(backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (manager *txManager) run(ctx context.Context, options interfaces.TxOptions, fn func(ctx context.Context) error) error {
+	tx, err := manager.db.BeginTx(ctx, &sql.TxOptions{Isolation: options.Isolation, ReadOnly: options.ReadOnly})
+	if err != nil {
+		logger.Error("Error starting transaction:", zap.Error(err))
+		return err
+	}
+	defer rollback(tx)
+
+	work := interfaces.WithUnitOfWork(context.WithValue(ctx, txKey{}, tx))
+	if err = fn(work); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error committing transaction:", zap.Error(err))
+		return err
+	}
+	interfaces.Committed(work)
+	return nil
+}
+
+// conn returns the transaction carried by ctx, or db outside of a unit of work
+func conn(ctx context.Context, db *sql.DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// inTx runs fn in the transaction carried by ctx, or in a new one it commits when fn succeeds
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(tx)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Error starting transaction:", zap.Error(err))
+		return err
+	}
+	defer rollback(tx)
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.Error("Error committing transaction:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// isRetryable reports whether err is a serialization failure or a deadlock, after which the
+// whole transaction can be run again. On SQLite that is a lock still held once busy_timeout ran out.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// Extended result codes keep the primary code in their low byte
+		primary := sqliteErr.Code() & 0xff
+		return primary == sqlite3.SQLITE_BUSY || primary == sqlite3.SQLITE_LOCKED
+	}
+	return false
+}
@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"html"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
@@ -13,22 +16,66 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ReadReplicas picks the database for reads that need not see the latest writes; *db.ReplicaSet
+// implements it
+type ReadReplicas interface {
+	Reader() *sql.DB
+}
+
 type userRepository struct {
-	db *sql.DB
+	db           *sql.DB
+	replicas     ReadReplicas
+	dialect      dialect
+	queryTimeout time.Duration
+}
+
+// NewUserRepository returns a repository whose calls each time out after queryTimeout, unless it
+// is zero. StreamUsers and CreateMany are bounded by their context only, since exports and bulk
+// imports may take long. Listing, searching, exporting, status histories and lookups by ID read
+// from replicas unless it is nil; lookups by username, which sign-ins depend on, and everything
+// in a unit of work or under interfaces.WithPrimary use db.
+func NewUserRepository(db *sql.DB, replicas ReadReplicas, queryTimeout time.Duration) interfaces.Repository {
+	return &userRepository{db, replicas, dialectOf(db), queryTimeout}
+}
+
+// readDB returns the database for a read that may lag behind the primary
+func (repository *userRepository) readDB(ctx context.Context) *sql.DB {
+	if repository.replicas == nil || interfaces.UsesPrimary(ctx) {
+		return repository.db
+	}
+	return repository.replicas.Reader()
 }
 
-func NewUserRepository(db *sql.DB) interfaces.Repository {
-	return &userRepository{db}
+// withTimeout bounds a call by the repository's query timeout on top of the caller's context
+func (repository *userRepository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if repository.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, repository.queryTimeout)
 }
 
-func (repository *userRepository) GetAll() ([]interfaces.User, error) {
+func (repository *userRepository) GetAll(ctx context.Context, organizationID int, filter interfaces.UserFilter) ([]interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
 	var users []interfaces.User
-	rows, err := squirrel.
-		Select("id", "name", "email", "status", "username", "password").
-		From("users").
-		OrderBy("id").
-		RunWith(repository.db).
-		Query()
+	query, err := repository.filterUsers(
+		squirrel.
+			Select("id", "organization_id", "name", "email", "status", "username", "password", "version", "attributes").
+			From("users").
+			Where(squirrel.Eq{"organization_id": organizationID, "deleted_at": nil}).
+			OrderBy("id").
+			PlaceholderFormat(repository.dialect.placeholders),
+		organizationID,
+		filter,
+	)
+	if err != nil {
+		logger.Error("Error building user filter:", zap.Error(err))
+		return nil, err
+	}
+	rows, err := query.
+		RunWith(conn(ctx, repository.readDB(ctx))).
+		QueryContext(ctx)
 	if err != nil {
 		logger.Error("Error building user query:", zap.Error(err))
 		return nil, err
@@ -44,11 +91,14 @@ func (repository *userRepository) GetAll() ([]interfaces.User, error) {
 		var retrievedUser interfaces.User
 		if err := rows.Scan(
 			&retrievedUser.ID,
+			&retrievedUser.OrganizationID,
 			&retrievedUser.Name,
 			&retrievedUser.Email,
 			&retrievedUser.Status,
 			&retrievedUser.Username,
 			&retrievedUser.Password,
+			&retrievedUser.Version,
+			attributesColumn(&retrievedUser.Attributes),
 		); err != nil {
 			logger.Error("Error scanning user row:", zap.Error(err))
 			return nil, err
@@ -59,13 +109,146 @@ func (repository *userRepository) GetAll() ([]interfaces.User, error) {
 	return users, nil
 }
 
-func (repository *userRepository) GetByUsername(username string) (interfaces.User, error) {
+// searchHeadline marks the words of the tsquery in a column; HighlightAll keeps the whole value
+const searchHeadline = "ts_headline('simple', %s, to_tsquery('simple', ?), " +
+	"'StartSel=" + interfaces.SearchHighlightStart + ", StopSel=" + interfaces.SearchHighlightStop + ", HighlightAll=true')"
+
+// searchFields are the columns matched and highlighted by Search
+var searchFields = []string{"name", "username", "email"}
+
+func (repository *userRepository) Search(
+	ctx context.Context,
+	organizationID int,
+	text string,
+	filter interfaces.UserFilter,
+	limit int,
+) ([]interfaces.UserSearchResult, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	filter.Search = ""
+	if repository.dialect.sqlite {
+		return repository.searchScored(ctx, organizationID, text, filter, limit)
+	}
+	prefixQuery, err := interfaces.SearchPrefixQuery(text)
+	if err != nil {
+		return nil, err
+	}
+
+	score := squirrel.Expr(
+		"ts_rank(search_vector, to_tsquery('simple', ?)) + "+
+			"GREATEST(similarity(name, ?), similarity(username, ?), similarity(email, ?)) AS score",
+		prefixQuery, text, text, text,
+	)
+	builder := squirrel.
+		Select("id", "organization_id", "name", "email", "status", "username", "version", "attributes").
+		Column(score).
+		From("users").
+		Where(squirrel.Eq{"organization_id": organizationID, "deleted_at": nil}).
+		Where(
+			"(search_vector @@ to_tsquery('simple', ?) OR name % ? OR username % ? OR email % ?)",
+			prefixQuery, text, text, text,
+		).
+		OrderBy("score DESC", "id").
+		Limit(uint64(limit)).
+		PlaceholderFormat(repository.dialect.placeholders)
+	for _, field := range searchFields {
+		builder = builder.Column(squirrel.Expr(fmt.Sprintf(searchHeadline, field), prefixQuery))
+	}
+
+	query, err := repository.filterUsers(builder, organizationID, filter)
+	if err != nil {
+		logger.Error("Error building user filter:", zap.Error(err))
+		return nil, err
+	}
+	rows, err := query.RunWith(conn(ctx, repository.readDB(ctx))).QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error searching users:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	results := []interfaces.UserSearchResult{}
+	for rows.Next() {
+		var result interfaces.UserSearchResult
+		headlines := make([]string, len(searchFields))
+		destinations := []interface{}{
+			&result.User.ID,
+			&result.User.OrganizationID,
+			&result.User.Name,
+			&result.User.Email,
+			&result.User.Status,
+			&result.User.Username,
+			&result.User.Version,
+			attributesColumn(&result.User.Attributes),
+			&result.Score,
+		}
+		for i := range headlines {
+			destinations = append(destinations, &headlines[i])
+		}
+		if err := rows.Scan(destinations...); err != nil {
+			logger.Error("Error scanning user search row:", zap.Error(err))
+			return nil, err
+		}
+		result.Highlights = searchHighlights(headlines)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// searchScored is Search for SQLite, which has neither full-text search nor trigram similarity:
+// every user matching the filter is scored with interfaces.MatchSearch
+func (repository *userRepository) searchScored(
+	ctx context.Context,
+	organizationID int,
+	text string,
+	filter interfaces.UserFilter,
+	limit int,
+) ([]interfaces.UserSearchResult, error) {
+	terms, err := interfaces.SearchTerms(text)
+	if err != nil {
+		return nil, err
+	}
+	users, err := repository.GetAll(ctx, organizationID, filter)
+	if err != nil {
+		return nil, err
+	}
+	results := []interfaces.UserSearchResult{}
+	for _, user := range users {
+		if result, ok := interfaces.MatchSearch(user, terms); ok {
+			results = append(results, result)
+		}
+	}
+	return interfaces.RankSearchResults(results, limit), nil
+}
+
+// searchHighlights keeps the headlines that marked something and HTML-escapes them around the marks
+func searchHighlights(headlines []string) map[string]string {
+	highlights := make(map[string]string)
+	for i, headline := range headlines {
+		if !strings.Contains(headline, interfaces.SearchHighlightStart) {
+			continue
+		}
+		escaped := html.EscapeString(headline)
+		highlights[searchFields[i]] = strings.NewReplacer(
+			html.EscapeString(interfaces.SearchHighlightStart), interfaces.SearchHighlightStart,
+			html.EscapeString(interfaces.SearchHighlightStop), interfaces.SearchHighlightStop,
+		).Replace(escaped)
+	}
+	return highlights
+}
+
+func (repository *userRepository) GetByUsername(ctx context.Context, organizationID int, username string) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
 	var retrievedUser interfaces.User
 	query, args, err := squirrel.
-		Select("id", "name", "email", "status", "username", "password").
+		Select("id", "organization_id", "name", "email", "status", "username", "password", "version", "attributes").
 		From("users").
-		Where(squirrel.Eq{"username": username}).
-		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"organization_id": organizationID, "deleted_at": nil}).
+		Where("username_normalized = "+repository.dialect.normalized("?"), username).
+		PlaceholderFormat(repository.dialect.placeholders).
 		ToSql()
 
 	if err != nil {
@@ -73,15 +256,18 @@ func (repository *userRepository) GetByUsername(username string) (interfaces.Use
 		return retrievedUser, err
 	}
 
-	err = repository.
-		db.QueryRow(query, args...).
+	err = conn(ctx, repository.db).
+		QueryRowContext(ctx, query, args...).
 		Scan(
 			&retrievedUser.ID,
+			&retrievedUser.OrganizationID,
 			&retrievedUser.Name,
 			&retrievedUser.Email,
 			&retrievedUser.Status,
 			&retrievedUser.Username,
 			&retrievedUser.Password,
+			&retrievedUser.Version,
+			attributesColumn(&retrievedUser.Attributes),
 		)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -94,13 +280,16 @@ func (repository *userRepository) GetByUsername(username string) (interfaces.Use
 	return retrievedUser, nil
 }
 
-func (repository *userRepository) GetByID(id int) (interfaces.User, error) {
+func (repository *userRepository) GetByID(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
 	var retrievedUser interfaces.User
 	query, args, err := squirrel.
-		Select("id", "name", "email", "status", "username", "password").
+		Select("id", "organization_id", "name", "email", "status", "username", "password", "version", "attributes").
 		From("users").
-		Where(squirrel.Eq{"id": id}).
-		PlaceholderFormat(squirrel.Dollar).
+		Where(squirrel.Eq{"organization_id": organizationID, "id": id, "deleted_at": nil}).
+		PlaceholderFormat(repository.dialect.placeholders).
 		ToSql()
 
 	if err != nil {
@@ -108,15 +297,18 @@ func (repository *userRepository) GetByID(id int) (interfaces.User, error) {
 		return retrievedUser, err
 	}
 
-	err = repository.
-		db.QueryRow(query, args...).
+	err = conn(ctx, repository.readDB(ctx)).
+		QueryRowContext(ctx, query, args...).
 		Scan(
 			&retrievedUser.ID,
+			&retrievedUser.OrganizationID,
 			&retrievedUser.Name,
 			&retrievedUser.Email,
 			&retrievedUser.Status,
 			&retrievedUser.Username,
 			&retrievedUser.Password,
+			&retrievedUser.Version,
+			attributesColumn(&retrievedUser.Attributes),
 		)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -129,49 +321,155 @@ func (repository *userRepository) GetByID(id int) (interfaces.User, error) {
 	return retrievedUser, nil
 }
 
-func (repository *userRepository) Create(createdUser interfaces.User) (interfaces.User, error) {
+// Create inserts the user and its member role in the user's organization in one transaction
+func (repository *userRepository) Create(ctx context.Context, createdUser interfaces.User) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	created, err := repository.CreateMany(ctx, []interfaces.User{createdUser})
+	if err != nil {
+		return createdUser, err
+	}
+	return created[0], nil
+}
+
+// CreateMany inserts all users and their memberships in one transaction: either every user is
+// created or none is
+func (repository *userRepository) CreateMany(ctx context.Context, users []interfaces.User) ([]interfaces.User, error) {
+	created := make([]interfaces.User, 0, len(users))
+	err := inTx(ctx, repository.db, func(tx *sql.Tx) error {
+		for _, user := range users {
+			user, err := repository.insertUser(ctx, tx, user)
+			if err != nil {
+				return err
+			}
+			created = append(created, user)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (repository *userRepository) insertUser(ctx context.Context, tx *sql.Tx, createdUser interfaces.User) (interfaces.User, error) {
+	attributes, err := attributesValue(createdUser.Attributes)
+	if err != nil {
+		return createdUser, err
+	}
+	status := interfaces.StatusOf(createdUser)
+	createdUser.Status = &status
 	query, args, err := squirrel.Insert("users").
-		Columns("name", "email", "status", "username", "password").
-		Values(createdUser.Name, createdUser.Email, createdUser.Status, createdUser.Username, createdUser.Password).
-		Suffix("RETURNING id").
-		PlaceholderFormat(squirrel.Dollar). // Ensure PostgreSQL-compatible placeholders
+		Columns("organization_id", "name", "email", "status", "username", "password", "attributes").
+		Values(
+			createdUser.OrganizationID,
+			createdUser.Name,
+			createdUser.Email,
+			status,
+			createdUser.Username,
+			createdUser.Password,
+			attributes,
+		).
+		Suffix("RETURNING id, version, attributes").
+		PlaceholderFormat(repository.dialect.placeholders).
 		ToSql()
 	if err != nil {
 		logger.Error("Error building SQL query:", zap.Error(err))
 		return createdUser, err
 	}
 
-	err = repository.db.QueryRow(query, args...).Scan(&createdUser.ID)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&createdUser.ID, &createdUser.Version, attributesColumn(&createdUser.Attributes))
 	if err != nil {
+		if conflict := userConflict(err); conflict != nil {
+			return createdUser, conflict
+		}
 		logger.Error("Error creating user:", zap.Error(err))
 		return createdUser, err
 	}
 
+	_, err = tx.ExecContext(
+		ctx,
+		"INSERT INTO organization_memberships (organization_id, user_id, role) VALUES ($1, $2, $3)",
+		createdUser.OrganizationID,
+		createdUser.ID,
+		interfaces.RoleMember,
+	)
+	if err != nil {
+		logger.Error("Error creating organization membership:", zap.Error(err))
+		return createdUser, err
+	}
 	return createdUser, nil
 }
 
-func (repository *userRepository) Update(updatedUser interfaces.User) (interfaces.User, error) {
-	queryBuilder := squirrel.Update("users")
+// FindExisting returns the organization's active users holding any of the usernames or emails
+func (repository *userRepository) FindExisting(ctx context.Context, organizationID int, usernames, emails []string) ([]interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
 
-	if updatedUser.Name != "" {
-		queryBuilder = queryBuilder.Set("name", updatedUser.Name)
+	usernameValues, err := repository.dialect.stringsValue(usernames)
+	if err != nil {
+		return nil, err
 	}
-	if updatedUser.Email != "" {
-		queryBuilder = queryBuilder.Set("email", updatedUser.Email)
+	emailValues, err := repository.dialect.stringsValue(emails)
+	if err != nil {
+		return nil, err
 	}
-	if updatedUser.Status != nil {
-		queryBuilder = queryBuilder.Set("status", updatedUser.Status)
+	rows, err := squirrel.
+		Select("id", "username", "email").
+		From("users").
+		Where(squirrel.Eq{"organization_id": organizationID, "deleted_at": nil}).
+		Where(squirrel.Or{
+			squirrel.Expr(repository.dialect.normalizedIn("username_normalized"), usernameValues),
+			squirrel.Expr(repository.dialect.normalizedIn("email_normalized"), emailValues),
+		}).
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.db)).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building existing user query:", zap.Error(err))
+		return nil, err
 	}
-	if updatedUser.Username != "" {
-		queryBuilder = queryBuilder.Set("username", updatedUser.Username)
+	defer closeRows(rows)
+
+	var users []interfaces.User
+	for rows.Next() {
+		var existingUser interfaces.User
+		if err := rows.Scan(&existingUser.ID, &existingUser.Username, &existingUser.Email); err != nil {
+			logger.Error("Error scanning existing user row:", zap.Error(err))
+			return nil, err
+		}
+		users = append(users, existingUser)
 	}
-	if updatedUser.Password != "" {
-		queryBuilder = queryBuilder.Set("password", updatedUser.Password)
+	return users, rows.Err()
+}
+
+// Update writes every mutable field, so empty values clear columns; callers merge changes into
+// the current user first. A non-zero version must match the stored one. The status is changed
+// with ChangeStatus only.
+func (repository *userRepository) Update(ctx context.Context, updatedUser interfaces.User) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	attributes, err := attributesValue(updatedUser.Attributes)
+	if err != nil {
+		return updatedUser, err
 	}
+	queryBuilder := squirrel.Update("users").
+		Set("name", updatedUser.Name).
+		Set("email", updatedUser.Email).
+		Set("username", updatedUser.Username).
+		Set("password", updatedUser.Password).
+		Set("attributes", attributes)
 
+	conditions := squirrel.Eq{"organization_id": updatedUser.OrganizationID, "id": updatedUser.ID, "deleted_at": nil}
+	if updatedUser.Version != 0 {
+		conditions["version"] = updatedUser.Version
+	}
 	query, args, err := queryBuilder.
-		Where(squirrel.Eq{"id": updatedUser.ID}).
-		PlaceholderFormat(squirrel.Dollar). // Ensure PostgreSQL-compatible placeholders
+		Set("version", squirrel.Expr("version + 1")).
+		Where(conditions).
+		PlaceholderFormat(repository.dialect.placeholders).
 		ToSql()
 
 	if err != nil {
@@ -179,49 +477,359 @@ func (repository *userRepository) Update(updatedUser interfaces.User) (interface
 		return updatedUser, err
 	}
 
-	_, err = repository.db.Exec(query, args...)
+	err = execAffectingRowContext(ctx, conn(ctx, repository.db), query, args...)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return updatedUser, repository.missingOrConflict(ctx, updatedUser.OrganizationID, updatedUser.ID)
+		}
+		if conflict := userConflict(err); conflict != nil {
+			return updatedUser, conflict
+		}
 		logger.Error("Error updating user:", zap.Error(err))
 		return updatedUser, err
 	}
 
-	return repository.GetByID(updatedUser.ID)
+	return repository.GetByID(ctx, updatedUser.OrganizationID, updatedUser.ID)
+}
+
+// missingOrConflict explains why a versioned write matched no row: the user is gone, or
+// another request changed it first
+func (repository *userRepository) missingOrConflict(ctx context.Context, organizationID, id int) error {
+	if _, err := repository.GetByID(ctx, organizationID, id); err != nil {
+		return err
+	}
+	return interfaces.ErrVersionConflict
 }
 
-func (repository *userRepository) Delete(id int) (interfaces.User, error) {
-	deletedUser, err := repository.GetByID(id)
+// Delete soft-deletes the user, moves it to the deleted status and revokes every session issued
+// to them so far. A non-zero version must match the stored one.
+func (repository *userRepository) Delete(ctx context.Context, organizationID, id, version int) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	deletedUser, err := repository.GetByID(ctx, organizationID, id)
 	if err != nil {
 		logger.Error("Error retrieving user to delete:", zap.Error(err))
 		return deletedUser, err
 	}
+	if version != 0 && deletedUser.Version != version {
+		return deletedUser, interfaces.ErrVersionConflict
+	}
 
 	logger.Info("Retrieved Deleted user: ", zap.Any("deletedUser", deletedUser))
 
-	query, args, err := squirrel.Delete("users").
-		Where(squirrel.Eq{"id": id}).
-		PlaceholderFormat(squirrel.Dollar). // Ensure PostgreSQL-compatible placeholders
+	now := time.Now()
+	query, args, err := squirrel.Update("users").
+		Set("deleted_at", now).
+		Set("sessions_revoked_at", now).
+		Set("status", interfaces.UserStatusDeleted).
+		Set("version", squirrel.Expr("version + 1")).
+		Where(squirrel.Eq{"organization_id": organizationID, "id": id, "version": deletedUser.Version, "deleted_at": nil}).
+		PlaceholderFormat(repository.dialect.placeholders).
 		ToSql()
 	if err != nil {
 		logger.Error("Error building SQL query: ", zap.Error(err))
 		return deletedUser, err
 	}
 
-	_, err = repository.db.Exec(query, args...)
+	err = inTx(ctx, repository.db, func(tx *sql.Tx) error {
+		if err := execAffectingRowContext(ctx, tx, query, args...); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return repository.missingOrConflict(ctx, organizationID, id)
+			}
+			logger.Error("Error deleting user:", zap.Error(err))
+			return err
+		}
+		return insertTransition(ctx, tx, interfaces.StatusTransition{
+			OrganizationID: organizationID,
+			UserID:         id,
+			FromStatus:     interfaces.StatusOf(deletedUser),
+			ToStatus:       interfaces.UserStatusDeleted,
+		})
+	})
 	if err != nil {
-		logger.Error("Error deleting user:", zap.Error(err))
 		return deletedUser, err
 	}
 
+	status := interfaces.UserStatusDeleted
+	deletedUser.Version++
+	deletedUser.DeletedAt = &now
+	deletedUser.Status = &status
 	return deletedUser, nil
 }
 
+// GetDeleted lists the soft-deleted users of the organization that have not been purged yet
+func (repository *userRepository) GetDeleted(ctx context.Context, organizationID int) ([]interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := squirrel.
+		Select("id", "organization_id", "name", "email", "status", "username", "password", "version", "deleted_at", "attributes").
+		From("users").
+		Where(squirrel.Eq{"organization_id": organizationID, "erased_at": nil}).
+		Where(squirrel.NotEq{"deleted_at": nil}).
+		OrderBy("deleted_at DESC").
+		PlaceholderFormat(repository.dialect.placeholders).
+		RunWith(conn(ctx, repository.readDB(ctx))).
+		QueryContext(ctx)
+	if err != nil {
+		logger.Error("Error building deleted user query:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	users := []interfaces.User{}
+	for rows.Next() {
+		var deletedUser interfaces.User
+		if err := rows.Scan(
+			&deletedUser.ID,
+			&deletedUser.OrganizationID,
+			&deletedUser.Name,
+			&deletedUser.Email,
+			&deletedUser.Status,
+			&deletedUser.Username,
+			&deletedUser.Password,
+			&deletedUser.Version,
+			&deletedUser.DeletedAt,
+			attributesColumn(&deletedUser.Attributes),
+		); err != nil {
+			logger.Error("Error scanning deleted user row:", zap.Error(err))
+			return nil, err
+		}
+		users = append(users, deletedUser)
+	}
+	return users, rows.Err()
+}
+
+// StreamUsers passes the organization's active users matching the filter to fn in ID order.
+// Rows are read through a server-side cursor in batches of interfaces.ExportBatchSize, so memory
+// use does not grow with the table; the cursor's transaction gives fn a consistent snapshot.
+// SQLite steps through the rows of a read transaction instead. Passwords are not read.
+func (repository *userRepository) StreamUsers(
+	ctx context.Context,
+	organizationID int,
+	filter interfaces.UserFilter,
+	fn func(interfaces.User) error,
+) error {
+	builder, err := repository.filterUsers(
+		squirrel.
+			Select("id", "organization_id", "name", "email", "status", "username", "version").
+			From("users").
+			Where(squirrel.Eq{"organization_id": organizationID, "deleted_at": nil}).
+			OrderBy("id").
+			PlaceholderFormat(repository.dialect.placeholders),
+		organizationID,
+		filter,
+	)
+	if err != nil {
+		logger.Error("Error building user export query:", zap.Error(err))
+		return err
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		logger.Error("Error building user export query:", zap.Error(err))
+		return err
+	}
+
+	tx, err := repository.readDB(ctx).BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		logger.Error("Error starting export transaction:", zap.Error(err))
+		return err
+	}
+	defer rollback(tx)
+
+	if repository.dialect.sqlite {
+		if _, err := fetchUsers(ctx, tx, fn, query, args...); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	if _, err = tx.ExecContext(ctx, "DECLARE user_export NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		logger.Error("Error declaring user export cursor:", zap.Error(err))
+		return err
+	}
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM user_export", interfaces.ExportBatchSize)
+	for {
+		fetched, err := fetchUsers(ctx, tx, fn, fetch)
+		if err != nil {
+			return err
+		}
+		if fetched < interfaces.ExportBatchSize {
+			return tx.Commit()
+		}
+	}
+}
+
+// filterUsers narrows a users query to the filter. Attribute values are matched by JSONB
+// containment, which the GIN index on attributes serves on PostgreSQL.
+func (repository *userRepository) filterUsers(
+	query squirrel.SelectBuilder,
+	organizationID int,
+	filter interfaces.UserFilter,
+) (squirrel.SelectBuilder, error) {
+	if len(filter.Statuses) > 0 {
+		query = query.Where(squirrel.Eq{"status": filter.Statuses})
+	}
+	if filter.Role != "" {
+		query = query.Where(
+			"id IN (SELECT user_id FROM organization_memberships WHERE organization_id = ? AND role = ?)",
+			organizationID,
+			filter.Role,
+		)
+	}
+	if filter.Search != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		query = query.Where(squirrel.Or{
+			squirrel.Expr(repository.dialect.iLike("name"), pattern),
+			squirrel.Expr(repository.dialect.iLike("email"), pattern),
+			squirrel.Expr(repository.dialect.iLike("username"), pattern),
+		})
+	}
+	if len(filter.Attributes) > 0 {
+		attributes, err := attributesValue(filter.Attributes)
+		if err != nil {
+			return query, err
+		}
+		query = query.Where(repository.dialect.containsAttributes("attributes", "?"), attributes)
+	}
+	return query, nil
+}
+
+// likeEscaper escapes the LIKE wildcards in user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// fetchUsers passes the users read by query to fn and returns how many there were
+func fetchUsers(ctx context.Context, tx *sql.Tx, fn func(interfaces.User) error, query string, args ...interface{}) (int, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("Error fetching from user export cursor:", zap.Error(err))
+		return 0, err
+	}
+	defer closeRows(rows)
+
+	fetched := 0
+	for rows.Next() {
+		var exportedUser interfaces.User
+		if err := rows.Scan(
+			&exportedUser.ID,
+			&exportedUser.OrganizationID,
+			&exportedUser.Name,
+			&exportedUser.Email,
+			&exportedUser.Status,
+			&exportedUser.Username,
+			&exportedUser.Version,
+		); err != nil {
+			logger.Error("Error scanning exported user row:", zap.Error(err))
+			return fetched, err
+		}
+		fetched++
+		if err := fn(exportedUser); err != nil {
+			return fetched, err
+		}
+	}
+	return fetched, rows.Err()
+}
+
+// Restore clears deleted_at and returns the user to the status it had before the deletion.
+// Sessions revoked by the deletion stay revoked; erased users cannot be restored.
+func (repository *userRepository) Restore(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	err := inTx(ctx, repository.db, func(tx *sql.Tx) error {
+		var restored string
+		err := tx.QueryRowContext(
+			ctx,
+			`UPDATE users SET deleted_at = NULL, version = version + 1,
+				status = COALESCE((
+					SELECT from_status FROM user_status_transitions
+					WHERE user_id = $2 AND to_status = $3
+					ORDER BY id DESC LIMIT 1
+				), $4)
+			WHERE organization_id = $1 AND id = $2 AND erased_at IS NULL AND deleted_at IS NOT NULL
+			RETURNING status`,
+			organizationID, id, interfaces.UserStatusDeleted, interfaces.UserStatusActive,
+		).Scan(&restored)
+		if err != nil {
+			if conflict := userConflict(err); conflict != nil {
+				return conflict
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				logger.Error("Error restoring user:", zap.Int("userID", id), zap.Error(err))
+			}
+			return err
+		}
+		return insertTransition(ctx, tx, interfaces.StatusTransition{
+			OrganizationID: organizationID,
+			UserID:         id,
+			FromStatus:     interfaces.UserStatusDeleted,
+			ToStatus:       restored,
+		})
+	})
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	return repository.GetByID(ctx, organizationID, id)
+}
+
+// Purge permanently removes users soft-deleted before the given time, across all organizations.
+// Erased users are kept as anonymized tombstones.
+func (repository *userRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	query, args, err := squirrel.Delete("users").
+		Where(squirrel.Lt{"deleted_at": deletedBefore}).
+		Where(squirrel.Eq{"erased_at": nil}).
+		Suffix("RETURNING id, organization_id, username").
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query: ", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := conn(ctx, repository.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("Error purging deleted users:", zap.Error(err))
+		return nil, err
+	}
+	defer closeRows(rows)
+
+	var purged []interfaces.User
+	for rows.Next() {
+		var user interfaces.User
+		if err := rows.Scan(&user.ID, &user.OrganizationID, &user.Username); err != nil {
+			logger.Error("Error scanning purged user row:", zap.Error(err))
+			return nil, err
+		}
+		purged = append(purged, user)
+	}
+	return purged, rows.Err()
+}
+
 func (repository *userRepository) GenerateHashFromPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
 }
 
-// BlacklistToken blacklists a given token until its expiration
-func (repository *userRepository) BlacklistToken(token string, expiry time.Time) error {
-	_, err := repository.db.Exec("INSERT INTO token_blacklist (token, expiry) VALUES ($1, $2)", token, expiry)
+// BlacklistToken blacklists a given token of the user until its expiration
+func (repository *userRepository) BlacklistToken(ctx context.Context, userID int, token string, expiry time.Time) error {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	_, err := conn(ctx, repository.db).ExecContext(
+		ctx,
+		"INSERT INTO token_blacklist (user_id, token, expiry) VALUES ($1, $2, $3)",
+		userID,
+		token,
+		expiry,
+	)
 	return err
 }
+
+// Evict does nothing, since the repository keeps no copies of the users it returns
+func (repository *userRepository) Evict(context.Context, ...interfaces.User) {}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ChangeStatus updates the status only while it still equals transition.FromStatus, so two
+// concurrent changes cannot both apply. Leaving the active status revokes the user's sessions.
+func (repository *userRepository) ChangeStatus(ctx context.Context, transition interfaces.StatusTransition) (interfaces.User, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	update := squirrel.Update("users").
+		Set("status", transition.ToStatus).
+		Set("version", squirrel.Expr("version + 1")).
+		Where(squirrel.Eq{
+			"organization_id": transition.OrganizationID,
+			"id":              transition.UserID,
+			"status":          transition.FromStatus,
+			"deleted_at":      nil,
+		}).
+		PlaceholderFormat(repository.dialect.placeholders)
+	if transition.FromStatus == interfaces.UserStatusActive {
+		update = update.Set("sessions_revoked_at", time.Now())
+	}
+	query, args, err := update.ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query: ", zap.Error(err))
+		return interfaces.User{}, err
+	}
+
+	err = inTx(ctx, repository.db, func(tx *sql.Tx) error {
+		if err := execAffectingRowContext(ctx, tx, query, args...); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return repository.missingOrConflict(ctx, transition.OrganizationID, transition.UserID)
+			}
+			logger.Error("Error changing user status:", zap.Int("userID", transition.UserID), zap.Error(err))
+			return err
+		}
+		return insertTransition(ctx, tx, transition)
+	})
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	return repository.GetByID(ctx, transition.OrganizationID, transition.UserID)
+}
+
+// GetStatusHistory lists the user's status transitions, oldest first
+func (repository *userRepository) GetStatusHistory(ctx context.Context, organizationID, userID int) ([]interfaces.StatusTransition, error) {
+	ctx, cancel := repository.withTimeout(ctx)
+	defer cancel()
+
+	query, args, err := squirrel.Select("id", "organization_id", "user_id", "from_status", "to_status", "reason", "changed_by", "created_at").
+		From("user_status_transitions").
+		Where(squirrel.Eq{"organization_id": organizationID, "user_id": userID}).
+		OrderBy("id").
+		PlaceholderFormat(repository.dialect.placeholders).
+		ToSql()
+	if err != nil {
+		logger.Error("Error building SQL query: ", zap.Error(err))
+		return nil, err
+	}
+
+	rows, err := conn(ctx, repository.readDB(ctx)).QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("Error retrieving status history:", zap.Int("userID", userID), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []interfaces.StatusTransition{}
+	for rows.Next() {
+		var transition interfaces.StatusTransition
+		var changedBy sql.NullInt64
+		if err := rows.Scan(
+			&transition.ID,
+			&transition.OrganizationID,
+			&transition.UserID,
+			&transition.FromStatus,
+			&transition.ToStatus,
+			&transition.Reason,
+			&changedBy,
+			&transition.CreatedAt,
+		); err != nil {
+			logger.Error("Error scanning status transition:", zap.Error(err))
+			return nil, err
+		}
+		if changedBy.Valid {
+			id := int(changedBy.Int64)
+			transition.ChangedBy = &id
+		}
+		history = append(history, transition)
+	}
+	return history, rows.Err()
+}
+
+// insertTransition records a status change in the same transaction as the change itself
+func insertTransition(ctx context.Context, tx *sql.Tx, transition interfaces.StatusTransition) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO user_status_transitions (organization_id, user_id, from_status, to_status, reason, changed_by)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		transition.OrganizationID, transition.UserID, transition.FromStatus, transition.ToStatus,
+		transition.Reason, transition.ChangedBy,
+	)
+	if err != nil {
+		logger.Error("Error recording status transition:", zap.Int("userID", transition.UserID), zap.Error(err))
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package interfaces
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	SearchDefaultLimit = 20
+	SearchMaxLimit     = 100
+
+	// SearchHighlightStart and SearchHighlightStop surround the matched words in highlights
+	SearchHighlightStart = "<mark>"
+	SearchHighlightStop  = "</mark>"
+)
+
+var ErrInvalidSearch = errors.New("search query must contain letters or digits")
+
+var (
+	searchWord          = regexp.MustCompile(`[\p{L}\p{N}]+`)
+	searchWordSeparator = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// searchWeights mirror the setweight of the users search_vector: names and usernames rank above
+// email addresses
+var searchWeights = map[string]float64{"name": 1, "username": 1, "email": 0.4}
+
+// UserSearchResult is a user matched by a search, best matches first
+type UserSearchResult struct {
+	User User `json:"user"`
+	// Score combines the full-text rank with the best trigram similarity of name, username and email
+	Score float64 `json:"score"`
+	// Highlights holds the name, username or email with the matched words marked, for the fields
+	// that matched as words. Text outside the marks is HTML-escaped.
+	Highlights map[string]string `json:"highlights"`
+}
+
+// SearchPrefixQuery turns free text into a tsquery matching every word as a prefix, e.g.
+// "jo smi" becomes "jo:* & smi:*". Everything but letters and digits separates words, so
+// the result never contains tsquery syntax from the input.
+func SearchPrefixQuery(text string) (string, error) {
+	var terms []string
+	for _, word := range searchWordSeparator.Split(strings.ToLower(text), -1) {
+		if word != "" {
+			terms = append(terms, word+":*")
+		}
+	}
+	if len(terms) == 0 {
+		return "", ErrInvalidSearch
+	}
+	return strings.Join(terms, " & "), nil
+}
+
+// SearchTerms splits free text into the lower-case words SearchPrefixQuery would match as prefixes
+func SearchTerms(text string) ([]string, error) {
+	terms := searchWord.FindAllString(strings.ToLower(text), -1)
+	if len(terms) == 0 {
+		return nil, ErrInvalidSearch
+	}
+	return terms, nil
+}
+
+// MatchSearch scores a user against the terms of SearchTerms for backends without full-text
+// search. Every term must start a word of the name, username or email, as with the tsquery of
+// SearchPrefixQuery, but misspellings never match and the score is not comparable with
+// PostgreSQL's: it only orders the results.
+func MatchSearch(user User, terms []string) (UserSearchResult, bool) {
+	fields := map[string]string{"name": user.Name, "username": user.Username, "email": user.Email}
+	score := 0.0
+	for _, term := range terms {
+		weight := 0.0
+		for field, value := range fields {
+			if hasWordPrefix(value, term) {
+				weight = max(weight, searchWeights[field])
+			}
+		}
+		if weight == 0 {
+			return UserSearchResult{}, false
+		}
+		score += weight / float64(len(terms))
+	}
+
+	highlights := make(map[string]string)
+	for field, value := range fields {
+		if highlight, ok := highlightWords(value, terms); ok {
+			highlights[field] = highlight
+		}
+	}
+	user.Password = ""
+	return UserSearchResult{User: user, Score: score, Highlights: highlights}, true
+}
+
+// RankSearchResults orders results of MatchSearch best first and keeps at most limit of them
+func RankSearchResults(results []UserSearchResult, limit int) []UserSearchResult {
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// hasWordPrefix reports whether a word of value starts with term, which is lower case
+func hasWordPrefix(value, term string) bool {
+	for _, word := range searchWord.FindAllString(strings.ToLower(value), -1) {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// highlightWords marks the words of value starting with one of the terms and HTML-escapes the
+// rest, like ts_headline and searchHighlights do together
+func highlightWords(value string, terms []string) (string, bool) {
+	var highlighted strings.Builder
+	marked := false
+	last := 0
+	for _, span := range searchWord.FindAllStringIndex(value, -1) {
+		word := value[span[0]:span[1]]
+		for _, term := range terms {
+			if strings.HasPrefix(strings.ToLower(word), term) {
+				highlighted.WriteString(html.EscapeString(value[last:span[0]]))
+				highlighted.WriteString(SearchHighlightStart + html.EscapeString(word) + SearchHighlightStop)
+				last = span[1]
+				marked = true
+				break
+			}
+		}
+	}
+	highlighted.WriteString(html.EscapeString(value[last:]))
+	return highlighted.String(), marked
+}
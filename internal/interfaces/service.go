@@ -1,15 +1,27 @@
 package interfaces
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
+// Service records an audit event for every change it makes to a user, attributed to the
+// AuditSource of ctx, in the unit of work of the change
 type Service interface {
-	GetUsers() ([]User, error)
-	GetUserByUsername(username string) (User, error)
-	GetUserByID(id int) (User, error)
-	CreateUser(user User) (User, error)
-	UpdateUser(user User) (User, error)
-	DeleteUser(id int) (User, error)
-	IsUsernameUnique(username string) (bool, error)
+	GetUsers(ctx context.Context, organizationID int, filter UserFilter) ([]User, error)
+	SearchUsers(ctx context.Context, organizationID int, text string, filter UserFilter, limit int) ([]UserSearchResult, error)
+	GetUserByUsername(ctx context.Context, organizationID int, username string) (User, error)
+	GetUserByID(ctx context.Context, organizationID, id int) (User, error)
+	CreateUser(ctx context.Context, user User) (User, error)
+	UpdateUser(ctx context.Context, user User) (User, error)
+	DeleteUser(ctx context.Context, organizationID, id, version int) (User, error)
+	GetDeletedUsers(ctx context.Context, organizationID int) ([]User, error)
+	RestoreUser(ctx context.Context, organizationID, id int) (User, error)
+	ChangeUserStatus(ctx context.Context, organizationID, id int, status, reason string, changedBy int) (User, error)
+	GetStatusHistory(ctx context.Context, organizationID, id int) ([]StatusTransition, error)
+	PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error)
 	HashPassword(password string) (string, error)
-	Logout(token string, expiry time.Time) error
+	// RecordLogin records the domain event of a user signing in
+	RecordLogin(ctx context.Context, user User) error
+	Logout(ctx context.Context, userID int, token string, expiry time.Time) error
 }
@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+// SessionRepository tells whether a signed session token may still authenticate. Its reads go
+// to the primary database, since a sign-out or revocation must take effect at once.
+type SessionRepository interface {
+	// IsBlacklisted reports whether the token was signed out
+	IsBlacklisted(ctx context.Context, token string) (bool, error)
+	// IsRevoked reports whether the user was deleted, purged or is no longer active, or had their
+	// sessions revoked at or after issuedAt
+	IsRevoked(ctx context.Context, userID int, issuedAt time.Time) (bool, error)
+}
@@ -0,0 +1,83 @@
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	UserStatusPending     = "pending"
+	UserStatusActive      = "active"
+	UserStatusSuspended   = "suspended"
+	UserStatusLocked      = "locked"
+	UserStatusDeactivated = "deactivated"
+	// UserStatusDeleted is set by soft deletion and left by restoring the user
+	UserStatusDeleted = "deleted"
+)
+
+var (
+	ErrInvalidStatus     = errors.New("invalid user status")
+	ErrIllegalTransition = errors.New("illegal user status transition")
+	// ErrUserInactive is returned when a user whose status does not allow signing in tries to sign in
+	ErrUserInactive = errors.New("user account is not active")
+)
+
+// userStatusTransitions lists the statuses each status may change to. Deletion and restoration
+// go through DeleteUser and RestoreUser, and erasure through the privacy endpoints.
+var userStatusTransitions = map[string][]string{
+	UserStatusPending:     {UserStatusActive, UserStatusDeactivated},
+	UserStatusActive:      {UserStatusSuspended, UserStatusLocked, UserStatusDeactivated},
+	UserStatusSuspended:   {UserStatusActive, UserStatusDeactivated},
+	UserStatusLocked:      {UserStatusActive, UserStatusSuspended, UserStatusDeactivated},
+	UserStatusDeactivated: {UserStatusActive},
+}
+
+// UserStatuses are the statuses a user can be created with
+var UserStatuses = []string{UserStatusPending, UserStatusActive}
+
+// StatusTransition records one change of a user's status
+type StatusTransition struct {
+	ID             int       `json:"id"`
+	OrganizationID int       `json:"organization_id"`
+	UserID         int       `json:"user_id"`
+	FromStatus     string    `json:"from_status"`
+	ToStatus       string    `json:"to_status"`
+	Reason         string    `json:"reason"`
+	ChangedBy      *int      `json:"changed_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type StatusChangeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CheckTransition returns ErrIllegalTransition unless the lifecycle allows moving from one status to the other
+func CheckTransition(from, to string) error {
+	if !slices.Contains(userStatusTransitions[from], to) {
+		return fmt.Errorf("%w: %s to %s", ErrIllegalTransition, from, to)
+	}
+	return nil
+}
+
+// CheckInitialStatus rejects users created with a status other than UserStatuses
+func CheckInitialStatus(user User) error {
+	if status := StatusOf(user); !slices.Contains(UserStatuses, status) {
+		return fmt.Errorf("%w: %w %q", ErrInvalidUser, ErrInvalidStatus, status)
+	}
+	return nil
+}
+
+// StatusOf returns the user's status; users without one are active
+func StatusOf(user User) string {
+	if user.Status == nil || *user.Status == "" {
+		return UserStatusActive
+	}
+	return *user.Status
+}
+
+// CanAuthenticate reports whether the user may sign in and use the tokens issued to them
+func CanAuthenticate(user User) bool {
+	return StatusOf(user) == UserStatusActive
+}
@@ -0,0 +1,93 @@
+package interfaces
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// TxDefaultRetries is how often a unit of work is retried after a serialization failure or a
+// deadlock when TxOptions.MaxRetries is zero
+const TxDefaultRetries = 3
+
+// TxOptions configures a unit of work. The zero value runs at the database's default isolation
+// level with TxDefaultRetries retries.
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// MaxRetries is how often the work is retried; a negative value disables retries
+	MaxRetries int
+}
+
+// TxManager runs units of work in a database transaction carried by their context. Repository
+// calls made with that context join the transaction, and so do nested units of work, which
+// commit with the outermost one. Work may run more than once when it is retried, so it must not
+// have side effects outside the database.
+type TxManager interface {
+	WithinTx(ctx context.Context, options TxOptions, fn func(ctx context.Context) error) error
+}
+
+type unitOfWorkKey struct{}
+
+// unitOfWork collects the functions to run once the work committed
+type unitOfWork struct {
+	mutex       sync.Mutex
+	afterCommit []func()
+}
+
+// WithUnitOfWork marks ctx as carrying a new unit of work. TxManager implementations mark the
+// context they pass to every attempt of the work, and call Committed with it once it committed.
+func WithUnitOfWork(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unitOfWorkKey{}, &unitOfWork{})
+}
+
+// InUnitOfWork reports whether ctx carries a unit of work, whose reads must see its own writes
+func InUnitOfWork(ctx context.Context) bool {
+	_, inside := ctx.Value(unitOfWorkKey{}).(*unitOfWork)
+	return inside
+}
+
+// AfterCommit runs fn once the unit of work carried by ctx committed, and never when it rolls
+// back, for effects that must not be seen before the change is, such as dropping cached copies
+// of what it changed. Outside of a unit of work fn runs immediately.
+func AfterCommit(ctx context.Context, fn func()) {
+	work, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork)
+	if !ok {
+		fn()
+		return
+	}
+	work.mutex.Lock()
+	defer work.mutex.Unlock()
+	work.afterCommit = append(work.afterCommit, fn)
+}
+
+// Committed runs the functions registered with AfterCommit in the unit of work carried by ctx,
+// in the order they were registered
+func Committed(ctx context.Context) {
+	work, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork)
+	if !ok {
+		return
+	}
+	work.mutex.Lock()
+	hooks := work.afterCommit
+	work.afterCommit = nil
+	work.mutex.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+type primaryKey struct{}
+
+// WithPrimary marks ctx so repository reads go to the primary database instead of a read
+// replica, for work that must see writes it or its caller just made. Reads in a unit of work
+// always go to the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UsesPrimary reports whether ctx was marked by WithPrimary
+func UsesPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
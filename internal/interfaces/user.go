@@ -1,12 +1,56 @@
 package interfaces
 
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+)
+
+// ErrInvalidUser wraps field validation failures
+var ErrInvalidUser = errors.New("invalid user")
+
+// ErrVersionConflict is returned when a write expected a version the user no longer has
+var ErrVersionConflict = errors.New("user was modified by another request")
+
 type User struct {
-	ID       int     `json:"id"`
-	Name     string  `json:"name"`
-	Email    string  `json:"email" gorm:"unique;not null" validate:"required,email"`
-	Status   *string `json:"status"`
-	Username string  `json:"username" gorm:"unique;not null" validate:"required"`
-	Password string  `json:"password" validate:"required"`
+	ID             int        `json:"id"`
+	OrganizationID int        `json:"organization_id" gorm:"uniqueIndex:idx_users_organization_username;uniqueIndex:idx_users_organization_email;not null"`
+	Name           string     `json:"name"`
+	Email          string     `json:"email" gorm:"uniqueIndex:idx_users_organization_email;not null" validate:"required,email"`
+	Status         *string    `json:"status"`
+	Username       string     `json:"username" gorm:"uniqueIndex:idx_users_organization_username;not null" validate:"required"`
+	Password       string     `json:"password" validate:"required"`
+	Version        int        `json:"version"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	// Attributes holds the organization's custom profile fields, see AttributeDefinition
+	Attributes map[string]interface{} `json:"attributes"`
+	// Avatar is set on responses only; it is managed through the avatar endpoints
+	Avatar *Avatar `json:"avatar,omitempty"`
+}
+
+// Validate checks the user's fields against the column constraints of the users table
+func (user User) Validate() error {
+	switch {
+	case user.Username == "":
+		return fmt.Errorf("%w: username is required", ErrInvalidUser)
+	case len(user.Username) > 100:
+		return fmt.Errorf("%w: username must be at most 100 characters", ErrInvalidUser)
+	case len(user.Name) > 100:
+		return fmt.Errorf("%w: name must be at most 100 characters", ErrInvalidUser)
+	case len(user.Email) > 100:
+		return fmt.Errorf("%w: email must be at most 100 characters", ErrInvalidUser)
+	}
+	if err := CheckUsername(user.Username); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidUser, err)
+	}
+	if _, ok := userStatusTransitions[StatusOf(user)]; !ok {
+		return fmt.Errorf("%w: %w %q", ErrInvalidUser, ErrInvalidStatus, StatusOf(user))
+	}
+	if address, err := mail.ParseAddress(user.Email); err != nil || address.Address != user.Email {
+		return fmt.Errorf("%w: email must be a valid address", ErrInvalidUser)
+	}
+	return nil
 }
 
 type LoginRequest struct {
@@ -15,9 +59,9 @@ type LoginRequest struct {
 }
 
 type RegisterRequest struct {
-	Name     string  `json:"name" validate:"required"`
-	Email    string  `json:"email" validate:"required,email"`
-	Status   *string `json:"status"`
-	Username string  `json:"username" validate:"required"`
-	Password string  `json:"password" validate:"required"`
+	Name       string                 `json:"name" validate:"required"`
+	Email      string                 `json:"email" validate:"required,email"`
+	Username   string                 `json:"username" validate:"required"`
+	Password   string                 `json:"password" validate:"required"`
+	Attributes map[string]interface{} `json:"attributes"`
 }
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ErasureInterval is how often erasures past their grace period are carried out
+const ErasureInterval = 15 * time.Minute
+
+// EraseDueUsers carries out the erasures whose grace period is over, once immediately and then
+// every interval, until stop is closed.
+func EraseDueUsers(service interfaces.PrivacyService, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		erased, err := service.EraseDueUsers(context.Background())
+		if err != nil {
+			logger.Error("Error erasing users:", zap.Error(err))
+		} else if erased > 0 {
+			logger.Info("Erased users", zap.Int("count", erased))
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
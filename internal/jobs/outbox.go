@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// RelayEvents publishes the user events waiting in the outbox, once immediately and then every
+// interval, until stop is closed.
+func RelayEvents(relay interfaces.OutboxRelay, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := relay.Relay(context.Background())
+		if err != nil {
+			logger.Error("Error relaying user events:", zap.Error(err))
+		} else if published > 0 {
+			logger.Debug("Relayed user events", zap.Int("count", published))
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// PurgeInterval is how often soft-deleted users past their retention period are removed
+const PurgeInterval = time.Hour
+
+// PurgeDeletedUsers permanently removes users soft-deleted longer than retention ago, once
+// immediately and then every interval, until stop is closed.
+func PurgeDeletedUsers(service interfaces.Service, retention, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := service.PurgeDeletedUsers(context.Background(), retention)
+		if err != nil {
+			logger.Error("Error purging deleted users:", zap.Error(err))
+		} else if purged > 0 {
+			logger.Info("Purged deleted users", zap.Int64("count", purged), zap.Duration("retention", retention))
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
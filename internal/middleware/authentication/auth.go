@@ -1,31 +1,49 @@
 package authentication
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	_ "github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
-	"github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
 	"github.com/redbonzai/user-management-api/pkg/logger"
 	"go.uber.org/zap"
 )
 
-// JWTMiddleware checks for a valid JWT token
-func JWTMiddleware() echo.MiddlewareFunc {
+// ClaimsContextKey is the echo context key holding the validated *Claims
+const ClaimsContextKey = "claims"
+
+// ErrSessionUnverified is returned when a token could not be checked against the sessions that
+// were signed out or revoked. The token is refused: a revoked session must never get through.
+var ErrSessionUnverified = errors.New("session could not be verified")
+
+// JWTMiddleware checks for a valid JWT token and stores its claims on the context. A token whose
+// session cannot be checked in sessions is answered with 503.
+func JWTMiddleware(sessions interfaces.SessionRepository) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			authHeader := c.Request().Header.Get("Authorization")
-			if authHeader == "" {
+			tokenStr, ok := TokenFromHeader(c.Request().Header.Get("Authorization"))
+			if !ok {
 				return c.JSON(http.StatusUnauthorized, "missing or malformed jwt")
 			}
-			tokenStr := strings.Split(authHeader, " ")[1]
 
-			// Check if token is blacklisted
-			if isBlacklisted(tokenStr) {
+			// Rejects blacklisted, revoked, expired and badly signed tokens
+			claims, err := ParseClaims(c.Request().Context(), sessions, tokenStr)
+			if errors.Is(err, ErrSessionUnverified) {
+				logger.Error("Error verifying session: ", zap.Error(err))
+				return c.JSON(http.StatusServiceUnavailable, "could not verify the session")
+			}
+			if err != nil {
+				logger.Error("Error parsing token: ", zap.Error(err))
 				return c.JSON(http.StatusUnauthorized, "invalid or expired jwt")
 			}
+			c.Set(ClaimsContextKey, claims)
+			c.Set("username", claims.Username)
 
 			// Continue with the next handler
 			return next(c)
@@ -33,34 +51,62 @@ func JWTMiddleware() echo.MiddlewareFunc {
 	}
 }
 
-func isBlacklisted(token string) bool {
-	var count int
-	query := `SELECT COUNT(*) FROM token_blacklist WHERE token = $1`
-	err := db.DB.QueryRow(query, token).Scan(&count)
+// TokenFromHeader extracts the token from a "Bearer <token>" Authorization header
+func TokenFromHeader(authHeader string) (string, bool) {
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// ClaimsFromContext returns the claims stored by JWTMiddleware, if any
+func ClaimsFromContext(c echo.Context) (*Claims, bool) {
+	claims, ok := c.Get(ClaimsContextKey).(*Claims)
+	return claims, ok
+}
+
+// checkSession fails closed: a session that cannot be checked is refused with
+// ErrSessionUnverified
+func checkSession(ctx context.Context, sessions interfaces.SessionRepository, token string, claims *Claims) error {
+	blacklisted, err := sessions.IsBlacklisted(ctx, token)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSessionUnverified, err)
+	}
+	if blacklisted {
+		return fmt.Errorf("token is blacklisted")
+	}
+	revoked, err := sessions.IsRevoked(ctx, claims.UserID, time.Unix(claims.IssuedAt, 0))
 	if err != nil {
-		logger.Error("Error checking token blacklist: ", zap.Error(err))
-		return false
+		return fmt.Errorf("%w: %v", ErrSessionUnverified, err)
+	}
+	if revoked {
+		return fmt.Errorf("token has been revoked")
 	}
-	return count > 0
+	return nil
 }
 
 // AuthMiddleware checks if the user is authenticated
-func AuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(context echo.Context) error {
-		authHeader := context.Request().Header.Get("Authorization")
-
-		if authHeader == "" {
-			return context.JSON(http.StatusUnauthorized, "missing or malformed jwt")
-		}
-		tokenStr := strings.Split(authHeader, " ")[1]
-		username, err := ParseToken(tokenStr)
-		fmt.Printf("USERNAME: %v\n", username)
+func AuthMiddleware(sessions interfaces.SessionRepository) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(context echo.Context) error {
+			tokenStr, ok := TokenFromHeader(context.Request().Header.Get("Authorization"))
+			if !ok {
+				return context.JSON(http.StatusUnauthorized, "missing or malformed jwt")
+			}
+			username, err := ParseToken(context.Request().Context(), sessions, tokenStr)
+			fmt.Printf("USERNAME: %v\n", username)
 
-		if err != nil {
-			logger.Error("Error parsing token: ", zap.Error(err))
-			return context.JSON(http.StatusUnauthorized, "invalid or expired jwt")
+			if errors.Is(err, ErrSessionUnverified) {
+				logger.Error("Error verifying session: ", zap.Error(err))
+				return context.JSON(http.StatusServiceUnavailable, "could not verify the session")
+			}
+			if err != nil {
+				logger.Error("Error parsing token: ", zap.Error(err))
+				return context.JSON(http.StatusUnauthorized, "invalid or expired jwt")
+			}
+			context.Set("username", username)
+			return next(context)
 		}
-		context.Set("username", username)
-		return next(context)
 	}
 }
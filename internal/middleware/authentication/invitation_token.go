@@ -0,0 +1,52 @@
+package authentication
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+const invitationSubject = "invitation"
+
+type InvitationClaims struct {
+	InvitationID   int    `json:"invitation_id"`
+	OrganizationID int    `json:"org_id"`
+	Email          string `json:"email"`
+	jwt.StandardClaims
+}
+
+// GenerateInvitationToken signs a token that expires together with the invitation
+func GenerateInvitationToken(invitation interfaces.Invitation) (string, error) {
+	claims := &InvitationClaims{
+		InvitationID:   invitation.ID,
+		OrganizationID: invitation.OrganizationID,
+		Email:          invitation.Email,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   invitationSubject,
+			ExpiresAt: invitation.ExpiresAt.Unix(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(os.Getenv("SECRET_KEY")))
+}
+
+// ParseInvitationToken validates an invitation token and returns its claims
+func ParseInvitationToken(tokenStr string) (*InvitationClaims, error) {
+	claims := &InvitationClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(os.Getenv("SECRET_KEY")), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.Subject != invitationSubject {
+		return nil, fmt.Errorf("invalid invitation token")
+	}
+	return claims, nil
+}
@@ -1,26 +1,33 @@
 package authentication
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
 )
 
 var jwtSecret = []byte(os.Getenv("SECRET_KEY"))
 
 type Claims struct {
-	Username string `json:"username"`
+	Username       string `json:"username"`
+	UserID         int    `json:"user_id"`
+	OrganizationID int    `json:"org_id"`
 	jwt.StandardClaims
 }
 
-// GenerateToken generates a JWT token
-func GenerateToken(username string) (string, error) {
+// GenerateToken generates a JWT token scoped to the user's organization
+func GenerateToken(user interfaces.User) (string, error) {
 	claims := &Claims{
-		Username: username,
+		Username:       user.Username,
+		UserID:         user.ID,
+		OrganizationID: user.OrganizationID,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(time.Hour * 72).Unix(),
+			IssuedAt:  time.Now().Unix(),
 		},
 	}
 
@@ -28,14 +35,12 @@ func GenerateToken(username string) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
-// ParseToken parses and validates the token, returning the username
-func ParseToken(tokenStr string) (string, error) {
-	if isBlacklisted(tokenStr) {
-		return "", fmt.Errorf("token is blacklisted")
-	}
-	
+// ParseClaims parses and validates the token, returning its claims. Tokens signed out or revoked
+// in sessions are rejected, and so are those it cannot check, with ErrSessionUnverified.
+func ParseClaims(ctx context.Context, sessions interfaces.SessionRepository, tokenStr string) (*Claims, error) {
 	secretKey := []byte(os.Getenv("SECRET_KEY"))
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
@@ -43,12 +48,24 @@ func ParseToken(tokenStr string) (string, error) {
 	})
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims["username"].(string), nil
+	// Invitation tokens share the signing key but never authenticate a session
+	if !token.Valid || claims.Subject == invitationSubject {
+		return nil, fmt.Errorf("invalid token")
+	}
+	if err := checkSession(ctx, sessions, tokenStr, claims); err != nil {
+		return nil, err
 	}
 
-	return "", fmt.Errorf("invalid token")
+	return claims, nil
+}
+
+// ParseToken parses and validates the token, returning the username
+func ParseToken(ctx context.Context, sessions interfaces.SessionRepository, tokenStr string) (string, error) {
+	claims, err := ParseClaims(ctx, sessions, tokenStr)
+	if err != nil {
+		return "", err
+	}
+	return claims.Username, nil
 }
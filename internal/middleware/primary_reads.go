@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// PrimaryReads sends every read of a request that changes something to the primary database, so
+// the checks it makes before writing see the latest state. Reads of safe requests may go to a
+// read replica.
+func PrimaryReads(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(context echo.Context) error {
+		switch context.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			request := context.Request()
+			context.SetRequest(request.WithContext(interfaces.WithPrimary(request.Context())))
+		}
+		return next(context)
+	}
+}
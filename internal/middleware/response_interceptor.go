@@ -3,6 +3,7 @@ package middleware
 import (
 	"bytes"
 	"encoding/json"
+	"mime"
 	"net/http"
 	"os"
 
@@ -17,17 +18,25 @@ type ResponseWrapper struct {
 type bodyWriter struct {
 	http.ResponseWriter
 	body *bytes.Buffer
+	// streaming is set when the handler writes a body that is not JSON, such as a file export;
+	// such bodies are written through as they come instead of being buffered and wrapped
+	streaming bool
 }
 
 func ResponseInterceptor(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(context echo.Context) error {
 		rec := context.Response().Writer
 		buf := new(bytes.Buffer)
-		context.Response().Writer = &bodyWriter{ResponseWriter: rec, body: buf}
+		writer := &bodyWriter{ResponseWriter: rec, body: buf}
+		context.Response().Writer = writer
 
 		if err := next(context); err != nil {
 			context.Error(err)
 		}
+		if writer.streaming {
+			context.Response().Writer = rec
+			return nil
+		}
 
 		respBody := buf.Bytes()
 		var originalResponse interface{}
@@ -58,5 +67,21 @@ func ResponseInterceptor(next echo.HandlerFunc) echo.HandlerFunc {
 }
 
 func (writer *bodyWriter) Write(bytes []byte) (int, error) {
+	if !writer.streaming && writer.body.Len() == 0 && !isJSON(writer.Header().Get(echo.HeaderContentType)) {
+		writer.streaming = true
+	}
+	if writer.streaming {
+		return writer.ResponseWriter.Write(bytes)
+	}
 	return writer.body.Write(bytes)
 }
+
+// isJSON reports whether a response with this content type gets wrapped. Responses without a
+// content type are assumed to be JSON.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == echo.MIMEApplicationJSON
+}
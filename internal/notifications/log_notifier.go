@@ -0,0 +1,32 @@
+package notifications
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type logNotifier struct {
+	acceptURL string
+}
+
+// NewLogNotifier returns a Notifier that logs the invitation link instead of sending it.
+// It stands in for an email provider in development.
+func NewLogNotifier(acceptURL string) interfaces.Notifier {
+	return &logNotifier{acceptURL}
+}
+
+func (notifier *logNotifier) NotifyInvitation(invitation interfaces.Invitation, token string) error {
+	link := fmt.Sprintf("%s?token=%s", notifier.acceptURL, url.QueryEscape(token))
+	logger.Info(
+		"Invitation issued",
+		zap.Int("invitationID", invitation.ID),
+		zap.Int("organizationID", invitation.OrganizationID),
+		zap.String("email", invitation.Email),
+		zap.String("link", link),
+	)
+	return nil
+}
@@ -0,0 +1,51 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	MediaTypeMergePatch = "application/merge-patch+json"
+	MediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalidPatch means the patch document is malformed or cannot be applied to the target
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrTestFailed means a JSON Patch test operation did not match
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to a JSON document: members set to null are
+// removed, objects are merged recursively and any other value replaces the target.
+func MergePatch(document, patch []byte) ([]byte, error) {
+	var target, changes interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return json.Marshal(mergeValue(target, changes))
+}
+
+func mergeValue(target, changes interface{}) interface{} {
+	changedMembers, ok := changes.(map[string]interface{})
+	if !ok {
+		return changes
+	}
+	members, ok := target.(map[string]interface{})
+	if !ok {
+		members = make(map[string]interface{})
+	}
+	for name, value := range changedMembers {
+		if value == nil {
+			delete(members, name)
+			continue
+		}
+		members[name] = mergeValue(members[name], value)
+	}
+	return members
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+type attributeService struct {
+	repo          interfaces.AttributeRepository
+	organizations interfaces.OrganizationService
+}
+
+func NewAttributeService(
+	repo interfaces.AttributeRepository,
+	organizations interfaces.OrganizationService,
+) interfaces.AttributeService {
+	return &attributeService{repo, organizations}
+}
+
+func (service *attributeService) GetDefinitions(
+	ctx context.Context,
+	organizationID int,
+) ([]interfaces.AttributeDefinition, error) {
+	return service.repo.GetDefinitions(ctx, organizationID)
+}
+
+func (service *attributeService) CreateDefinition(
+	ctx context.Context,
+	definition interfaces.AttributeDefinition,
+) (interfaces.AttributeDefinition, error) {
+	if definition.Visibility == "" {
+		definition.Visibility = interfaces.AttributeVisibilityPublic
+	}
+	if err := definition.Validate(); err != nil {
+		return definition, err
+	}
+	return service.repo.CreateDefinition(ctx, definition)
+}
+
+func (service *attributeService) UpdateDefinition(
+	ctx context.Context,
+	definition interfaces.AttributeDefinition,
+) (interfaces.AttributeDefinition, error) {
+	if definition.Visibility == "" {
+		definition.Visibility = interfaces.AttributeVisibilityPublic
+	}
+	if err := definition.Validate(); err != nil {
+		return definition, err
+	}
+	return service.repo.UpdateDefinition(ctx, definition)
+}
+
+func (service *attributeService) DeleteDefinition(ctx context.Context, organizationID int, name string) error {
+	return service.repo.DeleteDefinition(ctx, organizationID, name)
+}
+
+// ValidateAttributes rejects undefined attributes, missing required ones and values that do not
+// match their definition. Unique values are only claimed by ClaimUniqueValues, in the transaction
+// of the write.
+func (service *attributeService) ValidateAttributes(ctx context.Context, user interfaces.User) error {
+	definitions, err := service.definitionsByName(ctx, user.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(user.Attributes))
+	for name := range user.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := definitions[name]; !ok {
+			return fmt.Errorf("%w: %s is not defined", interfaces.ErrInvalidAttribute, name)
+		}
+	}
+
+	for _, definition := range sortedDefinitions(definitions) {
+		value, ok := user.Attributes[definition.Name]
+		if !ok || value == nil {
+			if definition.Required {
+				return fmt.Errorf("%w: %s is required", interfaces.ErrInvalidAttribute, definition.Name)
+			}
+			continue
+		}
+		if err := definition.Check(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (service *attributeService) ClaimUniqueValues(ctx context.Context, user interfaces.User) error {
+	values := map[string]interface{}{}
+	if user.DeletedAt == nil {
+		definitions, err := service.repo.GetDefinitions(ctx, user.OrganizationID)
+		if err != nil {
+			return err
+		}
+		for _, definition := range definitions {
+			if definition.Unique && user.Attributes[definition.Name] != nil {
+				values[definition.Name] = user.Attributes[definition.Name]
+			}
+		}
+	}
+	return service.repo.ClaimValues(ctx, user, values)
+}
+
+func (service *attributeService) ParseFilter(
+	ctx context.Context,
+	organizationID, callerID int,
+	values map[string]string,
+) (map[string]interface{}, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	visible, err := service.visibleDefinitions(ctx, organizationID, callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := make(map[string]interface{}, len(values))
+	for name, raw := range values {
+		definition, ok := visible[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not defined", interfaces.ErrInvalidAttribute, name)
+		}
+		value, err := definition.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		filter[name] = value
+	}
+	return filter, nil
+}
+
+// View keeps only the defined attributes the caller may see. Organization owners and admins see
+// every defined attribute.
+func (service *attributeService) View(
+	ctx context.Context,
+	organizationID, callerID int,
+	users []interfaces.User,
+) ([]interfaces.User, error) {
+	visible, err := service.visibleDefinitions(ctx, organizationID, callerID)
+	if err != nil {
+		return nil, err
+	}
+
+	viewed := make([]interfaces.User, len(users))
+	for i, user := range users {
+		attributes := make(map[string]interface{}, len(user.Attributes))
+		for name, value := range user.Attributes {
+			if _, ok := visible[name]; ok {
+				attributes[name] = value
+			}
+		}
+		user.Attributes = attributes
+		viewed[i] = user
+	}
+	return viewed, nil
+}
+
+func (service *attributeService) HiddenAttributes(
+	ctx context.Context,
+	organizationID, callerID int,
+) (map[string]bool, error) {
+	definitions, err := service.definitionsByName(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := service.visibleDefinitions(ctx, organizationID, callerID)
+	if err != nil {
+		return nil, err
+	}
+	hidden := make(map[string]bool)
+	for name := range definitions {
+		if _, ok := visible[name]; !ok {
+			hidden[name] = true
+		}
+	}
+	return hidden, nil
+}
+
+func (service *attributeService) visibleDefinitions(
+	ctx context.Context,
+	organizationID, callerID int,
+) (map[string]interfaces.AttributeDefinition, error) {
+	definitions, err := service.definitionsByName(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := service.organizations.GetMembership(ctx, organizationID, callerID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil && membership.CanManage() {
+		return definitions, nil
+	}
+
+	for name, definition := range definitions {
+		if definition.Visibility != interfaces.AttributeVisibilityPublic {
+			delete(definitions, name)
+		}
+	}
+	return definitions, nil
+}
+
+func (service *attributeService) definitionsByName(
+	ctx context.Context,
+	organizationID int,
+) (map[string]interfaces.AttributeDefinition, error) {
+	definitions, err := service.repo.GetDefinitions(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]interfaces.AttributeDefinition, len(definitions))
+	for _, definition := range definitions {
+		byName[definition.Name] = definition
+	}
+	return byName, nil
+}
+
+// sortedDefinitions orders definitions by name so validation reports the same error every time
+func sortedDefinitions(definitions map[string]interfaces.AttributeDefinition) []interfaces.AttributeDefinition {
+	sorted := make([]interfaces.AttributeDefinition, 0, len(definitions))
+	for _, definition := range definitions {
+		sorted = append(sorted, definition)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// auditVerifyBatch is how many events Verify loads per query
+const auditVerifyBatch = 500
+
+type auditService struct {
+	repo interfaces.AuditRepository
+}
+
+func NewAuditService(repo interfaces.AuditRepository) interfaces.AuditService {
+	return &auditService{repo}
+}
+
+func (service *auditService) Record(ctx context.Context, event interfaces.AuditEvent) error {
+	_, err := service.repo.Append(ctx, event)
+	return err
+}
+
+func (service *auditService) GetEvents(
+	ctx context.Context,
+	organizationID int,
+	filter interfaces.AuditFilter,
+) ([]interfaces.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = interfaces.AuditDefaultPageLimit
+	}
+	if filter.Limit > interfaces.AuditMaxPageLimit {
+		filter.Limit = interfaces.AuditMaxPageLimit
+	}
+	return service.repo.GetEvents(ctx, organizationID, filter)
+}
+
+// Verify walks the chain from the first event, checking that each event links to its
+// predecessor and that its hash still matches its content
+func (service *auditService) Verify(ctx context.Context) (interfaces.AuditVerification, error) {
+	result := interfaces.AuditVerification{Valid: true}
+	previousHash := interfaces.AuditGenesisHash
+	var lastID int64
+
+	for {
+		events, err := service.repo.GetChain(ctx, lastID, auditVerifyBatch)
+		if err != nil {
+			return result, err
+		}
+		for _, event := range events {
+			result.Checked++
+			switch {
+			case event.PrevHash != previousHash:
+				result.Valid = false
+				result.BrokenAt = event.ID
+				result.Reason = fmt.Sprintf("event %d does not link to the preceding event", event.ID)
+				return result, nil
+			case event.ComputeHash() != event.Hash:
+				result.Valid = false
+				result.BrokenAt = event.ID
+				result.Reason = fmt.Sprintf("event %d content does not match its hash", event.ID)
+				return result, nil
+			}
+			previousHash = event.Hash
+			lastID = event.ID
+		}
+		if len(events) < auditVerifyBatch {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/imaging"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type avatarService struct {
+	repo  interfaces.AvatarRepository
+	store interfaces.BlobStore
+	users interfaces.Service
+}
+
+func NewAvatarService(
+	repo interfaces.AvatarRepository,
+	store interfaces.BlobStore,
+	users interfaces.Service,
+) interfaces.AvatarService {
+	return &avatarService{repo, store, users}
+}
+
+// Upload stores the new variants under a fresh key prefix before switching the user over to them,
+// so a failed upload leaves the previous avatar in place
+func (service *avatarService) Upload(ctx context.Context, organizationID, userID int, body io.Reader) (interfaces.Avatar, error) {
+	if _, err := service.users.GetUserByID(ctx, organizationID, userID); err != nil {
+		return interfaces.Avatar{}, err
+	}
+	img, uploadedType, err := imaging.Decode(body)
+	if err != nil {
+		return interfaces.Avatar{}, err
+	}
+
+	avatar := interfaces.Avatar{
+		UserID:         userID,
+		OrganizationID: organizationID,
+		KeyPrefix:      fmt.Sprintf("avatars/org-%d/user-%d/%d", organizationID, userID, time.Now().UnixNano()),
+	}
+	var stored []string
+	for _, variant := range interfaces.AvatarVariants() {
+		rendered := img
+		if size, err := strconv.Atoi(variant); err == nil {
+			rendered = imaging.Thumbnail(img, size)
+		}
+		if err := service.put(&avatar, variant, rendered, uploadedType); err != nil {
+			service.discard(stored)
+			return interfaces.Avatar{}, err
+		}
+		stored = append(stored, avatar.Key(variant))
+	}
+
+	previous, err := service.repo.Get(ctx, organizationID, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		service.discard(stored)
+		return interfaces.Avatar{}, err
+	}
+	saved, err := service.repo.Save(ctx, avatar)
+	if err != nil {
+		service.discard(stored)
+		return interfaces.Avatar{}, err
+	}
+	if previous.KeyPrefix != "" {
+		service.discard(avatarKeys(previous))
+	}
+	return service.withURLs(saved)
+}
+
+func (service *avatarService) Open(
+	ctx context.Context,
+	organizationID, userID int,
+	variant string,
+) (io.ReadCloser, string, error) {
+	if !slices.Contains(interfaces.AvatarVariants(), variant) {
+		return nil, "", interfaces.ErrBlobNotFound
+	}
+	avatar, err := service.repo.Get(ctx, organizationID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	body, err := service.store.Get(avatar.Key(variant))
+	return body, avatar.ContentType, err
+}
+
+func (service *avatarService) Delete(ctx context.Context, organizationID, userID int) error {
+	avatar, err := service.repo.Get(ctx, organizationID, userID)
+	if err != nil {
+		return err
+	}
+	if err := service.repo.Delete(ctx, organizationID, userID); err != nil {
+		return err
+	}
+	return service.store.Delete(avatarKeys(avatar)...)
+}
+
+func (service *avatarService) Attach(
+	ctx context.Context,
+	organizationID int,
+	users []interfaces.User,
+) ([]interfaces.User, error) {
+	userIDs := make([]int, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+	avatars, err := service.repo.GetMany(ctx, organizationID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	attached := make([]interfaces.User, len(users))
+	for i, user := range users {
+		if avatar, ok := avatars[user.ID]; ok {
+			avatar, err = service.withURLs(avatar)
+			if err != nil {
+				return nil, err
+			}
+			user.Avatar = &avatar
+		}
+		attached[i] = user
+	}
+	return attached, nil
+}
+
+func (service *avatarService) put(avatar *interfaces.Avatar, variant string, img image.Image, uploadedType string) error {
+	var encoded bytes.Buffer
+	contentType, err := imaging.Encode(&encoded, img, uploadedType)
+	if err != nil {
+		return err
+	}
+	avatar.ContentType = contentType
+	return service.store.Put(avatar.Key(variant), contentType, &encoded)
+}
+
+// withURLs fills in presigned URLs, or the API paths that serve the variants when the store cannot sign them
+func (service *avatarService) withURLs(avatar interfaces.Avatar) (interfaces.Avatar, error) {
+	avatar.Thumbnails = make(map[string]string, len(interfaces.AvatarSizes))
+	for _, variant := range interfaces.AvatarVariants() {
+		url, err := service.store.URL(avatar.Key(variant), interfaces.AvatarURLExpiry)
+		if err != nil {
+			return avatar, err
+		}
+		if url == "" {
+			url = fmt.Sprintf("/v1/users/%d/avatar", avatar.UserID)
+			if variant != interfaces.AvatarOriginal {
+				url += "?size=" + variant
+			}
+		}
+		if variant == interfaces.AvatarOriginal {
+			avatar.URL = url
+		} else {
+			avatar.Thumbnails[variant] = url
+		}
+	}
+	return avatar, nil
+}
+
+// discard removes blobs that are no longer referenced; failures only leave orphaned objects behind
+func (service *avatarService) discard(keys []string) {
+	if err := service.store.Delete(keys...); err != nil {
+		logger.Error("Error removing avatar blobs:", zap.Strings("keys", keys), zap.Error(err))
+	}
+}
+
+func avatarKeys(avatar interfaces.Avatar) []string {
+	var keys []string
+	for _, variant := range interfaces.AvatarVariants() {
+		keys = append(keys, avatar.Key(variant))
+	}
+	return keys
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/exporter"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+type exportService struct {
+	repo  interfaces.Repository
+	store interfaces.ExportStore
+}
+
+// NewExportService streams users from the repository. Store may be nil when no export
+// destination is configured, in which case ExportToStore fails with ErrExportDestination.
+func NewExportService(repo interfaces.Repository, store interfaces.ExportStore) interfaces.ExportService {
+	return &exportService{repo, store}
+}
+
+// Export encodes the matching users to writer as they are read, without loading them all
+func (service *exportService) Export(
+	ctx context.Context,
+	organizationID int,
+	writer io.Writer,
+	options interfaces.ExportOptions,
+) (interfaces.ExportResult, error) {
+	options, err := exporter.ValidateOptions(options)
+	if err != nil {
+		return interfaces.ExportResult{}, err
+	}
+	output, err := exporter.NewWriter(writer, options.Format, options.Fields)
+	if err != nil {
+		return interfaces.ExportResult{}, err
+	}
+
+	result := interfaces.ExportResult{Format: options.Format}
+	err = service.repo.StreamUsers(ctx, organizationID, options.Filter, func(user interfaces.User) error {
+		result.Rows++
+		return output.Write(user)
+	})
+	if err != nil {
+		return result, err
+	}
+	return result, output.Close()
+}
+
+// ExportToStore pipes the export into the store as it is produced, under a key named after the
+// organization and the time of the export
+func (service *exportService) ExportToStore(
+	ctx context.Context,
+	organizationID int,
+	options interfaces.ExportOptions,
+) (interfaces.ExportResult, error) {
+	if service.store == nil {
+		return interfaces.ExportResult{}, interfaces.ErrExportDestination
+	}
+	options, err := exporter.ValidateOptions(options)
+	if err != nil {
+		return interfaces.ExportResult{}, err
+	}
+	key := fmt.Sprintf(
+		"users/org-%d/%s.%s",
+		organizationID,
+		time.Now().UTC().Format("20060102T150405.000Z"),
+		options.Format,
+	)
+
+	reader, writer := io.Pipe()
+	exported := make(chan error, 1)
+	var result interfaces.ExportResult
+	go func() {
+		var err error
+		result, err = service.Export(ctx, organizationID, writer, options)
+		writer.CloseWithError(err)
+		exported <- err
+	}()
+
+	location, uploadErr := service.store.Upload(key, exporter.ContentTypes[options.Format], reader)
+	// Unblocks the export if the upload stopped reading early
+	reader.CloseWithError(io.ErrClosedPipe)
+	exportErr := <-exported
+	if uploadErr != nil {
+		return result, uploadErr
+	}
+	if exportErr != nil {
+		return result, exportErr
+	}
+	result.Location = location
+	return result, nil
+}
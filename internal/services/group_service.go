@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type groupService struct {
+	repo          interfaces.GroupRepository
+	organizations interfaces.OrganizationService
+	tx            interfaces.TxManager
+}
+
+func NewGroupService(
+	repo interfaces.GroupRepository,
+	organizations interfaces.OrganizationService,
+	tx interfaces.TxManager,
+) interfaces.GroupService {
+	return &groupService{repo, organizations, tx}
+}
+
+func (service *groupService) GetGroups(ctx context.Context, organizationID int) ([]interfaces.Group, error) {
+	return service.repo.GetAll(ctx, organizationID)
+}
+
+func (service *groupService) GetGroup(ctx context.Context, organizationID, id int) (interfaces.Group, error) {
+	return service.repo.GetByID(ctx, organizationID, id)
+}
+
+func (service *groupService) CreateGroup(ctx context.Context, group interfaces.Group) (interfaces.Group, error) {
+	if group.ParentID != nil {
+		if _, err := service.repo.GetByID(ctx, group.OrganizationID, *group.ParentID); err != nil {
+			return group, err
+		}
+	}
+	return service.repo.Create(ctx, group)
+}
+
+// UpdateGroup rejects parent changes that would make the group its own ancestor. The check and
+// the update share a unit of work that locks the organization's groups, so two concurrent moves
+// cannot each pass the check and together form a cycle.
+func (service *groupService) UpdateGroup(ctx context.Context, group interfaces.Group) (interfaces.Group, error) {
+	updated := group
+	err := service.tx.WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+		if group.ParentID != nil {
+			if err := service.checkParent(ctx, group); err != nil {
+				return err
+			}
+		}
+		var err error
+		updated, err = service.repo.Update(ctx, group)
+		return err
+	})
+	return updated, err
+}
+
+// checkParent reports ErrGroupCycle when the group's new parent is the group or one of its
+// descendants, reading the hierarchy under lock
+func (service *groupService) checkParent(ctx context.Context, group interfaces.Group) error {
+	if *group.ParentID == group.ID {
+		return interfaces.ErrGroupCycle
+	}
+	parents, err := service.repo.GetParents(ctx, group.OrganizationID)
+	if err != nil {
+		return err
+	}
+	if _, ok := parents[*group.ParentID]; !ok {
+		return errGroupNotFound(*group.ParentID)
+	}
+	groups := make(map[int]interfaces.Group, len(parents))
+	for id, parentID := range parents {
+		groups[id] = interfaces.Group{ID: id, ParentID: parentID}
+	}
+	for _, ancestorID := range ancestors(groups, *group.ParentID) {
+		if ancestorID == group.ID {
+			return interfaces.ErrGroupCycle
+		}
+	}
+	return nil
+}
+
+func (service *groupService) DeleteGroup(ctx context.Context, organizationID, id int) error {
+	return service.repo.Delete(ctx, organizationID, id)
+}
+
+func (service *groupService) GetMembers(
+	ctx context.Context,
+	organizationID, groupID int,
+) ([]interfaces.GroupMember, error) {
+	if _, err := service.repo.GetByID(ctx, organizationID, groupID); err != nil {
+		return nil, err
+	}
+	return service.repo.GetMembers(ctx, organizationID, groupID)
+}
+
+func (service *groupService) AddMember(
+	ctx context.Context,
+	organizationID, groupID, userID int,
+) (interfaces.GroupMember, error) {
+	if _, err := service.organizations.GetMembership(ctx, organizationID, userID); err != nil {
+		logger.Error("Group member outside organization", zap.Int("userID", userID), zap.Error(err))
+		return interfaces.GroupMember{}, interfaces.ErrNotOrganization
+	}
+	return service.repo.AddMember(ctx, organizationID, groupID, userID)
+}
+
+func (service *groupService) RemoveMember(ctx context.Context, organizationID, groupID, userID int) error {
+	return service.repo.RemoveMember(ctx, organizationID, groupID, userID)
+}
+
+func (service *groupService) AssignRole(
+	ctx context.Context,
+	organizationID, groupID int,
+	role string,
+) (interfaces.Group, error) {
+	if !interfaces.IsValidRole(role) {
+		return interfaces.Group{}, interfaces.ErrInvalidRole
+	}
+	if _, err := service.repo.GetByID(ctx, organizationID, groupID); err != nil {
+		return interfaces.Group{}, err
+	}
+	if err := service.repo.AssignRole(ctx, organizationID, groupID, role); err != nil {
+		return interfaces.Group{}, err
+	}
+	return service.repo.GetByID(ctx, organizationID, groupID)
+}
+
+func (service *groupService) UnassignRole(
+	ctx context.Context,
+	organizationID, groupID int,
+	role string,
+) (interfaces.Group, error) {
+	if err := service.repo.UnassignRole(ctx, organizationID, groupID, role); err != nil {
+		return interfaces.Group{}, err
+	}
+	return service.repo.GetByID(ctx, organizationID, groupID)
+}
+
+// GetEffectiveGroups returns the user's direct groups plus every ancestor group they inherit
+func (service *groupService) GetEffectiveGroups(
+	ctx context.Context,
+	organizationID, userID int,
+) ([]interfaces.Group, error) {
+	groups, err := service.groupsByID(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	directIDs, err := service.repo.GetGroupIDsForUser(ctx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+	var effective []interfaces.Group
+	for _, groupID := range directIDs {
+		for _, id := range append([]int{groupID}, ancestors(groups, groupID)...) {
+			if group, ok := groups[id]; ok && !seen[id] {
+				seen[id] = true
+				effective = append(effective, group)
+			}
+		}
+	}
+	sort.Slice(effective, func(i, j int) bool { return effective[i].ID < effective[j].ID })
+	return effective, nil
+}
+
+// GetTransitiveMembers returns the members of the group and of all its descendant groups
+func (service *groupService) GetTransitiveMembers(
+	ctx context.Context,
+	organizationID, groupID int,
+) ([]interfaces.GroupMember, error) {
+	groups, err := service.groupsByID(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := groups[groupID]; !ok {
+		return nil, errGroupNotFound(groupID)
+	}
+
+	children := make(map[int][]int)
+	for _, group := range groups {
+		if group.ParentID != nil {
+			children[*group.ParentID] = append(children[*group.ParentID], group.ID)
+		}
+	}
+
+	visited := map[int]bool{groupID: true}
+	queue := []int{groupID}
+	groupIDs := []int{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		groupIDs = append(groupIDs, current)
+		for _, child := range children[current] {
+			if !visited[child] {
+				visited[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	members, err := service.repo.GetMembers(ctx, organizationID, groupIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	// A user reachable through several groups is reported once, via the first group found
+	seen := make(map[int]bool)
+	var transitive []interfaces.GroupMember
+	for _, member := range members {
+		if !seen[member.UserID] {
+			seen[member.UserID] = true
+			transitive = append(transitive, member)
+		}
+	}
+	return transitive, nil
+}
+
+// GetEffectivePermissions unions the permissions of the user's organization role and of every
+// role assigned to their effective groups
+func (service *groupService) GetEffectivePermissions(
+	ctx context.Context,
+	organizationID, userID int,
+) ([]string, error) {
+	membership, err := service.organizations.GetMembership(ctx, organizationID, userID)
+	if err != nil {
+		return nil, interfaces.ErrNotOrganization
+	}
+	groups, err := service.GetEffectiveGroups(ctx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	granted := make(map[string]bool)
+	roles := []string{membership.Role}
+	for _, group := range groups {
+		roles = append(roles, group.Roles...)
+	}
+	for _, role := range roles {
+		for _, permission := range interfaces.RolePermissions[role] {
+			granted[permission] = true
+		}
+	}
+
+	permissions := make([]string, 0, len(granted))
+	for permission := range granted {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+func (service *groupService) groupsByID(ctx context.Context, organizationID int) (map[int]interfaces.Group, error) {
+	groups, err := service.repo.GetAll(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]interfaces.Group, len(groups))
+	for _, group := range groups {
+		byID[group.ID] = group
+	}
+	return byID, nil
+}
+
+// ancestors walks parent links from groupID upwards, stopping if the chain loops back on itself
+func ancestors(groups map[int]interfaces.Group, groupID int) []int {
+	var chain []int
+	visited := map[int]bool{groupID: true}
+	current, ok := groups[groupID]
+	for ok && current.ParentID != nil {
+		parentID := *current.ParentID
+		if visited[parentID] {
+			logger.Error("Group hierarchy cycle detected", zap.Int("groupID", groupID), zap.Int("parentID", parentID))
+			break
+		}
+		visited[parentID] = true
+		chain = append(chain, parentID)
+		current, ok = groups[parentID]
+	}
+	return chain
+}
+
+func errGroupNotFound(id int) error {
+	return fmt.Errorf("group %d: %w", id, sql.ErrNoRows)
+}
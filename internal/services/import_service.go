@@ -0,0 +1,373 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+
+	"github.com/redbonzai/user-management-api/internal/importer"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type importService struct {
+	repo          interfaces.Repository
+	tx            interfaces.TxManager
+	events        interfaces.OutboxRepository
+	organizations interfaces.OrganizationService
+	invitations   interfaces.InvitationService
+}
+
+// NewImportService records a domain event in events for every user it creates, in the unit of
+// work creating the user
+func NewImportService(
+	repo interfaces.Repository,
+	tx interfaces.TxManager,
+	events interfaces.OutboxRepository,
+	organizations interfaces.OrganizationService,
+	invitations interfaces.InvitationService,
+) interfaces.ImportService {
+	return &importService{repo, tx, events, organizations, invitations}
+}
+
+// importRow is a validated row waiting to be written
+type importRow struct {
+	result     *interfaces.ImportRowResult
+	user       interfaces.User
+	role       string
+	invitation *interfaces.Invitation
+}
+
+// Import validates every row before writing anything. In transactional mode a single invalid
+// row aborts the whole import; in best-effort mode valid rows are imported regardless. Dry runs
+// stop after validation.
+func (service *importService) Import(
+	ctx context.Context,
+	organizationID int,
+	source io.Reader,
+	options interfaces.ImportOptions,
+) (interfaces.ImportReport, error) {
+	if options.Mode == "" {
+		options.Mode = interfaces.ImportModeTransactional
+	}
+	if options.Mode != interfaces.ImportModeTransactional && options.Mode != interfaces.ImportModeBestEffort {
+		return interfaces.ImportReport{}, fmt.Errorf("%w: %q", interfaces.ErrImportMode, options.Mode)
+	}
+	records, err := importer.ReadRecords(source, options.Format, options.Mapping)
+	if err != nil {
+		return interfaces.ImportReport{}, err
+	}
+
+	actor, err := service.actor(ctx, organizationID, options.InvitedBy)
+	if err != nil {
+		return interfaces.ImportReport{}, err
+	}
+
+	report := interfaces.ImportReport{
+		Format: options.Format,
+		Mode:   options.Mode,
+		DryRun: options.DryRun,
+		Total:  len(records),
+		Rows:   make([]interfaces.ImportRowResult, len(records)),
+	}
+	rows, err := service.validate(ctx, organizationID, records, options, actor, report.Rows)
+	if err != nil {
+		return report, err
+	}
+
+	invalid := len(records) - len(rows)
+	switch {
+	case options.DryRun:
+	case options.Mode == interfaces.ImportModeTransactional && invalid > 0:
+		for _, row := range rows {
+			row.result.Status = interfaces.ImportRowSkipped
+			row.result.Error = "not imported because other rows are invalid"
+		}
+	default:
+		service.write(ctx, rows, options.Mode, actor)
+	}
+	return summarize(report), nil
+}
+
+// actor returns the membership of the user running the import, if any, which decides whether
+// the import may assign the owner role
+func (service *importService) actor(ctx context.Context, organizationID, userID int) (interfaces.Membership, error) {
+	if userID == 0 {
+		return interfaces.Membership{}, nil
+	}
+	membership, err := service.organizations.GetMembership(ctx, organizationID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return interfaces.Membership{}, nil
+	}
+	return membership, err
+}
+
+// validate checks each record and detects duplicates within the file and against existing
+// users, filling results for the invalid rows and returning the valid ones
+func (service *importService) validate(
+	ctx context.Context,
+	organizationID int,
+	records []interfaces.ImportRecord,
+	options interfaces.ImportOptions,
+	actor interfaces.Membership,
+	results []interfaces.ImportRowResult,
+) ([]importRow, error) {
+	var usernames, emails []string
+	for _, record := range records {
+		usernames = append(usernames, record.Fields["username"])
+		emails = append(emails, record.Fields["email"])
+	}
+	existing, err := service.repo.FindExisting(ctx, organizationID, usernames, emails)
+	if err != nil {
+		return nil, err
+	}
+	takenUsernames, takenEmails := make(map[string]int), make(map[string]int)
+	for _, user := range existing {
+		takenUsernames[interfaces.NormalizeIdentity(user.Username)] = 0
+		takenEmails[interfaces.NormalizeIdentity(user.Email)] = 0
+	}
+
+	var rows []importRow
+	for i, record := range records {
+		results[i] = interfaces.ImportRowResult{
+			Line:     record.Line,
+			Username: record.Fields["username"],
+			Email:    record.Fields["email"],
+			Status:   interfaces.ImportRowValid,
+		}
+		row, err := buildRow(organizationID, record, options, actor)
+		if err == nil {
+			err = checkDuplicate(row, record.Line, takenUsernames, takenEmails)
+		}
+		if err != nil {
+			results[i].Status = interfaces.ImportRowFailed
+			results[i].Error = err.Error()
+			continue
+		}
+		row.result = &results[i]
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func buildRow(
+	organizationID int,
+	record interfaces.ImportRecord,
+	options interfaces.ImportOptions,
+	actor interfaces.Membership,
+) (importRow, error) {
+	fields := record.Fields
+	row := importRow{role: fields["role"]}
+	if row.role == "" {
+		row.role = interfaces.RoleMember
+	}
+	if !interfaces.IsValidRole(row.role) {
+		return row, interfaces.ErrInvalidRole
+	}
+	if row.role == interfaces.RoleOwner && actor.Role != interfaces.RoleOwner {
+		return row, interfaces.ErrOwnerRequired
+	}
+
+	if fields["password"] == "" {
+		if !options.InviteWithoutPassword {
+			return row, fmt.Errorf("password is required")
+		}
+		if address, err := mail.ParseAddress(fields["email"]); err != nil || address.Address != fields["email"] {
+			return row, fmt.Errorf("email must be a valid address")
+		}
+		row.invitation = &interfaces.Invitation{
+			OrganizationID: organizationID,
+			Email:          fields["email"],
+			Role:           row.role,
+		}
+		if options.InvitedBy != 0 {
+			row.invitation.InvitedBy = &options.InvitedBy
+		}
+		return row, nil
+	}
+
+	// bcrypt ignores everything past 72 bytes
+	if len(fields["password"]) > 72 {
+		return row, fmt.Errorf("password must be at most 72 bytes")
+	}
+	row.user = interfaces.User{
+		OrganizationID: organizationID,
+		Name:           fields["name"],
+		Email:          fields["email"],
+		Username:       fields["username"],
+		Password:       fields["password"],
+	}
+	if status := fields["status"]; status != "" {
+		row.user.Status = &status
+	}
+	if err := interfaces.CheckInitialStatus(row.user); err != nil {
+		return row, err
+	}
+	return row, row.user.Validate()
+}
+
+// checkDuplicate rejects identities already in use, then claims the row's own. Identities are
+// compared normalized, as the unique indexes do.
+func checkDuplicate(row importRow, line int, usernames, emails map[string]int) error {
+	email := row.user.Email
+	if row.invitation != nil {
+		email = row.invitation.Email
+	}
+	for _, identity := range []struct {
+		kind  string
+		value string
+		taken map[string]int
+	}{{"username", row.user.Username, usernames}, {"email", email, emails}} {
+		if identity.value == "" {
+			continue
+		}
+		if takenAt, ok := identity.taken[interfaces.NormalizeIdentity(identity.value)]; ok {
+			if takenAt == 0 {
+				return fmt.Errorf("%s %s already exists", identity.kind, identity.value)
+			}
+			return fmt.Errorf("%s %s duplicates line %d", identity.kind, identity.value, takenAt)
+		}
+	}
+	if row.user.Username != "" {
+		usernames[interfaces.NormalizeIdentity(row.user.Username)] = line
+	}
+	emails[interfaces.NormalizeIdentity(email)] = line
+	return nil
+}
+
+// write hashes passwords, creates the users with their roles and issues the invitations. In
+// transactional mode all of them are one unit of work, and invitations are delivered only once
+// it commits; in best-effort mode each row is its own.
+func (service *importService) write(
+	ctx context.Context,
+	rows []importRow,
+	mode string,
+	actor interfaces.Membership,
+) {
+	var users, invitations []importRow
+	for _, row := range rows {
+		if row.invitation != nil {
+			invitations = append(invitations, row)
+			continue
+		}
+		// bcrypt.DefaultCost keeps imports of thousands of rows to minutes rather than hours
+		hashed, err := bcrypt.GenerateFromPassword([]byte(row.user.Password), bcrypt.DefaultCost)
+		if err != nil {
+			fail(row, err)
+			continue
+		}
+		row.user.Password = string(hashed)
+		users = append(users, row)
+	}
+
+	if mode == interfaces.ImportModeTransactional {
+		err := service.tx.WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+			if err := service.create(ctx, users, actor, service.repo.CreateMany); err != nil {
+				return err
+			}
+			for _, row := range invitations {
+				if err := service.invite(ctx, row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Error("Error importing users:", zap.Error(err))
+			for _, row := range rows {
+				fail(row, fmt.Errorf("import rolled back: %w", err))
+			}
+		}
+		return
+	}
+
+	createOne := func(ctx context.Context, users []interfaces.User) ([]interfaces.User, error) {
+		user, err := service.repo.Create(ctx, users[0])
+		return []interfaces.User{user}, err
+	}
+	for _, row := range users {
+		err := service.tx.WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+			return service.create(ctx, []importRow{row}, actor, createOne)
+		})
+		if err != nil {
+			fail(row, err)
+		}
+	}
+	for _, row := range invitations {
+		if err := service.invite(ctx, row); err != nil {
+			fail(row, err)
+		}
+	}
+}
+
+// create inserts the users of rows in the unit of work carried by ctx, records their events
+// and assigns the roles other than member
+func (service *importService) create(
+	ctx context.Context,
+	rows []importRow,
+	actor interfaces.Membership,
+	insert func(ctx context.Context, users []interfaces.User) ([]interfaces.User, error),
+) error {
+	batch := make([]interfaces.User, len(rows))
+	for i, row := range rows {
+		batch[i] = row.user
+	}
+	created, err := insert(ctx, batch)
+	if err != nil {
+		return err
+	}
+
+	events := make([]interfaces.UserEvent, len(created))
+	for i, user := range created {
+		events[i] = interfaces.UserCreated{User: interfaces.NewEventUser(user)}
+	}
+	if err := service.events.Append(ctx, events...); err != nil {
+		return err
+	}
+
+	for i, user := range created {
+		if role := rows[i].role; role != interfaces.RoleMember {
+			membership := interfaces.Membership{OrganizationID: user.OrganizationID, UserID: user.ID, Role: role}
+			if _, err := service.organizations.UpdateMemberRole(ctx, actor, membership); err != nil {
+				return fmt.Errorf("assigning role %s to %s: %w", role, user.Username, err)
+			}
+		}
+		rows[i].result.Status = interfaces.ImportRowCreated
+		rows[i].result.UserID = user.ID
+	}
+	return nil
+}
+
+func (service *importService) invite(ctx context.Context, row importRow) error {
+	if _, err := service.invitations.Invite(ctx, *row.invitation); err != nil {
+		return err
+	}
+	row.result.Status = interfaces.ImportRowInvited
+	return nil
+}
+
+func fail(row importRow, err error) {
+	row.result.Status = interfaces.ImportRowFailed
+	row.result.Error = err.Error()
+}
+
+func summarize(report interfaces.ImportReport) interfaces.ImportReport {
+	for _, row := range report.Rows {
+		switch row.Status {
+		case interfaces.ImportRowCreated:
+			report.Created++
+		case interfaces.ImportRowInvited:
+			report.Invited++
+		case interfaces.ImportRowFailed:
+			report.Failed++
+		case interfaces.ImportRowSkipped:
+			report.Skipped++
+		}
+	}
+	return report
+}
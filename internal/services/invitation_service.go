@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// InvitationTTL is how long an invitation token stays valid after it is issued or resent
+const InvitationTTL = 7 * 24 * time.Hour
+
+type invitationService struct {
+	repo          interfaces.InvitationRepository
+	tx            interfaces.TxManager
+	users         interfaces.Service
+	organizations interfaces.OrganizationService
+	notifier      interfaces.Notifier
+}
+
+func NewInvitationService(
+	repo interfaces.InvitationRepository,
+	tx interfaces.TxManager,
+	users interfaces.Service,
+	organizations interfaces.OrganizationService,
+	notifier interfaces.Notifier,
+) interfaces.InvitationService {
+	return &invitationService{repo, tx, users, organizations, notifier}
+}
+
+func (service *invitationService) GetPendingInvitations(
+	ctx context.Context,
+	organizationID int,
+) ([]interfaces.Invitation, error) {
+	return service.repo.GetPending(ctx, organizationID)
+}
+
+func (service *invitationService) Invite(ctx context.Context, invitation interfaces.Invitation) (interfaces.Invitation, error) {
+	if invitation.Role == "" {
+		invitation.Role = interfaces.RoleMember
+	}
+	if !interfaces.IsValidRole(invitation.Role) {
+		return invitation, interfaces.ErrInvalidRole
+	}
+	if invitation.Role == interfaces.RoleOwner && !service.isOwner(
+		ctx,
+		invitation.OrganizationID,
+		invitation.InvitedBy,
+	) {
+		return invitation, interfaces.ErrOwnerRequired
+	}
+	invitation.Status = interfaces.InvitationPending
+	invitation.ExpiresAt = time.Now().Add(InvitationTTL).Truncate(time.Second)
+
+	created, err := service.repo.Create(ctx, invitation)
+	if err != nil {
+		return created, err
+	}
+	if !interfaces.InUnitOfWork(ctx) {
+		return created, service.deliver(created)
+	}
+	// A rolled back invitation must not reach the invitee
+	interfaces.AfterCommit(ctx, func() {
+		if err := service.deliver(created); err != nil {
+			logger.Error("Error delivering invitation:", zap.Int("invitationID", created.ID), zap.Error(err))
+		}
+	})
+	return created, nil
+}
+
+func (service *invitationService) Resend(ctx context.Context, organizationID, id int) (interfaces.Invitation, error) {
+	invitation, err := service.repo.GetByID(ctx, organizationID, id)
+	if err != nil {
+		return invitation, err
+	}
+	if invitation.Status != interfaces.InvitationPending {
+		return invitation, interfaces.ErrInvitationNotPending
+	}
+
+	// A new expiry invalidates every token issued before the resend
+	invitation.ExpiresAt = time.Now().Add(InvitationTTL).Truncate(time.Second)
+	updated, err := service.repo.Update(ctx, invitation)
+	if err != nil {
+		return updated, err
+	}
+	return updated, service.deliver(updated)
+}
+
+func (service *invitationService) Revoke(ctx context.Context, organizationID, id int) (interfaces.Invitation, error) {
+	invitation, err := service.repo.GetByID(ctx, organizationID, id)
+	if err != nil {
+		return invitation, err
+	}
+	if invitation.Status != interfaces.InvitationPending {
+		return invitation, interfaces.ErrInvitationNotPending
+	}
+	invitation.Status = interfaces.InvitationRevoked
+	return service.repo.Update(ctx, invitation)
+}
+
+// Accept validates the invitation token, then claims the invitation, creates the invitee through
+// the user service and grants the invited role in one unit of work
+func (service *invitationService) Accept(
+	ctx context.Context,
+	request interfaces.AcceptInvitationRequest,
+) (interfaces.User, error) {
+	claims, err := authentication.ParseInvitationToken(request.Token)
+	if err != nil {
+		logger.Error("Invalid invitation token: ", zap.Error(err))
+		return interfaces.User{}, interfaces.ErrInvitationToken
+	}
+
+	invitation, err := service.repo.GetByID(ctx, claims.OrganizationID, claims.InvitationID)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	if invitation.Status != interfaces.InvitationPending {
+		return interfaces.User{}, interfaces.ErrInvitationNotPending
+	}
+	if claims.ExpiresAt != invitation.ExpiresAt.Unix() {
+		// Superseded by a resend
+		return interfaces.User{}, interfaces.ErrInvitationToken
+	}
+	if time.Now().After(invitation.ExpiresAt) {
+		return interfaces.User{}, interfaces.ErrInvitationExpired
+	}
+
+	hashedPassword, err := service.users.HashPassword(request.Password)
+	if err != nil {
+		return interfaces.User{}, err
+	}
+
+	var user interfaces.User
+	err = service.tx.WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+		acceptedAt := time.Now()
+		invitation.AcceptedAt = &acceptedAt
+		if err := service.repo.Claim(ctx, invitation); err != nil {
+			return err
+		}
+
+		var err error
+		user, err = service.users.CreateUser(ctx, interfaces.User{
+			OrganizationID: invitation.OrganizationID,
+			Name:           request.Name,
+			Email:          invitation.Email,
+			Username:       request.Username,
+			Password:       hashedPassword,
+			Attributes:     request.Attributes,
+		})
+		if err != nil || invitation.Role == interfaces.RoleMember {
+			return err
+		}
+
+		// The role was checked when the invitation was issued
+		issuer := interfaces.Membership{OrganizationID: invitation.OrganizationID, Role: interfaces.RoleOwner}
+		_, err = service.organizations.UpdateMemberRole(ctx, issuer, interfaces.Membership{
+			OrganizationID: invitation.OrganizationID,
+			UserID:         user.ID,
+			Role:           invitation.Role,
+		})
+		return err
+	})
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	return user, nil
+}
+
+// isOwner reports whether the user is an owner of the organization
+func (service *invitationService) isOwner(ctx context.Context, organizationID int, userID *int) bool {
+	if userID == nil {
+		return false
+	}
+	membership, err := service.organizations.GetMembership(ctx, organizationID, *userID)
+	return err == nil && membership.Role == interfaces.RoleOwner
+}
+
+func (service *invitationService) deliver(invitation interfaces.Invitation) error {
+	token, err := authentication.GenerateInvitationToken(invitation)
+	if err != nil {
+		return err
+	}
+	return service.notifier.NotifyInvitation(invitation, token)
+}
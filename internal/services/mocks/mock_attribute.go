@@ -0,0 +1,264 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/interfaces/attribute.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	interfaces "github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// MockAttributeRepository is a mock of AttributeRepository interface.
+type MockAttributeRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAttributeRepositoryMockRecorder
+}
+
+// MockAttributeRepositoryMockRecorder is the mock recorder for MockAttributeRepository.
+type MockAttributeRepositoryMockRecorder struct {
+	mock *MockAttributeRepository
+}
+
+// NewMockAttributeRepository creates a new mock instance.
+func NewMockAttributeRepository(ctrl *gomock.Controller) *MockAttributeRepository {
+	mock := &MockAttributeRepository{ctrl: ctrl}
+	mock.recorder = &MockAttributeRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAttributeRepository) EXPECT() *MockAttributeRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClaimValues mocks base method.
+func (m *MockAttributeRepository) ClaimValues(ctx context.Context, user interfaces.User, values map[string]interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimValues", ctx, user, values)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClaimValues indicates an expected call of ClaimValues.
+func (mr *MockAttributeRepositoryMockRecorder) ClaimValues(ctx, user, values interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimValues", reflect.TypeOf((*MockAttributeRepository)(nil).ClaimValues), ctx, user, values)
+}
+
+// CreateDefinition mocks base method.
+func (m *MockAttributeRepository) CreateDefinition(ctx context.Context, definition interfaces.AttributeDefinition) (interfaces.AttributeDefinition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDefinition", ctx, definition)
+	ret0, _ := ret[0].(interfaces.AttributeDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDefinition indicates an expected call of CreateDefinition.
+func (mr *MockAttributeRepositoryMockRecorder) CreateDefinition(ctx, definition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDefinition", reflect.TypeOf((*MockAttributeRepository)(nil).CreateDefinition), ctx, definition)
+}
+
+// DeleteDefinition mocks base method.
+func (m *MockAttributeRepository) DeleteDefinition(ctx context.Context, organizationID int, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDefinition", ctx, organizationID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDefinition indicates an expected call of DeleteDefinition.
+func (mr *MockAttributeRepositoryMockRecorder) DeleteDefinition(ctx, organizationID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDefinition", reflect.TypeOf((*MockAttributeRepository)(nil).DeleteDefinition), ctx, organizationID, name)
+}
+
+// GetDefinitions mocks base method.
+func (m *MockAttributeRepository) GetDefinitions(ctx context.Context, organizationID int) ([]interfaces.AttributeDefinition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDefinitions", ctx, organizationID)
+	ret0, _ := ret[0].([]interfaces.AttributeDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDefinitions indicates an expected call of GetDefinitions.
+func (mr *MockAttributeRepositoryMockRecorder) GetDefinitions(ctx, organizationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefinitions", reflect.TypeOf((*MockAttributeRepository)(nil).GetDefinitions), ctx, organizationID)
+}
+
+// UpdateDefinition mocks base method.
+func (m *MockAttributeRepository) UpdateDefinition(ctx context.Context, definition interfaces.AttributeDefinition) (interfaces.AttributeDefinition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDefinition", ctx, definition)
+	ret0, _ := ret[0].(interfaces.AttributeDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateDefinition indicates an expected call of UpdateDefinition.
+func (mr *MockAttributeRepositoryMockRecorder) UpdateDefinition(ctx, definition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDefinition", reflect.TypeOf((*MockAttributeRepository)(nil).UpdateDefinition), ctx, definition)
+}
+
+// MockAttributeService is a mock of AttributeService interface.
+type MockAttributeService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAttributeServiceMockRecorder
+}
+
+// MockAttributeServiceMockRecorder is the mock recorder for MockAttributeService.
+type MockAttributeServiceMockRecorder struct {
+	mock *MockAttributeService
+}
+
+// NewMockAttributeService creates a new mock instance.
+func NewMockAttributeService(ctrl *gomock.Controller) *MockAttributeService {
+	mock := &MockAttributeService{ctrl: ctrl}
+	mock.recorder = &MockAttributeServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAttributeService) EXPECT() *MockAttributeServiceMockRecorder {
+	return m.recorder
+}
+
+// ClaimUniqueValues mocks base method.
+func (m *MockAttributeService) ClaimUniqueValues(ctx context.Context, user interfaces.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimUniqueValues", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClaimUniqueValues indicates an expected call of ClaimUniqueValues.
+func (mr *MockAttributeServiceMockRecorder) ClaimUniqueValues(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimUniqueValues", reflect.TypeOf((*MockAttributeService)(nil).ClaimUniqueValues), ctx, user)
+}
+
+// CreateDefinition mocks base method.
+func (m *MockAttributeService) CreateDefinition(ctx context.Context, definition interfaces.AttributeDefinition) (interfaces.AttributeDefinition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDefinition", ctx, definition)
+	ret0, _ := ret[0].(interfaces.AttributeDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDefinition indicates an expected call of CreateDefinition.
+func (mr *MockAttributeServiceMockRecorder) CreateDefinition(ctx, definition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDefinition", reflect.TypeOf((*MockAttributeService)(nil).CreateDefinition), ctx, definition)
+}
+
+// DeleteDefinition mocks base method.
+func (m *MockAttributeService) DeleteDefinition(ctx context.Context, organizationID int, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDefinition", ctx, organizationID, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDefinition indicates an expected call of DeleteDefinition.
+func (mr *MockAttributeServiceMockRecorder) DeleteDefinition(ctx, organizationID, name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDefinition", reflect.TypeOf((*MockAttributeService)(nil).DeleteDefinition), ctx, organizationID, name)
+}
+
+// GetDefinitions mocks base method.
+func (m *MockAttributeService) GetDefinitions(ctx context.Context, organizationID int) ([]interfaces.AttributeDefinition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDefinitions", ctx, organizationID)
+	ret0, _ := ret[0].([]interfaces.AttributeDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDefinitions indicates an expected call of GetDefinitions.
+func (mr *MockAttributeServiceMockRecorder) GetDefinitions(ctx, organizationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDefinitions", reflect.TypeOf((*MockAttributeService)(nil).GetDefinitions), ctx, organizationID)
+}
+
+// HiddenAttributes mocks base method.
+func (m *MockAttributeService) HiddenAttributes(ctx context.Context, organizationID, callerID int) (map[string]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HiddenAttributes", ctx, organizationID, callerID)
+	ret0, _ := ret[0].(map[string]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HiddenAttributes indicates an expected call of HiddenAttributes.
+func (mr *MockAttributeServiceMockRecorder) HiddenAttributes(ctx, organizationID, callerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HiddenAttributes", reflect.TypeOf((*MockAttributeService)(nil).HiddenAttributes), ctx, organizationID, callerID)
+}
+
+// ParseFilter mocks base method.
+func (m *MockAttributeService) ParseFilter(ctx context.Context, organizationID, callerID int, values map[string]string) (map[string]interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseFilter", ctx, organizationID, callerID, values)
+	ret0, _ := ret[0].(map[string]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseFilter indicates an expected call of ParseFilter.
+func (mr *MockAttributeServiceMockRecorder) ParseFilter(ctx, organizationID, callerID, values interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseFilter", reflect.TypeOf((*MockAttributeService)(nil).ParseFilter), ctx, organizationID, callerID, values)
+}
+
+// UpdateDefinition mocks base method.
+func (m *MockAttributeService) UpdateDefinition(ctx context.Context, definition interfaces.AttributeDefinition) (interfaces.AttributeDefinition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDefinition", ctx, definition)
+	ret0, _ := ret[0].(interfaces.AttributeDefinition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateDefinition indicates an expected call of UpdateDefinition.
+func (mr *MockAttributeServiceMockRecorder) UpdateDefinition(ctx, definition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDefinition", reflect.TypeOf((*MockAttributeService)(nil).UpdateDefinition), ctx, definition)
+}
+
+// ValidateAttributes mocks base method.
+func (m *MockAttributeService) ValidateAttributes(ctx context.Context, user interfaces.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateAttributes", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateAttributes indicates an expected call of ValidateAttributes.
+func (mr *MockAttributeServiceMockRecorder) ValidateAttributes(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateAttributes", reflect.TypeOf((*MockAttributeService)(nil).ValidateAttributes), ctx, user)
+}
+
+// View mocks base method.
+func (m *MockAttributeService) View(ctx context.Context, organizationID, callerID int, users []interfaces.User) ([]interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "View", ctx, organizationID, callerID, users)
+	ret0, _ := ret[0].([]interfaces.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// View indicates an expected call of View.
+func (mr *MockAttributeServiceMockRecorder) View(ctx, organizationID, callerID, users interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "View", reflect.TypeOf((*MockAttributeService)(nil).View), ctx, organizationID, callerID, users)
+}
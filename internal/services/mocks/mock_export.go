@@ -0,0 +1,105 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/interfaces/export.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	interfaces "github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// MockExportStore is a mock of ExportStore interface.
+type MockExportStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockExportStoreMockRecorder
+}
+
+// MockExportStoreMockRecorder is the mock recorder for MockExportStore.
+type MockExportStoreMockRecorder struct {
+	mock *MockExportStore
+}
+
+// NewMockExportStore creates a new mock instance.
+func NewMockExportStore(ctrl *gomock.Controller) *MockExportStore {
+	mock := &MockExportStore{ctrl: ctrl}
+	mock.recorder = &MockExportStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExportStore) EXPECT() *MockExportStoreMockRecorder {
+	return m.recorder
+}
+
+// Upload mocks base method.
+func (m *MockExportStore) Upload(key, contentType string, body io.Reader) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upload", key, contentType, body)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upload indicates an expected call of Upload.
+func (mr *MockExportStoreMockRecorder) Upload(key, contentType, body interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upload", reflect.TypeOf((*MockExportStore)(nil).Upload), key, contentType, body)
+}
+
+// MockExportService is a mock of ExportService interface.
+type MockExportService struct {
+	ctrl     *gomock.Controller
+	recorder *MockExportServiceMockRecorder
+}
+
+// MockExportServiceMockRecorder is the mock recorder for MockExportService.
+type MockExportServiceMockRecorder struct {
+	mock *MockExportService
+}
+
+// NewMockExportService creates a new mock instance.
+func NewMockExportService(ctrl *gomock.Controller) *MockExportService {
+	mock := &MockExportService{ctrl: ctrl}
+	mock.recorder = &MockExportServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockExportService) EXPECT() *MockExportServiceMockRecorder {
+	return m.recorder
+}
+
+// Export mocks base method.
+func (m *MockExportService) Export(ctx context.Context, organizationID int, writer io.Writer, options interfaces.ExportOptions) (interfaces.ExportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, organizationID, writer, options)
+	ret0, _ := ret[0].(interfaces.ExportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockExportServiceMockRecorder) Export(ctx, organizationID, writer, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockExportService)(nil).Export), ctx, organizationID, writer, options)
+}
+
+// ExportToStore mocks base method.
+func (m *MockExportService) ExportToStore(ctx context.Context, organizationID int, options interfaces.ExportOptions) (interfaces.ExportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportToStore", ctx, organizationID, options)
+	ret0, _ := ret[0].(interfaces.ExportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportToStore indicates an expected call of ExportToStore.
+func (mr *MockExportServiceMockRecorder) ExportToStore(ctx, organizationID, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportToStore", reflect.TypeOf((*MockExportService)(nil).ExportToStore), ctx, organizationID, options)
+}
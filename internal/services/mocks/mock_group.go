@@ -0,0 +1,433 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/interfaces/group.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	interfaces "github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// MockGroupRepository is a mock of GroupRepository interface.
+type MockGroupRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockGroupRepositoryMockRecorder
+}
+
+// MockGroupRepositoryMockRecorder is the mock recorder for MockGroupRepository.
+type MockGroupRepositoryMockRecorder struct {
+	mock *MockGroupRepository
+}
+
+// NewMockGroupRepository creates a new mock instance.
+func NewMockGroupRepository(ctrl *gomock.Controller) *MockGroupRepository {
+	mock := &MockGroupRepository{ctrl: ctrl}
+	mock.recorder = &MockGroupRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGroupRepository) EXPECT() *MockGroupRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddMember mocks base method.
+func (m *MockGroupRepository) AddMember(ctx context.Context, organizationID, groupID, userID int) (interfaces.GroupMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMember", ctx, organizationID, groupID, userID)
+	ret0, _ := ret[0].(interfaces.GroupMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddMember indicates an expected call of AddMember.
+func (mr *MockGroupRepositoryMockRecorder) AddMember(ctx, organizationID, groupID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMember", reflect.TypeOf((*MockGroupRepository)(nil).AddMember), ctx, organizationID, groupID, userID)
+}
+
+// AssignRole mocks base method.
+func (m *MockGroupRepository) AssignRole(ctx context.Context, organizationID, groupID int, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignRole", ctx, organizationID, groupID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AssignRole indicates an expected call of AssignRole.
+func (mr *MockGroupRepositoryMockRecorder) AssignRole(ctx, organizationID, groupID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignRole", reflect.TypeOf((*MockGroupRepository)(nil).AssignRole), ctx, organizationID, groupID, role)
+}
+
+// Create mocks base method.
+func (m *MockGroupRepository) Create(ctx context.Context, group interfaces.Group) (interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, group)
+	ret0, _ := ret[0].(interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockGroupRepositoryMockRecorder) Create(ctx, group interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockGroupRepository)(nil).Create), ctx, group)
+}
+
+// Delete mocks base method.
+func (m *MockGroupRepository) Delete(ctx context.Context, organizationID, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, organizationID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockGroupRepositoryMockRecorder) Delete(ctx, organizationID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockGroupRepository)(nil).Delete), ctx, organizationID, id)
+}
+
+// GetAll mocks base method.
+func (m *MockGroupRepository) GetAll(ctx context.Context, organizationID int) ([]interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, organizationID)
+	ret0, _ := ret[0].([]interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockGroupRepositoryMockRecorder) GetAll(ctx, organizationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockGroupRepository)(nil).GetAll), ctx, organizationID)
+}
+
+// GetByID mocks base method.
+func (m *MockGroupRepository) GetByID(ctx context.Context, organizationID, id int) (interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, organizationID, id)
+	ret0, _ := ret[0].(interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockGroupRepositoryMockRecorder) GetByID(ctx, organizationID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockGroupRepository)(nil).GetByID), ctx, organizationID, id)
+}
+
+// GetGroupIDsForUser mocks base method.
+func (m *MockGroupRepository) GetGroupIDsForUser(ctx context.Context, organizationID, userID int) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroupIDsForUser", ctx, organizationID, userID)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroupIDsForUser indicates an expected call of GetGroupIDsForUser.
+func (mr *MockGroupRepositoryMockRecorder) GetGroupIDsForUser(ctx, organizationID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroupIDsForUser", reflect.TypeOf((*MockGroupRepository)(nil).GetGroupIDsForUser), ctx, organizationID, userID)
+}
+
+// GetMembers mocks base method.
+func (m *MockGroupRepository) GetMembers(ctx context.Context, organizationID int, groupIDs ...int) ([]interfaces.GroupMember, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, organizationID}
+	for _, a := range groupIDs {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetMembers", varargs...)
+	ret0, _ := ret[0].([]interfaces.GroupMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMembers indicates an expected call of GetMembers.
+func (mr *MockGroupRepositoryMockRecorder) GetMembers(ctx, organizationID interface{}, groupIDs ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, organizationID}, groupIDs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembers", reflect.TypeOf((*MockGroupRepository)(nil).GetMembers), varargs...)
+}
+
+// GetParents mocks base method.
+func (m *MockGroupRepository) GetParents(ctx context.Context, organizationID int) (map[int]*int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetParents", ctx, organizationID)
+	ret0, _ := ret[0].(map[int]*int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetParents indicates an expected call of GetParents.
+func (mr *MockGroupRepositoryMockRecorder) GetParents(ctx, organizationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetParents", reflect.TypeOf((*MockGroupRepository)(nil).GetParents), ctx, organizationID)
+}
+
+// RemoveMember mocks base method.
+func (m *MockGroupRepository) RemoveMember(ctx context.Context, organizationID, groupID, userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMember", ctx, organizationID, groupID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveMember indicates an expected call of RemoveMember.
+func (mr *MockGroupRepositoryMockRecorder) RemoveMember(ctx, organizationID, groupID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMember", reflect.TypeOf((*MockGroupRepository)(nil).RemoveMember), ctx, organizationID, groupID, userID)
+}
+
+// UnassignRole mocks base method.
+func (m *MockGroupRepository) UnassignRole(ctx context.Context, organizationID, groupID int, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnassignRole", ctx, organizationID, groupID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnassignRole indicates an expected call of UnassignRole.
+func (mr *MockGroupRepositoryMockRecorder) UnassignRole(ctx, organizationID, groupID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnassignRole", reflect.TypeOf((*MockGroupRepository)(nil).UnassignRole), ctx, organizationID, groupID, role)
+}
+
+// Update mocks base method.
+func (m *MockGroupRepository) Update(ctx context.Context, group interfaces.Group) (interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, group)
+	ret0, _ := ret[0].(interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockGroupRepositoryMockRecorder) Update(ctx, group interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockGroupRepository)(nil).Update), ctx, group)
+}
+
+// MockGroupService is a mock of GroupService interface.
+type MockGroupService struct {
+	ctrl     *gomock.Controller
+	recorder *MockGroupServiceMockRecorder
+}
+
+// MockGroupServiceMockRecorder is the mock recorder for MockGroupService.
+type MockGroupServiceMockRecorder struct {
+	mock *MockGroupService
+}
+
+// NewMockGroupService creates a new mock instance.
+func NewMockGroupService(ctrl *gomock.Controller) *MockGroupService {
+	mock := &MockGroupService{ctrl: ctrl}
+	mock.recorder = &MockGroupServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGroupService) EXPECT() *MockGroupServiceMockRecorder {
+	return m.recorder
+}
+
+// AddMember mocks base method.
+func (m *MockGroupService) AddMember(ctx context.Context, organizationID, groupID, userID int) (interfaces.GroupMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMember", ctx, organizationID, groupID, userID)
+	ret0, _ := ret[0].(interfaces.GroupMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddMember indicates an expected call of AddMember.
+func (mr *MockGroupServiceMockRecorder) AddMember(ctx, organizationID, groupID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMember", reflect.TypeOf((*MockGroupService)(nil).AddMember), ctx, organizationID, groupID, userID)
+}
+
+// AssignRole mocks base method.
+func (m *MockGroupService) AssignRole(ctx context.Context, organizationID, groupID int, role string) (interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignRole", ctx, organizationID, groupID, role)
+	ret0, _ := ret[0].(interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssignRole indicates an expected call of AssignRole.
+func (mr *MockGroupServiceMockRecorder) AssignRole(ctx, organizationID, groupID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignRole", reflect.TypeOf((*MockGroupService)(nil).AssignRole), ctx, organizationID, groupID, role)
+}
+
+// CreateGroup mocks base method.
+func (m *MockGroupService) CreateGroup(ctx context.Context, group interfaces.Group) (interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateGroup", ctx, group)
+	ret0, _ := ret[0].(interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateGroup indicates an expected call of CreateGroup.
+func (mr *MockGroupServiceMockRecorder) CreateGroup(ctx, group interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateGroup", reflect.TypeOf((*MockGroupService)(nil).CreateGroup), ctx, group)
+}
+
+// DeleteGroup mocks base method.
+func (m *MockGroupService) DeleteGroup(ctx context.Context, organizationID, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteGroup", ctx, organizationID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteGroup indicates an expected call of DeleteGroup.
+func (mr *MockGroupServiceMockRecorder) DeleteGroup(ctx, organizationID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteGroup", reflect.TypeOf((*MockGroupService)(nil).DeleteGroup), ctx, organizationID, id)
+}
+
+// GetEffectiveGroups mocks base method.
+func (m *MockGroupService) GetEffectiveGroups(ctx context.Context, organizationID, userID int) ([]interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEffectiveGroups", ctx, organizationID, userID)
+	ret0, _ := ret[0].([]interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEffectiveGroups indicates an expected call of GetEffectiveGroups.
+func (mr *MockGroupServiceMockRecorder) GetEffectiveGroups(ctx, organizationID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEffectiveGroups", reflect.TypeOf((*MockGroupService)(nil).GetEffectiveGroups), ctx, organizationID, userID)
+}
+
+// GetEffectivePermissions mocks base method.
+func (m *MockGroupService) GetEffectivePermissions(ctx context.Context, organizationID, userID int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEffectivePermissions", ctx, organizationID, userID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEffectivePermissions indicates an expected call of GetEffectivePermissions.
+func (mr *MockGroupServiceMockRecorder) GetEffectivePermissions(ctx, organizationID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEffectivePermissions", reflect.TypeOf((*MockGroupService)(nil).GetEffectivePermissions), ctx, organizationID, userID)
+}
+
+// GetGroup mocks base method.
+func (m *MockGroupService) GetGroup(ctx context.Context, organizationID, id int) (interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroup", ctx, organizationID, id)
+	ret0, _ := ret[0].(interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroup indicates an expected call of GetGroup.
+func (mr *MockGroupServiceMockRecorder) GetGroup(ctx, organizationID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroup", reflect.TypeOf((*MockGroupService)(nil).GetGroup), ctx, organizationID, id)
+}
+
+// GetGroups mocks base method.
+func (m *MockGroupService) GetGroups(ctx context.Context, organizationID int) ([]interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGroups", ctx, organizationID)
+	ret0, _ := ret[0].([]interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGroups indicates an expected call of GetGroups.
+func (mr *MockGroupServiceMockRecorder) GetGroups(ctx, organizationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGroups", reflect.TypeOf((*MockGroupService)(nil).GetGroups), ctx, organizationID)
+}
+
+// GetMembers mocks base method.
+func (m *MockGroupService) GetMembers(ctx context.Context, organizationID, groupID int) ([]interfaces.GroupMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMembers", ctx, organizationID, groupID)
+	ret0, _ := ret[0].([]interfaces.GroupMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMembers indicates an expected call of GetMembers.
+func (mr *MockGroupServiceMockRecorder) GetMembers(ctx, organizationID, groupID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMembers", reflect.TypeOf((*MockGroupService)(nil).GetMembers), ctx, organizationID, groupID)
+}
+
+// GetTransitiveMembers mocks base method.
+func (m *MockGroupService) GetTransitiveMembers(ctx context.Context, organizationID, groupID int) ([]interfaces.GroupMember, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransitiveMembers", ctx, organizationID, groupID)
+	ret0, _ := ret[0].([]interfaces.GroupMember)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransitiveMembers indicates an expected call of GetTransitiveMembers.
+func (mr *MockGroupServiceMockRecorder) GetTransitiveMembers(ctx, organizationID, groupID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransitiveMembers", reflect.TypeOf((*MockGroupService)(nil).GetTransitiveMembers), ctx, organizationID, groupID)
+}
+
+// RemoveMember mocks base method.
+func (m *MockGroupService) RemoveMember(ctx context.Context, organizationID, groupID, userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMember", ctx, organizationID, groupID, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveMember indicates an expected call of RemoveMember.
+func (mr *MockGroupServiceMockRecorder) RemoveMember(ctx, organizationID, groupID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMember", reflect.TypeOf((*MockGroupService)(nil).RemoveMember), ctx, organizationID, groupID, userID)
+}
+
+// UnassignRole mocks base method.
+func (m *MockGroupService) UnassignRole(ctx context.Context, organizationID, groupID int, role string) (interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnassignRole", ctx, organizationID, groupID, role)
+	ret0, _ := ret[0].(interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnassignRole indicates an expected call of UnassignRole.
+func (mr *MockGroupServiceMockRecorder) UnassignRole(ctx, organizationID, groupID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnassignRole", reflect.TypeOf((*MockGroupService)(nil).UnassignRole), ctx, organizationID, groupID, role)
+}
+
+// UpdateGroup mocks base method.
+func (m *MockGroupService) UpdateGroup(ctx context.Context, group interfaces.Group) (interfaces.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateGroup", ctx, group)
+	ret0, _ := ret[0].(interfaces.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateGroup indicates an expected call of UpdateGroup.
+func (mr *MockGroupServiceMockRecorder) UpdateGroup(ctx, group interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGroup", reflect.TypeOf((*MockGroupService)(nil).UpdateGroup), ctx, group)
+}
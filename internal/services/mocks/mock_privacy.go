@@ -0,0 +1,241 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/interfaces/privacy.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	interfaces "github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+// MockPrivacyRepository is a mock of PrivacyRepository interface.
+type MockPrivacyRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPrivacyRepositoryMockRecorder
+}
+
+// MockPrivacyRepositoryMockRecorder is the mock recorder for MockPrivacyRepository.
+type MockPrivacyRepositoryMockRecorder struct {
+	mock *MockPrivacyRepository
+}
+
+// NewMockPrivacyRepository creates a new mock instance.
+func NewMockPrivacyRepository(ctrl *gomock.Controller) *MockPrivacyRepository {
+	mock := &MockPrivacyRepository{ctrl: ctrl}
+	mock.recorder = &MockPrivacyRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPrivacyRepository) EXPECT() *MockPrivacyRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CancelErasureRequest mocks base method.
+func (m *MockPrivacyRepository) CancelErasureRequest(ctx context.Context, organizationID, id int) (interfaces.ErasureRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelErasureRequest", ctx, organizationID, id)
+	ret0, _ := ret[0].(interfaces.ErasureRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelErasureRequest indicates an expected call of CancelErasureRequest.
+func (mr *MockPrivacyRepositoryMockRecorder) CancelErasureRequest(ctx, organizationID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelErasureRequest", reflect.TypeOf((*MockPrivacyRepository)(nil).CancelErasureRequest), ctx, organizationID, id)
+}
+
+// Erase mocks base method.
+func (m *MockPrivacyRepository) Erase(ctx context.Context, request interfaces.ErasureRequest) (interfaces.ErasureRequest, interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Erase", ctx, request)
+	ret0, _ := ret[0].(interfaces.ErasureRequest)
+	ret1, _ := ret[1].(interfaces.User)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Erase indicates an expected call of Erase.
+func (mr *MockPrivacyRepositoryMockRecorder) Erase(ctx, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Erase", reflect.TypeOf((*MockPrivacyRepository)(nil).Erase), ctx, request)
+}
+
+// GetDueErasureRequests mocks base method.
+func (m *MockPrivacyRepository) GetDueErasureRequests(ctx context.Context, now time.Time) ([]interfaces.ErasureRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueErasureRequests", ctx, now)
+	ret0, _ := ret[0].([]interfaces.ErasureRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueErasureRequests indicates an expected call of GetDueErasureRequests.
+func (mr *MockPrivacyRepositoryMockRecorder) GetDueErasureRequests(ctx, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueErasureRequests", reflect.TypeOf((*MockPrivacyRepository)(nil).GetDueErasureRequests), ctx, now)
+}
+
+// GetErasureRequests mocks base method.
+func (m *MockPrivacyRepository) GetErasureRequests(ctx context.Context, organizationID int) ([]interfaces.ErasureRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetErasureRequests", ctx, organizationID)
+	ret0, _ := ret[0].([]interfaces.ErasureRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetErasureRequests indicates an expected call of GetErasureRequests.
+func (mr *MockPrivacyRepositoryMockRecorder) GetErasureRequests(ctx, organizationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetErasureRequests", reflect.TypeOf((*MockPrivacyRepository)(nil).GetErasureRequests), ctx, organizationID)
+}
+
+// GetPersonalData mocks base method.
+func (m *MockPrivacyRepository) GetPersonalData(ctx context.Context, organizationID, userID int) (interfaces.PersonalData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPersonalData", ctx, organizationID, userID)
+	ret0, _ := ret[0].(interfaces.PersonalData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPersonalData indicates an expected call of GetPersonalData.
+func (mr *MockPrivacyRepositoryMockRecorder) GetPersonalData(ctx, organizationID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPersonalData", reflect.TypeOf((*MockPrivacyRepository)(nil).GetPersonalData), ctx, organizationID, userID)
+}
+
+// ScheduleErasure mocks base method.
+func (m *MockPrivacyRepository) ScheduleErasure(ctx context.Context, request interfaces.ErasureRequest) (interfaces.ErasureRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleErasure", ctx, request)
+	ret0, _ := ret[0].(interfaces.ErasureRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScheduleErasure indicates an expected call of ScheduleErasure.
+func (mr *MockPrivacyRepositoryMockRecorder) ScheduleErasure(ctx, request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleErasure", reflect.TypeOf((*MockPrivacyRepository)(nil).ScheduleErasure), ctx, request)
+}
+
+// MockPrivacyService is a mock of PrivacyService interface.
+type MockPrivacyService struct {
+	ctrl     *gomock.Controller
+	recorder *MockPrivacyServiceMockRecorder
+}
+
+// MockPrivacyServiceMockRecorder is the mock recorder for MockPrivacyService.
+type MockPrivacyServiceMockRecorder struct {
+	mock *MockPrivacyService
+}
+
+// NewMockPrivacyService creates a new mock instance.
+func NewMockPrivacyService(ctrl *gomock.Controller) *MockPrivacyService {
+	mock := &MockPrivacyService{ctrl: ctrl}
+	mock.recorder = &MockPrivacyServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPrivacyService) EXPECT() *MockPrivacyServiceMockRecorder {
+	return m.recorder
+}
+
+// CancelErasure mocks base method.
+func (m *MockPrivacyService) CancelErasure(ctx context.Context, organizationID, id int) (interfaces.ErasureRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelErasure", ctx, organizationID, id)
+	ret0, _ := ret[0].(interfaces.ErasureRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelErasure indicates an expected call of CancelErasure.
+func (mr *MockPrivacyServiceMockRecorder) CancelErasure(ctx, organizationID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelErasure", reflect.TypeOf((*MockPrivacyService)(nil).CancelErasure), ctx, organizationID, id)
+}
+
+// EraseDueUsers mocks base method.
+func (m *MockPrivacyService) EraseDueUsers(ctx context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EraseDueUsers", ctx)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EraseDueUsers indicates an expected call of EraseDueUsers.
+func (mr *MockPrivacyServiceMockRecorder) EraseDueUsers(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EraseDueUsers", reflect.TypeOf((*MockPrivacyService)(nil).EraseDueUsers), ctx)
+}
+
+// EraseNow mocks base method.
+func (m *MockPrivacyService) EraseNow(ctx context.Context, organizationID, userID int, reason string) (interfaces.ErasureRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EraseNow", ctx, organizationID, userID, reason)
+	ret0, _ := ret[0].(interfaces.ErasureRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EraseNow indicates an expected call of EraseNow.
+func (mr *MockPrivacyServiceMockRecorder) EraseNow(ctx, organizationID, userID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EraseNow", reflect.TypeOf((*MockPrivacyService)(nil).EraseNow), ctx, organizationID, userID, reason)
+}
+
+// ExportPersonalData mocks base method.
+func (m *MockPrivacyService) ExportPersonalData(ctx context.Context, organizationID, userID int) (interfaces.PersonalData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportPersonalData", ctx, organizationID, userID)
+	ret0, _ := ret[0].(interfaces.PersonalData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportPersonalData indicates an expected call of ExportPersonalData.
+func (mr *MockPrivacyServiceMockRecorder) ExportPersonalData(ctx, organizationID, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportPersonalData", reflect.TypeOf((*MockPrivacyService)(nil).ExportPersonalData), ctx, organizationID, userID)
+}
+
+// GetErasureRequests mocks base method.
+func (m *MockPrivacyService) GetErasureRequests(ctx context.Context, organizationID int) ([]interfaces.ErasureRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetErasureRequests", ctx, organizationID)
+	ret0, _ := ret[0].([]interfaces.ErasureRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetErasureRequests indicates an expected call of GetErasureRequests.
+func (mr *MockPrivacyServiceMockRecorder) GetErasureRequests(ctx, organizationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetErasureRequests", reflect.TypeOf((*MockPrivacyService)(nil).GetErasureRequests), ctx, organizationID)
+}
+
+// ScheduleErasure mocks base method.
+func (m *MockPrivacyService) ScheduleErasure(ctx context.Context, organizationID, userID, requestedBy int, reason string) (interfaces.ErasureRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleErasure", ctx, organizationID, userID, requestedBy, reason)
+	ret0, _ := ret[0].(interfaces.ErasureRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScheduleErasure indicates an expected call of ScheduleErasure.
+func (mr *MockPrivacyServiceMockRecorder) ScheduleErasure(ctx, organizationID, userID, requestedBy, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleErasure", reflect.TypeOf((*MockPrivacyService)(nil).ScheduleErasure), ctx, organizationID, userID, requestedBy, reason)
+}
@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
@@ -35,79 +36,124 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// ChangeUserStatus mocks base method.
+func (m *MockService) ChangeUserStatus(ctx context.Context, organizationID, id int, status, reason string, changedBy int) (interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeUserStatus", ctx, organizationID, id, status, reason, changedBy)
+	ret0, _ := ret[0].(interfaces.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeUserStatus indicates an expected call of ChangeUserStatus.
+func (mr *MockServiceMockRecorder) ChangeUserStatus(ctx, organizationID, id, status, reason, changedBy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeUserStatus", reflect.TypeOf((*MockService)(nil).ChangeUserStatus), ctx, organizationID, id, status, reason, changedBy)
+}
+
 // CreateUser mocks base method.
-func (m *MockService) CreateUser(user interfaces.User) (interfaces.User, error) {
+func (m *MockService) CreateUser(ctx context.Context, user interfaces.User) (interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", user)
+	ret := m.ctrl.Call(m, "CreateUser", ctx, user)
 	ret0, _ := ret[0].(interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockServiceMockRecorder) CreateUser(user interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) CreateUser(ctx, user interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockService)(nil).CreateUser), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockService)(nil).CreateUser), ctx, user)
 }
 
 // DeleteUser mocks base method.
-func (m *MockService) DeleteUser(id int) (interfaces.User, error) {
+func (m *MockService) DeleteUser(ctx context.Context, organizationID, id, version int) (interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUser", id)
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, organizationID, id, version)
 	ret0, _ := ret[0].(interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DeleteUser indicates an expected call of DeleteUser.
-func (mr *MockServiceMockRecorder) DeleteUser(id interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) DeleteUser(ctx, organizationID, id, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockService)(nil).DeleteUser), ctx, organizationID, id, version)
+}
+
+// GetDeletedUsers mocks base method.
+func (m *MockService) GetDeletedUsers(ctx context.Context, organizationID int) ([]interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletedUsers", ctx, organizationID)
+	ret0, _ := ret[0].([]interfaces.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletedUsers indicates an expected call of GetDeletedUsers.
+func (mr *MockServiceMockRecorder) GetDeletedUsers(ctx, organizationID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletedUsers", reflect.TypeOf((*MockService)(nil).GetDeletedUsers), ctx, organizationID)
+}
+
+// GetStatusHistory mocks base method.
+func (m *MockService) GetStatusHistory(ctx context.Context, organizationID, id int) ([]interfaces.StatusTransition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatusHistory", ctx, organizationID, id)
+	ret0, _ := ret[0].([]interfaces.StatusTransition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatusHistory indicates an expected call of GetStatusHistory.
+func (mr *MockServiceMockRecorder) GetStatusHistory(ctx, organizationID, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockService)(nil).DeleteUser), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatusHistory", reflect.TypeOf((*MockService)(nil).GetStatusHistory), ctx, organizationID, id)
 }
 
 // GetUserByID mocks base method.
-func (m *MockService) GetUserByID(id int) (interfaces.User, error) {
+func (m *MockService) GetUserByID(ctx context.Context, organizationID, id int) (interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByID", id)
+	ret := m.ctrl.Call(m, "GetUserByID", ctx, organizationID, id)
 	ret0, _ := ret[0].(interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserByID indicates an expected call of GetUserByID.
-func (mr *MockServiceMockRecorder) GetUserByID(id interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetUserByID(ctx, organizationID, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockService)(nil).GetUserByID), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockService)(nil).GetUserByID), ctx, organizationID, id)
 }
 
 // GetUserByUsername mocks base method.
-func (m *MockService) GetUserByUsername(username string) (interfaces.User, error) {
+func (m *MockService) GetUserByUsername(ctx context.Context, organizationID int, username string) (interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByUsername", username)
+	ret := m.ctrl.Call(m, "GetUserByUsername", ctx, organizationID, username)
 	ret0, _ := ret[0].(interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUserByUsername indicates an expected call of GetUserByUsername.
-func (mr *MockServiceMockRecorder) GetUserByUsername(username interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) GetUserByUsername(ctx, organizationID, username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockService)(nil).GetUserByUsername), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockService)(nil).GetUserByUsername), ctx, organizationID, username)
 }
 
 // GetUsers mocks base method.
-func (m *MockService) GetUsers() ([]interfaces.User, error) {
+func (m *MockService) GetUsers(ctx context.Context, organizationID int, filter interfaces.UserFilter) ([]interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUsers")
+	ret := m.ctrl.Call(m, "GetUsers", ctx, organizationID, filter)
 	ret0, _ := ret[0].([]interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetUsers indicates an expected call of GetUsers.
-func (mr *MockServiceMockRecorder) GetUsers() *gomock.Call {
+func (mr *MockServiceMockRecorder) GetUsers(ctx, organizationID, filter interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockService)(nil).GetUsers))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockService)(nil).GetUsers), ctx, organizationID, filter)
 }
 
 // HashPassword mocks base method.
@@ -125,46 +171,90 @@ func (mr *MockServiceMockRecorder) HashPassword(password interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HashPassword", reflect.TypeOf((*MockService)(nil).HashPassword), password)
 }
 
-// IsUsernameUnique mocks base method.
-func (m *MockService) IsUsernameUnique(username string) (bool, error) {
+// Logout mocks base method.
+func (m *MockService) Logout(ctx context.Context, userID int, token string, expiry time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "IsUsernameUnique", username)
-	ret0, _ := ret[0].(bool)
+	ret := m.ctrl.Call(m, "Logout", ctx, userID, token, expiry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockServiceMockRecorder) Logout(ctx, userID, token, expiry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockService)(nil).Logout), ctx, userID, token, expiry)
+}
+
+// PurgeDeletedUsers mocks base method.
+func (m *MockService) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedUsers", ctx, retention)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// IsUsernameUnique indicates an expected call of IsUsernameUnique.
-func (mr *MockServiceMockRecorder) IsUsernameUnique(username interface{}) *gomock.Call {
+// PurgeDeletedUsers indicates an expected call of PurgeDeletedUsers.
+func (mr *MockServiceMockRecorder) PurgeDeletedUsers(ctx, retention interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsUsernameUnique", reflect.TypeOf((*MockService)(nil).IsUsernameUnique), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedUsers", reflect.TypeOf((*MockService)(nil).PurgeDeletedUsers), ctx, retention)
 }
 
-// Logout mocks base method.
-func (m *MockService) Logout(token string, expiry time.Time) error {
+// RecordLogin mocks base method.
+func (m *MockService) RecordLogin(ctx context.Context, user interfaces.User) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Logout", token, expiry)
+	ret := m.ctrl.Call(m, "RecordLogin", ctx, user)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Logout indicates an expected call of Logout.
-func (mr *MockServiceMockRecorder) Logout(token, expiry interface{}) *gomock.Call {
+// RecordLogin indicates an expected call of RecordLogin.
+func (mr *MockServiceMockRecorder) RecordLogin(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLogin", reflect.TypeOf((*MockService)(nil).RecordLogin), ctx, user)
+}
+
+// RestoreUser mocks base method.
+func (m *MockService) RestoreUser(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", ctx, organizationID, id)
+	ret0, _ := ret[0].(interfaces.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockServiceMockRecorder) RestoreUser(ctx, organizationID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockService)(nil).RestoreUser), ctx, organizationID, id)
+}
+
+// SearchUsers mocks base method.
+func (m *MockService) SearchUsers(ctx context.Context, organizationID int, text string, filter interfaces.UserFilter, limit int) ([]interfaces.UserSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", ctx, organizationID, text, filter, limit)
+	ret0, _ := ret[0].([]interfaces.UserSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockServiceMockRecorder) SearchUsers(ctx, organizationID, text, filter, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockService)(nil).Logout), token, expiry)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockService)(nil).SearchUsers), ctx, organizationID, text, filter, limit)
 }
 
 // UpdateUser mocks base method.
-func (m *MockService) UpdateUser(user interfaces.User) (interfaces.User, error) {
+func (m *MockService) UpdateUser(ctx context.Context, user interfaces.User) (interfaces.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUser", user)
+	ret := m.ctrl.Call(m, "UpdateUser", ctx, user)
 	ret0, _ := ret[0].(interfaces.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateUser indicates an expected call of UpdateUser.
-func (mr *MockServiceMockRecorder) UpdateUser(user interface{}) *gomock.Call {
+func (mr *MockServiceMockRecorder) UpdateUser(ctx, user interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockService)(nil).UpdateUser), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockService)(nil).UpdateUser), ctx, user)
 }
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+type organizationService struct {
+	repo interfaces.OrganizationRepository
+	tx   interfaces.TxManager
+}
+
+func NewOrganizationService(repo interfaces.OrganizationRepository, tx interfaces.TxManager) interfaces.OrganizationService {
+	return &organizationService{repo, tx}
+}
+
+func (service *organizationService) GetOrganization(ctx context.Context, id int) (interfaces.Organization, error) {
+	return service.repo.GetByID(ctx, id)
+}
+
+func (service *organizationService) GetOrganizationBySlug(
+	ctx context.Context,
+	slug string,
+) (interfaces.Organization, error) {
+	return service.repo.GetBySlug(ctx, slug)
+}
+
+func (service *organizationService) GetOrganizationsForUser(
+	ctx context.Context,
+	userID int,
+) ([]interfaces.Organization, error) {
+	return service.repo.GetForUser(ctx, userID)
+}
+
+func (service *organizationService) CreateOrganization(
+	ctx context.Context,
+	organization interfaces.Organization,
+	ownerID int,
+) (interfaces.Organization, error) {
+	return service.repo.Create(ctx, organization, ownerID)
+}
+
+func (service *organizationService) GetMembers(
+	ctx context.Context,
+	organizationID int,
+) ([]interfaces.Membership, error) {
+	return service.repo.GetMembers(ctx, organizationID)
+}
+
+func (service *organizationService) GetMembership(
+	ctx context.Context,
+	organizationID, userID int,
+) (interfaces.Membership, error) {
+	return service.repo.GetMembership(ctx, organizationID, userID)
+}
+
+func (service *organizationService) AddMember(
+	ctx context.Context,
+	actor, membership interfaces.Membership,
+) (interfaces.Membership, error) {
+	if membership.Role == "" {
+		membership.Role = interfaces.RoleMember
+	}
+	if !interfaces.IsValidRole(membership.Role) {
+		return membership, interfaces.ErrInvalidRole
+	}
+	if membership.Role == interfaces.RoleOwner && actor.Role != interfaces.RoleOwner {
+		return membership, interfaces.ErrOwnerRequired
+	}
+	return service.repo.AddMember(ctx, membership)
+}
+
+// UpdateMemberRole reads the owners and changes the role in one unit of work, so concurrent
+// demotions cannot both pass the last owner check
+func (service *organizationService) UpdateMemberRole(
+	ctx context.Context,
+	actor, membership interfaces.Membership,
+) (interfaces.Membership, error) {
+	if !interfaces.IsValidRole(membership.Role) {
+		return membership, interfaces.ErrInvalidRole
+	}
+	updated := membership
+	err := service.tx.WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+		if err := service.checkOwners(ctx, actor, membership, membership.Role); err != nil {
+			return err
+		}
+		var err error
+		updated, err = service.repo.UpdateMemberRole(ctx, membership)
+		return err
+	})
+	return updated, err
+}
+
+func (service *organizationService) RemoveMember(
+	ctx context.Context,
+	actor interfaces.Membership,
+	organizationID, userID int,
+) error {
+	membership := interfaces.Membership{OrganizationID: organizationID, UserID: userID}
+	return service.tx.WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+		if err := service.checkOwners(ctx, actor, membership, ""); err != nil {
+			return err
+		}
+		return service.repo.RemoveMember(ctx, organizationID, userID)
+	})
+}
+
+// checkOwners allows actor to give the member role, or remove the member when role is empty:
+// only owners change the owner role, and the last owner keeps it
+func (service *organizationService) checkOwners(
+	ctx context.Context,
+	actor, membership interfaces.Membership,
+	role string,
+) error {
+	owners, err := service.repo.GetOwners(ctx, membership.OrganizationID)
+	if err != nil {
+		return err
+	}
+	owner := slices.Contains(owners, membership.UserID)
+	if (owner || role == interfaces.RoleOwner) && actor.Role != interfaces.RoleOwner {
+		return interfaces.ErrOwnerRequired
+	}
+	if owner && role != interfaces.RoleOwner && len(owners) == 1 {
+		return interfaces.ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type outboxRelay struct {
+	repo      interfaces.OutboxRepository
+	publisher interfaces.EventPublisher
+}
+
+func NewOutboxRelay(repo interfaces.OutboxRepository, publisher interfaces.EventPublisher) interfaces.OutboxRelay {
+	return &outboxRelay{repo, publisher}
+}
+
+// Relay publishes the pending messages oldest first, deleting each once it was published. When a
+// message fails, the later messages of its user wait for the next run, while other users' go
+// ahead. A message published but not deleted, e.g. because the process stopped in between, is
+// published again. Relays running elsewhere at the same time skip the run.
+func (relay *outboxRelay) Relay(ctx context.Context) (int, error) {
+	unlock, locked, err := relay.repo.Lock(ctx)
+	if err != nil || !locked {
+		return 0, err
+	}
+	defer unlock()
+
+	published := 0
+	blocked := make(map[string]bool)
+	var afterID int64
+	for {
+		messages, err := relay.repo.Pending(ctx, afterID, interfaces.OutboxRelayBatch)
+		if err != nil {
+			return published, err
+		}
+		for _, message := range messages {
+			afterID = message.ID
+			key := message.OrderingKey()
+			if blocked[key] {
+				continue
+			}
+			if err := relay.publisher.Publish(ctx, message); err != nil {
+				logger.Error(
+					"Error publishing event:",
+					zap.Int64("eventID", message.ID),
+					zap.String("type", message.Type),
+					zap.Int("userID", message.UserID),
+					zap.Int("attempt", message.Attempts+1),
+					zap.Error(err),
+				)
+				blocked[key] = true
+				if err := relay.repo.Fail(ctx, message.ID, err); err != nil {
+					return published, err
+				}
+				continue
+			}
+			if err := relay.repo.Delete(ctx, message.ID); err != nil {
+				return published, err
+			}
+			published++
+		}
+		if len(messages) < interfaces.OutboxRelayBatch {
+			return published, nil
+		}
+	}
+}
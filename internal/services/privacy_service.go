@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type privacyService struct {
+	repo        interfaces.PrivacyRepository
+	users       interfaces.Repository
+	tx          interfaces.TxManager
+	events      interfaces.OutboxRepository
+	audit       interfaces.AuditService
+	avatars     interfaces.AvatarService
+	gracePeriod time.Duration
+}
+
+// NewPrivacyService schedules admin-requested erasures gracePeriod after the request. An erasure
+// records its event in events and its audit event in audit in the unit of work of the erasure,
+// and evicts the erased user from the cache of users once it committed.
+func NewPrivacyService(
+	repo interfaces.PrivacyRepository,
+	users interfaces.Repository,
+	tx interfaces.TxManager,
+	events interfaces.OutboxRepository,
+	audit interfaces.AuditService,
+	avatars interfaces.AvatarService,
+	gracePeriod time.Duration,
+) interfaces.PrivacyService {
+	return &privacyService{repo, users, tx, events, audit, avatars, gracePeriod}
+}
+
+func (service *privacyService) ExportPersonalData(
+	ctx context.Context,
+	organizationID, userID int,
+) (interfaces.PersonalData, error) {
+	return service.repo.GetPersonalData(ctx, organizationID, userID)
+}
+
+func (service *privacyService) EraseNow(
+	ctx context.Context,
+	organizationID, userID int,
+	reason string,
+) (interfaces.ErasureRequest, error) {
+	request, err := service.repo.ScheduleErasure(ctx, interfaces.ErasureRequest{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		RequestedBy:    &userID,
+		Reason:         reason,
+		ScheduledFor:   time.Now(),
+	})
+	if err != nil {
+		return request, err
+	}
+	return service.erase(ctx, request)
+}
+
+func (service *privacyService) ScheduleErasure(
+	ctx context.Context,
+	organizationID, userID, requestedBy int,
+	reason string,
+) (interfaces.ErasureRequest, error) {
+	return service.repo.ScheduleErasure(ctx, interfaces.ErasureRequest{
+		OrganizationID: organizationID,
+		UserID:         userID,
+		RequestedBy:    &requestedBy,
+		Reason:         reason,
+		ScheduledFor:   time.Now().Add(service.gracePeriod),
+	})
+}
+
+func (service *privacyService) GetErasureRequests(
+	ctx context.Context,
+	organizationID int,
+) ([]interfaces.ErasureRequest, error) {
+	return service.repo.GetErasureRequests(ctx, organizationID)
+}
+
+func (service *privacyService) CancelErasure(
+	ctx context.Context,
+	organizationID, id int,
+) (interfaces.ErasureRequest, error) {
+	return service.repo.CancelErasureRequest(ctx, organizationID, id)
+}
+
+// EraseDueUsers runs every pending erasure whose grace period is over. A failed erasure stays
+// pending and is retried on the next run.
+func (service *privacyService) EraseDueUsers(ctx context.Context) (int, error) {
+	due, err := service.repo.GetDueErasureRequests(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	erased := 0
+	for _, request := range due {
+		if _, err := service.erase(ctx, request); err != nil {
+			logger.Error("Error erasing user:", zap.Int("requestID", request.ID), zap.Int("userID", request.UserID), zap.Error(err))
+			continue
+		}
+		erased++
+	}
+	return erased, nil
+}
+
+// erase runs the request and records it in the audit log. The audit event names no personal
+// data, since the log cannot be erased later.
+func (service *privacyService) erase(
+	ctx context.Context,
+	request interfaces.ErasureRequest,
+) (interfaces.ErasureRequest, error) {
+	// The avatar goes first: if removing the images fails the request stays pending and is retried
+	err := service.avatars.Delete(ctx, request.OrganizationID, request.UserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return request, err
+	}
+
+	completed := request
+	err = service.tx.WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+		var erased interfaces.User
+		completed, erased, err = service.repo.Erase(ctx, request)
+		if err != nil {
+			return err
+		}
+		if err := service.events.Append(ctx, interfaces.UserErased{User: interfaces.NewEventUser(erased)}); err != nil {
+			return err
+		}
+		err = service.audit.Record(ctx, interfaces.AuditEvent{
+			OrganizationID: completed.OrganizationID,
+			ActorID:        completed.RequestedBy,
+			Action:         interfaces.AuditUserErase,
+			TargetType:     interfaces.AuditTargetUser,
+			TargetID:       &completed.UserID,
+			Changes: map[string]interfaces.AuditChange{
+				"erasure_request": {After: completed.ID},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		service.users.Evict(ctx, erased)
+		return nil
+	})
+	if err != nil {
+		return request, err
+	}
+	return completed, nil
+}
@@ -1,61 +1,253 @@
 package services
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"time"
 
 	"github.com/redbonzai/user-management-api/internal/interfaces"
 )
 
+// auditChanges are the field changes an audit event records
+type auditChanges = map[string]interfaces.AuditChange
+
 type service struct {
-	repo interfaces.Repository
+	repo       interfaces.Repository
+	attributes interfaces.AttributeService
+	tx         interfaces.TxManager
+	events     interfaces.OutboxRepository
+	audit      interfaces.AuditService
+}
+
+// NewService records a domain event in events for every user it creates, changes or signs in,
+// and an audit event in audit for every change, in the unit of work of the change
+func NewService(
+	repo interfaces.Repository,
+	attributes interfaces.AttributeService,
+	tx interfaces.TxManager,
+	events interfaces.OutboxRepository,
+	audit interfaces.AuditService,
+) interfaces.Service {
+	return &service{repo, attributes, tx, events, audit}
 }
 
-func NewService(repo interfaces.Repository) interfaces.Service {
-	return &service{repo}
+func (service *service) GetUsers(ctx context.Context, organizationID int, filter interfaces.UserFilter) ([]interfaces.User, error) {
+	return service.repo.GetAll(ctx, organizationID, filter)
 }
 
-func (service *service) GetUsers() ([]interfaces.User, error) {
+// SearchUsers clamps limit to SearchMaxLimit and uses SearchDefaultLimit when it is not positive
+func (service *service) SearchUsers(
+	ctx context.Context,
+	organizationID int,
+	text string,
+	filter interfaces.UserFilter,
+	limit int,
+) ([]interfaces.UserSearchResult, error) {
+	if limit <= 0 {
+		limit = interfaces.SearchDefaultLimit
+	}
+	return service.repo.Search(ctx, organizationID, text, filter, min(limit, interfaces.SearchMaxLimit))
+}
 
-	return service.repo.GetAll()
+func (service *service) GetUserByUsername(ctx context.Context, organizationID int, username string) (interfaces.User, error) {
+	return service.repo.GetByUsername(ctx, organizationID, username)
 }
 
-func (service *service) GetUserByUsername(username string) (interfaces.User, error) {
-	return service.repo.GetByUsername(username)
+func (service *service) GetUserByID(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	return service.repo.GetByID(ctx, organizationID, id)
 }
 
-func (service *service) GetUserByID(id int) (interfaces.User, error) {
-	return service.repo.GetByID(id)
+// CreateUser rejects statuses other than UserStatuses; users are created pending or active
+func (service *service) CreateUser(ctx context.Context, user interfaces.User) (interfaces.User, error) {
+	if err := interfaces.CheckInitialStatus(user); err != nil {
+		return interfaces.User{}, err
+	}
+	if err := service.attributes.ValidateAttributes(ctx, user); err != nil {
+		return interfaces.User{}, err
+	}
+	return service.change(ctx, interfaces.AuditUserCreate, userCreated,
+		func(ctx context.Context) (interfaces.User, auditChanges, error) {
+			created, err := service.repo.Create(ctx, user)
+			return created, interfaces.UserChanges(nil, &created), err
+		})
 }
 
-func (service *service) CreateUser(user interfaces.User) (interfaces.User, error) {
-	return service.repo.Create(user)
+func (service *service) UpdateUser(ctx context.Context, user interfaces.User) (interfaces.User, error) {
+	if err := service.attributes.ValidateAttributes(ctx, user); err != nil {
+		return interfaces.User{}, err
+	}
+	// The write and the reads of the stored user before and after it share a transaction
+	return service.change(ctx, interfaces.AuditUserUpdate, userUpdated,
+		func(ctx context.Context) (interfaces.User, auditChanges, error) {
+			existing, err := service.repo.GetByID(ctx, user.OrganizationID, user.ID)
+			if err != nil {
+				return interfaces.User{}, nil, err
+			}
+			updated, err := service.repo.Update(ctx, user)
+			return updated, interfaces.UserChanges(&existing, &updated), err
+		})
 }
 
-func (service *service) UpdateUser(user interfaces.User) (interfaces.User, error) {
-	return service.repo.Update(user)
+func (service *service) DeleteUser(ctx context.Context, organizationID, id, version int) (interfaces.User, error) {
+	return service.change(ctx, interfaces.AuditUserDelete, userDeleted,
+		func(ctx context.Context) (interfaces.User, auditChanges, error) {
+			deleted, err := service.repo.Delete(ctx, organizationID, id, version)
+			return deleted, interfaces.UserChanges(&deleted, nil), err
+		})
 }
 
-func (service *service) DeleteUser(id int) (interfaces.User, error) {
-	return service.repo.Delete(id)
+func (service *service) GetDeletedUsers(ctx context.Context, organizationID int) ([]interfaces.User, error) {
+	return service.repo.GetDeleted(ctx, organizationID)
 }
 
-func (service *service) IsUsernameUnique(username string) (bool, error) {
-	_, err := service.repo.GetByUsername(username)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return true, nil // Username is unique
+func (service *service) RestoreUser(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	return service.change(ctx, interfaces.AuditUserRestore, userUpdated,
+		func(ctx context.Context) (interfaces.User, auditChanges, error) {
+			restored, err := service.repo.Restore(ctx, organizationID, id)
+			return restored, nil, err
+		})
+}
+
+// ChangeUserStatus moves the user to status if the lifecycle allows it, recording who changed it and why
+func (service *service) ChangeUserStatus(
+	ctx context.Context,
+	organizationID, id int,
+	status, reason string,
+	changedBy int,
+) (interfaces.User, error) {
+	return service.change(ctx, interfaces.AuditUserStatusChange, userUpdated,
+		func(ctx context.Context) (interfaces.User, auditChanges, error) {
+			user, err := service.repo.GetByID(ctx, organizationID, id)
+			if err != nil {
+				return interfaces.User{}, nil, err
+			}
+			from := interfaces.StatusOf(user)
+			if err := interfaces.CheckTransition(from, status); err != nil {
+				return interfaces.User{}, nil, err
+			}
+			transition := interfaces.StatusTransition{
+				OrganizationID: organizationID,
+				UserID:         id,
+				FromStatus:     from,
+				ToStatus:       status,
+				Reason:         reason,
+			}
+			if changedBy != 0 {
+				transition.ChangedBy = &changedBy
+			}
+			changed, err := service.repo.ChangeStatus(ctx, transition)
+			changes := interfaces.UserChanges(&user, &changed)
+			if reason != "" {
+				changes["reason"] = interfaces.AuditChange{After: reason}
+			}
+			return changed, changes, err
+		})
+}
+
+// GetStatusHistory reads the user and its history from one snapshot
+func (service *service) GetStatusHistory(ctx context.Context, organizationID, id int) ([]interfaces.StatusTransition, error) {
+	var history []interfaces.StatusTransition
+	options := interfaces.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := service.tx.WithinTx(ctx, options, func(ctx context.Context) error {
+		if _, err := service.repo.GetByID(ctx, organizationID, id); err != nil {
+			return err
 		}
-		return false, err // Some other error occurred
-	}
-	return false, nil // Username already exists
+		var err error
+		history, err = service.repo.GetStatusHistory(ctx, organizationID, id)
+		return err
+	})
+	return history, err
+}
+
+// PurgeDeletedUsers permanently removes users that have been soft-deleted for longer than retention
+func (service *service) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error) {
+	purged, err := service.repo.Purge(ctx, time.Now().Add(-retention))
+	return int64(len(purged)), err
 }
 
 func (service *service) HashPassword(password string) (string, error) {
 	return service.repo.GenerateHashFromPassword(password)
 }
 
-func (service *service) Logout(token string, expiry time.Time) error {
-	return service.repo.BlacklistToken(token, expiry)
+// RecordLogin records that the user signed in
+func (service *service) RecordLogin(ctx context.Context, user interfaces.User) error {
+	return service.events.Append(ctx, interfaces.UserLoggedIn{User: interfaces.NewEventUser(user)})
+}
+
+func (service *service) Logout(ctx context.Context, userID int, token string, expiry time.Time) error {
+	return service.repo.BlacklistToken(ctx, userID, token, expiry)
+}
+
+// change runs fn as one unit of work that also claims the unique attribute values of the user it
+// produced and records its event and an audit event of action with the changes fn reports
+func (service *service) change(
+	ctx context.Context,
+	action string,
+	event func(user interfaces.User) interfaces.UserEvent,
+	fn func(ctx context.Context) (interfaces.User, auditChanges, error),
+) (interfaces.User, error) {
+	return service.inTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) (interfaces.User, error) {
+		user, changes, err := fn(ctx)
+		if err != nil {
+			return interfaces.User{}, err
+		}
+		if err := service.attributes.ClaimUniqueValues(ctx, user); err != nil {
+			return interfaces.User{}, err
+		}
+		if err := service.events.Append(ctx, event(user)); err != nil {
+			return interfaces.User{}, err
+		}
+		return user, service.audit.Record(ctx, auditEvent(ctx, action, user, changes))
+	})
+}
+
+// auditEvent attributes the change to the source of ctx. Without an authenticated actor, as for
+// registrations and accepted invitations, the user made the change itself.
+func auditEvent(ctx context.Context, action string, user interfaces.User, changes auditChanges) interfaces.AuditEvent {
+	source := interfaces.AuditSourceFrom(ctx)
+	event := interfaces.AuditEvent{
+		OrganizationID: user.OrganizationID,
+		ActorID:        source.ActorID,
+		ActorUsername:  source.ActorUsername,
+		Action:         action,
+		TargetType:     interfaces.AuditTargetUser,
+		TargetID:       &user.ID,
+		Changes:        changes,
+		IP:             source.IP,
+		RequestID:      source.RequestID,
+	}
+	if event.ActorID == nil {
+		event.ActorID, event.ActorUsername = &user.ID, user.Username
+	}
+	return event
+}
+
+func userCreated(user interfaces.User) interfaces.UserEvent {
+	return interfaces.UserCreated{User: interfaces.NewEventUser(user)}
+}
+
+func userUpdated(user interfaces.User) interfaces.UserEvent {
+	return interfaces.UserUpdated{User: interfaces.NewEventUser(user)}
+}
+
+func userDeleted(user interfaces.User) interfaces.UserEvent {
+	return interfaces.UserDeleted{User: interfaces.NewEventUser(user)}
+}
+
+// inTx runs fn as one unit of work and returns the user it produced
+func (service *service) inTx(
+	ctx context.Context,
+	options interfaces.TxOptions,
+	fn func(ctx context.Context) (interfaces.User, error),
+) (interfaces.User, error) {
+	var user interfaces.User
+	err := service.tx.WithinTx(ctx, options, func(ctx context.Context) error {
+		var err error
+		user, err = fn(ctx)
+		return err
+	})
+	if err != nil {
+		return interfaces.User{}, err
+	}
+	return user, nil
 }
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type localStore struct {
+	root string
+}
+
+// NewLocalStore stores blobs as files below root. It cannot issue URLs, so blobs are served through the API.
+func NewLocalStore(root string) interfaces.BlobStore {
+	return &localStore{root}
+}
+
+func (store *localStore) Put(key, _ string, body io.Reader) error {
+	path, err := store.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial blob
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		logger.Error("Error storing blob:", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (store *localStore) Get(key string) (io.ReadCloser, error) {
+	path, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, interfaces.ErrBlobNotFound
+	}
+	return file, err
+}
+
+func (store *localStore) Delete(keys ...string) error {
+	for _, key := range keys {
+		path, err := store.path(key)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Error("Error deleting blob:", zap.String("key", key), zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *localStore) URL(string, time.Duration) (string, error) {
+	return "", nil
+}
+
+// path maps a key to a file below root, rejecting keys that would escape it
+func (store *localStore) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", errors.New("invalid blob key " + key)
+	}
+	return filepath.Join(store.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type s3Store struct {
+	client   *s3.S3
+	uploader *s3manager.Uploader
+	bucket   string
+}
+
+// NewS3Store stores blobs in an S3 bucket and hands out presigned URLs for them
+func NewS3Store(session *session.Session, bucket string) interfaces.BlobStore {
+	return &s3Store{
+		client:   s3.New(session),
+		uploader: s3manager.NewUploader(session),
+		bucket:   bucket,
+	}
+}
+
+func (store *s3Store) Put(key, contentType string, body io.Reader) error {
+	_, err := store.uploader.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(store.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	})
+	if err != nil {
+		logger.Error("Error uploading blob:", zap.String("bucket", store.bucket), zap.String("key", key), zap.Error(err))
+	}
+	return err
+}
+
+func (store *s3Store) Get(key string) (io.ReadCloser, error) {
+	output, err := store.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, interfaces.ErrBlobNotFound
+		}
+		logger.Error("Error downloading blob:", zap.String("bucket", store.bucket), zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+	return output.Body, nil
+}
+
+func (store *s3Store) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	objects := make([]*s3.ObjectIdentifier, len(keys))
+	for i, key := range keys {
+		objects[i] = &s3.ObjectIdentifier{Key: aws.String(key)}
+	}
+	output, err := store.client.DeleteObjects(&s3.DeleteObjectsInput{
+		Bucket: aws.String(store.bucket),
+		Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+	})
+	if err == nil && len(output.Errors) > 0 {
+		err = errors.New(aws.StringValue(output.Errors[0].Message))
+	}
+	if err != nil {
+		logger.Error("Error deleting blobs:", zap.String("bucket", store.bucket), zap.Strings("keys", keys), zap.Error(err))
+	}
+	return err
+}
+
+func (store *s3Store) URL(key string, expiry time.Duration) (string, error) {
+	request, _ := store.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(store.bucket),
+		Key:    aws.String(key),
+	})
+	return request.Presign(expiry)
+}
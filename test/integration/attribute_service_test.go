@@ -0,0 +1,224 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	userdb "github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+var _ = Describe("AttributeService", func() {
+	var (
+		mockCtrl         *gomock.Controller
+		attributeRepo    *mocks.MockAttributeRepository
+		organizations    *mocks.MockOrganizationService
+		attributeService interfaces.AttributeService
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		attributeRepo = mocks.NewMockAttributeRepository(mockCtrl)
+		organizations = mocks.NewMockOrganizationService(mockCtrl)
+		attributeService = services.NewAttributeService(attributeRepo, organizations)
+
+		attributeRepo.EXPECT().GetDefinitions(gomock.Any(), 2).Return([]interfaces.AttributeDefinition{
+			{Name: "department", Type: interfaces.AttributeTypeString, Required: true, Enum: []string{"sales", "support"}, Visibility: interfaces.AttributeVisibilityPublic},
+			{Name: "employee_id", Type: interfaces.AttributeTypeString, Unique: true, Pattern: `^E[0-9]+$`, Visibility: interfaces.AttributeVisibilityPublic},
+			{Name: "salary", Type: interfaces.AttributeTypeNumber, Visibility: interfaces.AttributeVisibilityAdmin},
+			{Name: "started_on", Type: interfaces.AttributeTypeDate, Visibility: interfaces.AttributeVisibilityPublic},
+		}, nil).AnyTimes()
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	user := func(attributes map[string]interface{}) interfaces.User {
+		return interfaces.User{ID: 9, OrganizationID: 2, Attributes: attributes}
+	}
+
+	It("should accept attributes matching their definitions", func() {
+		Expect(attributeService.ValidateAttributes(context.Background(), user(map[string]interface{}{
+			"department":  "sales",
+			"employee_id": "E42",
+			"salary":      float64(52000),
+			"started_on":  "2026-03-01",
+		}))).To(Succeed())
+	})
+
+	It("should reject undefined, missing and malformed attributes", func() {
+		Expect(attributeService.ValidateAttributes(context.Background(), user(map[string]interface{}{"department": "sales", "shoe_size": 42.0}))).
+			To(MatchError(interfaces.ErrInvalidAttribute))
+		Expect(attributeService.ValidateAttributes(context.Background(), user(nil))).
+			To(MatchError(ContainSubstring("department is required")))
+		Expect(attributeService.ValidateAttributes(context.Background(), user(map[string]interface{}{"department": "legal"}))).
+			To(MatchError(interfaces.ErrInvalidAttribute))
+		Expect(attributeService.ValidateAttributes(context.Background(), user(map[string]interface{}{"department": "sales", "employee_id": "42"}))).
+			To(MatchError(interfaces.ErrInvalidAttribute))
+		Expect(attributeService.ValidateAttributes(context.Background(), user(map[string]interface{}{"department": "sales", "started_on": "01/03/2026"}))).
+			To(MatchError(interfaces.ErrInvalidAttribute))
+	})
+
+	It("should claim the values of unique attributes in the unit of work of the write", func() {
+		ctx := context.Background()
+		claimed := user(map[string]interface{}{"department": "sales", "employee_id": "E42"})
+		attributeRepo.EXPECT().ClaimValues(ctx, claimed, map[string]interface{}{"employee_id": "E42"}).
+			Return(fmt.Errorf("%w: employee_id", interfaces.ErrAttributeConflict))
+
+		Expect(attributeService.ClaimUniqueValues(ctx, claimed)).To(MatchError(interfaces.ErrAttributeConflict))
+	})
+
+	It("should release the unique values of deleted users", func() {
+		ctx := context.Background()
+		deleted := user(map[string]interface{}{"employee_id": "E42"})
+		deleted.DeletedAt = &time.Time{}
+		attributeRepo.EXPECT().ClaimValues(ctx, deleted, map[string]interface{}{}).Return(nil)
+
+		Expect(attributeService.ClaimUniqueValues(ctx, deleted)).To(Succeed())
+	})
+
+	It("should hide admin attributes from members", func() {
+		organizations.EXPECT().GetMembership(gomock.Any(), 2, 5).
+			Return(interfaces.Membership{OrganizationID: 2, UserID: 5, Role: interfaces.RoleMember}, nil).Times(2)
+
+		viewed, err := attributeService.View(context.Background(), 2, 5, []interfaces.User{
+			user(map[string]interface{}{"department": "sales", "salary": 52000.0, "removed": true}),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(viewed[0].Attributes).To(Equal(map[string]interface{}{"department": "sales"}))
+
+		_, err = attributeService.ParseFilter(context.Background(), 2, 5, map[string]string{"salary": "52000"})
+		Expect(err).To(MatchError(interfaces.ErrInvalidAttribute))
+	})
+
+	It("should show admin attributes to admins and parse typed filters", func() {
+		organizations.EXPECT().GetMembership(gomock.Any(), 2, 1).
+			Return(interfaces.Membership{OrganizationID: 2, UserID: 1, Role: interfaces.RoleAdmin}, nil).Times(2)
+
+		viewed, err := attributeService.View(context.Background(), 2, 1, []interfaces.User{user(map[string]interface{}{"salary": 52000.0})})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(viewed[0].Attributes).To(HaveKey("salary"))
+
+		filter, err := attributeService.ParseFilter(context.Background(), 2, 1, map[string]string{"salary": "52000", "department": "sales"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filter).To(Equal(map[string]interface{}{"salary": 52000.0, "department": "sales"}))
+	})
+
+	It("should treat callers outside the organization as members", func() {
+		organizations.EXPECT().GetMembership(gomock.Any(), 2, 0).Return(interfaces.Membership{}, sql.ErrNoRows)
+
+		viewed, err := attributeService.View(context.Background(), 2, 0, []interfaces.User{user(map[string]interface{}{"salary": 52000.0})})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(viewed[0].Attributes).To(BeEmpty())
+	})
+
+	It("should validate definitions before storing them", func() {
+		_, err := attributeService.CreateDefinition(context.Background(), interfaces.AttributeDefinition{OrganizationID: 2, Name: "Phone", Type: interfaces.AttributeTypeString})
+		Expect(err).To(MatchError(interfaces.ErrInvalidAttributeDefinition))
+
+		_, err = attributeService.CreateDefinition(context.Background(), interfaces.AttributeDefinition{OrganizationID: 2, Name: "vip", Type: interfaces.AttributeTypeBoolean, Enum: []string{"yes"}})
+		Expect(err).To(MatchError(interfaces.ErrInvalidAttributeDefinition))
+
+		attributeRepo.EXPECT().CreateDefinition(gomock.Any(), interfaces.AttributeDefinition{
+			OrganizationID: 2, Name: "phone", Type: interfaces.AttributeTypeString, Visibility: interfaces.AttributeVisibilityPublic,
+		}).DoAndReturn(func(_ context.Context, definition interfaces.AttributeDefinition) (interfaces.AttributeDefinition, error) {
+			return definition, nil
+		})
+		created, err := attributeService.CreateDefinition(context.Background(), interfaces.AttributeDefinition{OrganizationID: 2, Name: "phone", Type: interfaces.AttributeTypeString})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(created.Visibility).To(Equal(interfaces.AttributeVisibilityPublic))
+	})
+})
+
+var _ = Describe("AttributeRepository", func() {
+	var (
+		ctx           context.Context
+		dir           string
+		database      *sql.DB
+		attributeRepo interfaces.AttributeRepository
+		txManager     interfaces.TxManager
+		jane, john    interfaces.User
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		dir, err = os.MkdirTemp("", "attributes")
+		Expect(err).ToNot(HaveOccurred())
+		database, err = userdb.OpenSQLite(filepath.Join(dir, "attributes.db"))
+		Expect(err).ToNot(HaveOccurred())
+		migrations, err := userdb.EmbeddedMigrations("sqlite")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(userdb.NewMigrator(database, migrations).Up(ctx)).To(Succeed())
+
+		attributeRepo = repository.NewAttributeRepository(database)
+		txManager = repository.NewTxManager(database)
+		for _, name := range []string{"employee_id", "badge"} {
+			_, err = attributeRepo.CreateDefinition(context.Background(), interfaces.AttributeDefinition{
+				OrganizationID: 1, Name: name, Type: interfaces.AttributeTypeString,
+				Unique: name == "employee_id", Visibility: interfaces.AttributeVisibilityPublic,
+			})
+			Expect(err).ToNot(HaveOccurred())
+		}
+		userRepo := repository.NewUserRepository(database, nil, 0)
+		jane, err = userRepo.Create(ctx, interfaces.User{
+			OrganizationID: 1, Name: "Jane", Email: "jane@example.com", Username: "jane", Password: "hash",
+			Attributes: map[string]interface{}{"employee_id": "E42", "badge": "blue"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		john, err = userRepo.Create(ctx, interfaces.User{
+			OrganizationID: 1, Name: "John", Email: "john@example.com", Username: "john", Password: "hash",
+			Attributes: map[string]interface{}{"badge": "blue"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		database.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should let one user at a time hold a unique value", func() {
+		Expect(attributeRepo.ClaimValues(ctx, jane, map[string]interface{}{"employee_id": "E42"})).To(Succeed())
+
+		err := txManager.WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+			return attributeRepo.ClaimValues(ctx, john, map[string]interface{}{"employee_id": "E42"})
+		})
+		Expect(err).To(MatchError(interfaces.ErrAttributeConflict))
+
+		Expect(attributeRepo.ClaimValues(ctx, jane, map[string]interface{}{})).To(Succeed())
+		Expect(attributeRepo.ClaimValues(ctx, john, map[string]interface{}{"employee_id": "E42"})).To(Succeed())
+	})
+
+	It("should refuse to make an attribute unique while users share a value", func() {
+		_, err := attributeRepo.UpdateDefinition(context.Background(), interfaces.AttributeDefinition{
+			OrganizationID: 1, Name: "badge", Type: interfaces.AttributeTypeString,
+			Unique: true, Visibility: interfaces.AttributeVisibilityPublic,
+		})
+		Expect(err).To(MatchError(interfaces.ErrAttributeConflict))
+
+		definitions, err := attributeRepo.GetDefinitions(context.Background(), 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(definitions[0].Name).To(Equal("badge"))
+		Expect(definitions[0].Unique).To(BeFalse())
+
+		_, err = attributeRepo.UpdateDefinition(context.Background(), interfaces.AttributeDefinition{
+			OrganizationID: 1, Name: "employee_id", Type: interfaces.AttributeTypeString,
+			Unique: true, Visibility: interfaces.AttributeVisibilityPublic,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		err = attributeRepo.ClaimValues(ctx, john, map[string]interface{}{"employee_id": "E42"})
+		Expect(err).To(MatchError(interfaces.ErrAttributeConflict))
+	})
+})
@@ -0,0 +1,99 @@
+package handler_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+var _ = Describe("AuditService", func() {
+	var (
+		mockCtrl     *gomock.Controller
+		auditRepo    *mocks.MockAuditRepository
+		auditService interfaces.AuditService
+		chain        []interfaces.AuditEvent
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		auditRepo = mocks.NewMockAuditRepository(mockCtrl)
+		auditService = services.NewAuditService(auditRepo)
+
+		actorID, targetID := 1, 2
+		chain = nil
+		previous := interfaces.AuditGenesisHash
+		for i, action := range []string{interfaces.AuditAuthLogin, interfaces.AuditUserUpdate, interfaces.AuditAuthLogout} {
+			event := interfaces.AuditEvent{
+				ID:             int64(i + 1),
+				OrganizationID: 1,
+				ActorID:        &actorID,
+				Action:         action,
+				TargetType:     interfaces.AuditTargetUser,
+				TargetID:       &targetID,
+				CreatedAt:      time.Date(2026, 10, 19, 9, 0, i, 0, time.UTC),
+				PrevHash:       previous,
+			}
+			event.Hash = event.ComputeHash()
+			previous = event.Hash
+			chain = append(chain, event)
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("should accept an intact chain", func() {
+		auditRepo.EXPECT().GetChain(gomock.Any(), int64(0), gomock.Any()).Return(chain, nil)
+
+		result, err := auditService.Verify(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Valid).To(BeTrue())
+		Expect(result.Checked).To(Equal(3))
+	})
+
+	It("should detect an edited event", func() {
+		chain[1].Action = interfaces.AuditUserCreate
+		auditRepo.EXPECT().GetChain(gomock.Any(), int64(0), gomock.Any()).Return(chain, nil)
+
+		result, err := auditService.Verify(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Valid).To(BeFalse())
+		Expect(result.BrokenAt).To(Equal(int64(2)))
+	})
+
+	It("should detect a removed event", func() {
+		auditRepo.EXPECT().GetChain(gomock.Any(), int64(0), gomock.Any()).Return([]interfaces.AuditEvent{chain[0], chain[2]}, nil)
+
+		result, err := auditService.Verify(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Valid).To(BeFalse())
+		Expect(result.BrokenAt).To(Equal(int64(3)))
+	})
+
+	It("should diff users and mask secrets and personal data", func() {
+		active, suspended := interfaces.UserStatusActive, interfaces.UserStatusSuspended
+		before := interfaces.User{ID: 2, Name: "Old", Email: "a@example.com", Password: "hash-1", Status: &active}
+		after := interfaces.User{
+			ID: 2, Name: "New", Email: "a@example.com", Password: "hash-2", Status: &suspended,
+			Attributes: map[string]interface{}{"phone": "+44 20 7946 0000"},
+		}
+
+		changes := interfaces.UserChanges(&before, &after)
+		Expect(changes).To(HaveLen(4))
+		Expect(changes["status"]).To(Equal(interfaces.AuditChange{Before: active, After: suspended}))
+		masked := interfaces.AuditChange{Before: interfaces.AuditMaskedValue, After: interfaces.AuditMaskedValue}
+		Expect(changes["name"]).To(Equal(masked))
+		Expect(changes["password"]).To(Equal(masked))
+		Expect(changes["attributes"]).To(Equal(interfaces.AuditChange{After: interfaces.AuditMaskedValue}))
+
+		created := interfaces.UserChanges(nil, &after)
+		Expect(created["email"]).To(Equal(interfaces.AuditChange{After: interfaces.AuditMaskedValue}))
+	})
+})
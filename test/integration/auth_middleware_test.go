@@ -0,0 +1,78 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+var _ = Describe("JWTMiddleware", func() {
+	var (
+		mockCtrl *gomock.Controller
+		sessions *mocks.MockSessionRepository
+		token    string
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		sessions = mocks.NewMockSessionRepository(mockCtrl)
+		var err error
+		token, err = authentication.GenerateToken(interfaces.User{ID: 7, OrganizationID: 1, Username: "jane"})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	authenticate := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler := authentication.JWTMiddleware(sessions)(func(context echo.Context) error {
+			claims, _ := authentication.ClaimsFromContext(context)
+			return context.JSON(http.StatusOK, claims.UserID)
+		})
+		Expect(handler(echo.New().NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	It("should let sessions that are neither signed out nor revoked through", func() {
+		sessions.EXPECT().IsBlacklisted(gomock.Any(), token).Return(false, nil)
+		sessions.EXPECT().IsRevoked(gomock.Any(), 7, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ int, issuedAt time.Time) (bool, error) {
+				Expect(issuedAt).To(BeTemporally("~", time.Now(), time.Second))
+				return false, nil
+			})
+
+		rec := authenticate()
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring("7"))
+	})
+
+	It("should refuse revoked sessions", func() {
+		sessions.EXPECT().IsBlacklisted(gomock.Any(), token).Return(false, nil)
+		sessions.EXPECT().IsRevoked(gomock.Any(), 7, gomock.Any()).Return(true, nil)
+
+		Expect(authenticate().Code).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("should refuse sessions it cannot check", func() {
+		sessions.EXPECT().IsBlacklisted(gomock.Any(), token).Return(false, nil)
+		sessions.EXPECT().IsRevoked(gomock.Any(), 7, gomock.Any()).Return(false, errors.New("connection refused"))
+		Expect(authenticate().Code).To(Equal(http.StatusServiceUnavailable))
+
+		sessions.EXPECT().IsBlacklisted(gomock.Any(), token).Return(false, errors.New("connection refused"))
+		Expect(authenticate().Code).To(Equal(http.StatusServiceUnavailable))
+	})
+})
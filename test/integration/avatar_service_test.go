@@ -0,0 +1,134 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+	"github.com/redbonzai/user-management-api/internal/storage"
+)
+
+var _ = Describe("AvatarService", func() {
+	var (
+		mockCtrl      *gomock.Controller
+		avatarRepo    *mocks.MockAvatarRepository
+		userService   *mocks.MockService
+		root          string
+		avatarService interfaces.AvatarService
+	)
+
+	pngImage := func(width, height int) []byte {
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		for x := 0; x < width; x++ {
+			for y := 0; y < height; y++ {
+				img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+			}
+		}
+		var encoded bytes.Buffer
+		Expect(png.Encode(&encoded, img)).To(Succeed())
+		return encoded.Bytes()
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		avatarRepo = mocks.NewMockAvatarRepository(mockCtrl)
+		userService = mocks.NewMockService(mockCtrl)
+		var err error
+		root, err = os.MkdirTemp("", "avatars")
+		Expect(err).ToNot(HaveOccurred())
+		avatarService = services.NewAvatarService(avatarRepo, storage.NewLocalStore(root), userService)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+		Expect(os.RemoveAll(root)).To(Succeed())
+	})
+
+	It("should store square thumbnails and replace the previous avatar", func() {
+		previous := interfaces.Avatar{UserID: 5, OrganizationID: 1, KeyPrefix: "avatars/org-1/user-5/1"}
+		Expect(os.MkdirAll(filepath.Join(root, previous.KeyPrefix), 0o750)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, previous.Key(interfaces.AvatarOriginal)), []byte("old"), 0o600)).To(Succeed())
+
+		userService.EXPECT().GetUserByID(gomock.Any(), 1, 5).Return(interfaces.User{ID: 5, OrganizationID: 1}, nil)
+		avatarRepo.EXPECT().Get(gomock.Any(), 1, 5).Return(previous, nil)
+		avatarRepo.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, avatar interfaces.Avatar) (interfaces.Avatar, error) {
+			return avatar, nil
+		})
+
+		avatar, err := avatarService.Upload(context.Background(), 1, 5, bytes.NewReader(pngImage(300, 200)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(avatar.ContentType).To(Equal("image/png"))
+		Expect(avatar.KeyPrefix).To(HavePrefix("avatars/org-1/user-5/"))
+		Expect(avatar.URL).To(Equal("/v1/users/5/avatar"))
+		Expect(avatar.Thumbnails).To(Equal(map[string]string{
+			"64":  "/v1/users/5/avatar?size=64",
+			"256": "/v1/users/5/avatar?size=256",
+		}))
+
+		thumbnail, err := os.Open(filepath.Join(root, avatar.Key("64")))
+		Expect(err).ToNot(HaveOccurred())
+		defer thumbnail.Close()
+		config, err := png.DecodeConfig(thumbnail)
+		Expect(err).ToNot(HaveOccurred())
+		Expect([]int{config.Width, config.Height}).To(Equal([]int{64, 64}))
+
+		_, err = os.Stat(filepath.Join(root, previous.Key(interfaces.AvatarOriginal)))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should reject files that are not images or are too large", func() {
+		userService.EXPECT().GetUserByID(gomock.Any(), 1, 5).Return(interfaces.User{ID: 5}, nil).Times(2)
+
+		_, err := avatarService.Upload(context.Background(), 1, 5, strings.NewReader("<svg xmlns='http://www.w3.org/2000/svg'/>"))
+		Expect(err).To(MatchError(interfaces.ErrAvatarType))
+
+		_, err = avatarService.Upload(context.Background(), 1, 5, io.LimitReader(zeroReader{}, interfaces.AvatarMaxBytes+1))
+		Expect(err).To(MatchError(interfaces.ErrAvatarTooLarge))
+	})
+
+	It("should serve stored variants and reject unknown sizes", func() {
+		avatar := interfaces.Avatar{UserID: 5, OrganizationID: 1, KeyPrefix: "avatars/org-1/user-5/1", ContentType: "image/png"}
+		Expect(os.MkdirAll(filepath.Join(root, avatar.KeyPrefix), 0o750)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, avatar.Key("256")), []byte("thumbnail"), 0o600)).To(Succeed())
+		avatarRepo.EXPECT().Get(gomock.Any(), 1, 5).Return(avatar, nil)
+
+		body, contentType, err := avatarService.Open(context.Background(), 1, 5, "256")
+		Expect(err).ToNot(HaveOccurred())
+		defer body.Close()
+		Expect(contentType).To(Equal("image/png"))
+		Expect(io.ReadAll(body)).To(Equal([]byte("thumbnail")))
+
+		_, _, err = avatarService.Open(context.Background(), 1, 5, "17")
+		Expect(err).To(MatchError(interfaces.ErrBlobNotFound))
+	})
+
+	It("should attach avatars to the users that have one", func() {
+		avatarRepo.EXPECT().GetMany(gomock.Any(), 1, []int{5, 6}).Return(map[int]interfaces.Avatar{
+			6: {UserID: 6, OrganizationID: 1, KeyPrefix: "avatars/org-1/user-6/1", ContentType: "image/jpeg"},
+		}, nil)
+
+		users, err := avatarService.Attach(context.Background(), 1, []interfaces.User{{ID: 5}, {ID: 6}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(users[0].Avatar).To(BeNil())
+		Expect(users[1].Avatar.URL).To(Equal("/v1/users/6/avatar"))
+	})
+})
+
+type zeroReader struct{}
+
+func (zeroReader) Read(buffer []byte) (int, error) {
+	clear(buffer)
+	return len(buffer), nil
+}
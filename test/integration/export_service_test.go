@@ -0,0 +1,195 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parquet-go/parquet-go"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/handler"
+	repositorymocks "github.com/redbonzai/user-management-api/internal/interfaces/repository/mocks"
+	internalMiddleware "github.com/redbonzai/user-management-api/internal/middleware"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+// exportedUser mirrors the Parquet schema of a full export
+type exportedUser struct {
+	ID             int64   `parquet:"id"`
+	OrganizationID int64   `parquet:"organization_id"`
+	Name           *string `parquet:"name,optional"`
+	Email          *string `parquet:"email,optional"`
+	Username       *string `parquet:"username,optional"`
+	Status         *string `parquet:"status,optional"`
+	Version        int64   `parquet:"version"`
+}
+
+var _ = Describe("ExportService", func() {
+	var (
+		mockCtrl      *gomock.Controller
+		userRepo      *repositorymocks.MockRepository
+		exportStore   *mocks.MockExportStore
+		exportService interfaces.ExportService
+		users         []interfaces.User
+	)
+
+	streamUsers := func(_ context.Context, organizationID int, filter interfaces.UserFilter, fn func(interfaces.User) error) error {
+		for _, user := range users {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		userRepo = repositorymocks.NewMockRepository(mockCtrl)
+		exportStore = mocks.NewMockExportStore(mockCtrl)
+		exportService = services.NewExportService(userRepo, exportStore)
+
+		active := "active"
+		users = []interfaces.User{
+			{ID: 1, OrganizationID: 1, Name: "Jane, Doe", Email: "jane@example.com", Username: "jane", Status: &active, Version: 2},
+			{ID: 2, OrganizationID: 1, Name: "John Doe", Email: "john@example.com", Username: "john", Version: 1},
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("should write the selected fields as CSV", func() {
+		filter := interfaces.UserFilter{Statuses: []string{"active"}}
+		userRepo.EXPECT().StreamUsers(gomock.Any(), 1, filter, gomock.Any()).DoAndReturn(streamUsers)
+
+		var output bytes.Buffer
+		result, err := exportService.Export(context.Background(), 1, &output, interfaces.ExportOptions{
+			Format: interfaces.ExportFormatCSV,
+			Fields: []string{"email", "name", "status"},
+			Filter: filter,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Rows).To(Equal(2))
+		Expect(output.String()).To(Equal("email,name,status\n" +
+			"jane@example.com,\"Jane, Doe\",active\n" +
+			"john@example.com,John Doe,\n"))
+	})
+
+	It("should write NDJSON objects in field order with null statuses", func() {
+		userRepo.EXPECT().StreamUsers(gomock.Any(), 1, gomock.Any(), gomock.Any()).DoAndReturn(streamUsers)
+
+		var output bytes.Buffer
+		_, err := exportService.Export(context.Background(), 1, &output, interfaces.ExportOptions{
+			Format: interfaces.ExportFormatNDJSON,
+			Fields: []string{"username", "id", "status"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output.String()).To(Equal(`{"username":"jane","id":1,"status":"active"}` + "\n" +
+			`{"username":"john","id":2,"status":null}` + "\n"))
+	})
+
+	It("should write a readable Parquet file", func() {
+		userRepo.EXPECT().StreamUsers(gomock.Any(), 1, gomock.Any(), gomock.Any()).DoAndReturn(streamUsers)
+
+		var output bytes.Buffer
+		result, err := exportService.Export(context.Background(), 1, &output, interfaces.ExportOptions{Format: interfaces.ExportFormatParquet})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Rows).To(Equal(2))
+
+		rows, err := parquet.Read[exportedUser](bytes.NewReader(output.Bytes()), int64(output.Len()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rows).To(HaveLen(2))
+		Expect(rows[0].ID).To(Equal(int64(1)))
+		Expect(*rows[0].Name).To(Equal("Jane, Doe"))
+		Expect(*rows[0].Status).To(Equal("active"))
+		Expect(rows[1].Status).To(BeNil())
+		Expect(rows[1].Version).To(Equal(int64(1)))
+	})
+
+	It("should reject unknown fields before reading any user", func() {
+		_, err := exportService.Export(context.Background(), 1, io.Discard, interfaces.ExportOptions{
+			Format: interfaces.ExportFormatCSV,
+			Fields: []string{"id", "password"},
+		})
+		Expect(err).To(MatchError(interfaces.ErrExportField))
+	})
+
+	It("should upload the export to the store", func() {
+		userRepo.EXPECT().StreamUsers(gomock.Any(), 1, gomock.Any(), gomock.Any()).DoAndReturn(streamUsers)
+		var uploaded []byte
+		exportStore.EXPECT().Upload(gomock.Any(), "application/x-ndjson", gomock.Any()).DoAndReturn(
+			func(key, contentType string, body io.Reader) (string, error) {
+				Expect(key).To(HavePrefix("users/org-1/"))
+				Expect(key).To(HaveSuffix(".ndjson"))
+				var err error
+				uploaded, err = io.ReadAll(body)
+				return "s3://exports/" + key, err
+			})
+
+		result, err := exportService.ExportToStore(context.Background(), 1, interfaces.ExportOptions{
+			Format: interfaces.ExportFormatNDJSON,
+			Fields: []string{"id"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Rows).To(Equal(2))
+		Expect(result.Location).To(HavePrefix("s3://exports/users/org-1/"))
+		Expect(string(uploaded)).To(Equal("{\"id\":1}\n{\"id\":2}\n"))
+	})
+
+	It("should report a failed upload without blocking the export", func() {
+		userRepo.EXPECT().StreamUsers(gomock.Any(), 1, gomock.Any(), gomock.Any()).DoAndReturn(streamUsers).AnyTimes()
+		uploadErr := errors.New("bucket not found")
+		exportStore.EXPECT().Upload(gomock.Any(), gomock.Any(), gomock.Any()).Return("", uploadErr)
+
+		_, err := exportService.ExportToStore(context.Background(), 1, interfaces.ExportOptions{Format: interfaces.ExportFormatCSV})
+		Expect(err).To(MatchError(uploadErr))
+	})
+
+	It("should refuse store exports when no store is configured", func() {
+		_, err := services.NewExportService(userRepo, nil).
+			ExportToStore(context.Background(), 1, interfaces.ExportOptions{Format: interfaces.ExportFormatCSV})
+		Expect(err).To(MatchError(interfaces.ErrExportDestination))
+	})
+
+	Describe("ExportHandler", func() {
+		It("should stream the export through the response interceptor unwrapped", func() {
+			userRepo.EXPECT().StreamUsers(gomock.Any(), 3, gomock.Any(), gomock.Any()).DoAndReturn(streamUsers)
+			audit := mocks.NewMockAuditService(mockCtrl)
+			audit.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil)
+			exportHandler := handler.NewExportHandler(exportService, audit)
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/export?format=csv&fields=id,username", nil)
+			ctx := e.NewContext(req, rec)
+			ctx.Set(tenant.ContextKey, 3)
+
+			Expect(internalMiddleware.ResponseInterceptor(exportHandler.ExportUsers)(ctx)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get(echo.HeaderContentType)).To(Equal("text/csv"))
+			Expect(rec.Header().Get(echo.HeaderContentDisposition)).To(ContainSubstring("users.csv"))
+			Expect(rec.Body.String()).To(Equal("id,username\n1,jane\n2,john\n"))
+		})
+
+		It("should reject an unsupported format", func() {
+			exportHandler := handler.NewExportHandler(exportService, mocks.NewMockAuditService(mockCtrl))
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/v1/users/export?format=xlsx", nil), rec)
+
+			Expect(exportHandler.ExportUsers(ctx)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+})
@@ -0,0 +1,171 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	userdb "github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+var _ = Describe("GroupService", func() {
+	var (
+		mockCtrl      *gomock.Controller
+		groupRepo     *mocks.MockGroupRepository
+		organizations *mocks.MockOrganizationService
+		groupService  interfaces.GroupService
+		groups        []interfaces.Group
+	)
+
+	parent := func(id int) *int { return &id }
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		groupRepo = mocks.NewMockGroupRepository(mockCtrl)
+		organizations = mocks.NewMockOrganizationService(mockCtrl)
+		groupService = services.NewGroupService(groupRepo, organizations, passthroughTx(mockCtrl))
+
+		// engineering <- backend <- databases
+		groups = []interfaces.Group{
+			{ID: 1, OrganizationID: 2, Name: "engineering", Roles: []string{interfaces.RoleMember}},
+			{ID: 2, OrganizationID: 2, Name: "backend", ParentID: parent(1), Roles: []string{interfaces.RoleAdmin}},
+			{ID: 3, OrganizationID: 2, Name: "databases", ParentID: parent(2)},
+			{ID: 4, OrganizationID: 2, Name: "sales"},
+		}
+		groupRepo.EXPECT().GetAll(gomock.Any(), 2).Return(groups, nil).AnyTimes()
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("should include ancestor groups in a user's effective groups", func() {
+		groupRepo.EXPECT().GetGroupIDsForUser(gomock.Any(), 2, 9).Return([]int{3}, nil)
+
+		effective, err := groupService.GetEffectiveGroups(context.Background(), 2, 9)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(effective).To(HaveLen(3))
+		Expect(effective[0].Name).To(Equal("engineering"))
+		Expect(effective[2].Name).To(Equal("databases"))
+	})
+
+	It("should resolve permissions from inherited group roles", func() {
+		organizations.EXPECT().GetMembership(gomock.Any(), 2, 9).
+			Return(interfaces.Membership{OrganizationID: 2, UserID: 9, Role: interfaces.RoleMember}, nil)
+		groupRepo.EXPECT().GetGroupIDsForUser(gomock.Any(), 2, 9).Return([]int{3}, nil)
+
+		permissions, err := groupService.GetEffectivePermissions(context.Background(), 2, 9)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(permissions).To(ContainElement(interfaces.PermissionGroupsManage))
+		Expect(permissions).ToNot(ContainElement(interfaces.PermissionOrganizationManage))
+	})
+
+	It("should collect members of descendant groups", func() {
+		groupRepo.EXPECT().GetMembers(gomock.Any(), 2, 1, 2, 3).Return([]interfaces.GroupMember{
+			{GroupID: 1, UserID: 5},
+			{GroupID: 3, UserID: 9},
+			{GroupID: 3, UserID: 5},
+		}, nil)
+
+		members, err := groupService.GetTransitiveMembers(context.Background(), 2, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(members).To(Equal([]interfaces.GroupMember{{GroupID: 1, UserID: 5}, {GroupID: 3, UserID: 9}}))
+	})
+
+	It("should reject moving a group under its own descendant", func() {
+		groupRepo.EXPECT().GetParents(gomock.Any(), 2).Return(map[int]*int{1: nil, 2: parent(1), 3: parent(2), 4: nil}, nil)
+
+		_, err := groupService.UpdateGroup(context.Background(), interfaces.Group{ID: 1, OrganizationID: 2, Name: "engineering", ParentID: parent(3)})
+		Expect(err).To(MatchError(interfaces.ErrGroupCycle))
+	})
+
+	It("should check the hierarchy and move the group in one unit of work", func() {
+		moved := interfaces.Group{ID: 4, OrganizationID: 2, Name: "sales", ParentID: parent(3)}
+		groupRepo.EXPECT().GetParents(gomock.Any(), 2).DoAndReturn(func(ctx context.Context, _ int) (map[int]*int, error) {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			return map[int]*int{1: nil, 2: parent(1), 3: parent(2), 4: nil}, nil
+		})
+		groupRepo.EXPECT().Update(gomock.Any(), moved).DoAndReturn(func(ctx context.Context, group interfaces.Group) (interfaces.Group, error) {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			return group, nil
+		})
+
+		updated, err := groupService.UpdateGroup(context.Background(), moved)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated.ParentID).To(HaveValue(Equal(3)))
+	})
+
+	It("should terminate when stored data already contains a cycle", func() {
+		groups[0].ParentID = parent(3)
+		groupRepo.EXPECT().GetGroupIDsForUser(gomock.Any(), 2, 9).Return([]int{3}, nil)
+
+		effective, err := groupService.GetEffectiveGroups(context.Background(), 2, 9)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(effective).To(HaveLen(3))
+	})
+})
+
+var _ = Describe("GroupRepository", func() {
+	var (
+		ctx          context.Context
+		dir          string
+		database     *sql.DB
+		groupService interfaces.GroupService
+		engineering  interfaces.Group
+		backend      interfaces.Group
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		dir, err = os.MkdirTemp("", "groups")
+		Expect(err).ToNot(HaveOccurred())
+		database, err = userdb.OpenSQLite(filepath.Join(dir, "groups.db"))
+		Expect(err).ToNot(HaveOccurred())
+		migrations, err := userdb.EmbeddedMigrations("sqlite")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(userdb.NewMigrator(database, migrations).Up(ctx)).To(Succeed())
+
+		groupRepo := repository.NewGroupRepository(database)
+		groupService = services.NewGroupService(groupRepo, nil, repository.NewTxManager(database))
+		engineering, err = groupRepo.Create(ctx, interfaces.Group{OrganizationID: 1, Name: "engineering"})
+		Expect(err).ToNot(HaveOccurred())
+		backend, err = groupRepo.Create(ctx, interfaces.Group{OrganizationID: 1, Name: "backend", ParentID: &engineering.ID})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		database.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should read the hierarchy of the organization", func() {
+		parents, err := repository.NewGroupRepository(database).GetParents(ctx, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parents).To(HaveLen(2))
+		Expect(parents[engineering.ID]).To(BeNil())
+		Expect(parents[backend.ID]).To(HaveValue(Equal(engineering.ID)))
+	})
+
+	It("should move groups and refuse moves that form a cycle", func() {
+		engineering.ParentID = &backend.ID
+		_, err := groupService.UpdateGroup(ctx, engineering)
+		Expect(err).To(MatchError(interfaces.ErrGroupCycle))
+
+		backend.ParentID = nil
+		moved, err := groupService.UpdateGroup(ctx, backend)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(moved.ParentID).To(BeNil())
+
+		_, err = groupService.UpdateGroup(ctx, engineering)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
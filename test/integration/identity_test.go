@@ -0,0 +1,42 @@
+package handler_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+var _ = Describe("User identity", func() {
+	It("should compare usernames and emails lower-cased and in NFKC form", func() {
+		Expect(interfaces.NormalizeIdentity("Jane")).To(Equal("jane"))
+		Expect(interfaces.NormalizeIdentity("ｊａｎｅ")).To(Equal("jane"))
+		Expect(interfaces.NormalizeIdentity("Jane.Doe@Example.COM")).To(Equal("jane.doe@example.com"))
+	})
+
+	It("should lower-case letters rather than case fold them", func() {
+		Expect(interfaces.NormalizeIdentity("Straße")).To(Equal("straße"))
+		Expect(interfaces.NormalizeIdentity("STRASSE")).To(Equal("strasse"))
+	})
+
+	It("should accept usernames written in a single script", func() {
+		Expect(interfaces.CheckUsername("jane_doe-42")).To(Succeed())
+		Expect(interfaces.CheckUsername("иван")).To(Succeed())
+		Expect(interfaces.CheckUsername("山田たろう")).To(Succeed())
+	})
+
+	It("should reject usernames mixing lookalike scripts or hiding characters", func() {
+		Expect(interfaces.CheckUsername("j\u0430ne")).To(MatchError(interfaces.ErrConfusableUsername))
+		Expect(interfaces.CheckUsername("ja\u200bne")).To(MatchError(interfaces.ErrConfusableUsername))
+	})
+
+	It("should reject confusable usernames as invalid users", func() {
+		user := interfaces.User{Username: "p\u0430ypal", Email: "pay@example.com"}
+		Expect(user.Validate()).To(MatchError(interfaces.ErrInvalidUser))
+	})
+
+	It("should name the field of a uniqueness error", func() {
+		Expect(interfaces.ConflictField(interfaces.ErrUsernameTaken)).To(Equal("username"))
+		Expect(interfaces.ConflictField(interfaces.ErrEmailTaken)).To(Equal("email"))
+		Expect(interfaces.ConflictField(interfaces.ErrUserConflict)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,215 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository/memory"
+	repositorymocks "github.com/redbonzai/user-management-api/internal/interfaces/repository/mocks"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+var _ = Describe("ImportService", func() {
+	var (
+		mockCtrl            *gomock.Controller
+		userRepo            *repositorymocks.MockRepository
+		organizationService *mocks.MockOrganizationService
+		invitationService   *mocks.MockInvitationService
+		importService       interfaces.ImportService
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		userRepo = repositorymocks.NewMockRepository(mockCtrl)
+		organizationService = mocks.NewMockOrganizationService(mockCtrl)
+		invitationService = mocks.NewMockInvitationService(mockCtrl)
+		importService = services.NewImportService(
+			userRepo,
+			passthroughTx(mockCtrl),
+			memory.NewOutboxRepository(),
+			organizationService,
+			invitationService,
+		)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("should validate mapped CSV columns without writing on a dry run", func() {
+		source := "full_name,mail,username,password\n" +
+			"Jane Doe,jane@example.com,jane,secret123\n" +
+			"John Doe,john@example.com,john,secret456\n"
+		userRepo.EXPECT().FindExisting(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, nil)
+
+		report, err := importService.Import(context.Background(), 1, strings.NewReader(source), interfaces.ImportOptions{
+			Format:  interfaces.ImportFormatCSV,
+			DryRun:  true,
+			Mapping: map[string]string{"full_name": "name", "mail": "email"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Total).To(Equal(2))
+		Expect(report.Failed).To(Equal(0))
+		Expect(report.Rows[0].Status).To(Equal(interfaces.ImportRowValid))
+		Expect(report.Rows[0].Email).To(Equal("jane@example.com"))
+		Expect(report.Rows[1].Line).To(Equal(3))
+	})
+
+	It("should treat usernames and emails differing in case as duplicates", func() {
+		source := "name,email,username,password\n" +
+			"Jane Doe,jane@example.com,jane,secret123\n" +
+			"Jane Again,JANE@example.com,Jane,secret123\n"
+		userRepo.EXPECT().FindExisting(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, nil)
+
+		report, err := importService.Import(context.Background(), 1, strings.NewReader(source), interfaces.ImportOptions{
+			Format: interfaces.ImportFormatCSV,
+			DryRun: true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Failed).To(Equal(1))
+		Expect(report.Rows[1].Error).To(ContainSubstring("duplicates line 2"))
+	})
+
+	It("should skip every valid row when a transactional import has duplicates", func() {
+		source := "name,email,username,password\n" +
+			"Jane Doe,jane@example.com,jane,secret123\n" +
+			"Jane Again,jane@example.com,jane2,secret123\n" +
+			"Taken,taken@example.com,taken,secret123\n"
+		userRepo.EXPECT().FindExisting(gomock.Any(), 1, gomock.Any(), gomock.Any()).
+			Return([]interfaces.User{{Username: "taken", Email: "other@example.com"}}, nil)
+
+		report, err := importService.Import(context.Background(), 1, strings.NewReader(source), interfaces.ImportOptions{
+			Format: interfaces.ImportFormatCSV,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Failed).To(Equal(2))
+		Expect(report.Skipped).To(Equal(1))
+		Expect(report.Rows[1].Error).To(ContainSubstring("duplicates line 2"))
+		Expect(report.Rows[2].Error).To(ContainSubstring("already exists"))
+	})
+
+	It("should create the valid rows in best-effort mode", func() {
+		source := "name,email,username,password,role\n" +
+			"Jane Doe,jane@example.com,jane,secret123,admin\n" +
+			"Broken,not-an-email,broken,secret123,member\n"
+		userRepo.EXPECT().FindExisting(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, nil)
+		userRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user interfaces.User) (interfaces.User, error) {
+			Expect(user.Password).ToNot(Equal("secret123"))
+			user.ID = 7
+			return user, nil
+		})
+		organizationService.EXPECT().
+			UpdateMemberRole(gomock.Any(), gomock.Any(), interfaces.Membership{OrganizationID: 1, UserID: 7, Role: interfaces.RoleAdmin}).
+			DoAndReturn(func(ctx context.Context, _, membership interfaces.Membership) (interfaces.Membership, error) {
+				Expect(inUnitOfWork(ctx)).To(BeTrue())
+				return membership, nil
+			})
+
+		report, err := importService.Import(context.Background(), 1, strings.NewReader(source), interfaces.ImportOptions{
+			Format: interfaces.ImportFormatCSV,
+			Mode:   interfaces.ImportModeBestEffort,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Created).To(Equal(1))
+		Expect(report.Failed).To(Equal(1))
+		Expect(report.Rows[0].UserID).To(Equal(7))
+	})
+
+	It("should invite NDJSON rows without a password", func() {
+		source := `{"name":"Jane Doe","email":"jane@example.com","username":"jane","password":"secret123"}` + "\n" +
+			`{"email":"invitee@example.com"}` + "\n"
+		userRepo.EXPECT().FindExisting(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, nil)
+		userRepo.EXPECT().CreateMany(gomock.Any(), gomock.Len(1)).DoAndReturn(func(_ context.Context, users []interfaces.User) ([]interfaces.User, error) {
+			users[0].ID = 3
+			return users, nil
+		})
+		organizationService.EXPECT().GetMembership(gomock.Any(), 1, 9).Return(interfaces.Membership{OrganizationID: 1, UserID: 9, Role: interfaces.RoleAdmin}, nil)
+		invitationService.EXPECT().Invite(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, invitation interfaces.Invitation) (interfaces.Invitation, error) {
+				Expect(inUnitOfWork(ctx)).To(BeTrue())
+				Expect(invitation.Email).To(Equal("invitee@example.com"))
+				Expect(invitation.InvitedBy).To(HaveValue(Equal(9)))
+				return invitation, nil
+			})
+
+		report, err := importService.Import(context.Background(), 1, strings.NewReader(source), interfaces.ImportOptions{
+			Format:                interfaces.ImportFormatNDJSON,
+			InviteWithoutPassword: true,
+			InvitedBy:             9,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Created).To(Equal(1))
+		Expect(report.Invited).To(Equal(1))
+	})
+
+	It("should let only owners import owners", func() {
+		source := "name,email,username,password,role\n" +
+			"Jane Doe,jane@example.com,jane,secret123,owner\n" +
+			"John Doe,john@example.com,john,secret456,member\n"
+		organizationService.EXPECT().GetMembership(gomock.Any(), 1, 9).Return(interfaces.Membership{OrganizationID: 1, UserID: 9, Role: interfaces.RoleAdmin}, nil)
+		userRepo.EXPECT().FindExisting(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, nil)
+
+		report, err := importService.Import(context.Background(), 1, strings.NewReader(source), interfaces.ImportOptions{
+			Format:    interfaces.ImportFormatCSV,
+			InvitedBy: 9,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Failed).To(Equal(1))
+		Expect(report.Skipped).To(Equal(1))
+		Expect(report.Rows[0].Error).To(Equal(interfaces.ErrOwnerRequired.Error()))
+	})
+
+	It("should assign roles as the owner running the import", func() {
+		source := "name,email,username,password,role\n" +
+			"Jane Doe,jane@example.com,jane,secret123,owner\n"
+		owner := interfaces.Membership{OrganizationID: 1, UserID: 9, Role: interfaces.RoleOwner}
+		organizationService.EXPECT().GetMembership(gomock.Any(), 1, 9).Return(owner, nil)
+		userRepo.EXPECT().FindExisting(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, nil)
+		userRepo.EXPECT().CreateMany(gomock.Any(), gomock.Len(1)).DoAndReturn(func(_ context.Context, users []interfaces.User) ([]interfaces.User, error) {
+			users[0].ID = 3
+			return users, nil
+		})
+		organizationService.EXPECT().
+			UpdateMemberRole(gomock.Any(), owner, interfaces.Membership{OrganizationID: 1, UserID: 3, Role: interfaces.RoleOwner}).
+			Return(interfaces.Membership{}, nil)
+
+		report, err := importService.Import(context.Background(), 1, strings.NewReader(source), interfaces.ImportOptions{
+			Format:    interfaces.ImportFormatCSV,
+			InvitedBy: 9,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Created).To(Equal(1))
+	})
+
+	It("should roll a transactional import back when a role cannot be assigned", func() {
+		source := `{"name":"Jane Doe","email":"jane@example.com","username":"jane","password":"secret123","role":"admin"}` + "\n" +
+			`{"email":"invitee@example.com"}` + "\n"
+		userRepo.EXPECT().FindExisting(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, nil)
+		userRepo.EXPECT().CreateMany(gomock.Any(), gomock.Len(1)).DoAndReturn(func(_ context.Context, users []interfaces.User) ([]interfaces.User, error) {
+			users[0].ID = 3
+			return users, nil
+		})
+		organizationService.EXPECT().UpdateMemberRole(gomock.Any(), gomock.Any(), gomock.Any()).Return(interfaces.Membership{}, sql.ErrConnDone)
+
+		report, err := importService.Import(context.Background(), 1, strings.NewReader(source), interfaces.ImportOptions{
+			Format:                interfaces.ImportFormatNDJSON,
+			InviteWithoutPassword: true,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Created).To(Equal(0))
+		Expect(report.Invited).To(Equal(0))
+		Expect(report.Failed).To(Equal(2))
+		Expect(report.Rows[0].Error).To(ContainSubstring("import rolled back"))
+	})
+
+	It("should reject an unknown mode", func() {
+		_, err := importService.Import(context.Background(), 1, strings.NewReader(""), interfaces.ImportOptions{Mode: "eventually"})
+		Expect(err).To(MatchError(interfaces.ErrImportMode))
+	})
+})
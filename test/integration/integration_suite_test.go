@@ -5,8 +5,13 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/pkg/logger"
 )
 
+var _ = BeforeSuite(func() {
+	logger.InitLogger()
+})
+
 func TestIntegration(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Integration Suite")
@@ -0,0 +1,226 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	userdb "github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+var _ = Describe("InvitationService", func() {
+	var (
+		mockCtrl          *gomock.Controller
+		invitationRepo    *mocks.MockInvitationRepository
+		userService       *mocks.MockService
+		organizations     *mocks.MockOrganizationService
+		notifier          *mocks.MockNotifier
+		invitationService interfaces.InvitationService
+		deliveredToken    string
+		stored            interfaces.Invitation
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		invitationRepo = mocks.NewMockInvitationRepository(mockCtrl)
+		userService = mocks.NewMockService(mockCtrl)
+		organizations = mocks.NewMockOrganizationService(mockCtrl)
+		notifier = mocks.NewMockNotifier(mockCtrl)
+		invitationService = services.NewInvitationService(invitationRepo, passthroughTx(mockCtrl), userService, organizations, notifier)
+
+		invitationRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, invitation interfaces.Invitation) (interfaces.Invitation, error) {
+				invitation.ID = 5
+				stored = invitation
+				return invitation, nil
+			})
+		notifier.EXPECT().NotifyInvitation(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interfaces.Invitation, token string) error {
+				deliveredToken = token
+				return nil
+			}).AnyTimes()
+
+		_, err := invitationService.Invite(context.Background(), interfaces.Invitation{OrganizationID: 2, Email: "new@example.com", Role: "admin"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deliveredToken).ToNot(BeEmpty())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("should claim the invitation, create the invitee and grant the invited role in one unit of work", func() {
+		invitationRepo.EXPECT().GetByID(gomock.Any(), 2, 5).Return(stored, nil)
+		userService.EXPECT().HashPassword("secret").Return("hashed", nil)
+		claim := invitationRepo.EXPECT().Claim(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, invitation interfaces.Invitation) error {
+				Expect(inUnitOfWork(ctx)).To(BeTrue())
+				Expect(invitation.ID).To(Equal(5))
+				Expect(invitation.AcceptedAt).ToNot(BeNil())
+				return nil
+			})
+		create := userService.EXPECT().CreateUser(gomock.Any(), interfaces.User{
+			OrganizationID: 2,
+			Name:           "New User",
+			Email:          "new@example.com",
+			Username:       "newbie",
+			Password:       "hashed",
+		}).DoAndReturn(func(ctx context.Context, _ interfaces.User) (interfaces.User, error) {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			return interfaces.User{ID: 11, OrganizationID: 2, Username: "newbie"}, nil
+		}).After(claim)
+		organizations.EXPECT().
+			UpdateMemberRole(gomock.Any(), gomock.Any(), interfaces.Membership{OrganizationID: 2, UserID: 11, Role: interfaces.RoleAdmin}).
+			DoAndReturn(func(ctx context.Context, _, membership interfaces.Membership) (interfaces.Membership, error) {
+				Expect(inUnitOfWork(ctx)).To(BeTrue())
+				return membership, nil
+			}).After(create)
+
+		user, err := invitationService.Accept(context.Background(), interfaces.AcceptInvitationRequest{
+			Token:    deliveredToken,
+			Name:     "New User",
+			Username: "newbie",
+			Password: "secret",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(user.ID).To(Equal(11))
+	})
+
+	It("should not create a user for an invitation claimed concurrently", func() {
+		invitationRepo.EXPECT().GetByID(gomock.Any(), 2, 5).Return(stored, nil)
+		userService.EXPECT().HashPassword("secret").Return("hashed", nil)
+		invitationRepo.EXPECT().Claim(gomock.Any(), gomock.Any()).Return(interfaces.ErrInvitationNotPending)
+
+		_, err := invitationService.Accept(context.Background(), interfaces.AcceptInvitationRequest{
+			Token:    deliveredToken,
+			Username: "newbie",
+			Password: "secret",
+		})
+		Expect(err).To(MatchError(interfaces.ErrInvitationNotPending))
+	})
+
+	It("should let only owners invite owners", func() {
+		inviter := 7
+		organizations.EXPECT().GetMembership(gomock.Any(), 2, inviter).
+			Return(interfaces.Membership{OrganizationID: 2, UserID: inviter, Role: interfaces.RoleAdmin}, nil)
+
+		_, err := invitationService.Invite(context.Background(), interfaces.Invitation{
+			OrganizationID: 2, Email: "boss@example.com", Role: interfaces.RoleOwner, InvitedBy: &inviter,
+		})
+		Expect(err).To(MatchError(interfaces.ErrOwnerRequired))
+	})
+
+	It("should deliver an invitation issued in a unit of work once it commits", func() {
+		invitationRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(interfaces.Invitation{ID: 6, OrganizationID: 2}, nil)
+		deliveredToken = ""
+
+		work := interfaces.WithUnitOfWork(context.Background())
+		_, err := invitationService.Invite(work, interfaces.Invitation{OrganizationID: 2, Email: "later@example.com"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deliveredToken).To(BeEmpty())
+
+		interfaces.Committed(work)
+		Expect(deliveredToken).ToNot(BeEmpty())
+	})
+
+	It("should reject a revoked invitation", func() {
+		revoked := stored
+		revoked.Status = interfaces.InvitationRevoked
+		invitationRepo.EXPECT().GetByID(gomock.Any(), 2, 5).Return(revoked, nil)
+
+		_, err := invitationService.Accept(context.Background(), interfaces.AcceptInvitationRequest{
+			Token:    deliveredToken,
+			Username: "newbie",
+			Password: "secret",
+		})
+		Expect(err).To(MatchError(interfaces.ErrInvitationNotPending))
+	})
+
+	It("should reject a tampered token", func() {
+		_, err := invitationService.Accept(context.Background(), interfaces.AcceptInvitationRequest{
+			Token:    deliveredToken + "x",
+			Username: "newbie",
+			Password: "secret",
+		})
+		Expect(err).To(MatchError(interfaces.ErrInvitationToken))
+	})
+})
+
+var _ = Describe("InvitationRepository", func() {
+	var (
+		ctx         context.Context
+		dir         string
+		database    *sql.DB
+		invitations interfaces.InvitationRepository
+		users       interfaces.Repository
+		invitation  interfaces.Invitation
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		dir, err = os.MkdirTemp("", "invitations")
+		Expect(err).ToNot(HaveOccurred())
+		database, err = userdb.OpenSQLite(filepath.Join(dir, "invitations.db"))
+		Expect(err).ToNot(HaveOccurred())
+		migrations, err := userdb.EmbeddedMigrations("sqlite")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(userdb.NewMigrator(database, migrations).Up(ctx)).To(Succeed())
+
+		invitations = repository.NewInvitationRepository(database)
+		users = repository.NewUserRepository(database, nil, 0)
+		inviter, err := users.Create(ctx, interfaces.User{
+			OrganizationID: 1, Name: "Jane Doe", Email: "jane@example.com", Username: "jane", Password: "hash",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		invitation, err = invitations.Create(ctx, interfaces.Invitation{
+			OrganizationID: 1,
+			Email:          "new@example.com",
+			Role:           interfaces.RoleMember,
+			Status:         interfaces.InvitationPending,
+			InvitedBy:      &inviter.ID,
+			ExpiresAt:      time.Now().Add(time.Hour),
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		database.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should read invitations whose inviter was purged", func() {
+		_, err := users.Delete(ctx, 1, *invitation.InvitedBy, 0)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = users.Purge(ctx, time.Now().Add(time.Minute))
+		Expect(err).ToNot(HaveOccurred())
+
+		read, err := invitations.GetByID(context.Background(), 1, invitation.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(read.InvitedBy).To(BeNil())
+		pending, err := invitations.GetPending(context.Background(), 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pending).To(HaveLen(1))
+	})
+
+	It("should let only one accept claim the invitation", func() {
+		acceptedAt := time.Now()
+		invitation.AcceptedAt = &acceptedAt
+		Expect(invitations.Claim(ctx, invitation)).To(Succeed())
+		Expect(invitations.Claim(ctx, invitation)).To(MatchError(interfaces.ErrInvitationNotPending))
+
+		read, err := invitations.GetByID(context.Background(), 1, invitation.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(read.Status).To(Equal(interfaces.InvitationAccepted))
+		Expect(read.AcceptedAt).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,200 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/handler"
+	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+var _ = Describe("OrganizationHandler", func() {
+	var (
+		e                   *echo.Echo
+		rec                 *httptest.ResponseRecorder
+		organizationHandler *handler.OrganizationHandler
+		mockCtrl            *gomock.Controller
+		organizationService *mocks.MockOrganizationService
+		claims              *authentication.Claims
+	)
+
+	BeforeEach(func() {
+		e = echo.New()
+		rec = httptest.NewRecorder()
+		mockCtrl = gomock.NewController(GinkgoT())
+		organizationService = mocks.NewMockOrganizationService(mockCtrl)
+		organizationHandler = handler.NewOrganizationHandler(organizationService)
+		claims = &authentication.Claims{Username: "owner", UserID: 7, OrganizationID: 2}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("CreateOrganization", func() {
+		It("should create an organization owned by the caller", func() {
+			organization := interfaces.Organization{ID: 2, Name: "Acme", Slug: "acme"}
+			organizationService.EXPECT().
+				CreateOrganization(gomock.Any(), interfaces.Organization{Name: "Acme", Slug: "acme"}, 7).
+				Return(organization, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/organizations", strings.NewReader(`{"name":"Acme","slug":"acme"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			ctx := e.NewContext(req, rec)
+			ctx.Set(authentication.ClaimsContextKey, claims)
+
+			err := organizationHandler.CreateOrganization(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(rec.Body.String()).To(ContainSubstring(`"slug":"acme"`))
+		})
+	})
+
+	Describe("AddMember", func() {
+		It("should forbid members without an admin role", func() {
+			organizationService.EXPECT().GetMembership(gomock.Any(), 2, 7).
+				Return(interfaces.Membership{OrganizationID: 2, UserID: 7, Role: interfaces.RoleMember}, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/organizations/2/members", strings.NewReader(`{"user_id":9}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("2")
+			ctx.Set(authentication.ClaimsContextKey, claims)
+
+			err := organizationHandler.AddMember(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("should add a member when the caller is an owner", func() {
+			organizationService.EXPECT().GetMembership(gomock.Any(), 2, 7).
+				Return(interfaces.Membership{OrganizationID: 2, UserID: 7, Role: interfaces.RoleOwner}, nil)
+			organizationService.EXPECT().
+				AddMember(gomock.Any(), interfaces.Membership{OrganizationID: 2, UserID: 7, Role: interfaces.RoleOwner}, interfaces.Membership{OrganizationID: 2, UserID: 9, Role: interfaces.RoleAdmin}).
+				Return(interfaces.Membership{OrganizationID: 2, UserID: 9, Role: interfaces.RoleAdmin}, nil)
+
+			req := httptest.NewRequest(
+				http.MethodPost,
+				"/v1/organizations/2/members",
+				strings.NewReader(`{"user_id":9,"role":"admin"}`),
+			)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("2")
+			ctx.Set(authentication.ClaimsContextKey, claims)
+
+			err := organizationHandler.AddMember(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(rec.Body.String()).To(ContainSubstring(`"role":"admin"`))
+		})
+	})
+
+	Describe("UpdateMember", func() {
+		It("should forbid admins granting the owner role", func() {
+			admin := interfaces.Membership{OrganizationID: 2, UserID: 7, Role: interfaces.RoleAdmin}
+			organizationService.EXPECT().GetMembership(gomock.Any(), 2, 7).Return(admin, nil)
+			organizationService.EXPECT().
+				UpdateMemberRole(gomock.Any(), admin, interfaces.Membership{OrganizationID: 2, UserID: 7, Role: interfaces.RoleOwner}).
+				Return(interfaces.Membership{}, interfaces.ErrOwnerRequired)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/organizations/2/members/7", strings.NewReader(`{"role":"owner"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id", "user_id")
+			ctx.SetParamValues("2", "7")
+			ctx.Set(authentication.ClaimsContextKey, claims)
+
+			err := organizationHandler.UpdateMember(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("should refuse to demote the last owner", func() {
+			organizationService.EXPECT().GetMembership(gomock.Any(), 2, 7).
+				Return(interfaces.Membership{OrganizationID: 2, UserID: 7, Role: interfaces.RoleOwner}, nil)
+			organizationService.EXPECT().UpdateMemberRole(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(interfaces.Membership{}, interfaces.ErrLastOwner)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/organizations/2/members/7", strings.NewReader(`{"role":"member"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id", "user_id")
+			ctx.SetParamValues("2", "7")
+			ctx.Set(authentication.ClaimsContextKey, claims)
+
+			err := organizationHandler.UpdateMember(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+		})
+	})
+
+	Describe("tenant.Middleware", func() {
+		var resolved int
+
+		next := func(c echo.Context) error {
+			resolved = tenant.FromContext(c)
+			return c.NoContent(http.StatusOK)
+		}
+
+		BeforeEach(func() {
+			resolved = 0
+		})
+
+		It("should use the organization from the token", func() {
+			ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/v1/users", nil), rec)
+			ctx.Set(authentication.ClaimsContextKey, claims)
+
+			Expect(tenant.Middleware(organizationService)(next)(ctx)).To(Succeed())
+			Expect(resolved).To(Equal(2))
+		})
+
+		It("should fall back to the default organization for anonymous requests", func() {
+			ctx := e.NewContext(httptest.NewRequest(http.MethodPost, "/users/login", nil), rec)
+
+			Expect(tenant.Middleware(organizationService)(next)(ctx)).To(Succeed())
+			Expect(resolved).To(Equal(tenant.DefaultOrganizationID))
+		})
+
+		It("should reject switching to an organization the caller does not belong to", func() {
+			organizationService.EXPECT().GetMembership(gomock.Any(), 3, 7).Return(interfaces.Membership{}, sql.ErrNoRows)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
+			req.Header.Set(tenant.HeaderOrganizationID, "3")
+			ctx := e.NewContext(req, rec)
+			ctx.Set(authentication.ClaimsContextKey, claims)
+
+			Expect(tenant.Middleware(organizationService)(next)(ctx)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+			Expect(resolved).To(Equal(0))
+		})
+
+		It("should check the membership with the request's context", func() {
+			requestCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			organizationService.EXPECT().GetMembership(requestCtx, 3, 7).
+				Return(interfaces.Membership{OrganizationID: 3, UserID: 7, Role: interfaces.RoleMember}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users", nil).WithContext(requestCtx)
+			req.Header.Set(tenant.HeaderOrganizationID, "3")
+			ctx := e.NewContext(req, rec)
+			ctx.Set(authentication.ClaimsContextKey, claims)
+
+			Expect(tenant.Middleware(organizationService)(next)(ctx)).To(Succeed())
+			Expect(resolved).To(Equal(3))
+		})
+	})
+})
@@ -0,0 +1,121 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	userdb "github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository"
+	"github.com/redbonzai/user-management-api/internal/services"
+)
+
+var _ = Describe("OrganizationService owners", func() {
+	var (
+		ctx                 context.Context
+		dir                 string
+		database            *sql.DB
+		organizations       interfaces.OrganizationRepository
+		organizationService interfaces.OrganizationService
+		owner, admin, other interfaces.Membership
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		dir, err = os.MkdirTemp("", "organizations")
+		Expect(err).ToNot(HaveOccurred())
+		database, err = userdb.OpenSQLite(filepath.Join(dir, "organizations.db"))
+		Expect(err).ToNot(HaveOccurred())
+		migrations, err := userdb.EmbeddedMigrations("sqlite")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(userdb.NewMigrator(database, migrations).Up(ctx)).To(Succeed())
+
+		organizations = repository.NewOrganizationRepository(database)
+		organizationService = services.NewOrganizationService(organizations, repository.NewTxManager(database))
+
+		users := repository.NewUserRepository(database, nil, 0)
+		member := func(username, role string) interfaces.Membership {
+			user, err := users.Create(ctx, interfaces.User{
+				OrganizationID: 1, Name: username, Email: username + "@example.com", Username: username, Password: "hash",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			membership, err := organizations.UpdateMemberRole(ctx, interfaces.Membership{OrganizationID: 1, UserID: user.ID, Role: role})
+			Expect(err).ToNot(HaveOccurred())
+			return membership
+		}
+		owner = member("jane", interfaces.RoleOwner)
+		admin = member("ada", interfaces.RoleAdmin)
+		other = member("bob", interfaces.RoleMember)
+	})
+
+	AfterEach(func() {
+		database.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	role := func(userID int) string {
+		membership, err := organizations.GetMembership(context.Background(), 1, userID)
+		Expect(err).ToNot(HaveOccurred())
+		return membership.Role
+	}
+
+	It("should let only owners grant the owner role", func() {
+		_, err := organizationService.UpdateMemberRole(ctx, admin, interfaces.Membership{OrganizationID: 1, UserID: admin.UserID, Role: interfaces.RoleOwner})
+		Expect(err).To(MatchError(interfaces.ErrOwnerRequired))
+		_, err = organizationService.UpdateMemberRole(ctx, admin, interfaces.Membership{OrganizationID: 1, UserID: other.UserID, Role: interfaces.RoleOwner})
+		Expect(err).To(MatchError(interfaces.ErrOwnerRequired))
+		Expect(role(other.UserID)).To(Equal(interfaces.RoleMember))
+
+		promoted, err := organizationService.UpdateMemberRole(ctx, owner, interfaces.Membership{OrganizationID: 1, UserID: other.UserID, Role: interfaces.RoleOwner})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(promoted.Role).To(Equal(interfaces.RoleOwner))
+		Expect(role(other.UserID)).To(Equal(interfaces.RoleOwner))
+	})
+
+	It("should let only owners demote or remove an owner", func() {
+		_, err := organizationService.UpdateMemberRole(ctx, owner, interfaces.Membership{OrganizationID: 1, UserID: other.UserID, Role: interfaces.RoleOwner})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = organizationService.UpdateMemberRole(ctx, admin, interfaces.Membership{OrganizationID: 1, UserID: owner.UserID, Role: interfaces.RoleMember})
+		Expect(err).To(MatchError(interfaces.ErrOwnerRequired))
+		Expect(organizationService.RemoveMember(ctx, admin, 1, owner.UserID)).To(MatchError(interfaces.ErrOwnerRequired))
+		Expect(role(owner.UserID)).To(Equal(interfaces.RoleOwner))
+
+		Expect(organizationService.RemoveMember(ctx, owner, 1, other.UserID)).To(Succeed())
+		_, err = organizations.GetMembership(context.Background(), 1, other.UserID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should keep the last owner", func() {
+		_, err := organizationService.UpdateMemberRole(ctx, owner, interfaces.Membership{OrganizationID: 1, UserID: owner.UserID, Role: interfaces.RoleAdmin})
+		Expect(err).To(MatchError(interfaces.ErrLastOwner))
+		Expect(organizationService.RemoveMember(ctx, owner, 1, owner.UserID)).To(MatchError(interfaces.ErrLastOwner))
+		Expect(role(owner.UserID)).To(Equal(interfaces.RoleOwner))
+
+		_, err = organizationService.UpdateMemberRole(ctx, owner, interfaces.Membership{OrganizationID: 1, UserID: admin.UserID, Role: interfaces.RoleOwner})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = organizationService.UpdateMemberRole(ctx, owner, interfaces.Membership{OrganizationID: 1, UserID: owner.UserID, Role: interfaces.RoleAdmin})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(role(owner.UserID)).To(Equal(interfaces.RoleAdmin))
+	})
+
+	It("should let only owners add owners", func() {
+		Expect(organizationService.RemoveMember(ctx, admin, 1, other.UserID)).To(Succeed())
+		_, err := organizationService.AddMember(ctx, admin, interfaces.Membership{OrganizationID: 1, UserID: other.UserID, Role: interfaces.RoleOwner})
+		Expect(err).To(MatchError(interfaces.ErrOwnerRequired))
+
+		added, err := organizationService.AddMember(ctx, admin, interfaces.Membership{OrganizationID: 1, UserID: other.UserID})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(added.Role).To(Equal(interfaces.RoleMember))
+	})
+
+	It("should report a missing membership", func() {
+		_, err := organizationService.UpdateMemberRole(ctx, owner, interfaces.Membership{OrganizationID: 1, UserID: 999, Role: interfaces.RoleAdmin})
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+})
@@ -0,0 +1,328 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	userdb "github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/events"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository/memory"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+// recordingPublisher keeps the messages it was given and fails those of the users in failing
+type recordingPublisher struct {
+	mutex     sync.Mutex
+	published []interfaces.OutboxMessage
+	failing   map[int]bool
+}
+
+func (publisher *recordingPublisher) Publish(_ context.Context, message interfaces.OutboxMessage) error {
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+	if publisher.failing[message.UserID] {
+		return errors.New("broker unavailable")
+	}
+	publisher.published = append(publisher.published, message)
+	return nil
+}
+
+func (publisher *recordingPublisher) types(userID int) []string {
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+	var types []string
+	for _, message := range publisher.published {
+		if message.UserID == userID {
+			types = append(types, message.Type)
+		}
+	}
+	return types
+}
+
+var _ = Describe("Outbox", func() {
+	var (
+		ctx      context.Context
+		dir      string
+		database *sql.DB
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		dir, err = os.MkdirTemp("", "outbox")
+		Expect(err).ToNot(HaveOccurred())
+		database, err = userdb.OpenSQLite(filepath.Join(dir, "outbox.db"))
+		Expect(err).ToNot(HaveOccurred())
+		migrations, err := userdb.EmbeddedMigrations("sqlite")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(userdb.NewMigrator(database, migrations).Up(ctx)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		database.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	user := func(id int) interfaces.EventUser {
+		return interfaces.EventUser{ID: id, OrganizationID: 1, Username: "jane"}
+	}
+
+	Describe("user service", func() {
+		var (
+			outbox      interfaces.OutboxRepository
+			audit       interfaces.AuditService
+			userService interfaces.Service
+		)
+
+		BeforeEach(func() {
+			mockCtrl := gomock.NewController(GinkgoT())
+			attributes := mocks.NewMockAttributeService(mockCtrl)
+			attributes.EXPECT().ValidateAttributes(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			attributes.EXPECT().ClaimUniqueValues(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			outbox = repository.NewOutboxRepository(database)
+			audit = services.NewAuditService(repository.NewAuditRepository(database))
+			userService = services.NewService(
+				repository.NewUserRepository(database, nil, 0),
+				attributes,
+				repository.NewTxManager(database),
+				outbox,
+				audit,
+			)
+		})
+
+		It("should record the events of the changes it commits, without the password", func() {
+			created, err := userService.CreateUser(ctx, interfaces.User{
+				OrganizationID: 1, Name: "Jane Doe", Email: "jane@example.com", Username: "jane", Password: "hash",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			created.Name = "Jane Roe"
+			updated, err := userService.UpdateUser(ctx, created)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(userService.RecordLogin(ctx, updated)).To(Succeed())
+			_, err = userService.DeleteUser(ctx, 1, created.ID, updated.Version)
+			Expect(err).ToNot(HaveOccurred())
+
+			messages, err := outbox.Pending(ctx, 0, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(messages).To(HaveLen(4))
+			var types []string
+			for _, message := range messages {
+				types = append(types, message.Type)
+				Expect(message.UserID).To(Equal(created.ID))
+				Expect(message.OrganizationID).To(Equal(1))
+				Expect(string(message.Payload)).ToNot(ContainSubstring("hash"))
+			}
+			Expect(types).To(Equal([]string{
+				interfaces.EventUserCreated, interfaces.EventUserUpdated, interfaces.EventUserLoggedIn, interfaces.EventUserDeleted,
+			}))
+
+			var event interfaces.UserUpdated
+			Expect(json.Unmarshal(messages[1].Payload, &event)).To(Succeed())
+			Expect(event.User.Name).To(Equal("Jane Roe"))
+			Expect(event.User.Version).To(Equal(updated.Version))
+		})
+
+		It("should audit the changes it commits and none it rolls back", func() {
+			actorID := 3
+			ctx := interfaces.WithAuditSource(ctx, interfaces.AuditSource{ActorID: &actorID, ActorUsername: "ada", RequestID: "req-1"})
+			created, err := userService.CreateUser(ctx, interfaces.User{
+				OrganizationID: 1, Name: "Jane Doe", Email: "jane@example.com", Username: "jane", Password: "hash",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			stale := created
+			stale.Version++
+			_, err = userService.UpdateUser(ctx, stale)
+			Expect(err).To(HaveOccurred())
+			created.Name = "Jane Roe"
+			_, err = userService.UpdateUser(ctx, created)
+			Expect(err).ToNot(HaveOccurred())
+
+			events, err := audit.GetEvents(context.Background(), 1, interfaces.AuditFilter{TargetID: &created.ID})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Action).To(Equal(interfaces.AuditUserUpdate))
+			Expect(events[0].Changes).To(HaveKeyWithValue("name", interfaces.AuditChange{
+				Before: interfaces.AuditMaskedValue,
+				After:  interfaces.AuditMaskedValue,
+			}))
+			Expect(events[1].Action).To(Equal(interfaces.AuditUserCreate))
+			Expect(events[1].ActorID).To(HaveValue(Equal(actorID)))
+			Expect(events[1].RequestID).To(Equal("req-1"))
+			Expect(audit.Verify(context.Background())).To(HaveField("Valid", BeTrue()))
+		})
+
+		It("should not record the events of changes that fail", func() {
+			created, err := userService.CreateUser(ctx, interfaces.User{
+				OrganizationID: 1, Name: "Jane Doe", Email: "jane@example.com", Username: "jane", Password: "hash",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = userService.DeleteUser(ctx, 1, created.ID, created.Version+1)
+			Expect(err).To(MatchError(interfaces.ErrVersionConflict))
+
+			messages, err := outbox.Pending(ctx, 0, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(messages).To(HaveLen(1))
+			Expect(messages[0].Type).To(Equal(interfaces.EventUserCreated))
+		})
+	})
+
+	Describe("repository", func() {
+		It("should roll events back with the unit of work that appended them", func() {
+			outbox := repository.NewOutboxRepository(database)
+			failure := errors.New("rolled back")
+			err := repository.NewTxManager(database).WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+				Expect(outbox.Append(ctx, interfaces.UserCreated{User: user(1)})).To(Succeed())
+				return failure
+			})
+			Expect(err).To(MatchError(failure))
+			Expect(outbox.Append(ctx, interfaces.UserDeleted{User: user(2)})).To(Succeed())
+
+			messages, err := outbox.Pending(ctx, 0, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(messages).To(HaveLen(1))
+			Expect(messages[0].UserID).To(Equal(2))
+		})
+
+		It("should let one relay hold the lock at a time", func() {
+			outbox := repository.NewOutboxRepository(database)
+			unlock, locked, err := outbox.Lock(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(locked).To(BeTrue())
+			_, locked, err = outbox.Lock(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(locked).To(BeFalse())
+			unlock()
+			unlock, locked, err = outbox.Lock(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(locked).To(BeTrue())
+			unlock()
+		})
+	})
+
+	for name, open := range map[string]func() interfaces.OutboxRepository{
+		"SQLite": func() interfaces.OutboxRepository { return repository.NewOutboxRepository(database) },
+		"memory": memory.NewOutboxRepository,
+	} {
+		open := open
+
+		Describe("relay over the "+name+" outbox", func() {
+			var (
+				outbox    interfaces.OutboxRepository
+				publisher *recordingPublisher
+				relay     interfaces.OutboxRelay
+			)
+
+			BeforeEach(func() {
+				outbox = open()
+				publisher = &recordingPublisher{failing: map[int]bool{}}
+				relay = services.NewOutboxRelay(outbox, publisher)
+			})
+
+			It("should publish the pending events in order and remove them", func() {
+				for id := 1; id <= interfaces.OutboxRelayBatch+5; id++ {
+					Expect(outbox.Append(ctx, interfaces.UserCreated{User: user(id)})).To(Succeed())
+				}
+				Expect(outbox.Append(ctx, interfaces.UserLoggedIn{User: user(1)})).To(Succeed())
+
+				published, err := relay.Relay(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(published).To(Equal(interfaces.OutboxRelayBatch + 6))
+				Expect(publisher.types(1)).To(Equal([]string{interfaces.EventUserCreated, interfaces.EventUserLoggedIn}))
+				for i := 1; i < len(publisher.published); i++ {
+					Expect(publisher.published[i].ID).To(BeNumerically(">", publisher.published[i-1].ID))
+				}
+
+				pending, err := outbox.Pending(ctx, 0, 10)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pending).To(BeEmpty())
+			})
+
+			It("should hold back the later events of a user whose event failed", func() {
+				Expect(outbox.Append(ctx, interfaces.UserCreated{User: user(1)})).To(Succeed())
+				Expect(outbox.Append(ctx, interfaces.UserCreated{User: user(2)})).To(Succeed())
+				Expect(outbox.Append(ctx, interfaces.UserUpdated{User: user(1)})).To(Succeed())
+				Expect(outbox.Append(ctx, interfaces.UserUpdated{User: user(2)})).To(Succeed())
+
+				publisher.failing[1] = true
+				published, err := relay.Relay(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(published).To(Equal(2))
+				Expect(publisher.types(1)).To(BeEmpty())
+				Expect(publisher.types(2)).To(Equal([]string{interfaces.EventUserCreated, interfaces.EventUserUpdated}))
+
+				pending, err := outbox.Pending(ctx, 0, 10)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pending).To(HaveLen(2))
+				Expect(pending[0].Attempts).To(Equal(1))
+				Expect(pending[1].Attempts).To(Equal(0))
+
+				publisher.failing[1] = false
+				published, err = relay.Relay(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(published).To(Equal(2))
+				Expect(publisher.types(1)).To(Equal([]string{interfaces.EventUserCreated, interfaces.EventUserUpdated}))
+			})
+
+			It("should skip the run while another relay holds the lock", func() {
+				Expect(outbox.Append(ctx, interfaces.UserCreated{User: user(1)})).To(Succeed())
+				unlock, locked, err := outbox.Lock(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(locked).To(BeTrue())
+
+				published, err := relay.Relay(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(published).To(BeZero())
+				unlock()
+
+				published, err = relay.Relay(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(published).To(Equal(1))
+			})
+		})
+	}
+
+	Describe("webhook publisher", func() {
+		It("should post the message with its ID and type, and fail on other answers than 2xx", func() {
+			status := http.StatusNoContent
+			var received *http.Request
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				received = request
+				body, _ = io.ReadAll(request.Body)
+				writer.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			publisher := events.NewWebhookPublisher(server.URL)
+			message, err := interfaces.NewOutboxMessage(interfaces.UserLoggedIn{User: user(7)})
+			Expect(err).ToNot(HaveOccurred())
+			message.ID = 42
+
+			Expect(publisher.Publish(ctx, message)).To(Succeed())
+			Expect(received.Method).To(Equal(http.MethodPost))
+			Expect(received.Header.Get(events.HeaderEventID)).To(Equal("42"))
+			Expect(received.Header.Get(events.HeaderEventType)).To(Equal(interfaces.EventUserLoggedIn))
+			var delivered interfaces.OutboxMessage
+			Expect(json.Unmarshal(body, &delivered)).To(Succeed())
+			Expect(delivered.UserID).To(Equal(7))
+			Expect(string(delivered.Payload)).To(ContainSubstring(`"username":"jane"`))
+
+			status = http.StatusServiceUnavailable
+			Expect(publisher.Publish(ctx, message)).To(MatchError(ContainSubstring("503")))
+		})
+	})
+})
@@ -0,0 +1,48 @@
+package handler_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/patch"
+)
+
+var _ = Describe("Patch", func() {
+	document := []byte(`{"name":"Ann","status":"active","tags":["a","b"],"profile":{"city":"Oslo","zip":"0150"}}`)
+
+	It("should merge objects and remove members set to null", func() {
+		patched, err := patch.MergePatch(document, []byte(`{"status":null,"profile":{"zip":null,"country":"NO"}}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patched).To(MatchJSON(`{"name":"Ann","tags":["a","b"],"profile":{"city":"Oslo","country":"NO"}}`))
+	})
+
+	It("should apply JSON Patch operations in order", func() {
+		patched, err := patch.ApplyJSONPatch(document, []byte(`[
+			{"op":"test","path":"/name","value":"Ann"},
+			{"op":"replace","path":"/status","value":null},
+			{"op":"add","path":"/tags/1","value":"x"},
+			{"op":"remove","path":"/tags/0"},
+			{"op":"move","from":"/profile/city","path":"/city"},
+			{"op":"copy","from":"/tags","path":"/profile/tags"}
+		]`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(patched).To(MatchJSON(`{"name":"Ann","status":null,"tags":["x","b"],"city":"Oslo","profile":{"zip":"0150","tags":["x","b"]}}`))
+	})
+
+	It("should fail the whole patch when a test operation does not match", func() {
+		_, err := patch.ApplyJSONPatch(document, []byte(`[{"op":"remove","path":"/status"},{"op":"test","path":"/name","value":"Bob"}]`))
+		Expect(err).To(MatchError(patch.ErrTestFailed))
+	})
+
+	It("should reject malformed operations", func() {
+		for _, operations := range []string{
+			`[{"op":"replace","path":"/missing","value":1}]`,
+			`[{"op":"add","path":"/tags/01","value":"x"}]`,
+			`[{"op":"add","path":"/name"}]`,
+			`[{"op":"move","from":"/profile","path":"/profile/inner"}]`,
+			`[{"op":"frobnicate","path":"/name"}]`,
+		} {
+			_, err := patch.ApplyJSONPatch(document, []byte(operations))
+			Expect(err).To(MatchError(patch.ErrInvalidPatch), operations)
+		}
+	})
+})
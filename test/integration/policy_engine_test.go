@@ -0,0 +1,118 @@
+package handler_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/authz"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+)
+
+var _ = Describe("PolicyEngine", func() {
+	var (
+		engine  interfaces.PolicyEngine
+		manager map[string]interface{}
+		support map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		data, err := os.ReadFile(filepath.Join("..", "..", "config", "policies.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		policies, err := authz.ParsePolicies(data)
+		Expect(err).ToNot(HaveOccurred())
+		engine, err = authz.NewEngineFromPolicies(policies)
+		Expect(err).ToNot(HaveOccurred())
+
+		manager = map[string]interface{}{"id": 1, "role": "member", "title": "manager", "department": "sales"}
+		support = map[string]interface{}{"id": 2, "role": "member", "department": "support"}
+	})
+
+	It("should let managers edit users in their own department only", func() {
+		request := interfaces.AuthzRequest{
+			Subject:  manager,
+			Action:   "users:update",
+			Resource: map[string]interface{}{"id": 7, "department": "sales"},
+		}
+		decision := engine.Evaluate(request, false)
+		Expect(decision.Allowed).To(BeTrue())
+		Expect(decision.PolicyID).To(Equal("managers-edit-own-department"))
+
+		request.Resource["department"] = "finance"
+		Expect(engine.Evaluate(request, false).Allowed).To(BeFalse())
+	})
+
+	It("should match the department policies on stored custom attributes", func() {
+		subject := authz.UserAttributes(interfaces.User{
+			ID: 1, Attributes: map[string]interface{}{"title": "manager", "department": "sales", "id": 7},
+		})
+		Expect(subject).To(HaveKeyWithValue("id", 1.0))
+		subject["role"] = "member"
+		request := interfaces.AuthzRequest{
+			Subject:  subject,
+			Action:   "users:update",
+			Resource: authz.UserAttributes(interfaces.User{ID: 7, Attributes: map[string]interface{}{"department": "sales"}}),
+		}
+		decision := engine.Evaluate(request, false)
+		Expect(decision.Allowed).To(BeTrue())
+		Expect(decision.PolicyID).To(Equal("managers-edit-own-department"))
+
+		request.Resource = authz.UserAttributes(interfaces.User{ID: 7, Attributes: map[string]interface{}{"department": "finance"}})
+		Expect(engine.Evaluate(request, false).Allowed).To(BeFalse())
+	})
+
+	It("should hide denied fields without denying the read", func() {
+		decision := engine.Evaluate(interfaces.AuthzRequest{
+			Subject:  support,
+			Action:   "users:read",
+			Resource: authz.UserAttributes(interfaces.User{ID: 7, Email: "a@example.com", Password: "secret"}),
+		}, false)
+		Expect(decision.Allowed).To(BeTrue())
+		Expect(decision.DeniedFields).To(Equal([]string{"email"}))
+	})
+
+	It("should let a deny override an allow", func() {
+		decision := engine.Evaluate(interfaces.AuthzRequest{
+			Subject:  map[string]interface{}{"id": 3, "role": "admin"},
+			Action:   "users:delete",
+			Resource: map[string]interface{}{"id": 3},
+		}, false)
+		Expect(decision.Allowed).To(BeFalse())
+		Expect(decision.PolicyID).To(Equal("no-self-delete"))
+	})
+
+	It("should explain every policy when asked", func() {
+		decision := engine.Evaluate(interfaces.AuthzRequest{
+			Subject:  support,
+			Action:   "users:delete",
+			Resource: map[string]interface{}{"id": 7},
+		}, true)
+		Expect(decision.Allowed).To(BeFalse())
+		Expect(decision.Explanation).To(HaveLen(6))
+		Expect(decision.Explanation[0].Matched).To(BeFalse())
+		Expect(decision.Explanation[0].Reason).To(ContainSubstring("condition 1 failed"))
+	})
+
+	It("should reject unknown keys and operators", func() {
+		_, err := authz.ParsePolicies([]byte("policies:\n  - id: x\n    effect: allow\n    actions: [\"*\"]\n    condition: []\n"))
+		Expect(err).To(HaveOccurred())
+		_, err = authz.ParsePolicies([]byte(`{"policies":[{"id":"x","effect":"allow","actions":["*"],"conditions":[{"attr":"subject.id","op":"gt"}]}]}`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should keep the previous policies when a reload fails", func() {
+		dir, err := os.MkdirTemp("", "policies")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "policies.yaml")
+		Expect(os.WriteFile(path, []byte("policies:\n  - id: all\n    effect: allow\n    actions: [\"*\"]\n"), 0o600)).To(Succeed())
+		fileEngine, err := authz.NewEngine(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fileEngine.Evaluate(interfaces.AuthzRequest{Action: "users:read"}, false).Allowed).To(BeTrue())
+
+		Expect(os.WriteFile(path, []byte("policies: [{id: broken}]"), 0o600)).To(Succeed())
+		Expect(fileEngine.Reload()).ToNot(Succeed())
+		Expect(fileEngine.Evaluate(interfaces.AuthzRequest{Action: "users:read"}, false).Allowed).To(BeTrue())
+	})
+})
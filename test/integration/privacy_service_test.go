@@ -0,0 +1,247 @@
+package handler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	userdb "github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/exporter"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository"
+	repositorymocks "github.com/redbonzai/user-management-api/internal/interfaces/repository/mocks"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+var _ = Describe("PrivacyService", func() {
+	const gracePeriod = 14 * 24 * time.Hour
+
+	var (
+		mockCtrl       *gomock.Controller
+		privacyRepo    *mocks.MockPrivacyRepository
+		userRepo       *repositorymocks.MockRepository
+		outbox         *mocks.MockOutboxRepository
+		auditService   *mocks.MockAuditService
+		avatarService  *mocks.MockAvatarService
+		privacyService interfaces.PrivacyService
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		privacyRepo = mocks.NewMockPrivacyRepository(mockCtrl)
+		userRepo = repositorymocks.NewMockRepository(mockCtrl)
+		outbox = mocks.NewMockOutboxRepository(mockCtrl)
+		auditService = mocks.NewMockAuditService(mockCtrl)
+		avatarService = mocks.NewMockAvatarService(mockCtrl)
+		privacyService = services.NewPrivacyService(
+			privacyRepo,
+			userRepo,
+			passthroughTx(mockCtrl),
+			outbox,
+			auditService,
+			avatarService,
+			gracePeriod,
+		)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	erasedUser := func(organizationID, userID int) interfaces.User {
+		status := interfaces.UserStatusErased
+		return interfaces.User{
+			ID: userID, OrganizationID: organizationID, Username: fmt.Sprintf("erased-%d", userID), Status: &status,
+		}
+	}
+
+	complete := func(
+		ctx context.Context,
+		request interfaces.ErasureRequest,
+	) (interfaces.ErasureRequest, interfaces.User, error) {
+		Expect(inUnitOfWork(ctx)).To(BeTrue())
+		now := time.Now()
+		request.Status = interfaces.ErasureCompleted
+		request.CompletedAt = &now
+		return request, erasedUser(request.OrganizationID, request.UserID), nil
+	}
+
+	It("should erase a user immediately and audit the erasure without personal data", func() {
+		privacyRepo.EXPECT().ScheduleErasure(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, request interfaces.ErasureRequest) (interfaces.ErasureRequest, error) {
+				Expect(*request.RequestedBy).To(Equal(5))
+				Expect(request.ScheduledFor).To(BeTemporally("~", time.Now(), time.Second))
+				request.ID = 11
+				request.Status = interfaces.ErasurePending
+				return request, nil
+			})
+		avatarService.EXPECT().Delete(gomock.Any(), 1, 5).Return(nil)
+		privacyRepo.EXPECT().Erase(gomock.Any(), gomock.Any()).DoAndReturn(complete)
+		outbox.EXPECT().Append(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, events ...interfaces.UserEvent) error {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			Expect(events).To(ConsistOf(interfaces.UserErased{User: interfaces.NewEventUser(erasedUser(1, 5))}))
+			return nil
+		})
+		userRepo.EXPECT().Evict(gomock.Any(), erasedUser(1, 5))
+		auditService.EXPECT().Record(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event interfaces.AuditEvent) error {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			Expect(event.Action).To(Equal(interfaces.AuditUserErase))
+			Expect(*event.TargetID).To(Equal(5))
+			Expect(event.ActorUsername).To(BeEmpty())
+			Expect(event.Changes).To(Equal(map[string]interfaces.AuditChange{"erasure_request": {After: 11}}))
+			return nil
+		})
+
+		request, err := privacyService.EraseNow(context.Background(), 1, 5, "leaving")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(request.Status).To(Equal(interfaces.ErasureCompleted))
+	})
+
+	It("should fail the erasure when its audit event cannot be recorded", func() {
+		request := interfaces.ErasureRequest{ID: 11, OrganizationID: 1, UserID: 5, Status: interfaces.ErasurePending}
+		privacyRepo.EXPECT().GetDueErasureRequests(gomock.Any(), gomock.Any()).Return([]interfaces.ErasureRequest{request}, nil)
+		avatarService.EXPECT().Delete(gomock.Any(), 1, 5).Return(nil)
+		privacyRepo.EXPECT().Erase(gomock.Any(), request).DoAndReturn(complete)
+		outbox.EXPECT().Append(gomock.Any(), gomock.Any()).Return(nil)
+		auditService.EXPECT().Record(gomock.Any(), gomock.Any()).Return(errors.New("audit log unavailable"))
+
+		erased, err := privacyService.EraseDueUsers(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(erased).To(BeZero())
+	})
+
+	It("should schedule admin-requested erasures after the grace period", func() {
+		privacyRepo.EXPECT().ScheduleErasure(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, request interfaces.ErasureRequest) (interfaces.ErasureRequest, error) {
+				Expect(request.UserID).To(Equal(5))
+				Expect(*request.RequestedBy).To(Equal(2))
+				Expect(request.ScheduledFor).To(BeTemporally("~", time.Now().Add(gracePeriod), time.Second))
+				return request, nil
+			})
+
+		_, err := privacyService.ScheduleErasure(context.Background(), 1, 5, 2, "account closed")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should keep erasing due users after one fails", func() {
+		privacyRepo.EXPECT().GetDueErasureRequests(gomock.Any(), gomock.Any()).Return([]interfaces.ErasureRequest{
+			{ID: 1, OrganizationID: 1, UserID: 5},
+			{ID: 2, OrganizationID: 1, UserID: 6},
+		}, nil)
+		avatarService.EXPECT().Delete(gomock.Any(), 1, 5).Return(nil)
+		privacyRepo.EXPECT().Erase(gomock.Any(), interfaces.ErasureRequest{ID: 1, OrganizationID: 1, UserID: 5}).
+			Return(interfaces.ErasureRequest{}, interfaces.User{}, errors.New("lock timeout"))
+		avatarService.EXPECT().Delete(gomock.Any(), 1, 6).Return(sql.ErrNoRows)
+		privacyRepo.EXPECT().Erase(gomock.Any(), interfaces.ErasureRequest{ID: 2, OrganizationID: 1, UserID: 6}).
+			DoAndReturn(complete)
+		outbox.EXPECT().Append(gomock.Any(), gomock.Any()).Return(nil)
+		auditService.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil)
+		userRepo.EXPECT().Evict(gomock.Any(), gomock.Any())
+
+		erased, err := privacyService.EraseDueUsers(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(erased).To(Equal(1))
+	})
+
+	It("should bundle personal data into a zip of JSON documents without the password", func() {
+		status := "active"
+		data := interfaces.PersonalData{
+			GeneratedAt: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+			Profile:     interfaces.User{ID: 5, OrganizationID: 1, Username: "jane", Email: "jane@example.com", Status: &status},
+			Memberships: []interfaces.Membership{{OrganizationID: 1, UserID: 5, Role: interfaces.RoleMember}},
+			Tokens:      []interfaces.RevokedToken{{ID: 3}},
+		}
+
+		var output bytes.Buffer
+		Expect(exporter.WritePersonalDataArchive(&output, data)).To(Succeed())
+		Expect(exporter.ArchiveName(data)).To(Equal("personal-data-5-2026-10-19.zip"))
+
+		archive, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+		Expect(err).ToNot(HaveOccurred())
+		files := map[string][]byte{}
+		for _, file := range archive.File {
+			reader, err := file.Open()
+			Expect(err).ToNot(HaveOccurred())
+			files[file.Name], err = io.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(files).To(HaveKey("sessions.json"))
+		Expect(files).To(HaveKey("audit_events.json"))
+		Expect(files).To(HaveKey("invitations.json"))
+
+		var profile map[string]interface{}
+		Expect(json.Unmarshal(files["profile.json"], &profile)).To(Succeed())
+		Expect(profile).To(HaveKeyWithValue("username", "jane"))
+		Expect(profile).ToNot(HaveKey("password"))
+		Expect(string(files["tokens.json"])).To(ContainSubstring(`"id": 3`))
+	})
+})
+
+var _ = Describe("PrivacyRepository", func() {
+	var (
+		ctx         context.Context
+		dir         string
+		database    *sql.DB
+		privacyRepo interfaces.PrivacyRepository
+		user        interfaces.User
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		var err error
+		dir, err = os.MkdirTemp("", "privacy")
+		Expect(err).ToNot(HaveOccurred())
+		database, err = userdb.OpenSQLite(filepath.Join(dir, "privacy.db"))
+		Expect(err).ToNot(HaveOccurred())
+		migrations, err := userdb.EmbeddedMigrations("sqlite")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(userdb.NewMigrator(database, migrations).Up(ctx)).To(Succeed())
+
+		privacyRepo = repository.NewPrivacyRepository(database)
+		user, err = repository.NewUserRepository(database, nil, 0).Create(ctx, interfaces.User{
+			OrganizationID: 1, Name: "Jane Doe", Email: "jane@example.com", Username: "jane", Password: "hash",
+			Attributes: map[string]interface{}{"phone": "+44 20 7946 0000", "employee_id": "E42"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		database.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should export the user's attributes and erase them", func() {
+		data, err := privacyRepo.GetPersonalData(context.Background(), 1, user.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data.Profile.Attributes).To(HaveKeyWithValue("phone", "+44 20 7946 0000"))
+
+		request, err := privacyRepo.ScheduleErasure(context.Background(), interfaces.ErasureRequest{
+			OrganizationID: 1, UserID: user.ID, ScheduledFor: time.Now(),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		_, erased, err := privacyRepo.Erase(ctx, request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(erased.Username).To(Equal(fmt.Sprintf("erased-%d", user.ID)))
+		Expect(erased.Version).To(Equal(user.Version + 1))
+		Expect(erased.Status).To(HaveValue(Equal(interfaces.UserStatusErased)))
+
+		var attributes, name string
+		Expect(database.QueryRow("SELECT attributes, name FROM users WHERE id = $1", user.ID).Scan(&attributes, &name)).To(Succeed())
+		Expect(attributes).To(Equal("{}"))
+		Expect(name).To(BeEmpty())
+		_, err = privacyRepo.GetPersonalData(context.Background(), 1, user.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+})
@@ -0,0 +1,150 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	userdb "github.com/redbonzai/user-management-api/internal/db"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository"
+	"github.com/redbonzai/user-management-api/internal/middleware"
+)
+
+var _ = Describe("Read replicas", func() {
+	var (
+		ctx       context.Context
+		dir       string
+		databases map[string]*sql.DB
+	)
+
+	// Every database is a migrated SQLite file of its own, so a spec can tell where a read went
+	open := func(name string) *sql.DB {
+		database, err := userdb.OpenSQLite(filepath.Join(dir, name+".db"))
+		Expect(err).ToNot(HaveOccurred())
+		migrations, err := userdb.EmbeddedMigrations("sqlite")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(userdb.NewMigrator(database, migrations).Up(ctx)).To(Succeed())
+		databases[name] = database
+		return database
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		databases = map[string]*sql.DB{}
+		var err error
+		dir, err = os.MkdirTemp("", "replicas")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		for _, database := range databases {
+			database.Close()
+		}
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Describe("ReplicaSet", func() {
+		It("should hand out the healthy replicas in turn and fall back to the primary", func() {
+			primary, first, second := open("primary"), open("first"), open("second")
+			set := userdb.NewReplicaSet(
+				"primary", primary,
+				userdb.Replica{Name: "first", DB: first},
+				userdb.Replica{Name: "second", DB: second},
+			)
+
+			readers := []*sql.DB{set.Reader(), set.Reader(), set.Reader(), set.Reader()}
+			Expect(readers).To(ConsistOf(first, second, first, second))
+
+			Expect(second.Close()).To(Succeed())
+			set.Check(ctx)
+			Expect(set.Reader()).To(BeIdenticalTo(first))
+			Expect(set.Reader()).To(BeIdenticalTo(first))
+
+			Expect(first.Close()).To(Succeed())
+			set.Check(ctx)
+			Expect(set.Reader()).To(BeIdenticalTo(primary))
+
+			stats := set.Stats()
+			Expect(stats).To(HaveLen(3))
+			Expect(stats[0].Name).To(Equal("primary"))
+			Expect(stats[0].Role).To(Equal("primary"))
+			Expect(stats[0].Healthy).To(BeTrue())
+			Expect(stats[1].Healthy).To(BeFalse())
+			Expect(stats[2].Healthy).To(BeFalse())
+		})
+
+		It("should read from the primary without replicas", func() {
+			primary := open("primary")
+			Expect(userdb.NewReplicaSet("primary", primary).Reader()).To(BeIdenticalTo(primary))
+		})
+	})
+
+	Describe("user repository", func() {
+		var (
+			primary, replica *sql.DB
+			repo             interfaces.Repository
+			user             interfaces.User
+		)
+
+		BeforeEach(func() {
+			primary, replica = open("primary"), open("replica")
+			replicas := userdb.NewReplicaSet("primary", primary, userdb.Replica{Name: "replica", DB: replica})
+			repo = repository.NewUserRepository(primary, replicas, 5*time.Second)
+
+			// The replica has not caught up with the user yet
+			var err error
+			user, err = repo.Create(ctx, interfaces.User{
+				OrganizationID: 1, Name: "Jane Doe", Email: "jane@example.com", Username: "jane", Password: "hash",
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should read users by ID and list them from the replica", func() {
+			_, err := repo.GetByID(ctx, 1, user.ID)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+			users, err := repo.GetAll(ctx, 1, interfaces.UserFilter{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(users).To(BeEmpty())
+		})
+
+		It("should read from the primary when asked to, in a unit of work and by username", func() {
+			_, err := repo.GetByID(interfaces.WithPrimary(ctx), 1, user.ID)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = repository.NewTxManager(primary).WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+				_, err := repo.GetByID(ctx, 1, user.ID)
+				return err
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = repo.GetByUsername(ctx, 1, "jane")
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("PrimaryReads", func() {
+		It("should send the reads of requests that change something to the primary", func() {
+			router := echo.New()
+			router.Use(middleware.PrimaryReads)
+			handler := func(context echo.Context) error {
+				return context.JSON(http.StatusOK, interfaces.UsesPrimary(context.Request().Context()))
+			}
+			router.GET("/users", handler)
+			router.PATCH("/users", handler)
+
+			for method, primary := range map[string]string{http.MethodGet: "false\n", http.MethodPatch: "true\n"} {
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, httptest.NewRequest(method, "/users", nil))
+				Expect(recorder.Body.String()).To(Equal(primary), method)
+			}
+		})
+	})
+})
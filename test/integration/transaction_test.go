@@ -0,0 +1,229 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	repositorymocks "github.com/redbonzai/user-management-api/internal/interfaces/repository/mocks"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+type unitOfWorkKey struct{}
+
+// passthroughTx runs every unit of work directly, marking its context so specs can tell which
+// repository calls were made inside one
+func passthroughTx(mockCtrl *gomock.Controller) *mocks.MockTxManager {
+	tx := mocks.NewMockTxManager(mockCtrl)
+	tx.EXPECT().WithinTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ interfaces.TxOptions, fn func(ctx context.Context) error) error {
+			return fn(context.WithValue(ctx, unitOfWorkKey{}, true))
+		},
+	).AnyTimes()
+	return tx
+}
+
+func inUnitOfWork(ctx context.Context) bool {
+	inside, _ := ctx.Value(unitOfWorkKey{}).(bool)
+	return inside
+}
+
+var _ = Describe("UserService transactions", func() {
+	var (
+		mockCtrl    *gomock.Controller
+		userRepo    *repositorymocks.MockRepository
+		attributes  *mocks.MockAttributeService
+		outbox      *mocks.MockOutboxRepository
+		audit       *mocks.MockAuditService
+		userService interfaces.Service
+	)
+
+	// expectEvents expects the events of the given types to be appended, one per call, inside a
+	// unit of work
+	expectEvents := func(types ...string) {
+		for _, eventType := range types {
+			outbox.EXPECT().Append(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, events ...interfaces.UserEvent) error {
+				Expect(inUnitOfWork(ctx)).To(BeTrue())
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].EventType()).To(Equal(eventType))
+				return nil
+			})
+		}
+	}
+
+	// expectAudits expects audit events of the given actions to be recorded, one per call, inside
+	// a unit of work
+	expectAudits := func(actions ...string) {
+		for _, action := range actions {
+			audit.EXPECT().Record(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event interfaces.AuditEvent) error {
+				Expect(inUnitOfWork(ctx)).To(BeTrue())
+				Expect(event.Action).To(Equal(action))
+				return nil
+			})
+		}
+	}
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		userRepo = repositorymocks.NewMockRepository(mockCtrl)
+		attributes = mocks.NewMockAttributeService(mockCtrl)
+		attributes.EXPECT().ClaimUniqueValues(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		outbox = mocks.NewMockOutboxRepository(mockCtrl)
+		audit = mocks.NewMockAuditService(mockCtrl)
+		userService = services.NewService(userRepo, attributes, passthroughTx(mockCtrl), outbox, audit)
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("should read and change the status in one unit of work", func() {
+		userRepo.EXPECT().GetByID(gomock.Any(), 1, 7).DoAndReturn(func(ctx context.Context, _, _ int) (interfaces.User, error) {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			return interfaces.User{ID: 7}, nil
+		})
+		userRepo.EXPECT().ChangeStatus(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, transition interfaces.StatusTransition) (interfaces.User, error) {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			return interfaces.User{ID: 7, Status: &transition.ToStatus}, nil
+		})
+		expectEvents(interfaces.EventUserUpdated)
+		expectAudits(interfaces.AuditUserStatusChange)
+
+		_, err := userService.ChangeUserStatus(context.Background(), 1, 7, interfaces.UserStatusLocked, "", 0)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should update and delete users inside a unit of work", func() {
+		attributes.EXPECT().ValidateAttributes(gomock.Any(), gomock.Any()).Return(nil)
+		userRepo.EXPECT().GetByID(gomock.Any(), 1, 7).DoAndReturn(func(ctx context.Context, _, id int) (interfaces.User, error) {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			return interfaces.User{ID: id, OrganizationID: 1}, nil
+		})
+		userRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, user interfaces.User) (interfaces.User, error) {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			return user, nil
+		})
+		userRepo.EXPECT().Delete(gomock.Any(), 1, 7, 2).DoAndReturn(func(ctx context.Context, _, id, _ int) (interfaces.User, error) {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			return interfaces.User{ID: id}, nil
+		})
+		expectEvents(interfaces.EventUserUpdated, interfaces.EventUserDeleted)
+		expectAudits(interfaces.AuditUserUpdate, interfaces.AuditUserDelete)
+
+		_, err := userService.UpdateUser(context.Background(), interfaces.User{ID: 7, OrganizationID: 1})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = userService.DeleteUser(context.Background(), 1, 7, 2)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should create a user and record its event in one unit of work", func() {
+		attributes.EXPECT().ValidateAttributes(gomock.Any(), gomock.Any()).Return(nil)
+		userRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, user interfaces.User) (interfaces.User, error) {
+			Expect(inUnitOfWork(ctx)).To(BeTrue())
+			user.ID = 7
+			return user, nil
+		})
+		expectEvents(interfaces.EventUserCreated)
+		expectAudits(interfaces.AuditUserCreate)
+
+		_, err := userService.CreateUser(context.Background(), interfaces.User{OrganizationID: 1, Username: "jane"})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should attribute the audit event to the source of the change", func() {
+		actorID := 3
+		source := interfaces.AuditSource{ActorID: &actorID, ActorUsername: "ada", IP: "10.0.0.1", RequestID: "req-1"}
+		userRepo.EXPECT().GetByID(gomock.Any(), 1, 7).Return(interfaces.User{ID: 7, OrganizationID: 1}, nil)
+		userRepo.EXPECT().ChangeStatus(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, transition interfaces.StatusTransition) (interfaces.User, error) {
+			return interfaces.User{ID: 7, OrganizationID: 1, Status: &transition.ToStatus}, nil
+		})
+		expectEvents(interfaces.EventUserUpdated)
+		audit.EXPECT().Record(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, event interfaces.AuditEvent) error {
+			Expect(event.OrganizationID).To(Equal(1))
+			Expect(event.ActorID).To(HaveValue(Equal(3)))
+			Expect(event.ActorUsername).To(Equal("ada"))
+			Expect(event.IP).To(Equal("10.0.0.1"))
+			Expect(event.RequestID).To(Equal("req-1"))
+			Expect(event.TargetID).To(HaveValue(Equal(7)))
+			Expect(event.Changes["status"].After).To(Equal(interfaces.UserStatusSuspended))
+			Expect(event.Changes["reason"].After).To(Equal("chargeback"))
+			return nil
+		})
+
+		ctx := interfaces.WithAuditSource(context.Background(), source)
+		_, err := userService.ChangeUserStatus(ctx, 1, 7, interfaces.UserStatusSuspended, "chargeback", actorID)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should attribute a change without an actor to the user itself", func() {
+		attributes.EXPECT().ValidateAttributes(gomock.Any(), gomock.Any()).Return(nil)
+		userRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user interfaces.User) (interfaces.User, error) {
+			user.ID = 7
+			return user, nil
+		})
+		expectEvents(interfaces.EventUserCreated)
+		audit.EXPECT().Record(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, event interfaces.AuditEvent) error {
+			Expect(event.ActorID).To(HaveValue(Equal(7)))
+			Expect(event.ActorUsername).To(Equal("jane"))
+			Expect(event.Changes).To(HaveKey("username"))
+			return nil
+		})
+
+		_, err := userService.CreateUser(context.Background(), interfaces.User{OrganizationID: 1, Username: "jane"})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should fail the change when its audit event cannot be recorded", func() {
+		failure := errors.New("audit log unavailable")
+		userRepo.EXPECT().Restore(gomock.Any(), 1, 7).Return(interfaces.User{ID: 7}, nil)
+		expectEvents(interfaces.EventUserUpdated)
+		audit.EXPECT().Record(gomock.Any(), gomock.Any()).Return(failure)
+
+		user, err := userService.RestoreUser(context.Background(), 1, 7)
+		Expect(err).To(MatchError(failure))
+		Expect(user).To(Equal(interfaces.User{}))
+	})
+
+	It("should fail the change when its event cannot be recorded", func() {
+		failure := errors.New("outbox unavailable")
+		userRepo.EXPECT().Delete(gomock.Any(), 1, 7, 2).Return(interfaces.User{ID: 7}, nil)
+		outbox.EXPECT().Append(gomock.Any(), gomock.Any()).Return(failure)
+
+		_, err := userService.DeleteUser(context.Background(), 1, 7, 2)
+		Expect(err).To(MatchError(failure))
+	})
+
+	It("should read the status history from a read-only snapshot", func() {
+		tx := mocks.NewMockTxManager(mockCtrl)
+		userService = services.NewService(userRepo, attributes, tx, outbox, audit)
+		tx.EXPECT().WithinTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, options interfaces.TxOptions, fn func(ctx context.Context) error) error {
+				Expect(options.Isolation).To(Equal(sql.LevelRepeatableRead))
+				Expect(options.ReadOnly).To(BeTrue())
+				return fn(ctx)
+			},
+		)
+		userRepo.EXPECT().GetByID(gomock.Any(), 1, 7).Return(interfaces.User{ID: 7}, nil)
+		userRepo.EXPECT().GetStatusHistory(gomock.Any(), 1, 7).Return([]interfaces.StatusTransition{{UserID: 7}}, nil)
+
+		history, err := userService.GetStatusHistory(context.Background(), 1, 7)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(history).To(HaveLen(1))
+	})
+
+	It("should return the error that rolled the unit of work back", func() {
+		tx := mocks.NewMockTxManager(mockCtrl)
+		userService = services.NewService(userRepo, attributes, tx, outbox, audit)
+		failure := errors.New("could not serialize access")
+		tx.EXPECT().WithinTx(gomock.Any(), gomock.Any(), gomock.Any()).Return(failure)
+
+		user, err := userService.RestoreUser(context.Background(), 1, 7)
+		Expect(err).To(MatchError(failure))
+		Expect(user).To(Equal(interfaces.User{}))
+	})
+})
@@ -0,0 +1,447 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository/cache"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository/memory"
+)
+
+// countingRepository counts the lookups that reach the repository it wraps
+type countingRepository struct {
+	interfaces.Repository
+	byID, byUsername atomic.Int32
+	delay            time.Duration
+}
+
+func (repository *countingRepository) GetByID(ctx context.Context, organizationID, id int) (interfaces.User, error) {
+	repository.byID.Add(1)
+	time.Sleep(repository.delay)
+	return repository.Repository.GetByID(ctx, organizationID, id)
+}
+
+func (repository *countingRepository) GetByUsername(ctx context.Context, organizationID int, username string) (interfaces.User, error) {
+	repository.byUsername.Add(1)
+	time.Sleep(repository.delay)
+	return repository.Repository.GetByUsername(ctx, organizationID, username)
+}
+
+var _ = describeUserCache("memory", func() (cache.Backend, func()) {
+	return cache.NewLRU(100), func() {}
+})
+
+var _ = describeUserCache("redis", func() (cache.Backend, func()) {
+	server := startRedisStandIn()
+	backend, err := cache.NewRedis("redis://:secret@" + server.address + "/2")
+	Expect(err).ToNot(HaveOccurred())
+	return backend, func() {
+		Expect(backend.Close()).To(Succeed())
+		server.close()
+	}
+})
+
+// describeUserCache specifies the caching decorator on top of a backend
+func describeUserCache(name string, newBackend func() (cache.Backend, func())) bool {
+	return Describe("User cache: "+name, func() {
+		var (
+			ctx     context.Context
+			next    *countingRepository
+			backend cache.Backend
+			repo    interfaces.Repository
+			user    interfaces.User
+			cleanup func()
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			next = &countingRepository{Repository: memory.NewUserRepository()}
+			backend, cleanup = newBackend()
+			repo = cache.NewUserRepository(next, backend, time.Minute)
+
+			var err error
+			user, err = repo.Create(ctx, interfaces.User{
+				OrganizationID: 1, Name: "Jane Doe", Email: "jane@example.com", Username: "Jane", Password: "hash",
+				Attributes: map[string]interface{}{"department": "sales"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			// Cached users come without their password hash
+			user.Password = ""
+		})
+
+		AfterEach(func() {
+			cleanup()
+		})
+
+		It("should read a user once by ID and by username", func() {
+			for range 3 {
+				cached, err := repo.GetByID(ctx, 1, user.ID)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cached).To(Equal(user))
+			}
+			Expect(next.byID.Load()).To(Equal(int32(1)))
+
+			for _, username := range []string{"Jane", "jane", "ＪＡＮＥ"} {
+				cached, err := repo.GetByUsername(ctx, 1, username)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cached).To(Equal(user))
+			}
+			Expect(next.byUsername.Load()).To(Equal(int32(1)))
+			Expect(next.byID.Load()).To(Equal(int32(1)))
+		})
+
+		It("should give every caller a copy of its own", func() {
+			cached, err := repo.GetByID(ctx, 1, user.ID)
+			Expect(err).ToNot(HaveOccurred())
+			cached.Attributes["department"] = "support"
+
+			cached, err = repo.GetByID(ctx, 1, user.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.Attributes).To(HaveKeyWithValue("department", "sales"))
+		})
+
+		It("should drop a user's entries when it is updated, renamed or deleted", func() {
+			_, err := repo.GetByUsername(ctx, 1, "jane")
+			Expect(err).ToNot(HaveOccurred())
+
+			renamed := user
+			renamed.Username, renamed.Name = "janet", "Janet Doe"
+			renamed, err = repo.Update(ctx, renamed)
+			Expect(err).ToNot(HaveOccurred())
+
+			cached, err := repo.GetByID(ctx, 1, user.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.Name).To(Equal("Janet Doe"))
+			_, err = repo.GetByUsername(ctx, 1, "jane")
+			Expect(err).To(MatchError(sql.ErrNoRows))
+			cached, err = repo.GetByUsername(ctx, 1, "janet")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.Version).To(Equal(renamed.Version))
+
+			_, err = repo.Delete(ctx, 1, user.ID, renamed.Version)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = repo.GetByID(ctx, 1, user.ID)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+			_, err = repo.GetByUsername(ctx, 1, "janet")
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+
+		It("should keep password hashes out of the cache and give them to latest-state lookups", func() {
+			_, err := repo.GetByUsername(ctx, 1, "jane")
+			Expect(err).ToNot(HaveOccurred())
+			encoded, ok, err := backend.Get(ctx, fmt.Sprintf("user:1:id:%d", user.ID))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(string(encoded)).ToNot(ContainSubstring("hash"))
+
+			primary := interfaces.WithPrimary(ctx)
+			found, err := repo.GetByUsername(primary, 1, "jane")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found.Password).To(Equal("hash"))
+			found, err = repo.GetByID(primary, 1, user.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found.Password).To(Equal("hash"))
+			Expect(next.byUsername.Load()).To(Equal(int32(2)))
+			Expect(next.byID.Load()).To(Equal(int32(1)))
+		})
+
+		It("should drop a user's entries only once the unit of work committed", func() {
+			_, err := repo.GetByID(ctx, 1, user.ID)
+			Expect(err).ToNot(HaveOccurred())
+			err = memory.NewTxManager().WithinTx(ctx, interfaces.TxOptions{}, func(work context.Context) error {
+				changed := user
+				changed.Name = "Jane Roe"
+				if _, err := repo.Update(work, changed); err != nil {
+					return err
+				}
+				// A lookup outside the work still hits the entry the commit will drop
+				cached, err := repo.GetByID(ctx, 1, user.ID)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cached.Name).To(Equal("Jane Doe"))
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			cached, err := repo.GetByID(ctx, 1, user.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.Name).To(Equal("Jane Roe"))
+		})
+
+		It("should evict users changed without it once the unit of work committed", func() {
+			_, err := repo.GetByID(ctx, 1, user.ID)
+			Expect(err).ToNot(HaveOccurred())
+			err = memory.NewTxManager().WithinTx(ctx, interfaces.TxOptions{}, func(work context.Context) error {
+				_, err := next.Delete(work, 1, user.ID, user.Version)
+				repo.Evict(work, user)
+				return err
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = repo.GetByID(ctx, 1, user.ID)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+
+		It("should drop the entries of purged users", func() {
+			_, err := repo.GetByUsername(ctx, 1, "jane")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = next.Delete(ctx, 1, user.ID, user.Version)
+			Expect(err).ToNot(HaveOccurred())
+
+			purged, err := repo.Purge(ctx, time.Now().Add(time.Minute))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(purged).To(HaveLen(1))
+			_, err = repo.GetByID(ctx, 1, user.ID)
+			Expect(err).To(MatchError(sql.ErrNoRows))
+			_, err = repo.GetByUsername(ctx, 1, "jane")
+			Expect(err).To(MatchError(sql.ErrNoRows))
+		})
+
+		It("should serve a username to the user created with it after a deletion", func() {
+			_, err := repo.GetByUsername(ctx, 1, "jane")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = repo.Delete(ctx, 1, user.ID, user.Version)
+			Expect(err).ToNot(HaveOccurred())
+
+			created, err := repo.Create(ctx, interfaces.User{
+				OrganizationID: 1, Name: "Jane Roe", Email: "roe@example.com", Username: "jane", Password: "hash",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			cached, err := repo.GetByUsername(ctx, 1, "Jane")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.ID).To(Equal(created.ID))
+		})
+
+		It("should bypass the cache in a unit of work", func() {
+			_, err := repo.GetByID(ctx, 1, user.ID)
+			Expect(err).ToNot(HaveOccurred())
+			err = memory.NewTxManager().WithinTx(ctx, interfaces.TxOptions{}, func(ctx context.Context) error {
+				_, err := repo.GetByID(ctx, 1, user.ID)
+				return err
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(next.byID.Load()).To(Equal(int32(2)))
+		})
+
+		It("should share one lookup among concurrent misses", func() {
+			next.delay = 50 * time.Millisecond
+			var wg sync.WaitGroup
+			for range 10 {
+				wg.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wg.Done()
+					cached, err := repo.GetByID(ctx, 1, user.ID)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(cached.ID).To(Equal(user.ID))
+				}()
+			}
+			wg.Wait()
+			Expect(next.byID.Load()).To(Equal(int32(1)))
+		})
+
+		It("should stop waiting for a shared lookup when the caller's context ends", func() {
+			next.delay = 200 * time.Millisecond
+			timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+			defer cancel()
+			_, err := repo.GetByID(timeout, 1, user.ID)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+
+			// The lookup ran on and was cached
+			Eventually(func() error {
+				_, err := repo.GetByID(ctx, 1, user.ID)
+				return err
+			}).Should(Succeed())
+			Expect(next.byID.Load()).To(Equal(int32(1)))
+		})
+	})
+}
+
+var _ = Describe("User cache", func() {
+	It("should expire entries after their time to live", func() {
+		ctx := context.Background()
+		next := &countingRepository{Repository: memory.NewUserRepository()}
+		repo := cache.NewUserRepository(next, cache.NewLRU(10), 20*time.Millisecond)
+		user, err := repo.Create(ctx, interfaces.User{OrganizationID: 1, Name: "Jane Doe", Email: "jane@example.com", Username: "jane", Password: "hash"})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = repo.GetByID(ctx, 1, user.ID)
+		Expect(err).ToNot(HaveOccurred())
+		time.Sleep(30 * time.Millisecond)
+		_, err = repo.GetByID(ctx, 1, user.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(next.byID.Load()).To(Equal(int32(2)))
+	})
+
+	It("should read through when the backend cannot be reached", func() {
+		ctx := context.Background()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		address := listener.Addr().String()
+		Expect(listener.Close()).To(Succeed())
+		backend, err := cache.NewRedis("redis://" + address)
+		Expect(err).ToNot(HaveOccurred())
+
+		repo := cache.NewUserRepository(memory.NewUserRepository(), backend, time.Minute)
+		user, err := repo.Create(ctx, interfaces.User{OrganizationID: 1, Name: "Jane Doe", Email: "jane@example.com", Username: "jane", Password: "hash"})
+		Expect(err).ToNot(HaveOccurred())
+		cached, err := repo.GetByUsername(ctx, 1, "jane")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cached.ID).To(Equal(user.ID))
+	})
+
+	It("should evict the least recently used entries beyond its size", func() {
+		ctx := context.Background()
+		lru := cache.NewLRU(2)
+		Expect(lru.Set(ctx, "a", []byte("1"), time.Minute)).To(Succeed())
+		Expect(lru.Set(ctx, "b", []byte("2"), time.Minute)).To(Succeed())
+		_, ok, _ := lru.Get(ctx, "a")
+		Expect(ok).To(BeTrue())
+		Expect(lru.Set(ctx, "c", []byte("3"), time.Minute)).To(Succeed())
+
+		Expect(lru.Len()).To(Equal(2))
+		_, ok, _ = lru.Get(ctx, "b")
+		Expect(ok).To(BeFalse())
+		value, ok, _ := lru.Get(ctx, "a")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal([]byte("1")))
+	})
+
+	It("should reject URLs that are not redis://", func() {
+		_, err := cache.NewRedis("http://localhost:6379")
+		Expect(err).To(HaveOccurred())
+		_, err = cache.NewRedis("redis://localhost:6379/x")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+// redisStandIn serves GET, SET with PX, DEL, AUTH and SELECT over the Redis protocol, which is
+// all the cache uses
+type redisStandIn struct {
+	address  string
+	listener net.Listener
+	mutex    sync.Mutex
+	entries  map[string]redisEntry
+}
+
+type redisEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+func startRedisStandIn() *redisStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+	server := &redisStandIn{address: listener.Addr().String(), listener: listener, entries: map[string]redisEntry{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (server *redisStandIn) close() {
+	server.listener.Close()
+}
+
+func (server *redisStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := false
+	for {
+		args, err := readRedisCommand(reader)
+		if err != nil {
+			return
+		}
+		var reply string
+		switch command := strings.ToUpper(args[0]); {
+		case command == "AUTH":
+			authenticated = args[len(args)-1] == "secret"
+			reply = "+OK\r\n"
+			if !authenticated {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case command == "SELECT", command == "PING":
+			reply = "+OK\r\n"
+		case command == "GET":
+			reply = server.get(args[1])
+		case command == "SET" && len(args) == 5 && strings.ToUpper(args[3]) == "PX":
+			milliseconds, _ := strconv.Atoi(args[4])
+			server.mutex.Lock()
+			server.entries[args[1]] = redisEntry{args[2], time.Now().Add(time.Duration(milliseconds) * time.Millisecond)}
+			server.mutex.Unlock()
+			reply = "+OK\r\n"
+		case command == "DEL":
+			server.mutex.Lock()
+			deleted := 0
+			for _, key := range args[1:] {
+				if _, ok := server.entries[key]; ok {
+					delete(server.entries, key)
+					deleted++
+				}
+			}
+			server.mutex.Unlock()
+			reply = fmt.Sprintf(":%d\r\n", deleted)
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err = io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (server *redisStandIn) get(key string) string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	entry, ok := server.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return "$-1\r\n"
+	}
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(entry.value), entry.value)
+}
+
+func readRedisCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, length+2)
+		if _, err = io.ReadFull(reader, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:length])
+	}
+	return args, nil
+}
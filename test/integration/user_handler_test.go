@@ -1,6 +1,8 @@
 package handler_test
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -13,6 +15,8 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/redbonzai/user-management-api/internal/interfaces"
 	"github.com/redbonzai/user-management-api/internal/interfaces/handler"
+	"github.com/redbonzai/user-management-api/internal/middleware/authentication"
+	"github.com/redbonzai/user-management-api/internal/middleware/tenant"
 	"github.com/redbonzai/user-management-api/internal/services/mocks"
 )
 
@@ -23,6 +27,10 @@ var _ = Describe("UserHandler", func() {
 		userHandler *handler.UserHandler
 		mockCtrl    *gomock.Controller
 		userService *mocks.MockService
+		audit       *mocks.MockAuditService
+		attributes  *mocks.MockAttributeService
+		avatars     *mocks.MockAvatarService
+		hidden      map[string]bool
 	)
 
 	BeforeEach(func() {
@@ -30,7 +38,23 @@ var _ = Describe("UserHandler", func() {
 		rec = httptest.NewRecorder()
 		mockCtrl = gomock.NewController(GinkgoT())
 		userService = mocks.NewMockService(mockCtrl)
-		userHandler = handler.NewUserHandler(userService)
+		audit = mocks.NewMockAuditService(mockCtrl)
+		audit.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		attributes = mocks.NewMockAttributeService(mockCtrl)
+		attributes.EXPECT().View(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _, _ int, users []interfaces.User) ([]interfaces.User, error) {
+				return users, nil
+			},
+		).AnyTimes()
+		hidden = nil
+		attributes.EXPECT().HiddenAttributes(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _, _ int) (map[string]bool, error) { return hidden, nil },
+		).AnyTimes()
+		avatars = mocks.NewMockAvatarService(mockCtrl)
+		avatars.EXPECT().Attach(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ int, users []interfaces.User) ([]interfaces.User, error) { return users, nil },
+		).AnyTimes()
+		userHandler = handler.NewUserHandler(userService, audit, attributes, avatars)
 	})
 
 	AfterEach(func() {
@@ -44,7 +68,8 @@ var _ = Describe("UserHandler", func() {
 				{ID: 2, Name: "User Two", Email: "user2@example.com"},
 			}
 
-			userService.EXPECT().GetUsers().Return(users, nil)
+			attributes.EXPECT().ParseFilter(gomock.Any(), 1, 0, map[string]string{}).Return(nil, nil)
+			userService.EXPECT().GetUsers(gomock.Any(), 1, interfaces.UserFilter{}).Return(users, nil)
 
 			req := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
 			ctx := e.NewContext(req, rec)
@@ -55,13 +80,77 @@ var _ = Describe("UserHandler", func() {
 			Expect(rec.Body.String()).To(ContainSubstring("User One"))
 			Expect(rec.Body.String()).To(ContainSubstring("User Two"))
 		})
+
+		It("should filter by status and attributes", func() {
+			attributes.EXPECT().ParseFilter(gomock.Any(), 1, 0, map[string]string{"department": "sales"}).
+				Return(map[string]interface{}{"department": "sales"}, nil)
+			userService.EXPECT().GetUsers(gomock.Any(), 1, interfaces.UserFilter{
+				Statuses:   []string{"active", "invited"},
+				Attributes: map[string]interface{}{"department": "sales"},
+			}).Return([]interfaces.User{}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users?status=active,invited&attr.department=sales", nil)
+			ctx := e.NewContext(req, rec)
+
+			Expect(userHandler.GetUsers(ctx)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should reject filters on unknown attributes", func() {
+			attributes.EXPECT().ParseFilter(gomock.Any(), 1, 0, map[string]string{"salary": "1"}).
+				Return(nil, fmt.Errorf("%w: salary is not defined", interfaces.ErrInvalidAttribute))
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users?attr.salary=1", nil)
+			ctx := e.NewContext(req, rec)
+
+			Expect(userHandler.GetUsers(ctx)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("SearchUsers", func() {
+		It("should search with the list filters and a capped limit", func() {
+			attributes.EXPECT().ParseFilter(gomock.Any(), 1, 0, map[string]string{}).Return(nil, nil)
+			userService.EXPECT().SearchUsers(gomock.Any(), 1, "jon smi", interfaces.UserFilter{Statuses: []string{"active"}}, 5).
+				Return([]interfaces.UserSearchResult{{
+					User:       interfaces.User{ID: 3, Name: "Jonathan Smith"},
+					Score:      0.75,
+					Highlights: map[string]string{"name": "<mark>Jonathan</mark> <mark>Smith</mark>"},
+				}}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/search?q=jon+smi&status=active&limit=5", nil)
+			ctx := e.NewContext(req, rec)
+
+			Expect(userHandler.SearchUsers(ctx)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"score":0.75`))
+			Expect(rec.Body.String()).To(ContainSubstring(`"name":"Jonathan Smith"`))
+		})
+
+		It("should require search text", func() {
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/search?q=+", nil)
+			ctx := e.NewContext(req, rec)
+
+			Expect(userHandler.SearchUsers(ctx)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("SearchPrefixQuery", func() {
+		It("should match every word as a prefix and drop tsquery syntax", func() {
+			Expect(interfaces.SearchPrefixQuery("Jon O'Brien")).To(Equal("jon:* & o:* & brien:*"))
+			Expect(interfaces.SearchPrefixQuery("a|b & !c:*")).To(Equal("a:* & b:* & c:*"))
+
+			_, err := interfaces.SearchPrefixQuery("!@#")
+			Expect(err).To(MatchError(interfaces.ErrInvalidSearch))
+		})
 	})
 
 	Describe("GetUser", func() {
 		It("should return a user by ID", func() {
-			user := interfaces.User{ID: 1, Name: "User One", Email: "user1@example.com"}
+			user := interfaces.User{ID: 1, Name: "User One", Email: "user1@example.com", Version: 3}
 
-			userService.EXPECT().GetUserByID(1).Return(user, nil)
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(user, nil)
 
 			req := httptest.NewRequest(http.MethodGet, "/v1/users/1", nil)
 			ctx := e.NewContext(req, rec)
@@ -71,15 +160,48 @@ var _ = Describe("UserHandler", func() {
 			err := userHandler.GetUser(ctx)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("ETag")).To(Equal(`"3"`))
 			Expect(rec.Body.String()).To(ContainSubstring("User One"))
 		})
+
+		It("should pass the request's context to the service", func() {
+			requestCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			userService.EXPECT().GetUserByID(requestCtx, 1, 1).Return(interfaces.User{ID: 1}, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/1", nil).WithContext(requestCtx)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.GetUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should return 304 when the ETag still matches", func() {
+			user := interfaces.User{ID: 1, Name: "User One", Email: "user1@example.com", Version: 3}
+
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(user, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/users/1", nil)
+			req.Header.Set("If-None-Match", `W/"3"`)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.GetUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusNotModified))
+			Expect(rec.Body.Len()).To(BeZero())
+		})
 	})
 
 	Describe("CreateUser", func() {
 		It("should create a new user", func() {
 			user := interfaces.User{Username: "newuser", Password: "newpass", Name: "New User", Email: "new@example.com"}
 
-			userService.EXPECT().CreateUser(gomock.Any()).Return(user, nil)
+			userService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(user, nil)
 
 			req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{"username":"newuser","password":"newpass","name":"New User","email":"new@example.com"}`))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -90,18 +212,32 @@ var _ = Describe("UserHandler", func() {
 			Expect(rec.Code).To(Equal(http.StatusCreated))
 			Expect(rec.Body.String()).To(ContainSubstring(`"username":"newuser"`))
 		})
+
+		It("should reject invalid attributes", func() {
+			userService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+				Return(interfaces.User{}, fmt.Errorf("%w: locale is required", interfaces.ErrInvalidAttribute))
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{"username":"newuser","attributes":{}}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			ctx := e.NewContext(req, rec)
+
+			Expect(userHandler.CreateUser(ctx)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(rec.Body.String()).To(ContainSubstring("locale is required"))
+		})
 	})
 
 	Describe("UpdateUser", func() {
 		It("should update a user", func() {
-			existingUser := interfaces.User{ID: 1, Username: "existinguser", Password: "existingpass", Name: "Existing User", Email: "existing@example.com"}
-			updatedUser := interfaces.User{ID: 1, Username: "updateduser", Password: "updatedpass", Name: "Updated User", Email: "updated@example.com"}
+			existingUser := interfaces.User{ID: 1, Username: "existinguser", Password: "existingpass", Name: "Existing User", Email: "existing@example.com", Version: 2}
+			updatedUser := interfaces.User{ID: 1, Username: "updateduser", Password: "updatedpass", Name: "Updated User", Email: "updated@example.com", Version: 3}
 
-			userService.EXPECT().GetUserByID(1).Return(existingUser, nil)
-			userService.EXPECT().UpdateUser(gomock.Any()).Return(updatedUser, nil)
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(existingUser, nil)
+			userService.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Return(updatedUser, nil)
 
 			req := httptest.NewRequest(http.MethodPut, "/v1/users/1", strings.NewReader(`{"username":"updateduser","password":"updatedpass","name":"Updated User","email":"updated@example.com"}`))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("If-Match", `"2"`)
 			ctx := e.NewContext(req, rec)
 			ctx.SetParamNames("id")
 			ctx.SetParamValues("1")
@@ -109,17 +245,196 @@ var _ = Describe("UserHandler", func() {
 			err := userHandler.UpdateUser(ctx)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("ETag")).To(Equal(`"3"`))
 			Expect(rec.Body.String()).To(ContainSubstring(`"username":"updateduser"`))
 		})
+
+		It("should reject an update against a stale version", func() {
+			existingUser := interfaces.User{ID: 1, Username: "existinguser", Version: 4}
+
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(existingUser, nil)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/users/1", strings.NewReader(`{"name":"Updated User"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("If-Match", `"2"`)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.UpdateUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+		})
+
+		It("should clear fields set to null by a merge patch", func() {
+			existingUser := interfaces.User{
+				ID: 1, Username: "existinguser", Name: "Existing User", Email: "existing@example.com",
+				Attributes: map[string]interface{}{"department": "sales"}, Version: 2,
+			}
+
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(existingUser, nil)
+			userService.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user interfaces.User) (interfaces.User, error) {
+				Expect(user.Attributes).To(BeNil())
+				Expect(user.Name).To(BeEmpty())
+				Expect(user.Username).To(Equal("existinguser"))
+				user.Version++
+				return user, nil
+			})
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/users/1", strings.NewReader(`{"attributes":null,"name":""}`))
+			req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+			req.Header.Set("If-Match", `"2"`)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.UpdateUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("should reject status changes, which go through the lifecycle endpoints", func() {
+			existingUser := interfaces.User{ID: 1, Username: "existinguser", Email: "existing@example.com", Version: 2}
+
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(existingUser, nil)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/users/1", strings.NewReader(`{"status":"suspended"}`))
+			req.Header.Set(echo.HeaderContentType, "application/merge-patch+json")
+			req.Header.Set("If-Match", `"2"`)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.UpdateUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(rec.Body.String()).To(ContainSubstring("status is immutable"))
+		})
+
+		It("should reject JSON Patch changes to immutable fields", func() {
+			existingUser := interfaces.User{ID: 1, Username: "existinguser", Email: "existing@example.com", Version: 2}
+
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(existingUser, nil)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/users/1", strings.NewReader(`[{"op":"replace","path":"/id","value":7}]`))
+			req.Header.Set(echo.HeaderContentType, "application/json-patch+json")
+			req.Header.Set("If-Match", `"2"`)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.UpdateUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(rec.Body.String()).To(ContainSubstring("id is immutable"))
+		})
+
+		It("should report a failed JSON Patch test operation as a conflict", func() {
+			existingUser := interfaces.User{ID: 1, Username: "existinguser", Email: "existing@example.com", Version: 2}
+
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(existingUser, nil)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/users/1", strings.NewReader(`[{"op":"test","path":"/username","value":"someone"},{"op":"replace","path":"/name","value":"X"}]`))
+			req.Header.Set(echo.HeaderContentType, "application/json-patch+json")
+			req.Header.Set("If-Match", `"2"`)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.UpdateUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+		})
+
+		Context("with attributes the caller may not see", func() {
+			var existingUser interfaces.User
+
+			BeforeEach(func() {
+				hidden = map[string]bool{"salary": true}
+				existingUser = interfaces.User{
+					ID: 1, Username: "existinguser", Email: "existing@example.com", Version: 2,
+					Attributes: map[string]interface{}{"department": "sales", "salary": 50000.0},
+				}
+				userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(existingUser, nil)
+			})
+
+			patch := func(contentType, body string) {
+				req := httptest.NewRequest(http.MethodPatch, "/v1/users/1", strings.NewReader(body))
+				req.Header.Set(echo.HeaderContentType, contentType)
+				req.Header.Set("If-Match", `"2"`)
+				ctx := e.NewContext(req, rec)
+				ctx.SetParamNames("id")
+				ctx.SetParamValues("1")
+				Expect(userHandler.UpdateUser(ctx)).To(Succeed())
+			}
+
+			It("should keep their values when the visible attributes are replaced", func() {
+				userService.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, user interfaces.User) (interfaces.User, error) {
+					Expect(user.Attributes).To(Equal(map[string]interface{}{"department": "support", "salary": 50000.0}))
+					return user, nil
+				})
+
+				patch("application/json-patch+json", `[{"op":"replace","path":"/attributes","value":{"department":"support"}}]`)
+				Expect(rec.Code).To(Equal(http.StatusOK))
+			})
+
+			It("should reject a merge patch that changes them", func() {
+				patch("application/merge-patch+json", `{"attributes":{"salary":90000}}`)
+				Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+				Expect(rec.Body.String()).To(ContainSubstring("salary is not defined"))
+			})
+
+			It("should reject a JSON Patch that tests them", func() {
+				patch("application/json-patch+json", `[{"op":"test","path":"/attributes/salary","value":50000}]`)
+				Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+
+			It("should reject a JSON Patch that copies them elsewhere", func() {
+				patch("application/json-patch+json", `[{"op":"copy","from":"/attributes/salary","path":"/name"}]`)
+				Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
+		It("should require If-Match", func() {
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(interfaces.User{ID: 1, Version: 4}, nil)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/users/1", strings.NewReader(`{"name":"Updated User"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.UpdateUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusPreconditionRequired))
+		})
+
+		It("should reject a weak If-Match tag, which If-Match compares strongly", func() {
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(interfaces.User{ID: 1, Version: 4}, nil)
+
+			req := httptest.NewRequest(http.MethodPatch, "/v1/users/1", strings.NewReader(`{"name":"Updated User"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set("If-Match", `W/"4"`)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.UpdateUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+			Expect(rec.Header().Get("ETag")).To(Equal(`"4"`))
+		})
 	})
 
 	Describe("DeleteUser", func() {
 		It("should delete a user", func() {
-			user := interfaces.User{ID: 1, Name: "User One", Email: "user1@example.com"}
+			user := interfaces.User{ID: 1, Name: "User One", Email: "user1@example.com", Version: 5}
 
-			userService.EXPECT().DeleteUser(1).Return(user, nil)
+			userService.EXPECT().GetUserByID(gomock.Any(), 1, 1).Return(user, nil)
+			userService.EXPECT().DeleteUser(gomock.Any(), 1, 1, 5).Return(user, nil)
 
 			req := httptest.NewRequest(http.MethodDelete, "/v1/users/1", nil)
+			req.Header.Set("If-Match", `"5"`)
 			ctx := e.NewContext(req, rec)
 			ctx.SetParamNames("id")
 			ctx.SetParamValues("1")
@@ -131,12 +446,44 @@ var _ = Describe("UserHandler", func() {
 		})
 	})
 
+	Describe("RestoreUser", func() {
+		It("should restore a deleted user", func() {
+			user := interfaces.User{ID: 1, Name: "User One", Email: "user1@example.com"}
+
+			userService.EXPECT().RestoreUser(gomock.Any(), 1, 1).Return(user, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/1/restore", nil)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.RestoreUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring("User One"))
+		})
+
+		It("should report a conflict when the username was taken in the meantime", func() {
+			userService.EXPECT().RestoreUser(gomock.Any(), 1, 1).Return(interfaces.User{}, interfaces.ErrUserConflict)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/1/restore", nil)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("1")
+
+			err := userHandler.RestoreUser(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+		})
+	})
+
 	Describe("Login", func() {
 		It("should login a user and return a token", func() {
 			user := interfaces.User{ID: 1, Username: "testuser", Password: "$2a$10$7.qGVUb5v4PQcK/n1Ub0RODnpDFnx/38TF/1ntCR3IUmY/ma1DLG2", Name: "Test User", Email: "test@example.com"} // hashed password for "testpass"
 
-			userService.EXPECT().GetUserByUsername("testuser").Return(user, nil)
+			userService.EXPECT().GetUserByUsername(gomock.Any(), 1, "testuser").Return(user, nil)
 			userService.EXPECT().HashPassword(gomock.Any()).Return(user.Password, nil)
+			userService.EXPECT().RecordLogin(gomock.Any(), user).Return(nil)
 
 			req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"testuser","password":"testpass"}`))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -163,7 +510,7 @@ var _ = Describe("UserHandler", func() {
 			ctx.Set("user", token)
 
 			expTime := time.Unix(token.Claims.(jwt.MapClaims)["exp"].(int64), 0)
-			userService.EXPECT().Logout(tokenString, expTime).Return(nil)
+			userService.EXPECT().Logout(gomock.Any(), 0, tokenString, expTime).Return(nil)
 
 			err := userHandler.Logout(ctx)
 			Expect(err).ToNot(HaveOccurred())
@@ -177,9 +524,8 @@ var _ = Describe("UserHandler", func() {
 			user := interfaces.User{Username: "newuser", Password: "$2a$10$7.qGVUb5v4PQcK/n1Ub0RODnpDFnx/38TF/1ntCR3IUmY/ma1DLG2", Name: "New User", Email: "new@example.com"} // hashed password for "newpass"
 			registerRequest := interfaces.RegisterRequest{Username: "newuser", Password: "newpass", Name: "New User", Email: "new@example.com"}
 
-			userService.EXPECT().IsUsernameUnique(registerRequest.Username).Return(true, nil)
 			userService.EXPECT().HashPassword(registerRequest.Password).Return(user.Password, nil)
-			userService.EXPECT().CreateUser(gomock.Any()).Return(user, nil)
+			userService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(user, nil)
 
 			req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"username":"newuser","password":"newpass","name":"New User","email":"new@example.com"}`))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -190,24 +536,57 @@ var _ = Describe("UserHandler", func() {
 			Expect(rec.Code).To(Equal(http.StatusCreated))
 			Expect(rec.Body.String()).To(ContainSubstring(`"username":"newuser"`))
 		})
+
+		It("should name the field an existing user already holds", func() {
+			userService.EXPECT().HashPassword("newpass").Return("hashed", nil)
+			userService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(interfaces.User{}, interfaces.ErrEmailTaken)
+
+			req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"username":"newuser","password":"newpass","name":"New User","email":"New@Example.com"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			ctx := e.NewContext(req, rec)
+
+			err := userHandler.Register(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Body.String()).To(ContainSubstring(`"field":"email"`))
+		})
+		It("should only register users in the default organization", func() {
+			req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"username":"newuser","password":"newpass"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(tenant.HeaderOrganizationID, "2")
+			ctx := e.NewContext(req, rec)
+
+			Expect(userHandler.Register(ctx)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+
+			userService.EXPECT().HashPassword("newpass").Return("hashed", nil)
+			userService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, user interfaces.User) (interfaces.User, error) {
+					Expect(user.OrganizationID).To(Equal(tenant.DefaultOrganizationID))
+					return user, nil
+				})
+			rec = httptest.NewRecorder()
+			req = httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(`{"username":"newuser","password":"newpass"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(tenant.HeaderOrganizationID, "1")
+			ctx = e.NewContext(req, rec)
+			ctx.Set(tenant.ContextKey, 2)
+
+			Expect(userHandler.Register(ctx)).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+		})
+
 	})
 
 	Describe("GetAuthenticatedUser", func() {
 		It("should return the authenticated user", func() {
 			user := interfaces.User{ID: 1, Username: "testuser", Name: "Test User", Email: "test@example.com"}
 
-			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-				"username": "testuser",
-				"exp":      time.Now().Add(time.Hour * 1).Unix(),
-			})
-			tokenString, _ := token.SignedString([]byte("secret"))
-
-			userService.EXPECT().GetUserByUsername("testuser").Return(user, nil)
+			userService.EXPECT().GetUserByUsername(gomock.Any(), 1, "testuser").Return(user, nil)
 
 			req := httptest.NewRequest(http.MethodGet, "/v1/current-user", nil)
-			req.Header.Set(echo.HeaderAuthorization, "Bearer "+tokenString)
 			ctx := e.NewContext(req, rec)
-			ctx.Set("user", token.Claims.(jwt.MapClaims))
+			ctx.Set(authentication.ClaimsContextKey, &authentication.Claims{Username: "testuser", UserID: 1, OrganizationID: 1})
 
 			err := userHandler.GetAuthenticatedUser(ctx)
 			Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,157 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redbonzai/user-management-api/internal/interfaces"
+	"github.com/redbonzai/user-management-api/internal/interfaces/handler"
+	"github.com/redbonzai/user-management-api/internal/interfaces/repository/memory"
+	repositorymocks "github.com/redbonzai/user-management-api/internal/interfaces/repository/mocks"
+	"github.com/redbonzai/user-management-api/internal/services"
+	"github.com/redbonzai/user-management-api/internal/services/mocks"
+)
+
+var _ = Describe("User status lifecycle", func() {
+	var mockCtrl *gomock.Controller
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	Describe("CheckTransition", func() {
+		It("should allow the lifecycle's transitions only", func() {
+			Expect(interfaces.CheckTransition(interfaces.UserStatusActive, interfaces.UserStatusSuspended)).To(Succeed())
+			Expect(interfaces.CheckTransition(interfaces.UserStatusSuspended, interfaces.UserStatusActive)).To(Succeed())
+			Expect(interfaces.CheckTransition(interfaces.UserStatusPending, interfaces.UserStatusSuspended)).
+				To(MatchError(interfaces.ErrIllegalTransition))
+			Expect(interfaces.CheckTransition(interfaces.UserStatusActive, interfaces.UserStatusActive)).
+				To(MatchError(interfaces.ErrIllegalTransition))
+			Expect(interfaces.CheckTransition(interfaces.UserStatusDeleted, interfaces.UserStatusActive)).
+				To(MatchError(interfaces.ErrIllegalTransition))
+		})
+
+		It("should only let active users authenticate", func() {
+			suspended := interfaces.UserStatusSuspended
+			Expect(interfaces.CanAuthenticate(interfaces.User{})).To(BeTrue())
+			Expect(interfaces.CanAuthenticate(interfaces.User{Status: &suspended})).To(BeFalse())
+		})
+	})
+
+	Describe("UserService", func() {
+		var (
+			userRepo    *repositorymocks.MockRepository
+			userService interfaces.Service
+		)
+
+		BeforeEach(func() {
+			userRepo = repositorymocks.NewMockRepository(mockCtrl)
+			audit := mocks.NewMockAuditService(mockCtrl)
+			audit.EXPECT().Record(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			attributes := mocks.NewMockAttributeService(mockCtrl)
+			attributes.EXPECT().ClaimUniqueValues(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			userService = services.NewService(
+				userRepo,
+				attributes,
+				passthroughTx(mockCtrl),
+				memory.NewOutboxRepository(),
+				audit,
+			)
+		})
+
+		It("should record the transition with its reason and author", func() {
+			userRepo.EXPECT().GetByID(gomock.Any(), 1, 7).Return(interfaces.User{ID: 7, OrganizationID: 1}, nil)
+			userRepo.EXPECT().ChangeStatus(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, transition interfaces.StatusTransition) (interfaces.User, error) {
+				Expect(transition.FromStatus).To(Equal(interfaces.UserStatusActive))
+				Expect(transition.ToStatus).To(Equal(interfaces.UserStatusSuspended))
+				Expect(transition.Reason).To(Equal("chargeback"))
+				Expect(*transition.ChangedBy).To(Equal(3))
+				return interfaces.User{ID: 7, Status: &transition.ToStatus}, nil
+			})
+
+			user, err := userService.ChangeUserStatus(context.Background(), 1, 7, interfaces.UserStatusSuspended, "chargeback", 3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(interfaces.StatusOf(user)).To(Equal(interfaces.UserStatusSuspended))
+		})
+
+		It("should reject an illegal transition without writing", func() {
+			pending := interfaces.UserStatusPending
+			userRepo.EXPECT().GetByID(gomock.Any(), 1, 7).Return(interfaces.User{ID: 7, Status: &pending}, nil)
+
+			_, err := userService.ChangeUserStatus(context.Background(), 1, 7, interfaces.UserStatusLocked, "", 3)
+			Expect(err).To(MatchError(interfaces.ErrIllegalTransition))
+		})
+
+		It("should not create users in a status they must transition to", func() {
+			suspended := interfaces.UserStatusSuspended
+			_, err := userService.CreateUser(context.Background(), interfaces.User{Username: "jane", Status: &suspended})
+			Expect(err).To(MatchError(interfaces.ErrInvalidStatus))
+			Expect(err).To(MatchError(interfaces.ErrInvalidUser))
+		})
+	})
+
+	Describe("UserHandler", func() {
+		var (
+			e           *echo.Echo
+			rec         *httptest.ResponseRecorder
+			userService *mocks.MockService
+			userHandler *handler.UserHandler
+		)
+
+		BeforeEach(func() {
+			e = echo.New()
+			rec = httptest.NewRecorder()
+			userService = mocks.NewMockService(mockCtrl)
+			audit := mocks.NewMockAuditService(mockCtrl)
+			attributes := mocks.NewMockAttributeService(mockCtrl)
+			attributes.EXPECT().View(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, _, _ int, users []interfaces.User) ([]interfaces.User, error) {
+					return users, nil
+				},
+			).AnyTimes()
+			avatars := mocks.NewMockAvatarService(mockCtrl)
+			avatars.EXPECT().Attach(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ int, users []interfaces.User) ([]interfaces.User, error) { return users, nil },
+			).AnyTimes()
+			userHandler = handler.NewUserHandler(userService, audit, attributes, avatars)
+		})
+
+		suspend := func(body string) echo.Context {
+			req := httptest.NewRequest(http.MethodPost, "/v1/users/7/suspend", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("id")
+			ctx.SetParamValues("7")
+			return ctx
+		}
+
+		It("should suspend a user with the reason", func() {
+			suspended := interfaces.UserStatusSuspended
+			userService.EXPECT().ChangeUserStatus(gomock.Any(), 1, 7, interfaces.UserStatusSuspended, "chargeback", 0).
+				Return(interfaces.User{ID: 7, Status: &suspended, Version: 2}, nil)
+
+			Expect(userHandler.SuspendUser(suspend(`{"reason":"chargeback"}`))).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"status":"suspended"`))
+		})
+
+		It("should answer 422 to an illegal transition", func() {
+			deactivated := interfaces.UserStatusDeactivated
+			userService.EXPECT().ChangeUserStatus(gomock.Any(), 1, 7, interfaces.UserStatusSuspended, "", 0).
+				Return(interfaces.User{}, interfaces.CheckTransition(deactivated, interfaces.UserStatusSuspended))
+
+			Expect(userHandler.SuspendUser(suspend(`{}`))).To(Succeed())
+			Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		})
+	})
+})